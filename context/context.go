@@ -7,7 +7,9 @@ package context
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/omec-project/openapi/models"
 )
@@ -47,6 +49,8 @@ type UDRContext struct {
 	UESubsCollection                        sync.Map // map[ueId]*UESubsData
 	UEGroupCollection                       sync.Map // map[ueGroupId]*UEGroupSubsData
 	mtx                                     sync.RWMutex
+	subscriptionDataMtx                     sync.RWMutex
+	policyDataMtx                           sync.RWMutex
 	SBIPort                                 int
 	EeSubscriptionIDGenerator               int
 	SdmSubscriptionIDGenerator              int
@@ -79,16 +83,20 @@ func (context *UDRContext) Reset() {
 		context.UEGroupCollection.Delete(key)
 		return true
 	})
+	context.subscriptionDataMtx.Lock()
 	for key := range context.SubscriptionDataSubscriptions {
 		delete(context.SubscriptionDataSubscriptions, key)
 	}
+	context.SubscriptionDataSubscriptionIDGenerator = 1
+	context.subscriptionDataMtx.Unlock()
+	context.policyDataMtx.Lock()
 	for key := range context.PolicyDataSubscriptions {
 		delete(context.PolicyDataSubscriptions, key)
 	}
+	context.PolicyDataSubscriptionIDGenerator = 1
+	context.policyDataMtx.Unlock()
 	context.EeSubscriptionIDGenerator = 1
 	context.SdmSubscriptionIDGenerator = 1
-	context.SubscriptionDataSubscriptionIDGenerator = 1
-	context.PolicyDataSubscriptionIDGenerator = 1
 	context.UriScheme = models.UriScheme_HTTPS
 	context.Name = "udr"
 }
@@ -121,3 +129,105 @@ func (context *UDRContext) NewAppDataInfluDataSubscriptionID() uint64 {
 	context.appDataInfluDataSubscriptionIdGenerator++
 	return context.appDataInfluDataSubscriptionIdGenerator
 }
+
+// NewSubscriptionDataSubscription stores subscription and returns its ID.
+func (context *UDRContext) NewSubscriptionDataSubscription(subscription *models.SubscriptionDataSubscriptions) string {
+	context.subscriptionDataMtx.Lock()
+	defer context.subscriptionDataMtx.Unlock()
+	subscriptionId := strconv.Itoa(context.SubscriptionDataSubscriptionIDGenerator)
+	context.SubscriptionDataSubscriptions[subscriptionId] = subscription
+	context.SubscriptionDataSubscriptionIDGenerator++
+	return subscriptionId
+}
+
+// DeleteSubscriptionDataSubscription returns false if there is no such
+// subscription.
+func (context *UDRContext) DeleteSubscriptionDataSubscription(subscriptionId string) bool {
+	context.subscriptionDataMtx.Lock()
+	defer context.subscriptionDataMtx.Unlock()
+	if _, ok := context.SubscriptionDataSubscriptions[subscriptionId]; !ok {
+		return false
+	}
+	delete(context.SubscriptionDataSubscriptions, subscriptionId)
+	return true
+}
+
+// SubscriptionDataSubscribers returns the subscriptions to the data of ueId
+// which have not expired at now.
+func (context *UDRContext) SubscriptionDataSubscribers(ueId string, now time.Time) []models.SubscriptionDataSubscriptions {
+	context.subscriptionDataMtx.RLock()
+	defer context.subscriptionDataMtx.RUnlock()
+	var subscriptions []models.SubscriptionDataSubscriptions
+	for _, subscription := range context.SubscriptionDataSubscriptions {
+		if subscription.UeId == ueId && !subscriptionExpired(subscription.Expiry, now) {
+			subscriptions = append(subscriptions, *subscription)
+		}
+	}
+	return subscriptions
+}
+
+// DeleteExpiredSubscriptionDataSubscriptions deletes the subscriptions
+// expired at now and returns their IDs.
+func (context *UDRContext) DeleteExpiredSubscriptionDataSubscriptions(now time.Time) []string {
+	context.subscriptionDataMtx.Lock()
+	defer context.subscriptionDataMtx.Unlock()
+	var expired []string
+	for subscriptionId, subscription := range context.SubscriptionDataSubscriptions {
+		if subscriptionExpired(subscription.Expiry, now) {
+			delete(context.SubscriptionDataSubscriptions, subscriptionId)
+			expired = append(expired, subscriptionId)
+		}
+	}
+	return expired
+}
+
+// NewPolicyDataSubscription stores subscription and returns its ID.
+func (context *UDRContext) NewPolicyDataSubscription(subscription *models.PolicyDataSubscription) string {
+	context.policyDataMtx.Lock()
+	defer context.policyDataMtx.Unlock()
+	subscriptionId := strconv.Itoa(context.PolicyDataSubscriptionIDGenerator)
+	context.PolicyDataSubscriptions[subscriptionId] = subscription
+	context.PolicyDataSubscriptionIDGenerator++
+	return subscriptionId
+}
+
+// ReplacePolicyDataSubscription returns false if there is no such
+// subscription.
+func (context *UDRContext) ReplacePolicyDataSubscription(subscriptionId string,
+	subscription *models.PolicyDataSubscription,
+) bool {
+	context.policyDataMtx.Lock()
+	defer context.policyDataMtx.Unlock()
+	if _, ok := context.PolicyDataSubscriptions[subscriptionId]; !ok {
+		return false
+	}
+	context.PolicyDataSubscriptions[subscriptionId] = subscription
+	return true
+}
+
+// DeletePolicyDataSubscription returns false if there is no such
+// subscription.
+func (context *UDRContext) DeletePolicyDataSubscription(subscriptionId string) bool {
+	context.policyDataMtx.Lock()
+	defer context.policyDataMtx.Unlock()
+	if _, ok := context.PolicyDataSubscriptions[subscriptionId]; !ok {
+		return false
+	}
+	delete(context.PolicyDataSubscriptions, subscriptionId)
+	return true
+}
+
+// PolicyDataSubscribers returns a snapshot of the policy data subscriptions.
+func (context *UDRContext) PolicyDataSubscribers() []models.PolicyDataSubscription {
+	context.policyDataMtx.RLock()
+	defer context.policyDataMtx.RUnlock()
+	subscriptions := make([]models.PolicyDataSubscription, 0, len(context.PolicyDataSubscriptions))
+	for _, subscription := range context.PolicyDataSubscriptions {
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return subscriptions
+}
+
+func subscriptionExpired(expiry *time.Time, now time.Time) bool {
+	return expiry != nil && !now.Before(*expiry)
+}
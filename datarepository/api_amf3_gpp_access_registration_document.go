@@ -55,23 +55,16 @@ func HTTPAmfContext3gpp(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &patchItemArray, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, patchItemArray)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
 	rsp := producer.HandleAmfContext3gpp(req)
 
-	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-		problemDetails := models.ProblemDetails{
-			Status: http.StatusInternalServerError,
-			Cause:  "SYSTEM_FAILURE",
-			Detail: err.Error(),
-		}
-		c.JSON(http.StatusInternalServerError, problemDetails)
-	} else {
-		c.Data(rsp.Status, "application/json", responseBody)
-	}
+	sendResponse(c, rsp)
 }
 
 // HTTPCreateAmfContext3gpp - To store the AMF context data of a UE using 3gpp access in the UDR
@@ -104,23 +97,16 @@ func HTTPCreateAmfContext3gpp(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &amf3GppAccessRegistration, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, amf3GppAccessRegistration)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
 	rsp := producer.HandleCreateAmfContext3gpp(req)
 
-	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-		problemDetails := models.ProblemDetails{
-			Status: http.StatusInternalServerError,
-			Cause:  "SYSTEM_FAILURE",
-			Detail: err.Error(),
-		}
-		c.JSON(http.StatusInternalServerError, problemDetails)
-	} else {
-		c.Data(rsp.Status, "application/json", responseBody)
-	}
+	sendResponse(c, rsp)
 }
 
 // HTTPQueryAmfContext3gpp - Retrieves the AMF context data of a UE using 3gpp access
@@ -130,16 +116,5 @@ func HTTPQueryAmfContext3gpp(c *gin.Context) {
 
 	rsp := producer.HandleQueryAmfContext3gpp(req)
 
-	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-		problemDetails := models.ProblemDetails{
-			Status: http.StatusInternalServerError,
-			Cause:  "SYSTEM_FAILURE",
-			Detail: err.Error(),
-		}
-		c.JSON(http.StatusInternalServerError, problemDetails)
-	} else {
-		c.Data(rsp.Status, "application/json", responseBody)
-	}
+	sendResponse(c, rsp)
 }
@@ -55,23 +55,16 @@ func HTTPAmfContextNon3gpp(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &patchItemArray, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, patchItemArray)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
 	rsp := producer.HandleAmfContextNon3gpp(req)
 
-	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-		problemDetails := models.ProblemDetails{
-			Status: http.StatusInternalServerError,
-			Cause:  "SYSTEM_FAILURE",
-			Detail: err.Error(),
-		}
-		c.JSON(http.StatusInternalServerError, problemDetails)
-	} else {
-		c.Data(rsp.Status, "application/json", responseBody)
-	}
+	sendResponse(c, rsp)
 }
 
 // HTTPCreateAmfContextNon3gpp - To store the AMF context data of a UE using non-3gpp access in the UDR
@@ -104,23 +97,16 @@ func HTTPCreateAmfContextNon3gpp(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &amfNon3GppAccessRegistration, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, amfNon3GppAccessRegistration)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
 	rsp := producer.HandleCreateAmfContextNon3gpp(req)
 
-	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-		problemDetails := models.ProblemDetails{
-			Status: http.StatusInternalServerError,
-			Cause:  "SYSTEM_FAILURE",
-			Detail: err.Error(),
-		}
-		c.JSON(http.StatusInternalServerError, problemDetails)
-	} else {
-		c.Data(rsp.Status, "application/json", responseBody)
-	}
+	sendResponse(c, rsp)
 }
 
 // HTTPQueryAmfContextNon3gpp - Retrieves the AMF context data of a UE using non-3gpp access
@@ -130,16 +116,5 @@ func HTTPQueryAmfContextNon3gpp(c *gin.Context) {
 
 	rsp := producer.HandleQueryAmfContextNon3gpp(req)
 
-	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-		problemDetails := models.ProblemDetails{
-			Status: http.StatusInternalServerError,
-			Cause:  "SYSTEM_FAILURE",
-			Detail: err.Error(),
-		}
-		c.JSON(http.StatusInternalServerError, problemDetails)
-	} else {
-		c.Data(rsp.Status, "application/json", responseBody)
-	}
+	sendResponse(c, rsp)
 }
@@ -0,0 +1,19 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package datarepository
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/omec-project/udr/producer"
+	"github.com/omec-project/util/httpwrapper"
+)
+
+// HTTPQueryAmfRegistrationHistory - lists the AMF registrations of a UE
+// replaced by another AMF
+func HTTPQueryAmfRegistrationHistory(c *gin.Context) {
+	req := httpwrapper.NewRequest(c.Request, nil)
+	req.Params["ueId"] = c.Params.ByName("ueId")
+
+	rsp := producer.HandleQueryAmfRegistrationHistory(req)
+	sendResponse(c, rsp)
+}
@@ -55,6 +55,10 @@ func HTTPModifyAmfSubscriptionInfo(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &patchItemArray, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, patchItemArray)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 	req.Params["subsId"] = c.Params.ByName("subsId")
@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package datarepository
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/omec-project/udr/producer"
+)
+
+// HTTPApplicationDataResourceGet - retrieves the application data of resource
+func HTTPApplicationDataResourceGet(resource *producer.ApplicationDataResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rsp := producer.HandleApplicationDataResourceGet(resource, c.Request.URL.Query())
+		sendResponse(c, rsp)
+	}
+}
+
+// HTTPApplicationDataResourceIdGet - retrieves an individual application data resource
+func HTTPApplicationDataResourceIdGet(resource *producer.ApplicationDataResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rsp := producer.HandleApplicationDataResourceIdGet(resource, c.Params.ByName(resource.IdParam))
+		sendResponse(c, rsp)
+	}
+}
+
+// HTTPApplicationDataResourceIdPut - creates or replaces an individual application data resource
+func HTTPApplicationDataResourceIdPut(resource *producer.ApplicationDataResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqBody, err := getRawRequestBody(c)
+		if err != nil {
+			return
+		}
+		rsp := producer.HandleApplicationDataResourceIdPut(resource, c.Params.ByName(resource.IdParam), reqBody)
+		sendResponse(c, rsp)
+	}
+}
+
+// HTTPApplicationDataResourceIdPatch - modifies an individual application data resource
+func HTTPApplicationDataResourceIdPatch(resource *producer.ApplicationDataResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqBody, err := getRawRequestBody(c)
+		if err != nil {
+			return
+		}
+		rsp := producer.HandleApplicationDataResourceIdPatch(resource, c.Params.ByName(resource.IdParam), reqBody)
+		sendResponse(c, rsp)
+	}
+}
+
+// HTTPApplicationDataResourceIdDelete - deletes an individual application data resource
+func HTTPApplicationDataResourceIdDelete(resource *producer.ApplicationDataResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rsp := producer.HandleApplicationDataResourceIdDelete(resource, c.Params.ByName(resource.IdParam))
+		sendResponse(c, rsp)
+	}
+}
+
+// HTTPApplicationDataSubsToNotifyPost - creates a subscription to application data changes
+func HTTPApplicationDataSubsToNotifyPost(c *gin.Context) {
+	var applicationDataSubs producer.ApplicationDataSubs
+
+	if err := getDataFromRequestBody(c, &applicationDataSubs); err != nil {
+		return
+	}
+
+	rsp := producer.HandleApplicationDataSubsToNotifyPost(&applicationDataSubs)
+	sendResponse(c, rsp)
+}
+
+// HTTPApplicationDataSubsToNotifySubsIdGet - retrieves a subscription to application data changes
+func HTTPApplicationDataSubsToNotifySubsIdGet(c *gin.Context) {
+	rsp := producer.HandleApplicationDataSubsToNotifySubsIdGet(c.Params.ByName("subsId"))
+	sendResponse(c, rsp)
+}
+
+// HTTPApplicationDataSubsToNotifySubsIdPut - replaces a subscription to application data changes
+func HTTPApplicationDataSubsToNotifySubsIdPut(c *gin.Context) {
+	var applicationDataSubs producer.ApplicationDataSubs
+
+	if err := getDataFromRequestBody(c, &applicationDataSubs); err != nil {
+		return
+	}
+
+	rsp := producer.HandleApplicationDataSubsToNotifySubsIdPut(c.Params.ByName("subsId"), &applicationDataSubs)
+	sendResponse(c, rsp)
+}
+
+// HTTPApplicationDataSubsToNotifySubsIdDelete - deletes a subscription to application data changes
+func HTTPApplicationDataSubsToNotifySubsIdDelete(c *gin.Context) {
+	rsp := producer.HandleApplicationDataSubsToNotifySubsIdDelete(c.Params.ByName("subsId"))
+	sendResponse(c, rsp)
+}
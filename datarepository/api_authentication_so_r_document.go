@@ -55,6 +55,10 @@ func HTTPCreateAuthenticationSoR(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &sorData, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, sorData)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
@@ -55,6 +55,10 @@ func HTTPCreateAuthenticationStatus(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &authEvent, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, authEvent)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package datarepository
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/omec-project/udr/producer"
+	"github.com/omec-project/util/httpwrapper"
+)
+
+// HTTPGetContextData - retrieves the context data of a UE
+func HTTPGetContextData(resource *producer.ContextDataResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rsp := producer.HandleGetContextData(resource, contextDataRequest(c, resource, nil))
+		sendResponse(c, rsp)
+	}
+}
+
+// HTTPCreateContextData - creates or replaces the context data of a UE
+func HTTPCreateContextData(resource *producer.ContextDataResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqBody, err := getRawRequestBody(c)
+		if err != nil {
+			return
+		}
+		rsp := producer.HandleCreateContextData(resource, contextDataRequest(c, resource, reqBody))
+		sendResponse(c, rsp)
+	}
+}
+
+// HTTPModifyContextData - modifies the context data of a UE
+func HTTPModifyContextData(resource *producer.ContextDataResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqBody, err := getRawRequestBody(c)
+		if err != nil {
+			return
+		}
+		rsp := producer.HandleModifyContextData(resource, contextDataRequest(c, resource, reqBody))
+		sendResponse(c, rsp)
+	}
+}
+
+// HTTPDeleteContextData - deletes the context data of a UE
+func HTTPDeleteContextData(resource *producer.ContextDataResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rsp := producer.HandleDeleteContextData(resource, contextDataRequest(c, resource, nil))
+		sendResponse(c, rsp)
+	}
+}
+
+// contextDataRequest returns the request for the context data of resource
+// with the UE and the key of its path.
+func contextDataRequest(c *gin.Context, resource *producer.ContextDataResource, body interface{}) *httpwrapper.Request {
+	req := httpwrapper.NewRequest(c.Request, body)
+	req.Params["ueId"] = c.Params.ByName("ueId")
+	if resource.KeyParam != "" {
+		req.Params[resource.KeyParam] = c.Params.ByName(resource.KeyParam)
+	}
+	return req
+}
+
+// contextDataRoutes returns the routes of the context data resources.
+func contextDataRoutes(resources ...*producer.ContextDataResource) Routes {
+	var routes Routes
+	for _, resource := range resources {
+		// gin cannot tell "context-data" apart from ":servingPlmnId" in the
+		// other subscription data routes, contextDataOnly does
+		pattern := "/subscription-data/:ueId/:servingPlmnId/" + resource.Path
+		if resource.KeyParam != "" {
+			pattern += "/:" + resource.KeyParam
+		}
+		routes = append(routes,
+			Route{"HTTPGetContextData " + resource.Path, "GET", pattern,
+				contextDataOnly(HTTPGetContextData(resource))},
+			Route{"HTTPCreateContextData " + resource.Path, "PUT", pattern,
+				contextDataOnly(HTTPCreateContextData(resource))},
+			Route{"HTTPModifyContextData " + resource.Path, "PATCH", pattern,
+				contextDataOnly(HTTPModifyContextData(resource))},
+			Route{"HTTPDeleteContextData " + resource.Path, "DELETE", pattern,
+				contextDataOnly(HTTPDeleteContextData(resource))},
+		)
+	}
+	return routes
+}
+
+// contextDataOnly restricts handler to the paths under context-data.
+func contextDataOnly(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Param("servingPlmnId") != contextDataStr {
+			c.String(http.StatusNotFound, "404 page not found")
+			return
+		}
+		handler(c)
+	}
+}
@@ -15,6 +15,7 @@
 package datarepository
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -57,7 +58,30 @@ func getDataFromRequestBody(c *gin.Context, data interface{}) error {
 		c.JSON(http.StatusBadRequest, pd)
 		return err
 	}
-	return err
+	return validateRequestBody(c, data, reqBody)
+}
+
+// validateRequestBody checks the request body against the model of data and
+// answers the request with the attributes which do not match it.
+func validateRequestBody(c *gin.Context, data interface{}, reqBody []byte) error {
+	pd := producer.ValidateRequestBody(data, reqBody)
+	if pd == nil {
+		return nil
+	}
+	logger.DataRepoLog.Errorf("Request Body does not match its schema: %+v", pd.InvalidParams)
+	c.JSON(int(pd.Status), pd)
+	return errors.New(pd.Detail)
+}
+
+// getRawRequestBody returns the request body, left to the handler to decode.
+func getRawRequestBody(c *gin.Context) ([]byte, error) {
+	reqBody, err := c.GetRawData()
+	if err != nil {
+		logger.DataRepoLog.Errorf("Get Request Body error: %+v", err)
+		pd := util.ProblemDetailsSystemFailure(err.Error())
+		c.JSON(http.StatusInternalServerError, pd)
+	}
+	return reqBody, err
 }
 
 // HTTPApplicationDataInfluenceDataGet -
@@ -180,6 +204,44 @@ func HTTPApplicationDataPfdsGet(c *gin.Context) {
 	sendResponse(c, rsp)
 }
 
+// HTTPApplicationDataPfdsSubsToNotifyPost - creates a subscription to PFD changes
+func HTTPApplicationDataPfdsSubsToNotifyPost(c *gin.Context) {
+	var pfdSubscription models.PfdSubscription
+
+	if err := getDataFromRequestBody(c, &pfdSubscription); err != nil {
+		return
+	}
+
+	rsp := producer.HandleApplicationDataPfdsSubsToNotifyPost(&pfdSubscription)
+
+	sendResponse(c, rsp)
+}
+
+// HTTPApplicationDataPfdsSubsToNotifySubsIdDelete - deletes a subscription to PFD changes
+func HTTPApplicationDataPfdsSubsToNotifySubsIdDelete(c *gin.Context) {
+	rsp := producer.HandleApplicationDataPfdsSubsToNotifySubsIdDelete(c.Params.ByName("subsId"))
+	sendResponse(c, rsp)
+}
+
+// HTTPApplicationDataPfdsSubsToNotifySubsIdGet - retrieves a subscription to PFD changes
+func HTTPApplicationDataPfdsSubsToNotifySubsIdGet(c *gin.Context) {
+	rsp := producer.HandleApplicationDataPfdsSubsToNotifySubsIdGet(c.Params.ByName("subsId"))
+	sendResponse(c, rsp)
+}
+
+// HTTPApplicationDataPfdsSubsToNotifySubsIdPut - replaces a subscription to PFD changes
+func HTTPApplicationDataPfdsSubsToNotifySubsIdPut(c *gin.Context) {
+	var pfdSubscription models.PfdSubscription
+
+	if err := getDataFromRequestBody(c, &pfdSubscription); err != nil {
+		return
+	}
+
+	rsp := producer.HandleApplicationDataPfdsSubsToNotifySubsIdPut(c.Params.ByName("subsId"), &pfdSubscription)
+
+	sendResponse(c, rsp)
+}
+
 // HTTPExposureDataSubsToNotifyPost -
 func HTTPExposureDataSubsToNotifyPost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{})
@@ -250,6 +312,56 @@ func HTTPPolicyDataPlmnsPlmnIdUePolicySetGet(c *gin.Context) {
 	sendResponse(c, rsp)
 }
 
+// HTTPPolicyDataPlmnsPlmnIdUePolicySetDelete -
+func HTTPPolicyDataPlmnsPlmnIdUePolicySetDelete(c *gin.Context) {
+	req := httpwrapper.NewRequest(c.Request, nil)
+	req.Params["plmnId"] = c.Params.ByName("plmnId")
+
+	rsp := producer.HandlePolicyDataPlmnsPlmnIdUePolicySetDelete(req)
+
+	sendResponse(c, rsp)
+}
+
+// HTTPPolicyDataPlmnsPlmnIdUePolicySetPatch -
+func HTTPPolicyDataPlmnsPlmnIdUePolicySetPatch(c *gin.Context) {
+	reqBody, err := getRawRequestBody(c)
+	if err != nil {
+		return
+	}
+
+	req := httpwrapper.NewRequest(c.Request, reqBody)
+	req.Params["plmnId"] = c.Params.ByName("plmnId")
+
+	rsp := producer.HandlePolicyDataPlmnsPlmnIdUePolicySetPatch(req)
+
+	sendResponse(c, rsp)
+}
+
+// HTTPPolicyDataPlmnsPlmnIdUePolicySetPut -
+func HTTPPolicyDataPlmnsPlmnIdUePolicySetPut(c *gin.Context) {
+	reqBody, err := getRawRequestBody(c)
+	if err != nil {
+		return
+	}
+
+	req := httpwrapper.NewRequest(c.Request, reqBody)
+	req.Params["plmnId"] = c.Params.ByName("plmnId")
+
+	rsp := producer.HandlePolicyDataPlmnsPlmnIdUePolicySetPut(req)
+
+	sendResponse(c, rsp)
+}
+
+// HTTPPolicyDataSponsorConnectivityDataSponsorIdDelete -
+func HTTPPolicyDataSponsorConnectivityDataSponsorIdDelete(c *gin.Context) {
+	req := httpwrapper.NewRequest(c.Request, nil)
+	req.Params["sponsorId"] = c.Params.ByName("sponsorId")
+
+	rsp := producer.HandlePolicyDataSponsorConnectivityDataSponsorIdDelete(req)
+
+	sendResponse(c, rsp)
+}
+
 // HTTPPolicyDataSponsorConnectivityDataSponsorIdGet -
 func HTTPPolicyDataSponsorConnectivityDataSponsorIdGet(c *gin.Context) {
 	req := httpwrapper.NewRequest(c.Request, nil)
@@ -260,6 +372,36 @@ func HTTPPolicyDataSponsorConnectivityDataSponsorIdGet(c *gin.Context) {
 	sendResponse(c, rsp)
 }
 
+// HTTPPolicyDataSponsorConnectivityDataSponsorIdPatch -
+func HTTPPolicyDataSponsorConnectivityDataSponsorIdPatch(c *gin.Context) {
+	reqBody, err := getRawRequestBody(c)
+	if err != nil {
+		return
+	}
+
+	req := httpwrapper.NewRequest(c.Request, reqBody)
+	req.Params["sponsorId"] = c.Params.ByName("sponsorId")
+
+	rsp := producer.HandlePolicyDataSponsorConnectivityDataSponsorIdPatch(req)
+
+	sendResponse(c, rsp)
+}
+
+// HTTPPolicyDataSponsorConnectivityDataSponsorIdPut -
+func HTTPPolicyDataSponsorConnectivityDataSponsorIdPut(c *gin.Context) {
+	reqBody, err := getRawRequestBody(c)
+	if err != nil {
+		return
+	}
+
+	req := httpwrapper.NewRequest(c.Request, reqBody)
+	req.Params["sponsorId"] = c.Params.ByName("sponsorId")
+
+	rsp := producer.HandlePolicyDataSponsorConnectivityDataSponsorIdPut(req)
+
+	sendResponse(c, rsp)
+}
+
 // HTTPPolicyDataSubsToNotifyPost -
 func HTTPPolicyDataSubsToNotifyPost(c *gin.Context) {
 	var policyDataSubscription models.PolicyDataSubscription
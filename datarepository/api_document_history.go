@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package datarepository
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/omec-project/udr/producer"
+)
+
+// HTTPListDocumentVersions - lists the prior versions of the subscriber data
+// of a UE
+func HTTPListDocumentVersions(c *gin.Context) {
+	rsp := producer.HandleListDocumentVersions(c.Params.ByName("ueId"), c.Query("resource"))
+	sendResponse(c, rsp)
+}
+
+// HTTPGetDocumentVersion - retrieves a prior version of a subscriber data
+// document
+func HTTPGetDocumentVersion(c *gin.Context) {
+	rsp := producer.HandleGetDocumentVersion(c.Params.ByName("ueId"), c.Params.ByName("versionId"))
+	sendResponse(c, rsp)
+}
+
+// HTTPDiffDocumentVersions - compares a prior version of a subscriber data
+// document to another one or to the current document
+func HTTPDiffDocumentVersions(c *gin.Context) {
+	rsp := producer.HandleDiffDocumentVersions(c.Params.ByName("ueId"), c.Params.ByName("versionId"),
+		c.Query("to"))
+	sendResponse(c, rsp)
+}
+
+// HTTPRestoreDocumentVersion - writes a prior version of a subscriber data
+// document back
+func HTTPRestoreDocumentVersion(c *gin.Context) {
+	rsp := producer.HandleRestoreDocumentVersion(c.Params.ByName("ueId"), c.Params.ByName("versionId"),
+		c.Request.Header)
+	sendResponse(c, rsp)
+}
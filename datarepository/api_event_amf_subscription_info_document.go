@@ -55,6 +55,10 @@ func HTTPCreateAMFSubscriptions(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &amfSubscriptionInfoArray, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, amfSubscriptionInfoArray)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 	req.Params["subsId"] = c.Params.ByName("subsId")
@@ -77,6 +77,10 @@ func HTTPUpdateEeGroupSubscriptions(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &eeSubscription, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, eeSubscription)
 	req.Params["ueGroupId"] = c.Params.ByName("ueGroupId")
 	req.Params["subsId"] = c.Params.ByName("subsId")
@@ -54,6 +54,10 @@ func HTTPCreateEeGroupSubscriptions(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &eeSubscription, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, eeSubscription)
 	req.Params["ueGroupId"] = c.Params.ByName("ueGroupId")
 
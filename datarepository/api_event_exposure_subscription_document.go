@@ -77,6 +77,10 @@ func HTTPUpdateEesubscriptions(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &eeSubscription, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, eeSubscription)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 	req.Params["subsId"] = c.Params.ByName("subsId")
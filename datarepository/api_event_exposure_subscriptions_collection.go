@@ -55,6 +55,10 @@ func HTTPCreateEeSubscriptions(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &eeSubscription, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, eeSubscription)
 	req.Params["ueGroupId"] = c.Params.ByName("ueGroupId")
 
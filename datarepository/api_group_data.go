@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package datarepository
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/omec-project/udr/producer"
+	"github.com/omec-project/util/httpwrapper"
+)
+
+// HTTPQueryGroupIdentifiers - maps between external and internal group identifiers
+func HTTPQueryGroupIdentifiers(c *gin.Context) {
+	req := httpwrapper.NewRequest(c.Request, nil)
+
+	rsp := producer.HandleQueryGroupIdentifiers(req)
+	sendResponse(c, rsp)
+}
+
+// HTTPGetGroupData - retrieves the identifiers and the members of a group
+func HTTPGetGroupData(c *gin.Context) {
+	req := httpwrapper.NewRequest(c.Request, nil)
+	req.Params["extGroupId"] = c.Params.ByName("extGroupId")
+
+	rsp := producer.HandleGetGroupData(req)
+	sendResponse(c, rsp)
+}
+
+// HTTPPutGroupData - creates or replaces the identifiers and the members of a group
+func HTTPPutGroupData(c *gin.Context) {
+	var groupIdentifiers producer.GroupIdentifiers
+
+	if err := getDataFromRequestBody(c, &groupIdentifiers); err != nil {
+		return
+	}
+
+	req := httpwrapper.NewRequest(c.Request, groupIdentifiers)
+	req.Params["extGroupId"] = c.Params.ByName("extGroupId")
+
+	rsp := producer.HandlePutGroupData(req)
+	sendResponse(c, rsp)
+}
+
+// HTTPDeleteGroupData - deletes a group
+func HTTPDeleteGroupData(c *gin.Context) {
+	req := httpwrapper.NewRequest(c.Request, nil)
+	req.Params["extGroupId"] = c.Params.ByName("extGroupId")
+
+	rsp := producer.HandleDeleteGroupData(req)
+	sendResponse(c, rsp)
+}
+
+// HTTPQuery5GVnGroups - retrieves the 5G VN groups, e.g. those of UEs
+func HTTPQuery5GVnGroups(c *gin.Context) {
+	rsp := producer.HandleQuery5GVnGroups(c.Request.URL.Query())
+	sendResponse(c, rsp)
+}
+
+// HTTPGet5GVnGroup - retrieves the configuration of a 5G VN group
+func HTTPGet5GVnGroup(c *gin.Context) {
+	rsp := producer.HandleGet5GVnGroup(c.Params.ByName("extGroupId"))
+	sendResponse(c, rsp)
+}
+
+// HTTPCreate5GVnGroup - creates or replaces a 5G VN group
+func HTTPCreate5GVnGroup(c *gin.Context) {
+	var vnGroupConfiguration producer.VnGroupConfiguration
+
+	if err := getDataFromRequestBody(c, &vnGroupConfiguration); err != nil {
+		return
+	}
+
+	rsp := producer.HandleCreate5GVnGroup(c.Params.ByName("extGroupId"), &vnGroupConfiguration)
+	sendResponse(c, rsp)
+}
+
+// HTTPModify5GVnGroup - modifies a 5G VN group
+func HTTPModify5GVnGroup(c *gin.Context) {
+	reqBody, err := getRawRequestBody(c)
+	if err != nil {
+		return
+	}
+
+	rsp := producer.HandleModify5GVnGroup(c.Params.ByName("extGroupId"), reqBody)
+	sendResponse(c, rsp)
+}
+
+// HTTPDelete5GVnGroup - deletes a 5G VN group
+func HTTPDelete5GVnGroup(c *gin.Context) {
+	rsp := producer.HandleDelete5GVnGroup(c.Params.ByName("extGroupId"))
+	sendResponse(c, rsp)
+}
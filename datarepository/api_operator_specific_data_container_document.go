@@ -55,23 +55,16 @@ func HTTPPatchOperSpecData(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &patchItemArray, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, patchItemArray)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
 	rsp := producer.HandlePatchOperSpecData(req)
 
-	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-		problemDetails := models.ProblemDetails{
-			Status: http.StatusInternalServerError,
-			Cause:  "SYSTEM_FAILURE",
-			Detail: err.Error(),
-		}
-		c.JSON(http.StatusInternalServerError, problemDetails)
-	} else {
-		c.Data(rsp.Status, "application/json", responseBody)
-	}
+	sendResponse(c, rsp)
 }
 
 // HTTPQueryOperSpecData - Retrieves the operator specific data of a UE
@@ -81,16 +74,5 @@ func HTTPQueryOperSpecData(c *gin.Context) {
 
 	rsp := producer.HandleQueryOperSpecData(req)
 
-	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-		problemDetails := models.ProblemDetails{
-			Status: http.StatusInternalServerError,
-			Cause:  "SYSTEM_FAILURE",
-			Detail: err.Error(),
-		}
-		c.JSON(http.StatusInternalServerError, problemDetails)
-	} else {
-		c.Data(rsp.Status, "application/json", responseBody)
-	}
+	sendResponse(c, rsp)
 }
@@ -15,12 +15,7 @@
 package datarepository
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
-	"github.com/omec-project/openapi"
-	"github.com/omec-project/openapi/models"
-	"github.com/omec-project/udr/logger"
 	"github.com/omec-project/udr/producer"
 	"github.com/omec-project/util/httpwrapper"
 )
@@ -32,16 +27,5 @@ func HTTPGetppData(c *gin.Context) {
 
 	rsp := producer.HandleGetppData(req)
 
-	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-		problemDetails := models.ProblemDetails{
-			Status: http.StatusInternalServerError,
-			Cause:  "SYSTEM_FAILURE",
-			Detail: err.Error(),
-		}
-		c.JSON(http.StatusInternalServerError, problemDetails)
-	} else {
-		c.Data(rsp.Status, "application/json", responseBody)
-	}
+	sendResponse(c, rsp)
 }
@@ -31,7 +31,7 @@ func HTTPQueryProvisionedData(c *gin.Context) {
 	req.Params["ueId"] = c.Params.ByName("ueId")
 	req.Params["servingPlmnId"] = c.Params.ByName("servingPlmnId")
 
-	rsp := producer.HandleQueryProvisionedData(req)
+	rsp := producer.HandleQueryProvisionedData(c.Request.Context(), req)
 
 	responseBody, err := openapi.Serialize(rsp.Body, "application/json")
 	if err != nil {
@@ -46,3 +46,30 @@ func HTTPQueryProvisionedData(c *gin.Context) {
 		c.Data(rsp.Status, "application/json", responseBody)
 	}
 }
+
+// HTTPQueryProvisionedDataResource - Retrieve one provisioned data set of a UE
+func HTTPQueryProvisionedDataResource(resource *producer.ProvisionedDataResource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := httpwrapper.NewRequest(c.Request, nil)
+		req.Params["ueId"] = c.Params.ByName("ueId")
+		req.Params["servingPlmnId"] = c.Params.ByName("servingPlmnId")
+
+		rsp := producer.HandleQueryProvisionedDataResource(c.Request.Context(), resource, req)
+		sendResponse(c, rsp)
+	}
+}
+
+// provisionedDataRoutes returns the routes of the provisioned data sets
+// retrieved on their own.
+func provisionedDataRoutes(resources ...*producer.ProvisionedDataResource) Routes {
+	var routes Routes
+	for _, resource := range resources {
+		routes = append(routes, Route{
+			"HTTPQueryProvisionedData " + resource.Path,
+			"GET",
+			"/subscription-data/:ueId/:servingPlmnId/provisioned-data/" + resource.Path,
+			HTTPQueryProvisionedDataResource(resource),
+		})
+	}
+	return routes
+}
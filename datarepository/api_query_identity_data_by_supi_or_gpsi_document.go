@@ -16,6 +16,7 @@ package datarepository
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/omec-project/openapi"
@@ -45,3 +46,48 @@ func HTTPGetIdentityData(c *gin.Context) {
 		c.Data(rsp.Status, "application/json", responseBody)
 	}
 }
+
+// HTTPPutIdentityData - Create or replace the identities of a UE
+func HTTPPutIdentityData(c *gin.Context) {
+	var identityData producer.IdentityData
+
+	if err := getDataFromRequestBody(c, &identityData); err != nil {
+		return
+	}
+
+	req := httpwrapper.NewRequest(c.Request, identityData)
+	req.Params["ueId"] = c.Params.ByName("ueId")
+
+	rsp := producer.HandlePutIdentityData(req)
+	sendResponse(c, rsp)
+}
+
+// HTTPDeleteIdentityData - Delete the identities of a UE
+func HTTPDeleteIdentityData(c *gin.Context) {
+	req := httpwrapper.NewRequest(c.Request, nil)
+	req.Params["ueId"] = c.Params.ByName("ueId")
+
+	rsp := producer.HandleDeleteIdentityData(req)
+	sendResponse(c, rsp)
+}
+
+// resolveUeId replaces the GPSI or IMPU ueId of the subscription data
+// requests by the SUPI of the UE, under which its data is stored.
+func resolveUeId(c *gin.Context) {
+	if !strings.HasPrefix(c.FullPath(), "/nudr-dr/v1/subscription-data/:ueId") {
+		return
+	}
+	for i, param := range c.Params {
+		if param.Key != "ueId" {
+			continue
+		}
+		supi, err := producer.ResolveUeId(param.Value)
+		if err != nil {
+			logger.DataRepoLog.Errorln(err)
+			problemDetails := producer.DBProblemDetails(err)
+			c.AbortWithStatusJSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+		c.Params[i].Value = supi
+	}
+}
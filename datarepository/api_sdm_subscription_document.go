@@ -77,6 +77,10 @@ func HTTPUpdatesdmsubscriptions(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &sdmSubscription, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, sdmSubscription)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 	req.Params["subsId"] = c.Params.ByName("subsId")
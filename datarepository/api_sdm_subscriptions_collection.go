@@ -55,6 +55,10 @@ func HTTPCreateSdmSubscriptions(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &sdmSubscription, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, sdmSubscription)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package datarepository
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/omec-project/udr/producer"
+)
+
+// HTTPGetIndividualSharedData - retrieves one shared data
+func HTTPGetIndividualSharedData(c *gin.Context) {
+	rsp := producer.HandleGetIndividualSharedData(c.Params.ByName("sharedDataId"))
+	sendResponse(c, rsp)
+}
+
+// HTTPPutSharedData - creates or replaces a shared data
+func HTTPPutSharedData(c *gin.Context) {
+	reqBody, err := getRawRequestBody(c)
+	if err != nil {
+		return
+	}
+	rsp := producer.HandlePutSharedData(c.Params.ByName("sharedDataId"), reqBody)
+	sendResponse(c, rsp)
+}
+
+// HTTPModifySharedData - modifies a shared data
+func HTTPModifySharedData(c *gin.Context) {
+	reqBody, err := getRawRequestBody(c)
+	if err != nil {
+		return
+	}
+	rsp := producer.HandleModifySharedData(c.Params.ByName("sharedDataId"), reqBody)
+	sendResponse(c, rsp)
+}
+
+// HTTPDeleteSharedData - deletes a shared data no UE references
+func HTTPDeleteSharedData(c *gin.Context) {
+	rsp := producer.HandleDeleteSharedData(c.Params.ByName("sharedDataId"))
+	sendResponse(c, rsp)
+}
@@ -55,6 +55,10 @@ func HTTPCreateSmfContextNon3gpp(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &smfRegistration, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, smfRegistration)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
@@ -55,6 +55,10 @@ func HTTPCreateSmsfContext3gpp(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &smsfRegistration, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, smsfRegistration)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
@@ -55,6 +55,10 @@ func HTTPPostSubscriptionDataSubscriptions(c *gin.Context) {
 		return
 	}
 
+	if err = validateRequestBody(c, &subscriptionDataSubscriptions, requestBody); err != nil {
+		return
+	}
+
 	req := httpwrapper.NewRequest(c.Request, subscriptionDataSubscriptions)
 	req.Params["ueId"] = c.Params.ByName("ueId")
 
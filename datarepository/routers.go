@@ -20,10 +20,17 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/omec-project/udr/logger"
+	"github.com/omec-project/udr/producer"
 	utilLogger "github.com/omec-project/util/logger"
 )
 
-var subsToNotifyStr = "subs-to-notify"
+var (
+	subsToNotifyStr = "subs-to-notify"
+	groupDataStr    = "group-data"
+	sharedDataStr   = "shared-data"
+	vnGroupsStr     = "5g-vn-groups"
+	contextDataStr  = "context-data"
+)
 
 // Route is the information for every URI.
 type Route struct {
@@ -61,6 +68,14 @@ func subMsgShortDispatchHandlerFunc(c *gin.Context) {
 func subMsgDispatchHandlerFunc(c *gin.Context) {
 	op := c.Param("servingPlmnId")
 	subsToNotify := c.Param("ueId")
+	if subsToNotify == groupDataStr {
+		groupDataMsgDispatchHandlerFunc(c)
+		return
+	}
+	if subsToNotify == sharedDataStr {
+		sharedDataMsgDispatchHandlerFunc(c)
+		return
+	}
 	for _, route := range subRoutes {
 		if strings.Contains(route.Pattern, op) && route.Method == c.Request.Method {
 			route.HandlerFunc(c)
@@ -76,6 +91,39 @@ func subMsgDispatchHandlerFunc(c *gin.Context) {
 	c.String(http.StatusMethodNotAllowed, "Method Not Allowed")
 }
 
+// Handler to distinguish the group-identifiers from ":extGroupId".
+func groupDataMsgDispatchHandlerFunc(c *gin.Context) {
+	op := c.Param("servingPlmnId")
+	for _, route := range groupDataRoutes {
+		if route.Method != c.Request.Method {
+			continue
+		}
+		if strings.HasSuffix(route.Pattern, "/"+op) {
+			route.HandlerFunc(c)
+			return
+		}
+		if strings.HasSuffix(route.Pattern, "/:extGroupId") {
+			c.Params = append(c.Params, gin.Param{Key: "extGroupId", Value: op})
+			route.HandlerFunc(c)
+			return
+		}
+	}
+	c.String(http.StatusMethodNotAllowed, "Method Not Allowed")
+}
+
+// Handler for "/subscription-data/shared-data/:sharedDataId", which
+// ":ueId/:servingPlmnId" shadows.
+func sharedDataMsgDispatchHandlerFunc(c *gin.Context) {
+	for _, route := range sharedDataRoutes {
+		if route.Method == c.Request.Method {
+			c.Params = append(c.Params, gin.Param{Key: "sharedDataId", Value: c.Param("servingPlmnId")})
+			route.HandlerFunc(c)
+			return
+		}
+	}
+	c.String(http.StatusMethodNotAllowed, "Method Not Allowed")
+}
+
 func eeMsgShortDispatchHandlerFunc(c *gin.Context) {
 	groupData := c.Param("ueId")
 	contextData := c.Param("servingPlmnId")
@@ -110,6 +158,23 @@ func eeMsgDispatchHandlerFunc(c *gin.Context) {
 	c.String(http.StatusMethodNotAllowed, "Method Not Allowed")
 }
 
+// Handler to route '/subscription-data/group-data/5g-vn-groups/:extGroupId'
+// among the other resources of a UE.
+func vnGroupMsgDispatchHandlerFunc(c *gin.Context) {
+	if c.Param("ueId") != groupDataStr || c.Param("servingPlmnId") != vnGroupsStr {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+	for _, route := range vnGroupRoutes {
+		if route.Method == c.Request.Method {
+			c.Params = append(c.Params, gin.Param{Key: "extGroupId", Value: c.Param("vnGroupId")})
+			route.HandlerFunc(c)
+			return
+		}
+	}
+	c.String(http.StatusMethodNotAllowed, "Method Not Allowed")
+}
+
 // Handler to distinguish subsToNotifyStr from ":influenceId".
 func appInfluDataMsgDispatchHandlerFunc(c *gin.Context) {
 	influID := c.Param("influenceId")
@@ -148,9 +213,15 @@ func expoMsgDispatchHandlerFunc(c *gin.Context) {
 }
 
 func AddService(engine *gin.Engine) *gin.RouterGroup {
-	group := engine.Group("/nudr-dr/v1")
-
-	for _, route := range routes {
+	group := engine.Group("/nudr-dr/v1", resolveUeId)
+
+	allRoutes := append(routes, contextDataRoutes(producer.ContextDataIpSmGwAccess,
+		producer.ContextDataMessageWaitingData, producer.ContextDataLocation,
+		producer.ContextDataNiddAuthorizations, producer.ContextDataServiceSpecificAuthorizations)...)
+	allRoutes = append(allRoutes, provisionedDataRoutes(producer.ProvisionedDataLcsPrivacy,
+		producer.ProvisionedDataLcsMo, producer.ProvisionedDataLcsBca, producer.ProvisionedDataV2x,
+		producer.ProvisionedDataProse, producer.ProvisionedDataMbs)...)
+	for _, route := range allRoutes {
 		switch route.Method {
 		case "GET":
 			group.GET(route.Pattern, route.HandlerFunc)
@@ -177,6 +248,9 @@ func AddService(engine *gin.Engine) *gin.RouterGroup {
 	eePattern := "/subscription-data/:ueId/:servingPlmnId/ee-subscriptions/:subsId"
 	group.Any(eePattern, eeMsgDispatchHandlerFunc)
 
+	vnGroupPattern := "/subscription-data/:ueId/:servingPlmnId/:vnGroupId"
+	group.Any(vnGroupPattern, vnGroupMsgDispatchHandlerFunc)
+
 	/*
 	 * GIN wildcard issue:
 	 * '/application-data/influenceData/:influenceId' and
@@ -196,6 +270,16 @@ func AddService(engine *gin.Engine) *gin.RouterGroup {
 	return group
 }
 
+// AddAdminService registers the admin API, which is served on a listener of
+// its own rather than with the SBI.
+func AddAdminService(engine *gin.Engine) *gin.RouterGroup {
+	group := engine.Group("/nudr-dr-admin/v1")
+	for _, route := range append(documentHistoryRoutes, amfRegistrationHistoryRoutes...) {
+		group.Handle(route.Method, route.Pattern, route.HandlerFunc)
+	}
+	return group
+}
+
 // Index is the index handler.
 func Index(c *gin.Context) {
 	c.String(http.StatusOK, "Hello World!")
@@ -363,6 +447,237 @@ var routes = Routes{
 		HTTPApplicationDataPfdsGet,
 	},
 
+	{
+		"HTTPApplicationDataPfdsSubsToNotifyPost",
+		strings.ToUpper("Post"),
+		"/application-data/pfds/subs-to-notify",
+		HTTPApplicationDataPfdsSubsToNotifyPost,
+	},
+
+	{
+		"HTTPApplicationDataPfdsSubsToNotifySubsIdGet",
+		strings.ToUpper("Get"),
+		"/application-data/pfds/subs-to-notify/:subsId",
+		HTTPApplicationDataPfdsSubsToNotifySubsIdGet,
+	},
+
+	{
+		"HTTPApplicationDataPfdsSubsToNotifySubsIdPut",
+		strings.ToUpper("Put"),
+		"/application-data/pfds/subs-to-notify/:subsId",
+		HTTPApplicationDataPfdsSubsToNotifySubsIdPut,
+	},
+
+	{
+		"HTTPApplicationDataPfdsSubsToNotifySubsIdDelete",
+		strings.ToUpper("Delete"),
+		"/application-data/pfds/subs-to-notify/:subsId",
+		HTTPApplicationDataPfdsSubsToNotifySubsIdDelete,
+	},
+
+	{
+		"HTTPApplicationDataSubsToNotifyPost",
+		strings.ToUpper("Post"),
+		"/application-data/subs-to-notify",
+		HTTPApplicationDataSubsToNotifyPost,
+	},
+
+	{
+		"HTTPApplicationDataSubsToNotifySubsIdGet",
+		strings.ToUpper("Get"),
+		"/application-data/subs-to-notify/:subsId",
+		HTTPApplicationDataSubsToNotifySubsIdGet,
+	},
+
+	{
+		"HTTPApplicationDataSubsToNotifySubsIdPut",
+		strings.ToUpper("Put"),
+		"/application-data/subs-to-notify/:subsId",
+		HTTPApplicationDataSubsToNotifySubsIdPut,
+	},
+
+	{
+		"HTTPApplicationDataSubsToNotifySubsIdDelete",
+		strings.ToUpper("Delete"),
+		"/application-data/subs-to-notify/:subsId",
+		HTTPApplicationDataSubsToNotifySubsIdDelete,
+	},
+
+	{
+		"HTTPApplicationDataBdtPolicyDataGet",
+		strings.ToUpper("Get"),
+		"/application-data/bdtPolicyData",
+		HTTPApplicationDataResourceGet(producer.AppDataBdtPolicyData),
+	},
+
+	{
+		"HTTPApplicationDataBdtPolicyDataIdGet",
+		strings.ToUpper("Get"),
+		"/application-data/bdtPolicyData/:bdtPolicyId",
+		HTTPApplicationDataResourceIdGet(producer.AppDataBdtPolicyData),
+	},
+
+	{
+		"HTTPApplicationDataBdtPolicyDataIdPut",
+		strings.ToUpper("Put"),
+		"/application-data/bdtPolicyData/:bdtPolicyId",
+		HTTPApplicationDataResourceIdPut(producer.AppDataBdtPolicyData),
+	},
+
+	{
+		"HTTPApplicationDataBdtPolicyDataIdPatch",
+		strings.ToUpper("Patch"),
+		"/application-data/bdtPolicyData/:bdtPolicyId",
+		HTTPApplicationDataResourceIdPatch(producer.AppDataBdtPolicyData),
+	},
+
+	{
+		"HTTPApplicationDataBdtPolicyDataIdDelete",
+		strings.ToUpper("Delete"),
+		"/application-data/bdtPolicyData/:bdtPolicyId",
+		HTTPApplicationDataResourceIdDelete(producer.AppDataBdtPolicyData),
+	},
+
+	{
+		"HTTPApplicationDataIptvConfigDataGet",
+		strings.ToUpper("Get"),
+		"/application-data/iptvConfigData",
+		HTTPApplicationDataResourceGet(producer.AppDataIptvConfigData),
+	},
+
+	{
+		"HTTPApplicationDataIptvConfigDataIdGet",
+		strings.ToUpper("Get"),
+		"/application-data/iptvConfigData/:configurationId",
+		HTTPApplicationDataResourceIdGet(producer.AppDataIptvConfigData),
+	},
+
+	{
+		"HTTPApplicationDataIptvConfigDataIdPut",
+		strings.ToUpper("Put"),
+		"/application-data/iptvConfigData/:configurationId",
+		HTTPApplicationDataResourceIdPut(producer.AppDataIptvConfigData),
+	},
+
+	{
+		"HTTPApplicationDataIptvConfigDataIdPatch",
+		strings.ToUpper("Patch"),
+		"/application-data/iptvConfigData/:configurationId",
+		HTTPApplicationDataResourceIdPatch(producer.AppDataIptvConfigData),
+	},
+
+	{
+		"HTTPApplicationDataIptvConfigDataIdDelete",
+		strings.ToUpper("Delete"),
+		"/application-data/iptvConfigData/:configurationId",
+		HTTPApplicationDataResourceIdDelete(producer.AppDataIptvConfigData),
+	},
+
+	{
+		"HTTPApplicationDataServiceParamDataGet",
+		strings.ToUpper("Get"),
+		"/application-data/serviceParamData",
+		HTTPApplicationDataResourceGet(producer.AppDataServiceParamData),
+	},
+
+	{
+		"HTTPApplicationDataServiceParamDataIdGet",
+		strings.ToUpper("Get"),
+		"/application-data/serviceParamData/:serviceParamId",
+		HTTPApplicationDataResourceIdGet(producer.AppDataServiceParamData),
+	},
+
+	{
+		"HTTPApplicationDataServiceParamDataIdPut",
+		strings.ToUpper("Put"),
+		"/application-data/serviceParamData/:serviceParamId",
+		HTTPApplicationDataResourceIdPut(producer.AppDataServiceParamData),
+	},
+
+	{
+		"HTTPApplicationDataServiceParamDataIdPatch",
+		strings.ToUpper("Patch"),
+		"/application-data/serviceParamData/:serviceParamId",
+		HTTPApplicationDataResourceIdPatch(producer.AppDataServiceParamData),
+	},
+
+	{
+		"HTTPApplicationDataServiceParamDataIdDelete",
+		strings.ToUpper("Delete"),
+		"/application-data/serviceParamData/:serviceParamId",
+		HTTPApplicationDataResourceIdDelete(producer.AppDataServiceParamData),
+	},
+
+	{
+		"HTTPApplicationDataAmInfluenceDataGet",
+		strings.ToUpper("Get"),
+		"/application-data/amInfluenceData",
+		HTTPApplicationDataResourceGet(producer.AppDataAmInfluenceData),
+	},
+
+	{
+		"HTTPApplicationDataAmInfluenceDataIdGet",
+		strings.ToUpper("Get"),
+		"/application-data/amInfluenceData/:amInfluenceId",
+		HTTPApplicationDataResourceIdGet(producer.AppDataAmInfluenceData),
+	},
+
+	{
+		"HTTPApplicationDataAmInfluenceDataIdPut",
+		strings.ToUpper("Put"),
+		"/application-data/amInfluenceData/:amInfluenceId",
+		HTTPApplicationDataResourceIdPut(producer.AppDataAmInfluenceData),
+	},
+
+	{
+		"HTTPApplicationDataAmInfluenceDataIdPatch",
+		strings.ToUpper("Patch"),
+		"/application-data/amInfluenceData/:amInfluenceId",
+		HTTPApplicationDataResourceIdPatch(producer.AppDataAmInfluenceData),
+	},
+
+	{
+		"HTTPApplicationDataAmInfluenceDataIdDelete",
+		strings.ToUpper("Delete"),
+		"/application-data/amInfluenceData/:amInfluenceId",
+		HTTPApplicationDataResourceIdDelete(producer.AppDataAmInfluenceData),
+	},
+
+	{
+		"HTTPApplicationDataEventExposureDataGet",
+		strings.ToUpper("Get"),
+		"/application-data/eventExposureData",
+		HTTPApplicationDataResourceGet(producer.AppDataEventExposureData),
+	},
+
+	{
+		"HTTPApplicationDataEventExposureDataIdGet",
+		strings.ToUpper("Get"),
+		"/application-data/eventExposureData/:eventExposureId",
+		HTTPApplicationDataResourceIdGet(producer.AppDataEventExposureData),
+	},
+
+	{
+		"HTTPApplicationDataEventExposureDataIdPut",
+		strings.ToUpper("Put"),
+		"/application-data/eventExposureData/:eventExposureId",
+		HTTPApplicationDataResourceIdPut(producer.AppDataEventExposureData),
+	},
+
+	{
+		"HTTPApplicationDataEventExposureDataIdPatch",
+		strings.ToUpper("Patch"),
+		"/application-data/eventExposureData/:eventExposureId",
+		HTTPApplicationDataResourceIdPatch(producer.AppDataEventExposureData),
+	},
+
+	{
+		"HTTPApplicationDataEventExposureDataIdDelete",
+		strings.ToUpper("Delete"),
+		"/application-data/eventExposureData/:eventExposureId",
+		HTTPApplicationDataResourceIdDelete(producer.AppDataEventExposureData),
+	},
+
 	{
 		"HTTPPolicyDataBdtDataBdtReferenceIdDelete",
 		strings.ToUpper("Delete"),
@@ -398,6 +713,27 @@ var routes = Routes{
 		HTTPPolicyDataPlmnsPlmnIdUePolicySetGet,
 	},
 
+	{
+		"HTTPPolicyDataPlmnsPlmnIdUePolicySetPut",
+		strings.ToUpper("Put"),
+		"/policy-data/plmns/:plmnId/ue-policy-set",
+		HTTPPolicyDataPlmnsPlmnIdUePolicySetPut,
+	},
+
+	{
+		"HTTPPolicyDataPlmnsPlmnIdUePolicySetPatch",
+		strings.ToUpper("Patch"),
+		"/policy-data/plmns/:plmnId/ue-policy-set",
+		HTTPPolicyDataPlmnsPlmnIdUePolicySetPatch,
+	},
+
+	{
+		"HTTPPolicyDataPlmnsPlmnIdUePolicySetDelete",
+		strings.ToUpper("Delete"),
+		"/policy-data/plmns/:plmnId/ue-policy-set",
+		HTTPPolicyDataPlmnsPlmnIdUePolicySetDelete,
+	},
+
 	{
 		"HTTPPolicyDataSponsorConnectivityDataSponsorIdGet",
 		strings.ToUpper("Get"),
@@ -405,6 +741,27 @@ var routes = Routes{
 		HTTPPolicyDataSponsorConnectivityDataSponsorIdGet,
 	},
 
+	{
+		"HTTPPolicyDataSponsorConnectivityDataSponsorIdPut",
+		strings.ToUpper("Put"),
+		"/policy-data/sponsor-connectivity-data/:sponsorId",
+		HTTPPolicyDataSponsorConnectivityDataSponsorIdPut,
+	},
+
+	{
+		"HTTPPolicyDataSponsorConnectivityDataSponsorIdPatch",
+		strings.ToUpper("Patch"),
+		"/policy-data/sponsor-connectivity-data/:sponsorId",
+		HTTPPolicyDataSponsorConnectivityDataSponsorIdPatch,
+	},
+
+	{
+		"HTTPPolicyDataSponsorConnectivityDataSponsorIdDelete",
+		strings.ToUpper("Delete"),
+		"/policy-data/sponsor-connectivity-data/:sponsorId",
+		HTTPPolicyDataSponsorConnectivityDataSponsorIdDelete,
+	},
+
 	{
 		"HTTPPolicyDataSubsToNotifyPost",
 		strings.ToUpper("Post"),
@@ -722,6 +1079,20 @@ var subRoutes = Routes{
 		HTTPGetIdentityData,
 	},
 
+	{
+		"HTTPPutIdentityData",
+		strings.ToUpper("Put"),
+		"/subscription-data/:ueId/identity-data",
+		HTTPPutIdentityData,
+	},
+
+	{
+		"HTTPDeleteIdentityData",
+		strings.ToUpper("Delete"),
+		"/subscription-data/:ueId/identity-data",
+		HTTPDeleteIdentityData,
+	},
+
 	{
 		"HTTPGetOdbData",
 		strings.ToUpper("Get"),
@@ -879,6 +1250,103 @@ var expoRoutes = Routes{
 	},
 }
 
+var groupDataRoutes = Routes{
+	{
+		"HTTPQueryGroupIdentifiers",
+		strings.ToUpper("Get"),
+		"/subscription-data/group-data/group-identifiers",
+		HTTPQueryGroupIdentifiers,
+	},
+
+	{
+		"HTTPQuery5GVnGroups",
+		strings.ToUpper("Get"),
+		"/subscription-data/group-data/5g-vn-groups",
+		HTTPQuery5GVnGroups,
+	},
+
+	{
+		"HTTPGetGroupData",
+		strings.ToUpper("Get"),
+		"/subscription-data/group-data/:extGroupId",
+		HTTPGetGroupData,
+	},
+
+	{
+		"HTTPPutGroupData",
+		strings.ToUpper("Put"),
+		"/subscription-data/group-data/:extGroupId",
+		HTTPPutGroupData,
+	},
+
+	{
+		"HTTPDeleteGroupData",
+		strings.ToUpper("Delete"),
+		"/subscription-data/group-data/:extGroupId",
+		HTTPDeleteGroupData,
+	},
+}
+
+var sharedDataRoutes = Routes{
+	{
+		"HTTPGetIndividualSharedData",
+		strings.ToUpper("Get"),
+		"/subscription-data/shared-data/:sharedDataId",
+		HTTPGetIndividualSharedData,
+	},
+
+	{
+		"HTTPPutSharedData",
+		strings.ToUpper("Put"),
+		"/subscription-data/shared-data/:sharedDataId",
+		HTTPPutSharedData,
+	},
+
+	{
+		"HTTPModifySharedData",
+		strings.ToUpper("Patch"),
+		"/subscription-data/shared-data/:sharedDataId",
+		HTTPModifySharedData,
+	},
+
+	{
+		"HTTPDeleteSharedData",
+		strings.ToUpper("Delete"),
+		"/subscription-data/shared-data/:sharedDataId",
+		HTTPDeleteSharedData,
+	},
+}
+
+var vnGroupRoutes = Routes{
+	{
+		"HTTPGet5GVnGroup",
+		strings.ToUpper("Get"),
+		"/subscription-data/group-data/5g-vn-groups/:extGroupId",
+		HTTPGet5GVnGroup,
+	},
+
+	{
+		"HTTPCreate5GVnGroup",
+		strings.ToUpper("Put"),
+		"/subscription-data/group-data/5g-vn-groups/:extGroupId",
+		HTTPCreate5GVnGroup,
+	},
+
+	{
+		"HTTPModify5GVnGroup",
+		strings.ToUpper("Patch"),
+		"/subscription-data/group-data/5g-vn-groups/:extGroupId",
+		HTTPModify5GVnGroup,
+	},
+
+	{
+		"HTTPDelete5GVnGroup",
+		strings.ToUpper("Delete"),
+		"/subscription-data/group-data/5g-vn-groups/:extGroupId",
+		HTTPDelete5GVnGroup,
+	},
+}
+
 var appInfluDataRoutes = Routes{
 	{
 		"HTTPApplicationDataInfluenceDataSubsToNotifyGet",
@@ -929,3 +1397,46 @@ var appInfluDataRoutes = Routes{
 		HTTPApplicationDataInfluenceDataSubsToNotifyPost,
 	},
 }
+
+// documentHistoryRoutes are the admin routes of the prior versions of the
+// subscriber data, under /nudr-dr-admin/v1.
+var documentHistoryRoutes = Routes{
+	{
+		"HTTPListDocumentVersions",
+		strings.ToUpper("Get"),
+		"/subscription-data/:ueId/history",
+		HTTPListDocumentVersions,
+	},
+
+	{
+		"HTTPGetDocumentVersion",
+		strings.ToUpper("Get"),
+		"/subscription-data/:ueId/history/:versionId",
+		HTTPGetDocumentVersion,
+	},
+
+	{
+		"HTTPDiffDocumentVersions",
+		strings.ToUpper("Get"),
+		"/subscription-data/:ueId/history/:versionId/diff",
+		HTTPDiffDocumentVersions,
+	},
+
+	{
+		"HTTPRestoreDocumentVersion",
+		strings.ToUpper("Post"),
+		"/subscription-data/:ueId/history/:versionId/restore",
+		HTTPRestoreDocumentVersion,
+	},
+}
+
+// amfRegistrationHistoryRoutes are the admin routes of the AMF registrations
+// replaced by another AMF, under /nudr-dr-admin/v1.
+var amfRegistrationHistoryRoutes = Routes{
+	{
+		"HTTPQueryAmfRegistrationHistory",
+		strings.ToUpper("Get"),
+		"/subscription-data/:ueId/amf-registration-history",
+		HTTPQueryAmfRegistrationHistory,
+	},
+}
@@ -11,6 +11,8 @@
 package factory
 
 import (
+	"time"
+
 	protos "github.com/5GC-DEV/config5g-cdac/proto/sdcoreConfig"
 	"github.com/omec-project/openapi/models"
 	"github.com/omec-project/udr/logger"
@@ -37,6 +39,8 @@ const (
 	UDR_DEFAULT_IPV4     = "127.0.0.4"
 	UDR_DEFAULT_PORT     = "8000"
 	UDR_DEFAULT_PORT_INT = 8000
+	// the admin API is only reachable from the node by default
+	UDR_DEFAULT_ADMIN_ADDR = "127.0.0.1:8081"
 )
 
 type Configuration struct {
@@ -45,6 +49,38 @@ type Configuration struct {
 	NrfUri          string            `yaml:"nrfUri"`
 	WebuiUri        string            `yaml:"webuiUri"`
 	PlmnSupportList []PlmnSupportItem `yaml:"plmnSupportList,omitempty"`
+	// ManagedByConfigPod enables the config pod gRPC client. It can also be
+	// set through the legacy MANAGED_BY_CONFIG_POD environment variable.
+	ManagedByConfigPod bool   `yaml:"managedByConfigPod,omitempty"`
+	Cache              *Cache `yaml:"cache,omitempty"`
+	// NotifyOnDbChanges notifies subscribers of every change of the
+	// subscription and policy data, also of writes that bypass the UDR. It
+	// needs MongoDB to run as a replica set and must be set alike on every
+	// UDR replica: they leave the notifications to the one replica holding
+	// the watcher lease.
+	NotifyOnDbChanges bool           `yaml:"notifyOnDbChanges,omitempty"`
+	Registrations     *Registrations `yaml:"registrations,omitempty"`
+	// DisableSchemaValidation stores the request bodies and the results of
+	// JSON patches without checking them against the 3GPP models.
+	DisableSchemaValidation bool             `yaml:"disableSchemaValidation,omitempty"`
+	DocumentHistory         *DocumentHistory `yaml:"documentHistory,omitempty"`
+	// AdminAddr is the host:port of the admin API, served apart from the SBI
+	// while the document history or the AMF registration history is kept.
+	// It has no access control of its own.
+	AdminAddr string `yaml:"adminAddr,omitempty"`
+}
+
+func (c *Configuration) GetAdminAddr() string {
+	if c.AdminAddr != "" {
+		return c.AdminAddr
+	}
+	return UDR_DEFAULT_ADMIN_ADDR
+}
+
+// AdminAPIEnabled reports whether the admin API has anything to serve.
+func (c *Configuration) AdminAPIEnabled() bool {
+	return (c.DocumentHistory != nil && c.DocumentHistory.Enable) ||
+		(c.Registrations != nil && c.Registrations.AmfHistory)
 }
 
 type PlmnSupportItem struct {
@@ -73,6 +109,91 @@ type Mongodb struct {
 	AuthUrl        string `yaml:"authUrl"`
 }
 
+const (
+	CACHE_DEFAULT_MAX_ENTRIES = 10000
+	CACHE_DEFAULT_TTL         = 30 * time.Second
+)
+
+// Cache configures the read-through cache of hot subscription data sets.
+type Cache struct {
+	Enable     bool   `yaml:"enable"`
+	MaxEntries int    `yaml:"maxEntries,omitempty"` // per database
+	Ttl        string `yaml:"ttl,omitempty"`        // e.g. "30s"
+	// ChangeStreams invalidates entries on writes of other UDR replicas or
+	// tools. It needs MongoDB to run as a replica set.
+	ChangeStreams bool `yaml:"changeStreams,omitempty"`
+}
+
+func (c *Cache) GetMaxEntries() int {
+	if c.MaxEntries > 0 {
+		return c.MaxEntries
+	}
+	return CACHE_DEFAULT_MAX_ENTRIES
+}
+
+func (c *Cache) GetTtl() time.Duration {
+	if ttl, err := time.ParseDuration(c.Ttl); err == nil && ttl > 0 {
+		return ttl
+	}
+	return CACHE_DEFAULT_TTL
+}
+
+const HISTORY_DEFAULT_MAX_VERSIONS = 10
+
+// DocumentHistory keeps the prior versions of the subscriber data documents
+// written through the UDR, for the admin API to list and restore them.
+type DocumentHistory struct {
+	Enable      bool `yaml:"enable"`
+	MaxVersions int  `yaml:"maxVersions,omitempty"` // per document
+	// MaxAge has the database drop the versions older than this, e.g.
+	// "720h", through a TTL index. Versions are only dropped beyond
+	// MaxVersions if it is unset.
+	MaxAge string `yaml:"maxAge,omitempty"`
+	// EnableRestore lets the admin API write versions back.
+	EnableRestore bool `yaml:"enableRestore,omitempty"`
+}
+
+func (h *DocumentHistory) GetMaxVersions() int {
+	if h.MaxVersions > 0 {
+		return h.MaxVersions
+	}
+	return HISTORY_DEFAULT_MAX_VERSIONS
+}
+
+// GetMaxAge returns the age of the versions to drop, zero if they are kept
+// regardless of age.
+func (h *DocumentHistory) GetMaxAge() time.Duration {
+	if maxAge, err := time.ParseDuration(h.MaxAge); err == nil && maxAge > 0 {
+		return maxAge
+	}
+	return 0
+}
+
+// Registrations configures the handling of the AMF and SMF registrations
+// beyond storing them.
+type Registrations struct {
+	// AmfHistory keeps the deregCallbackUri of the AMF serving a UE when
+	// another AMF registers for the same access type, for the admin API to
+	// list them.
+	AmfHistory bool `yaml:"amfHistory,omitempty"`
+	// SmfMaxAge deletes the SMF registrations not refreshed for this long,
+	// e.g. "24h". SMF registrations never expire if it is unset.
+	SmfMaxAge string `yaml:"smfMaxAge,omitempty"`
+}
+
+// GetSmfMaxAge returns the age of the SMF registrations to delete, zero if
+// they never expire.
+func (r *Registrations) GetSmfMaxAge() time.Duration {
+	if maxAge, err := time.ParseDuration(r.SmfMaxAge); err == nil && maxAge > 0 {
+		return maxAge
+	}
+	return 0
+}
+
+// ConfigUpdateDbQueueSize is the capacity of ConfigUpdateDbTrigger. The
+// consumer drains it in batches, so it only needs to absorb bursts.
+const ConfigUpdateDbQueueSize = 1024
+
 var (
 	ConfigPodTrigger      chan bool
 	ConfigUpdateDbTrigger chan *UpdateDb
@@ -80,7 +201,7 @@ var (
 
 func init() {
 	ConfigPodTrigger = make(chan bool)
-	ConfigUpdateDbTrigger = make(chan *UpdateDb, 10)
+	ConfigUpdateDbTrigger = make(chan *UpdateDb, ConfigUpdateDbQueueSize)
 }
 
 func (c *Config) GetVersion() string {
@@ -90,30 +211,19 @@ func (c *Config) GetVersion() string {
 	return ""
 }
 
-func (c *Config) addSmPolicyInfo(nwSlice *protos.NetworkSlice, dbUpdateChannel chan *UpdateDb) error {
-	for _, devGrp := range nwSlice.DeviceGroup {
-		for _, imsi := range devGrp.Imsi {
-			// Iterate over the IpDomainDetails slice
-			for _, ipDomain := range devGrp.IpDomainDetails {
-				smPolicyEntry := &SmPolicyUpdateEntry{
-					Imsi:   imsi,
-					Dnn:    ipDomain.DnnName, // Access DnnName from the IpDomain struct
-					Snssai: nwSlice.Nssai,
-				}
-				dbUpdate := &UpdateDb{
-					SmPolicyTable: smPolicyEntry,
-				}
-				dbUpdateChannel <- dbUpdate
-			}
-		}
-	}
-	return nil
-}
-
 func (c *Config) UpdateConfig(commChannel chan *protos.NetworkSliceResponse, dbUpdateChannel chan *UpdateDb) bool {
 	var minConfig bool
+	if staticPlmns == nil {
+		staticPlmns = append([]PlmnSupportItem{}, UdrConfig.Configuration.PlmnSupportList...)
+	}
+	<-slicesRestored
+	first := true
 	for rsp := range commChannel {
 		logger.GrpcLog.Infoln("received updateConfig in the udr app:", rsp)
+		if first {
+			reconcileDeletedSlices(rsp, dbUpdateChannel)
+			first = false
+		}
 		for _, ns := range rsp.NetworkSlice {
 			logger.GrpcLog.Infoln("network slice name", ns.Name)
 			if ns.Site != nil {
@@ -122,28 +232,13 @@ func (c *Config) UpdateConfig(commChannel chan *protos.NetworkSliceResponse, dbU
 				logger.GrpcLog.Infoln("site name", site.SiteName)
 				if site.Plmn != nil {
 					logger.GrpcLog.Infoln("plmn mcc", site.Plmn.Mcc)
-					plmn := PlmnSupportItem{}
-					plmn.PlmnId.Mnc = site.Plmn.Mnc
-					plmn.PlmnId.Mcc = site.Plmn.Mcc
-					found := false
-					for _, cplmn := range UdrConfig.Configuration.PlmnSupportList {
-						if (cplmn.PlmnId.Mnc == plmn.PlmnId.Mnc) && (cplmn.PlmnId.Mcc == plmn.PlmnId.Mcc) {
-							found = true
-							break
-						}
-					}
-					if !found {
-						UdrConfig.Configuration.PlmnSupportList = append(UdrConfig.Configuration.PlmnSupportList, plmn)
-					}
 				} else {
 					logger.GrpcLog.Infoln("plmn not present in the message")
 				}
 			}
-			err := c.addSmPolicyInfo(ns, dbUpdateChannel)
-			if err != nil {
-				logger.GrpcLog.Errorf("error in adding sm policy info to db %v", err)
-			}
+			reconcileSlice(ns, dbUpdateChannel)
 		}
+		reconcilePlmnSupportList()
 		if !minConfig {
 			// first slice Created
 			if len(UdrConfig.Configuration.PlmnSupportList) > 0 {
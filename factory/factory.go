@@ -21,8 +21,21 @@ import (
 
 var UdrConfig Config
 
+// DbOperation tells the config update DB routine what to do with an entry.
+type DbOperation int
+
+const (
+	DbOperationAdd DbOperation = iota
+	DbOperationRemove
+)
+
+// UpdateDb carries either an SM policy entry, the subscriber data of one IMSI
+// or a network slice whose data was applied.
 type UpdateDb struct {
-	SmPolicyTable *SmPolicyUpdateEntry
+	SmPolicyTable  *SmPolicyUpdateEntry
+	SubscriberData *SubscriberDataEntry
+	AppliedSlice   *protos.NetworkSlice
+	Operation      DbOperation
 }
 
 type SmPolicyUpdateEntry struct {
@@ -33,6 +46,13 @@ type SmPolicyUpdateEntry struct {
 
 // TODO: Support configuration update from REST api
 func InitConfigFactory(f string) error {
+	return InitConfigFactoryWithOverrides(f, nil)
+}
+
+// InitConfigFactoryWithOverrides loads the configuration file and layers
+// overrides on top of it. Precedence is defaults < file < environment
+// (UDR_* variables, see EnvName) < flagValues.
+func InitConfigFactoryWithOverrides(f string, flagValues map[string]string) error {
 	if content, err := os.ReadFile(f); err != nil {
 		return err
 	} else {
@@ -41,12 +61,23 @@ func InitConfigFactory(f string) error {
 		if yamlErr := yaml.Unmarshal(content, &UdrConfig); yamlErr != nil {
 			return yamlErr
 		}
-		if UdrConfig.Configuration.Mongodb.AuthUrl == "" {
-			authUrl := UdrConfig.Configuration.Mongodb.Url
-			UdrConfig.Configuration.Mongodb.AuthUrl = authUrl
+		if err := UdrConfig.ApplyEnvOverrides(os.LookupEnv); err != nil {
+			return err
+		}
+		if err := UdrConfig.ApplyOverrides(flagValues); err != nil {
+			return err
+		}
+		if UdrConfig.Configuration == nil {
+			return ConfigErrors{{Field: "configuration", Reason: "required block is missing"}}
 		}
-		if UdrConfig.Configuration.Mongodb.AuthKeysDbName == "" {
-			UdrConfig.Configuration.Mongodb.AuthKeysDbName = "authentication"
+		if UdrConfig.Configuration.Mongodb != nil {
+			if UdrConfig.Configuration.Mongodb.AuthUrl == "" {
+				authUrl := UdrConfig.Configuration.Mongodb.Url
+				UdrConfig.Configuration.Mongodb.AuthUrl = authUrl
+			}
+			if UdrConfig.Configuration.Mongodb.AuthKeysDbName == "" {
+				UdrConfig.Configuration.Mongodb.AuthKeysDbName = "authentication"
+			}
 		}
 		if UdrConfig.Configuration.WebuiUri == "" {
 			UdrConfig.Configuration.WebuiUri = "webui:9876"
@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+ * UDR Configuration Overrides
+ */
+
+package factory
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is prepended to every environment variable that overrides a
+// configuration field, e.g. UDR_CONFIGURATION_MONGODB_URL.
+const EnvPrefix = "UDR"
+
+// legacyManagedByConfigPodEnv is the pre-override-scheme way of enabling the
+// config pod client. It is still honored, below UDR_* variables.
+const legacyManagedByConfigPodEnv = "MANAGED_BY_CONFIG_POD"
+
+const redacted = "REDACTED"
+
+// OverrideKeys returns the dotted YAML path of every configuration field that
+// can be overridden, e.g. "configuration.sbi.port". Nested lists such as
+// plmnSupportList are overridden as a whole with a YAML/JSON value.
+func OverrideKeys() []string {
+	var keys []string
+	walkFields(reflect.TypeOf(Config{}), "", func(path string) {
+		keys = append(keys, path)
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// EnvName returns the environment variable that overrides the field at the
+// given dotted YAML path.
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// ApplyEnvOverrides sets every configuration field whose environment
+// variable, as returned by EnvName, is present in lookup.
+func (c *Config) ApplyEnvOverrides(lookup func(string) (string, bool)) error {
+	values := make(map[string]string)
+	if v, ok := lookup(legacyManagedByConfigPodEnv); ok {
+		// anything but "true" disables the config pod client, as it always did
+		values["configuration.managedByConfigPod"] = strconv.FormatBool(v == "true")
+	}
+	for _, key := range OverrideKeys() {
+		if v, ok := lookup(EnvName(key)); ok {
+			values[key] = v
+		}
+	}
+	return c.ApplyOverrides(values)
+}
+
+// ApplyOverrides sets configuration fields from a map of dotted YAML paths to
+// raw string values. Missing intermediate blocks are allocated.
+func (c *Config) ApplyOverrides(values map[string]string) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs ConfigErrors
+	for _, key := range keys {
+		field, err := lookupField(reflect.ValueOf(c).Elem(), strings.Split(key, "."))
+		if err != nil {
+			errs.add(key, "%v", err)
+			continue
+		}
+		if err := setField(field, values[key]); err != nil {
+			errs.add(key, "cannot use %q: %v", values[key], err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Redacted returns the configuration rendered as YAML with credentials
+// embedded in database URLs masked, suitable for logging.
+func (c *Config) Redacted() string {
+	cp := *c
+	if c.Configuration != nil {
+		cfg := *c.Configuration
+		if cfg.Mongodb != nil {
+			mongodb := *cfg.Mongodb
+			mongodb.Url = redactUrl(mongodb.Url)
+			mongodb.AuthUrl = redactUrl(mongodb.AuthUrl)
+			cfg.Mongodb = &mongodb
+		}
+		cp.Configuration = &cfg
+	}
+	out, err := yaml.Marshal(&cp)
+	if err != nil {
+		return fmt.Sprintf("<unable to render configuration: %v>", err)
+	}
+	return string(out)
+}
+
+func redactUrl(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	return u.String()
+}
+
+func yamlName(f reflect.StructField) string {
+	tag := f.Tag.Get("yaml")
+	name := strings.Split(tag, ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func walkFields(t reflect.Type, prefix string, fn func(path string)) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := yamlName(f)
+		if name == "" || !f.IsExported() {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			walkFields(ft, path, fn)
+			continue
+		}
+		fn(path)
+	}
+}
+
+func lookupField(v reflect.Value, path []string) (reflect.Value, error) {
+	for _, name := range path {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown configuration field")
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if yamlName(v.Type().Field(i)) == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, fmt.Errorf("unknown configuration field")
+		}
+	}
+	return v, nil
+}
+
+func setField(v reflect.Value, raw string) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Struct:
+		return fmt.Errorf("is a block, override its individual fields instead")
+	default:
+		// lists and maps are given as a YAML (or JSON) document
+		ptr := reflect.New(v.Type())
+		if err := yaml.Unmarshal([]byte(raw), ptr.Interface()); err != nil {
+			return err
+		}
+		v.Set(ptr.Elem())
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR Configuration Overrides
+ */
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "UDR_CONFIGURATION_MONGODB_URL", EnvName("configuration.mongodb.url"))
+	assert.Equal(t, "UDR_CONFIGURATION_SBI_PORT", EnvName("configuration.sbi.port"))
+}
+
+// Flags take precedence over environment variables, which take precedence over the file
+func TestOverridePrecedence(t *testing.T) {
+	t.Setenv("UDR_CONFIGURATION_MONGODB_URL", "mongodb://env:27017")
+	t.Setenv("UDR_CONFIGURATION_SBI_PORT", "9000")
+	t.Setenv("MANAGED_BY_CONFIG_POD", "true")
+
+	err := InitConfigFactoryWithOverrides("udr_config.yaml", map[string]string{
+		"configuration.sbi.port": "9001",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "mongodb://env:27017", UdrConfig.Configuration.Mongodb.Url)
+	assert.Equal(t, "http://dummy", UdrConfig.Configuration.Mongodb.AuthUrl)
+	assert.Equal(t, 9001, UdrConfig.Configuration.Sbi.Port)
+	assert.Equal(t, "https", UdrConfig.Configuration.Sbi.Scheme)
+	assert.True(t, UdrConfig.Configuration.ManagedByConfigPod)
+}
+
+func TestLegacyManagedByConfigPodEnv(t *testing.T) {
+	for value, managed := range map[string]bool{"true": true, "": false, "yes": false, "1": false} {
+		cfg := Config{}
+		err := cfg.ApplyEnvOverrides(func(name string) (string, bool) {
+			return value, name == legacyManagedByConfigPodEnv
+		})
+		assert.NoError(t, err, value)
+		assert.Equal(t, managed, cfg.Configuration.ManagedByConfigPod, value)
+	}
+
+	// the UDR_* variables are parsed strictly
+	cfg := Config{}
+	err := cfg.ApplyEnvOverrides(func(name string) (string, bool) {
+		return "yes", name == EnvName("configuration.managedByConfigPod")
+	})
+	assert.Equal(t, []string{"configuration.managedByConfigPod"}, fields(err.(ConfigErrors)))
+}
+
+func TestOverrideAllocatesMissingBlocks(t *testing.T) {
+	cfg := Config{}
+	err := cfg.ApplyOverrides(map[string]string{
+		"configuration.sbi.tls.pem":       "/etc/udr/udr.pem",
+		"configuration.plmnSupportList":   `[{"plmnId":{"mcc":"001","mnc":"01"}}]`,
+		"logger.UDR.debugLevel":           "debug",
+		"configuration.mongodb.authUrl":   "mongodb://auth:27017",
+		"configuration.sbi.unknownOption": "x",
+	})
+	assert.Equal(t, []string{"configuration.sbi.unknownOption"}, fields(err.(ConfigErrors)))
+	assert.Equal(t, "/etc/udr/udr.pem", cfg.Configuration.Sbi.Tls.Pem)
+	assert.Equal(t, "001", cfg.Configuration.PlmnSupportList[0].PlmnId.Mcc)
+	assert.Equal(t, "debug", cfg.Logger.UDR.DebugLevel)
+	assert.Equal(t, "mongodb://auth:27017", cfg.Configuration.Mongodb.AuthUrl)
+}
+
+func TestRedactedMasksPasswords(t *testing.T) {
+	cfg := Config{Configuration: &Configuration{Mongodb: &Mongodb{Url: "mongodb://user:secret@db:27017"}}}
+	out := cfg.Redacted()
+	assert.NotContains(t, out, "secret")
+	assert.Contains(t, out, "user:REDACTED@db:27017")
+	assert.Equal(t, "mongodb://user:secret@db:27017", cfg.Configuration.Mongodb.Url)
+}
@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+ * UDR config pod state reconciliation
+ */
+
+package factory
+
+import (
+	"sort"
+	"sync"
+
+	protos "github.com/5GC-DEV/config5g-cdac/proto/sdcoreConfig"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+)
+
+type smPolicyKey struct {
+	Imsi string
+	Dnn  string
+	Sst  string
+	Sd   string
+}
+
+// sliceState is what was last applied to the DB and to the PLMN support list
+// for one network slice received from the config pod.
+type sliceState struct {
+	slice    *protos.NetworkSlice
+	plmn     *models.PlmnId
+	smPolicy map[smPolicyKey]struct{}
+	imsis    map[string]struct{}
+}
+
+var (
+	// appliedSlices is owned by the UpdateConfig routine, keyed by slice name.
+	appliedSlices = make(map[string]*sliceState)
+	// staticPlmns holds the PLMNs from the config file, which are never
+	// removed by config pod updates. nil until the first update arrives.
+	staticPlmns []PlmnSupportItem
+	// slicesRestored is closed once the slices applied before a restart are
+	// back in appliedSlices, see RestoreAppliedSlices.
+	slicesRestored = make(chan struct{})
+	restoreSlices  sync.Once
+)
+
+// RestoreAppliedSlices restores the network slices whose data was applied to
+// the DB before the UDR restarted. The config pod updates wait for them: the
+// first update carries all the current slices, so the restored slices it
+// lacks were deleted while the UDR was down and their data is removed.
+func RestoreAppliedSlices(slices []*protos.NetworkSlice) {
+	restoreSlices.Do(func() {
+		for _, nwSlice := range slices {
+			appliedSlices[nwSlice.Name] = newSliceState(nwSlice)
+		}
+		close(slicesRestored)
+	})
+}
+
+// reconcileDeletedSlices deletes the applied slices missing from rsp, the
+// first update of the config pod.
+func reconcileDeletedSlices(rsp *protos.NetworkSliceResponse, dbUpdateChannel chan *UpdateDb) {
+	current := make(map[string]struct{}, len(rsp.NetworkSlice))
+	for _, nwSlice := range rsp.NetworkSlice {
+		current[nwSlice.Name] = struct{}{}
+	}
+	var deleted []string
+	for name := range appliedSlices {
+		if _, ok := current[name]; !ok {
+			deleted = append(deleted, name)
+		}
+	}
+	sort.Strings(deleted)
+	for _, name := range deleted {
+		logger.GrpcLog.Infof("network slice %s deleted while the UDR was down", name)
+		reconcileSlice(&protos.NetworkSlice{Name: name, OperationType: protos.OpType_SLICE_DELETE}, dbUpdateChannel)
+	}
+}
+
+func newSliceState(nwSlice *protos.NetworkSlice) *sliceState {
+	state := &sliceState{
+		slice:    nwSlice,
+		smPolicy: make(map[smPolicyKey]struct{}),
+		imsis:    make(map[string]struct{}),
+	}
+	for _, devGrp := range nwSlice.DeviceGroup {
+		for _, imsi := range devGrp.Imsi {
+			state.imsis[imsi] = struct{}{}
+		}
+	}
+	if nwSlice.Site != nil && nwSlice.Site.Plmn != nil {
+		state.plmn = &models.PlmnId{Mcc: nwSlice.Site.Plmn.Mcc, Mnc: nwSlice.Site.Plmn.Mnc}
+	}
+	if nwSlice.Nssai == nil {
+		return state
+	}
+	for _, devGrp := range nwSlice.DeviceGroup {
+		for _, imsi := range devGrp.Imsi {
+			for _, ipDomain := range devGrp.IpDomainDetails {
+				state.smPolicy[smPolicyKey{
+					Imsi: imsi,
+					Dnn:  ipDomain.DnnName,
+					Sst:  nwSlice.Nssai.Sst,
+					Sd:   nwSlice.Nssai.Sd,
+				}] = struct{}{}
+			}
+		}
+	}
+	return state
+}
+
+// smPolicyRefs counts how many applied slices need each SM policy entry, so
+// that an entry shared by two slices survives the removal of one of them.
+func smPolicyRefs() map[smPolicyKey]int {
+	refs := make(map[smPolicyKey]int)
+	for _, state := range appliedSlices {
+		for key := range state.smPolicy {
+			refs[key]++
+		}
+	}
+	return refs
+}
+
+// reconcileSlice diffs nwSlice against the previously applied state of the
+// same slice and queues DB additions and removals accordingly.
+func reconcileSlice(nwSlice *protos.NetworkSlice, dbUpdateChannel chan *UpdateDb) {
+	before := smPolicyRefs()
+	affected := make(map[string]struct{})
+	if old, ok := appliedSlices[nwSlice.Name]; ok {
+		for imsi := range old.imsis {
+			affected[imsi] = struct{}{}
+		}
+	}
+	if nwSlice.OperationType == protos.OpType_SLICE_DELETE {
+		logger.GrpcLog.Infof("network slice %s deleted", nwSlice.Name)
+		delete(appliedSlices, nwSlice.Name)
+	} else {
+		state := newSliceState(nwSlice)
+		appliedSlices[nwSlice.Name] = state
+		for imsi := range state.imsis {
+			affected[imsi] = struct{}{}
+		}
+		// entries are re-sent on every update; adding is idempotent and
+		// repairs the DB if it lost them
+		for key := range state.smPolicy {
+			dbUpdateChannel <- &UpdateDb{SmPolicyTable: key.entry(), Operation: DbOperationAdd}
+		}
+	}
+	after := smPolicyRefs()
+	for key := range before {
+		if after[key] == 0 {
+			dbUpdateChannel <- &UpdateDb{SmPolicyTable: key.entry(), Operation: DbOperationRemove}
+		}
+	}
+
+	imsis := make([]string, 0, len(affected))
+	for imsi := range affected {
+		imsis = append(imsis, imsi)
+	}
+	sort.Strings(imsis)
+	for _, imsi := range imsis {
+		if entry := buildSubscriberData(imsi); entry != nil {
+			dbUpdateChannel <- &UpdateDb{SubscriberData: entry, Operation: DbOperationAdd}
+		} else {
+			dbUpdateChannel <- &UpdateDb{
+				SubscriberData: &SubscriberDataEntry{Imsi: imsi},
+				Operation:      DbOperationRemove,
+			}
+		}
+	}
+
+	// the slice is recorded as applied once its data is queued
+	if nwSlice.OperationType == protos.OpType_SLICE_DELETE {
+		dbUpdateChannel <- &UpdateDb{AppliedSlice: &protos.NetworkSlice{Name: nwSlice.Name}, Operation: DbOperationRemove}
+	} else {
+		dbUpdateChannel <- &UpdateDb{AppliedSlice: nwSlice, Operation: DbOperationAdd}
+	}
+}
+
+func (key smPolicyKey) entry() *SmPolicyUpdateEntry {
+	return &SmPolicyUpdateEntry{
+		Imsi:   key.Imsi,
+		Dnn:    key.Dnn,
+		Snssai: &protos.NSSAI{Sst: key.Sst, Sd: key.Sd},
+	}
+}
+
+// reconcilePlmnSupportList rebuilds the PLMN support list from the config
+// file PLMNs plus those of the currently applied slices.
+func reconcilePlmnSupportList() {
+	names := make([]string, 0, len(appliedSlices))
+	for name := range appliedSlices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	plmns := append([]PlmnSupportItem{}, staticPlmns...)
+	for _, name := range names {
+		state := appliedSlices[name]
+		if state.plmn == nil {
+			continue
+		}
+		found := false
+		for _, cplmn := range plmns {
+			if (cplmn.PlmnId.Mnc == state.plmn.Mnc) && (cplmn.PlmnId.Mcc == state.plmn.Mcc) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			plmns = append(plmns, PlmnSupportItem{PlmnId: *state.plmn})
+		}
+	}
+	UdrConfig.Configuration.PlmnSupportList = plmns
+}
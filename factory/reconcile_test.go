@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR config pod state reconciliation
+ */
+
+package factory
+
+import (
+	"testing"
+
+	protos "github.com/5GC-DEV/config5g-cdac/proto/sdcoreConfig"
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSlice(name string, mcc string, imsis []string, dnns ...string) *protos.NetworkSlice {
+	devGrp := &protos.DeviceGroup{Name: name + "-group", Imsi: imsis}
+	for _, dnn := range dnns {
+		devGrp.IpDomainDetails = append(devGrp.IpDomainDetails, &protos.IpDomain{DnnName: dnn})
+	}
+	return &protos.NetworkSlice{
+		Name:        name,
+		Nssai:       &protos.NSSAI{Sst: "1", Sd: "010203"},
+		Site:        &protos.SiteInfo{Plmn: &protos.PlmnId{Mcc: mcc, Mnc: "93"}},
+		DeviceGroup: []*protos.DeviceGroup{devGrp},
+	}
+}
+
+func drain(ch chan *UpdateDb) (added, removed []string) {
+	for {
+		select {
+		case msg := <-ch:
+			if msg.SmPolicyTable == nil {
+				continue
+			}
+			key := msg.SmPolicyTable.Imsi + "/" + msg.SmPolicyTable.Dnn
+			if msg.Operation == DbOperationRemove {
+				removed = append(removed, key)
+			} else {
+				added = append(added, key)
+			}
+		default:
+			return added, removed
+		}
+	}
+}
+
+func TestReconcileSliceRemovesStaleEntries(t *testing.T) {
+	appliedSlices = make(map[string]*sliceState)
+	staticPlmns = []PlmnSupportItem{{PlmnId: models.PlmnId{Mcc: "001", Mnc: "01"}}}
+	UdrConfig = Config{Configuration: &Configuration{}}
+	ch := make(chan *UpdateDb, 100)
+
+	reconcileSlice(testSlice("slice1", "208", []string{"1", "2"}, "internet", "ims"), ch)
+	reconcileSlice(testSlice("slice2", "310", []string{"2"}, "internet"), ch)
+	reconcilePlmnSupportList()
+	added, removed := drain(ch)
+	assert.ElementsMatch(t, []string{"1/internet", "1/ims", "2/internet", "2/ims", "2/internet"}, added)
+	assert.Empty(t, removed)
+	assert.Len(t, UdrConfig.Configuration.PlmnSupportList, 3)
+
+	// IMSI 2 and DNN ims leave slice1; 2/internet is still used by slice2
+	reconcileSlice(testSlice("slice1", "208", []string{"1"}, "internet"), ch)
+	_, removed = drain(ch)
+	assert.ElementsMatch(t, []string{"1/ims", "2/ims"}, removed)
+
+	deleted := testSlice("slice2", "310", nil)
+	deleted.OperationType = protos.OpType_SLICE_DELETE
+	reconcileSlice(deleted, ch)
+	reconcilePlmnSupportList()
+	added, removed = drain(ch)
+	assert.Empty(t, added)
+	assert.Equal(t, []string{"2/internet"}, removed)
+	assert.Equal(t, []PlmnSupportItem{
+		{PlmnId: models.PlmnId{Mcc: "001", Mnc: "01"}},
+		{PlmnId: models.PlmnId{Mcc: "208", Mnc: "93"}},
+	}, UdrConfig.Configuration.PlmnSupportList)
+}
+
+func TestReconcileSlicesDeletedWhileDown(t *testing.T) {
+	appliedSlices = make(map[string]*sliceState)
+	for _, nwSlice := range []*protos.NetworkSlice{
+		testSlice("slice1", "208", []string{"1"}, "internet"),
+		testSlice("slice2", "310", []string{"2"}, "internet"),
+	} {
+		appliedSlices[nwSlice.Name] = newSliceState(nwSlice)
+	}
+	ch := make(chan *UpdateDb, 100)
+
+	reconcileDeletedSlices(&protos.NetworkSliceResponse{
+		NetworkSlice: []*protos.NetworkSlice{testSlice("slice1", "208", []string{"1"}, "internet")},
+	}, ch)
+	assert.Contains(t, appliedSlices, "slice1")
+	assert.NotContains(t, appliedSlices, "slice2")
+
+	var removedSlices, removedImsis []string
+	for len(ch) > 0 {
+		msg := <-ch
+		switch {
+		case msg.AppliedSlice != nil && msg.Operation == DbOperationRemove:
+			removedSlices = append(removedSlices, msg.AppliedSlice.Name)
+		case msg.SubscriberData != nil && msg.Operation == DbOperationRemove:
+			removedImsis = append(removedImsis, msg.SubscriberData.Imsi)
+		}
+	}
+	assert.Equal(t, []string{"slice2"}, removedSlices)
+	assert.Equal(t, []string{"2"}, removedImsis)
+}
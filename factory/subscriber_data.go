@@ -0,0 +1,240 @@
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+ * UDR subscriber data derived from config pod device groups
+ */
+
+package factory
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	protos "github.com/5GC-DEV/config5g-cdac/proto/sdcoreConfig"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+)
+
+// SubscriberDataEntry is the subscriber data implied by the config pod for
+// one IMSI, aggregated over every network slice the IMSI belongs to.
+type SubscriberDataEntry struct {
+	Imsi string
+	// ServingPlmns is keyed by serving PLMN ID (MCC followed by MNC).
+	ServingPlmns map[string]*ServingPlmnSubscriberData
+	AmPolicyData *models.AmPolicyData
+}
+
+// ServingPlmnSubscriberData holds the provisioned datasets of one serving PLMN.
+type ServingPlmnSubscriberData struct {
+	AmData     *models.AccessAndMobilitySubscriptionData
+	SmfSelData *models.SmfSelectionSubscriptionData
+	// SmData holds one entry per S-NSSAI.
+	SmData []models.SessionManagementSubscriptionData
+}
+
+// ueAmbr is the UE AMBR of a serving PLMN, in bits per second.
+type ueAmbr struct {
+	uplink, downlink int64
+}
+
+// buildSubscriberData aggregates the applied slices into the subscriber data
+// of imsi. It returns nil when the IMSI is not part of any applied slice.
+func buildSubscriberData(imsi string) *SubscriberDataEntry {
+	names := make([]string, 0, len(appliedSlices))
+	for name := range appliedSlices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entry := &SubscriberDataEntry{
+		Imsi:         imsi,
+		ServingPlmns: make(map[string]*ServingPlmnSubscriberData),
+	}
+	// the UE AMBR of a serving PLMN is the largest MBR of its DNNs
+	ueAmbrs := make(map[string]ueAmbr)
+	smData := make(map[string]map[string]*models.SessionManagementSubscriptionData)
+	for _, name := range names {
+		nwSlice := appliedSlices[name].slice
+		if nwSlice == nil || nwSlice.Nssai == nil || nwSlice.Site == nil || nwSlice.Site.Plmn == nil {
+			continue
+		}
+		sst, err := strconv.ParseUint(nwSlice.Nssai.Sst, 10, 8)
+		if err != nil {
+			logger.GrpcLog.Errorf("network slice %s has invalid sst %q: %v", name, nwSlice.Nssai.Sst, err)
+			continue
+		}
+		snssai := models.Snssai{Sst: int32(sst), Sd: nwSlice.Nssai.Sd}
+		hexSnssai := fmt.Sprintf("%02x%s", snssai.Sst, snssai.Sd)
+		plmnId := nwSlice.Site.Plmn.Mcc + nwSlice.Site.Plmn.Mnc
+
+		for _, devGrp := range nwSlice.DeviceGroup {
+			if !containsImsi(devGrp, imsi) {
+				continue
+			}
+			plmnData, ok := entry.ServingPlmns[plmnId]
+			if !ok {
+				plmnData = &ServingPlmnSubscriberData{
+					AmData: &models.AccessAndMobilitySubscriptionData{
+						Nssai: &models.Nssai{},
+					},
+					SmfSelData: &models.SmfSelectionSubscriptionData{
+						SubscribedSnssaiInfos: make(map[string]models.SnssaiInfo),
+					},
+				}
+				entry.ServingPlmns[plmnId] = plmnData
+				smData[plmnId] = make(map[string]*models.SessionManagementSubscriptionData)
+			}
+			if !containsSnssai(plmnData.AmData.Nssai.DefaultSingleNssais, snssai) {
+				plmnData.AmData.Nssai.DefaultSingleNssais = append(plmnData.AmData.Nssai.DefaultSingleNssais, snssai)
+			}
+			sm, ok := smData[plmnId][hexSnssai]
+			if !ok {
+				singleNssai := snssai
+				sm = &models.SessionManagementSubscriptionData{
+					SingleNssai:       &singleNssai,
+					DnnConfigurations: make(map[string]models.DnnConfiguration),
+				}
+				smData[plmnId][hexSnssai] = sm
+			}
+			snssaiInfo := plmnData.SmfSelData.SubscribedSnssaiInfos[hexSnssai]
+			for _, ipDomain := range devGrp.IpDomainDetails {
+				if _, exists := sm.DnnConfigurations[ipDomain.DnnName]; !exists {
+					snssaiInfo.DnnInfos = append(snssaiInfo.DnnInfos, models.DnnInfo{Dnn: ipDomain.DnnName})
+				}
+				sm.DnnConfigurations[ipDomain.DnnName] = dnnConfiguration(ipDomain)
+				if qos := ipDomain.UeDnnQos; qos != nil {
+					ambr := ueAmbrs[plmnId]
+					ambr.uplink = max(ambr.uplink, qos.DnnMbrUplink)
+					ambr.downlink = max(ambr.downlink, qos.DnnMbrDownlink)
+					ueAmbrs[plmnId] = ambr
+				}
+			}
+			plmnData.SmfSelData.SubscribedSnssaiInfos[hexSnssai] = snssaiInfo
+		}
+		// the slices a UE belongs to are its subscription categories for AM policy
+		if containsSliceImsi(nwSlice, imsi) {
+			if entry.AmPolicyData == nil {
+				entry.AmPolicyData = &models.AmPolicyData{}
+			}
+			entry.AmPolicyData.SubscCats = append(entry.AmPolicyData.SubscCats, name)
+		}
+	}
+	if len(entry.ServingPlmns) == 0 {
+		return nil
+	}
+
+	plmnIds := make([]string, 0, len(smData))
+	for plmnId := range smData {
+		plmnIds = append(plmnIds, plmnId)
+	}
+	sort.Strings(plmnIds)
+	for _, plmnId := range plmnIds {
+		plmnData := entry.ServingPlmns[plmnId]
+		if ambr := ueAmbrs[plmnId]; ambr.uplink > 0 || ambr.downlink > 0 {
+			plmnData.AmData.SubscribedUeAmbr = &models.AmbrRm{
+				Uplink:   bitRate(ambr.uplink),
+				Downlink: bitRate(ambr.downlink),
+			}
+		}
+		hexSnssais := make([]string, 0, len(smData[plmnId]))
+		for hexSnssai := range smData[plmnId] {
+			hexSnssais = append(hexSnssais, hexSnssai)
+		}
+		sort.Strings(hexSnssais)
+		for _, hexSnssai := range hexSnssais {
+			plmnData.SmData = append(plmnData.SmData, *smData[plmnId][hexSnssai])
+		}
+	}
+	return entry
+}
+
+// dnnConfiguration maps a config pod IP domain onto a DNN configuration. The
+// session AMBR comes from the DNN MBR and the default QoS from the traffic
+// class, left out if its ARP priority is not within 1..15 of TS 29.571.
+func dnnConfiguration(ipDomain *protos.IpDomain) models.DnnConfiguration {
+	dnnConfig := models.DnnConfiguration{
+		PduSessionTypes: &models.PduSessionTypes{
+			DefaultSessionType:  models.PduSessionType_IPV4,
+			AllowedSessionTypes: []models.PduSessionType{models.PduSessionType_IPV4},
+		},
+		SscModes: &models.SscModes{
+			DefaultSscMode:  models.SscMode__1,
+			AllowedSscModes: []models.SscMode{models.SscMode__1},
+		},
+	}
+	qos := ipDomain.UeDnnQos
+	if qos == nil {
+		return dnnConfig
+	}
+	if qos.DnnMbrUplink > 0 || qos.DnnMbrDownlink > 0 {
+		dnnConfig.SessionAmbr = &models.Ambr{
+			Uplink:   bitRate(qos.DnnMbrUplink),
+			Downlink: bitRate(qos.DnnMbrDownlink),
+		}
+	}
+	if tc := qos.TrafficClass; tc != nil && tc.Qci != 0 {
+		if tc.Arp < 1 || tc.Arp > 15 {
+			logger.GrpcLog.Warnf("DNN %s: ARP priority %d is out of range, no default QoS", ipDomain.DnnName, tc.Arp)
+			return dnnConfig
+		}
+		// the traffic class carries the ARP priority only, the priority level
+		// of the 5QI is left to its default
+		dnnConfig.Var5gQosProfile = &models.SubscribedDefaultQos{
+			Var5qi: tc.Qci,
+			Arp: &models.Arp{
+				PriorityLevel: tc.Arp,
+				PreemptCap:    models.PreemptionCapability_MAY_PREEMPT,
+				PreemptVuln:   models.PreemptionVulnerability_PREEMPTABLE,
+			},
+		}
+	}
+	return dnnConfig
+}
+
+// bitRate formats a rate in bits per second as a TS 29.571 BitRate string,
+// using the largest unit that represents it exactly.
+func bitRate(bps int64) string {
+	units := []struct {
+		name  string
+		scale int64
+	}{
+		{"Tbps", 1e12},
+		{"Gbps", 1e9},
+		{"Mbps", 1e6},
+		{"Kbps", 1e3},
+	}
+	for _, unit := range units {
+		if bps != 0 && bps%unit.scale == 0 {
+			return fmt.Sprintf("%d %s", bps/unit.scale, unit.name)
+		}
+	}
+	return fmt.Sprintf("%d bps", bps)
+}
+
+func containsImsi(devGrp *protos.DeviceGroup, imsi string) bool {
+	for _, i := range devGrp.Imsi {
+		if i == imsi {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSliceImsi(nwSlice *protos.NetworkSlice, imsi string) bool {
+	for _, devGrp := range nwSlice.DeviceGroup {
+		if containsImsi(devGrp, imsi) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSnssai(list []models.Snssai, snssai models.Snssai) bool {
+	for _, s := range list {
+		if s == snssai {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR subscriber data derived from config pod device groups
+ */
+
+package factory
+
+import (
+	"testing"
+
+	protos "github.com/5GC-DEV/config5g-cdac/proto/sdcoreConfig"
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSubscriberData(t *testing.T) {
+	appliedSlices = make(map[string]*sliceState)
+	nwSlice := testSlice("slice1", "208", []string{"1"}, "internet")
+	nwSlice.DeviceGroup[0].IpDomainDetails[0].UeDnnQos = &protos.UeDnnQosInfo{
+		DnnMbrUplink:   20000000,
+		DnnMbrDownlink: 200000000,
+		TrafficClass:   &protos.TrafficClassInfo{Qci: 9, Arp: 6},
+	}
+	appliedSlices["slice1"] = newSliceState(nwSlice)
+
+	assert.Nil(t, buildSubscriberData("2"))
+
+	entry := buildSubscriberData("1")
+	if !assert.NotNil(t, entry) {
+		return
+	}
+	assert.Equal(t, []string{"slice1"}, entry.AmPolicyData.SubscCats)
+	plmnData := entry.ServingPlmns["20893"]
+	if !assert.NotNil(t, plmnData) {
+		return
+	}
+	snssai := models.Snssai{Sst: 1, Sd: "010203"}
+	assert.Equal(t, []models.Snssai{snssai}, plmnData.AmData.Nssai.DefaultSingleNssais)
+	assert.Equal(t, &models.AmbrRm{Uplink: "20 Mbps", Downlink: "200 Mbps"}, plmnData.AmData.SubscribedUeAmbr)
+	assert.Equal(t, []models.DnnInfo{{Dnn: "internet"}}, plmnData.SmfSelData.SubscribedSnssaiInfos["01010203"].DnnInfos)
+	if assert.Len(t, plmnData.SmData, 1) {
+		dnnConfig := plmnData.SmData[0].DnnConfigurations["internet"]
+		assert.Equal(t, &snssai, plmnData.SmData[0].SingleNssai)
+		assert.Equal(t, &models.Ambr{Uplink: "20 Mbps", Downlink: "200 Mbps"}, dnnConfig.SessionAmbr)
+		assert.Equal(t, int32(9), dnnConfig.Var5gQosProfile.Var5qi)
+		assert.Equal(t, int32(6), dnnConfig.Var5gQosProfile.Arp.PriorityLevel)
+		assert.Zero(t, dnnConfig.Var5gQosProfile.PriorityLevel)
+	}
+}
+
+func TestBuildSubscriberDataPerPlmn(t *testing.T) {
+	appliedSlices = make(map[string]*sliceState)
+	slice1 := testSlice("slice1", "208", []string{"1"}, "internet")
+	slice1.DeviceGroup[0].IpDomainDetails[0].UeDnnQos = &protos.UeDnnQosInfo{
+		DnnMbrUplink:   20000000,
+		DnnMbrDownlink: 200000000,
+		TrafficClass:   &protos.TrafficClassInfo{Qci: 9},
+	}
+	slice2 := testSlice("slice2", "001", []string{"1"}, "internet")
+	slice2.DeviceGroup[0].IpDomainDetails[0].UeDnnQos = &protos.UeDnnQosInfo{
+		DnnMbrUplink:   1000000,
+		DnnMbrDownlink: 10000000,
+	}
+	appliedSlices["slice1"] = newSliceState(slice1)
+	appliedSlices["slice2"] = newSliceState(slice2)
+
+	entry := buildSubscriberData("1")
+	if !assert.NotNil(t, entry) || !assert.Len(t, entry.ServingPlmns, 2) {
+		return
+	}
+	assert.Equal(t, &models.AmbrRm{Uplink: "20 Mbps", Downlink: "200 Mbps"},
+		entry.ServingPlmns["20893"].AmData.SubscribedUeAmbr)
+	assert.Equal(t, &models.AmbrRm{Uplink: "1 Mbps", Downlink: "10 Mbps"},
+		entry.ServingPlmns["00193"].AmData.SubscribedUeAmbr)
+	// no ARP priority, no default QoS
+	if assert.Len(t, entry.ServingPlmns["20893"].SmData, 1) {
+		assert.Nil(t, entry.ServingPlmns["20893"].SmData[0].DnnConfigurations["internet"].Var5gQosProfile)
+	}
+}
+
+func TestBitRate(t *testing.T) {
+	assert.Equal(t, "1 Gbps", bitRate(1000000000))
+	assert.Equal(t, "1500 Kbps", bitRate(1500000))
+	assert.Equal(t, "999 bps", bitRate(999))
+	assert.Equal(t, "0 bps", bitRate(0))
+}
@@ -0,0 +1,253 @@
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+ * UDR Configuration Validation
+ */
+
+package factory
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	mccRegex = regexp.MustCompile(`^[0-9]{3}$`)
+	mncRegex = regexp.MustCompile(`^[0-9]{2,3}$`)
+	sdRegex  = regexp.MustCompile(`^[A-Fa-f0-9]{6}$`)
+)
+
+// ConfigError describes a single problem found in the configuration.
+// Field is the YAML path of the offending value, e.g. "configuration.sbi.port".
+type ConfigError struct {
+	Field  string
+	Reason string
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// ConfigErrors collects every problem found by Validate so that they can be
+// reported at once instead of failing on the first one.
+type ConfigErrors []ConfigError
+
+func (errs ConfigErrors) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problem(s)):", len(errs))
+	for _, e := range errs {
+		b.WriteString("\n  - ")
+		b.WriteString(e.Error())
+	}
+	return b.String()
+}
+
+func (errs *ConfigErrors) add(field, format string, args ...interface{}) {
+	*errs = append(*errs, ConfigError{Field: field, Reason: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the configuration for missing required blocks and
+// malformed values. It returns nil or a ConfigErrors listing every problem.
+func (c *Config) Validate() error {
+	var errs ConfigErrors
+
+	if c.Info == nil {
+		errs.add("info", "required block is missing")
+	} else if c.Info.Version == "" {
+		errs.add("info.version", "must be set (expected %q)", UDR_EXPECTED_CONFIG_VERSION)
+	}
+
+	if c.Configuration == nil {
+		errs.add("configuration", "required block is missing")
+	} else {
+		c.Configuration.validate("configuration", &errs)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (c *Configuration) validate(path string, errs *ConfigErrors) {
+	if c.Sbi == nil {
+		errs.add(path+".sbi", "required block is missing")
+	} else {
+		c.Sbi.validate(path+".sbi", errs)
+	}
+
+	if c.Mongodb == nil {
+		errs.add(path+".mongodb", "required block is missing")
+	} else {
+		c.Mongodb.validate(path+".mongodb", errs)
+	}
+
+	if c.NrfUri != "" {
+		validateHttpUrl(path+".nrfUri", c.NrfUri, errs)
+	}
+
+	if c.WebuiUri != "" {
+		validateHostPort(path+".webuiUri", c.WebuiUri, errs)
+	}
+
+	for i, item := range c.PlmnSupportList {
+		item.validate(fmt.Sprintf("%s.plmnSupportList[%d]", path, i), errs)
+	}
+
+	if c.Cache != nil {
+		c.Cache.validate(path+".cache", errs)
+	}
+
+	if c.Registrations != nil {
+		c.Registrations.validate(path+".registrations", errs)
+	}
+
+	if c.AdminAddr != "" {
+		// the host may be left out to listen on every interface
+		_, port, err := net.SplitHostPort(c.AdminAddr)
+		if err != nil {
+			errs.add(path+".adminAddr", "%q must be in host:port form: %v", c.AdminAddr, err)
+		} else if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			errs.add(path+".adminAddr", "%q has an invalid port, must be between 1 and 65535", c.AdminAddr)
+		}
+	}
+}
+
+func (c *Cache) validate(path string, errs *ConfigErrors) {
+	if c.MaxEntries < 0 {
+		errs.add(path+".maxEntries", "%d must not be negative", c.MaxEntries)
+	}
+	if c.Ttl != "" {
+		if ttl, err := time.ParseDuration(c.Ttl); err != nil {
+			errs.add(path+".ttl", "%q is not a valid duration: %v", c.Ttl, err)
+		} else if ttl <= 0 {
+			errs.add(path+".ttl", "%q must be positive", c.Ttl)
+		}
+	}
+}
+
+func (r *Registrations) validate(path string, errs *ConfigErrors) {
+	if r.SmfMaxAge != "" {
+		if maxAge, err := time.ParseDuration(r.SmfMaxAge); err != nil {
+			errs.add(path+".smfMaxAge", "%q is not a valid duration: %v", r.SmfMaxAge, err)
+		} else if maxAge <= 0 {
+			errs.add(path+".smfMaxAge", "%q must be positive", r.SmfMaxAge)
+		}
+	}
+}
+
+func (s *Sbi) validate(path string, errs *ConfigErrors) {
+	switch s.Scheme {
+	case "http", "https":
+	case "":
+		errs.add(path+".scheme", "must be set to \"http\" or \"https\"")
+	default:
+		errs.add(path+".scheme", "unsupported scheme %q, must be \"http\" or \"https\"", s.Scheme)
+	}
+
+	// A zero port means "use the default", see UDR_DEFAULT_PORT_INT.
+	if s.Port < 0 || s.Port > 65535 {
+		errs.add(path+".port", "%d is out of range, must be between 1 and 65535", s.Port)
+	}
+
+	if s.RegisterIPv4 != "" && strings.ContainsAny(s.RegisterIPv4, "/: ") {
+		errs.add(path+".registerIPv4", "%q must be a bare IP address or hostname", s.RegisterIPv4)
+	}
+
+	if s.Tls == nil {
+		return
+	}
+	validateFile(path+".tls.pem", s.Tls.Pem, errs)
+	validateFile(path+".tls.key", s.Tls.Key, errs)
+}
+
+func (m *Mongodb) validate(path string, errs *ConfigErrors) {
+	if m.Name == "" {
+		errs.add(path+".name", "must be set")
+	}
+	if m.Url == "" {
+		errs.add(path+".url", "must be set")
+	} else {
+		validateUrl(path+".url", m.Url, errs)
+	}
+	if m.AuthUrl != "" {
+		validateUrl(path+".authUrl", m.AuthUrl, errs)
+	}
+}
+
+func (p *PlmnSupportItem) validate(path string, errs *ConfigErrors) {
+	if !mccRegex.MatchString(p.PlmnId.Mcc) {
+		errs.add(path+".plmnId.mcc", "%q must be exactly 3 digits", p.PlmnId.Mcc)
+	}
+	if !mncRegex.MatchString(p.PlmnId.Mnc) {
+		errs.add(path+".plmnId.mnc", "%q must be 2 or 3 digits", p.PlmnId.Mnc)
+	}
+	for i, snssai := range p.SNssaiList {
+		snssaiPath := fmt.Sprintf("%s.snssaiList[%d]", path, i)
+		if snssai.Sst < 0 || snssai.Sst > 255 {
+			errs.add(snssaiPath+".sst", "%d is out of range, must be between 0 and 255", snssai.Sst)
+		}
+		if snssai.Sd != "" && !sdRegex.MatchString(snssai.Sd) {
+			errs.add(snssaiPath+".sd", "%q must be 6 hexadecimal digits", snssai.Sd)
+		}
+	}
+}
+
+func validateUrl(field, raw string, errs *ConfigErrors) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		errs.add(field, "%q is not a valid URL: %v", raw, err)
+		return
+	}
+	if u.Scheme == "" || u.Host == "" {
+		errs.add(field, "%q must be an absolute URL with scheme and host", raw)
+	}
+}
+
+func validateHttpUrl(field, raw string, errs *ConfigErrors) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		errs.add(field, "%q is not a valid URL: %v", raw, err)
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		errs.add(field, "%q must use the \"http\" or \"https\" scheme", raw)
+	} else if u.Host == "" {
+		errs.add(field, "%q has no host", raw)
+	}
+}
+
+func validateHostPort(field, raw string, errs *ConfigErrors) {
+	host, port, err := net.SplitHostPort(raw)
+	if err != nil {
+		errs.add(field, "%q must be in host:port form: %v", raw, err)
+		return
+	}
+	if host == "" {
+		errs.add(field, "%q has no host", raw)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		errs.add(field, "%q has an invalid port, must be between 1 and 65535", raw)
+	}
+}
+
+func validateFile(field, path string, errs *ConfigErrors) {
+	if path == "" {
+		errs.add(field, "must be set")
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		errs.add(field, "cannot access %q: %v", path, err)
+		return
+	}
+	if info.IsDir() {
+		errs.add(field, "%q is a directory, expected a file", path)
+	}
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR Configuration Validation
+ */
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Info: &Info{Version: UDR_EXPECTED_CONFIG_VERSION},
+		Configuration: &Configuration{
+			Sbi: &Sbi{Scheme: "http", Port: 8000},
+			Mongodb: &Mongodb{
+				Name: "aether",
+				Url:  "mongodb://mongodb:27017",
+			},
+			NrfUri:   "https://nrf:29510",
+			WebuiUri: "webui:9876",
+			PlmnSupportList: []PlmnSupportItem{{
+				PlmnId:     models.PlmnId{Mcc: "208", Mnc: "93"},
+				SNssaiList: []models.Snssai{{Sst: 1, Sd: "010203"}},
+			}},
+		},
+	}
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	assert.NoError(t, validConfig().Validate())
+}
+
+func TestValidateReportsMissingBlocks(t *testing.T) {
+	err := (&Config{}).Validate()
+	errs, ok := err.(ConfigErrors)
+	if !assert.True(t, ok, "expected ConfigErrors, got %T", err) {
+		return
+	}
+	assert.Equal(t, []string{"info", "configuration"}, fields(errs))
+
+	err = (&Config{Info: &Info{Version: "1.0.0"}, Configuration: &Configuration{}}).Validate()
+	assert.Equal(t, []string{"configuration.sbi", "configuration.mongodb"}, fields(err.(ConfigErrors)))
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := validConfig()
+	cfg.Configuration.Sbi.Scheme = "ftp"
+	cfg.Configuration.Sbi.Port = 70000
+	cfg.Configuration.Sbi.Tls = &Tls{Pem: "/nonexistent/udr.pem"}
+	cfg.Configuration.Mongodb.Url = "dummy"
+	cfg.Configuration.NrfUri = "nrf:29510"
+	cfg.Configuration.WebuiUri = "webui"
+	cfg.Configuration.PlmnSupportList[0].PlmnId = models.PlmnId{Mcc: "20", Mnc: "9a"}
+	cfg.Configuration.PlmnSupportList[0].SNssaiList[0] = models.Snssai{Sst: 256, Sd: "xyz"}
+	cfg.Configuration.Cache = &Cache{Enable: true, MaxEntries: -1, Ttl: "soon"}
+	cfg.Configuration.Registrations = &Registrations{SmfMaxAge: "-1h"}
+	cfg.Configuration.AdminAddr = "localhost"
+
+	err := cfg.Validate()
+	errs, ok := err.(ConfigErrors)
+	if !assert.True(t, ok, "expected ConfigErrors, got %T", err) {
+		return
+	}
+	assert.Equal(t, []string{
+		"configuration.sbi.scheme",
+		"configuration.sbi.port",
+		"configuration.sbi.tls.pem",
+		"configuration.sbi.tls.key",
+		"configuration.mongodb.url",
+		"configuration.nrfUri",
+		"configuration.webuiUri",
+		"configuration.plmnSupportList[0].plmnId.mcc",
+		"configuration.plmnSupportList[0].plmnId.mnc",
+		"configuration.plmnSupportList[0].snssaiList[0].sst",
+		"configuration.plmnSupportList[0].snssaiList[0].sd",
+		"configuration.cache.maxEntries",
+		"configuration.cache.ttl",
+		"configuration.registrations.smfMaxAge",
+		"configuration.adminAddr",
+	}, fields(errs))
+}
+
+func TestSampleConfigsAreValid(t *testing.T) {
+	for _, file := range []string{"udr_config.yaml", "udr_config_with_custom_webui_url.yaml"} {
+		assert.NoError(t, InitConfigFactory(file))
+		assert.NoError(t, UdrConfig.Validate(), file)
+	}
+}
+
+func TestInitConfigFactoryWithoutMongodbDoesNotPanic(t *testing.T) {
+	assert.NotPanics(t, func() {
+		err := InitConfigFactory("udr_config_test_missing_mongodb.yaml")
+		assert.NoError(t, err)
+		assert.Error(t, UdrConfig.Validate())
+	})
+}
+
+func fields(errs ConfigErrors) []string {
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Field)
+	}
+	return out
+}
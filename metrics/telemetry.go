@@ -17,9 +17,12 @@ import (
 
 // UdrStats captures UDR stats
 type UdrStats struct {
-	udrSubscriptionData *prometheus.CounterVec
-	udrApplicationData  *prometheus.CounterVec
-	udrPolicyData       *prometheus.CounterVec
+	udrSubscriptionData      *prometheus.CounterVec
+	udrApplicationData       *prometheus.CounterVec
+	udrPolicyData            *prometheus.CounterVec
+	udrConfigDbUpdates       *prometheus.CounterVec
+	udrConfigDbUpdatePending prometheus.Gauge
+	udrDbCacheRequests       *prometheus.CounterVec
 }
 
 var udrStats *UdrStats
@@ -38,6 +41,18 @@ func initUdrStats() *UdrStats {
 			Name: "udr_policy_data",
 			Help: "Counter of total Policy data queries",
 		}, []string{"query_type", "resource_type", "result"}),
+		udrConfigDbUpdates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "udr_config_db_updates",
+			Help: "Counter of total config pod driven DB updates",
+		}, []string{"dataset", "result"}),
+		udrConfigDbUpdatePending: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "udr_config_db_updates_pending",
+			Help: "Number of config pod driven DB updates waiting to be applied",
+		}),
+		udrDbCacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "udr_db_cache_requests",
+			Help: "Counter of total DB reads served by the subscription data cache",
+		}, []string{"collection", "result"}),
 	}
 }
 
@@ -51,6 +66,15 @@ func (ps *UdrStats) register() error {
 	if err := prometheus.Register(ps.udrPolicyData); err != nil {
 		return err
 	}
+	if err := prometheus.Register(ps.udrConfigDbUpdates); err != nil {
+		return err
+	}
+	if err := prometheus.Register(ps.udrConfigDbUpdatePending); err != nil {
+		return err
+	}
+	if err := prometheus.Register(ps.udrDbCacheRequests); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -84,3 +108,18 @@ func IncrementUdrApplicationDataStats(queryType, resourceType, result string) {
 func IncrementUdrPolicyDataStats(queryType, resourceType, result string) {
 	udrStats.udrPolicyData.WithLabelValues(queryType, resourceType, result).Inc()
 }
+
+// AddUdrConfigDbUpdateStats adds count config pod driven DB updates of a dataset with the given result
+func AddUdrConfigDbUpdateStats(dataset, result string, count int) {
+	udrStats.udrConfigDbUpdates.WithLabelValues(dataset, result).Add(float64(count))
+}
+
+// SetUdrConfigDbUpdatePending sets the number of config pod driven DB updates waiting to be applied
+func SetUdrConfigDbUpdatePending(count int) {
+	udrStats.udrConfigDbUpdatePending.Set(float64(count))
+}
+
+// IncrementUdrDbCacheStats increments number of DB reads of a collection served by the cache with the given result (hit or miss)
+func IncrementUdrDbCacheStats(collection, result string) {
+	udrStats.udrDbCacheRequests.WithLabelValues(collection, result).Inc()
+}
@@ -0,0 +1,664 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omec-project/openapi/models"
+	udr_context "github.com/omec-project/udr/context"
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"github.com/omec-project/udr/producer/callback"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ApplicationDataResource describes an application data resource of
+// TS 29.519 stored one document per individual resource.
+type ApplicationDataResource struct {
+	// Path of the collection resource under /application-data
+	Path string
+	// IdParam is the path parameter of the individual resources
+	IdParam  string
+	metric   string
+	collName string
+	// idKey holds the resource ID in the stored documents
+	idKey   string
+	dataSub DataSubType
+	newData func() interface{}
+	// validate checks the data decoded by newData
+	validate func(data interface{}) error
+	queries  []applicationDataQuery
+	// the attributes matched against the data filters of subscriptions
+	dnnAttr, snssaiAttr, appIdAttr string
+	setNotifData                   func(notif *ApplicationDataChangeNotif, data interface{})
+}
+
+// applicationDataQuery maps a query parameter to the attribute it selects.
+type applicationDataQuery struct {
+	param string
+	attr  string
+	kind  queryKind
+}
+
+type queryKind int
+
+const (
+	queryString queryKind = iota
+	querySnssai
+	queryBool
+)
+
+var AppDataBdtPolicyData = &ApplicationDataResource{
+	Path:     "bdtPolicyData",
+	IdParam:  "bdtPolicyId",
+	metric:   "bdt-policy-data",
+	collName: APPDATA_BDTPOLICYDATA_DB_COLLECTION_NAME,
+	idKey:    "bdtPolicyId",
+	dataSub:  DataSubType_BDT_POLICY_DATA,
+	newData:  func() interface{} { return &ApplicationBdtPolicyData{} },
+	validate: func(data interface{}) error {
+		if data.(*ApplicationBdtPolicyData).BdtRefId == "" {
+			return fmt.Errorf("bdtRefId is missing")
+		}
+		return nil
+	},
+	queries: []applicationDataQuery{
+		{param: "bdt-policy-ids", attr: "bdtPolicyId"},
+		{param: "internal-group-ids", attr: "interGroupId"},
+		{param: "supis", attr: "supi"},
+	},
+	dnnAttr:    "dnn",
+	snssaiAttr: "snssai",
+	setNotifData: func(notif *ApplicationDataChangeNotif, data interface{}) {
+		notif.BdtPolicyData = data.(*ApplicationBdtPolicyData)
+	},
+}
+
+var AppDataIptvConfigData = &ApplicationDataResource{
+	Path:     "iptvConfigData",
+	IdParam:  "configurationId",
+	metric:   "iptv-config-data",
+	collName: APPDATA_IPTVCONFIGDATA_DB_COLLECTION_NAME,
+	idKey:    "configurationId",
+	dataSub:  DataSubType_IPTV_CONFIGURATION_DATA,
+	newData:  func() interface{} { return &IptvConfigData{} },
+	validate: func(data interface{}) error {
+		iptvConfigData := data.(*IptvConfigData)
+		if iptvConfigData.AfAppId == "" {
+			return fmt.Errorf("afAppId is missing")
+		}
+		if len(iptvConfigData.MultiAccCtrls) == 0 {
+			return fmt.Errorf("multiAccCtrls must not be empty")
+		}
+		for id, multiAccCtrl := range iptvConfigData.MultiAccCtrls {
+			if multiAccCtrl.AccStatus != "ALLOWED" && multiAccCtrl.AccStatus != "NOT_ALLOWED" {
+				return fmt.Errorf("multiAccCtrls[%s]: invalid accStatus %q", id, multiAccCtrl.AccStatus)
+			}
+		}
+		return nil
+	},
+	queries: []applicationDataQuery{
+		{param: "config-ids", attr: "configurationId"},
+		{param: "dnns", attr: "dnn"},
+		{param: "snssais", attr: "snssai", kind: querySnssai},
+		{param: "supis", attr: "supi"},
+		{param: "inter-group-ids", attr: "interGroupId"},
+	},
+	dnnAttr:    "dnn",
+	snssaiAttr: "snssai",
+	appIdAttr:  "afAppId",
+	setNotifData: func(notif *ApplicationDataChangeNotif, data interface{}) {
+		notif.IptvConfigData = data.(*IptvConfigData)
+	},
+}
+
+var AppDataServiceParamData = &ApplicationDataResource{
+	Path:     "serviceParamData",
+	IdParam:  "serviceParamId",
+	metric:   "service-param-data",
+	collName: APPDATA_SERVICEPARAMDATA_DB_COLLECTION_NAME,
+	idKey:    "serviceParamId",
+	dataSub:  DataSubType_SERVICE_PARAMETER_DATA,
+	newData:  func() interface{} { return &ServiceParameterData{} },
+	validate: func(data interface{}) error {
+		serviceParamData := data.(*ServiceParameterData)
+		return validateTargetUe(serviceParamData.Supi, serviceParamData.InterGroupId, serviceParamData.AnyUeInd ||
+			serviceParamData.UeIpv4 != "" || serviceParamData.UeIpv6 != "" || serviceParamData.UeMac != "")
+	},
+	queries: []applicationDataQuery{
+		{param: "ser-param-ids", attr: "serviceParamId"},
+		{param: "dnns", attr: "dnn"},
+		{param: "snssais", attr: "snssai", kind: querySnssai},
+		{param: "internal-group-ids", attr: "interGroupId"},
+		{param: "supis", attr: "supi"},
+		{param: "ue-ipv4s", attr: "ueIpv4"},
+		{param: "ue-ipv6s", attr: "ueIpv6"},
+		{param: "ue-macs", attr: "ueMac"},
+		{param: "any-ue", attr: "anyUeInd", kind: queryBool},
+	},
+	dnnAttr:    "dnn",
+	snssaiAttr: "snssai",
+	appIdAttr:  "appId",
+	setNotifData: func(notif *ApplicationDataChangeNotif, data interface{}) {
+		notif.SerParamData = data.(*ServiceParameterData)
+	},
+}
+
+var AppDataAmInfluenceData = &ApplicationDataResource{
+	Path:     "amInfluenceData",
+	IdParam:  "amInfluenceId",
+	metric:   "am-influence-data",
+	collName: APPDATA_AMINFLUDATA_DB_COLLECTION_NAME,
+	idKey:    "amInfluenceId",
+	dataSub:  DataSubType_AM_INFLUENCE_DATA,
+	newData:  func() interface{} { return &AmInfluData{} },
+	validate: func(data interface{}) error {
+		amInfluData := data.(*AmInfluData)
+		return validateTargetUe(amInfluData.Supi, amInfluData.InterGroupId, amInfluData.AnyUeInd)
+	},
+	queries: []applicationDataQuery{
+		{param: "am-influence-ids", attr: "amInfluenceId"},
+		{param: "dnns", attr: "dnnSnssaiInfos.dnn"},
+		{param: "snssais", attr: "dnnSnssaiInfos.snssai", kind: querySnssai},
+		{param: "internal-group-ids", attr: "interGroupId"},
+		{param: "supis", attr: "supi"},
+		{param: "any-ue", attr: "anyUeInd", kind: queryBool},
+	},
+	dnnAttr:    "dnnSnssaiInfos.dnn",
+	snssaiAttr: "dnnSnssaiInfos.snssai",
+	appIdAttr:  "appIds",
+	setNotifData: func(notif *ApplicationDataChangeNotif, data interface{}) {
+		notif.AmInfluData = data.(*AmInfluData)
+	},
+}
+
+var AppDataEventExposureData = &ApplicationDataResource{
+	Path:     "eventExposureData",
+	IdParam:  "eventExposureId",
+	metric:   "event-exposure-data",
+	collName: APPDATA_EVENTEXPOSUREDATA_DB_COLLECTION_NAME,
+	idKey:    "eventExposureId",
+	dataSub:  DataSubType_EVENT_EXPOSURE_DATA,
+	newData:  func() interface{} { return &EventExposureData{} },
+	validate: func(data interface{}) error {
+		eventExposureData := data.(*EventExposureData)
+		if len(eventExposureData.Events) == 0 {
+			return fmt.Errorf("events must not be empty")
+		}
+		if eventExposureData.NotifUri == "" {
+			return fmt.Errorf("notifUri is missing")
+		}
+		return validateTargetUe(eventExposureData.Supi, eventExposureData.InterGroupId, eventExposureData.AnyUeInd)
+	},
+	queries: []applicationDataQuery{
+		{param: "event-exposure-ids", attr: "eventExposureId"},
+		{param: "af-app-ids", attr: "afAppId"},
+		{param: "dnns", attr: "dnn"},
+		{param: "snssais", attr: "snssai", kind: querySnssai},
+		{param: "internal-group-ids", attr: "interGroupId"},
+		{param: "supis", attr: "supi"},
+		{param: "any-ue", attr: "anyUeInd", kind: queryBool},
+	},
+	dnnAttr:    "dnn",
+	snssaiAttr: "snssai",
+	appIdAttr:  "afAppId",
+	setNotifData: func(notif *ApplicationDataChangeNotif, data interface{}) {
+		notif.EventExposureData = data.(*EventExposureData)
+	},
+}
+
+// validateTargetUe checks that the data targets either a UE, a group of UEs or
+// any UE.
+func validateTargetUe(supi string, interGroupId string, anyUe bool) error {
+	targets := 0
+	for _, target := range []bool{supi != "", interGroupId != "", anyUe} {
+		if target {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return fmt.Errorf("exactly one of supi, interGroupId or any UE must be given")
+	}
+	return nil
+}
+
+func (resource *ApplicationDataResource) resourceUri(id string) string {
+	return fmt.Sprintf("%s/application-data/%s/%s",
+		udr_context.UDR_Self().GetIPv4GroupUri(udr_context.NUDR_DR), resource.Path, url.PathEscape(id))
+}
+
+// queryFilter turns the query parameters into a DB filter. List parameters
+// can be repeated or comma separated, S-NSSAIs are JSON encoded.
+func (resource *ApplicationDataResource) queryFilter(queryParams url.Values) (bson.M, error) {
+	filter := bson.M{}
+	for _, query := range resource.queries {
+		values := queryParams[query.param]
+		if len(values) == 0 {
+			continue
+		}
+		switch query.kind {
+		case queryString:
+			filter[query.attr] = bson.M{"$in": splitQueryValues(values)}
+		case queryBool:
+			anyUe, err := parseQueryBool(values[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", query.param, err)
+			}
+			filter[query.attr] = anyUe
+		case querySnssai:
+			snssais, err := parseQuerySnssais(values)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", query.param, err)
+			}
+			var matches []bson.M
+			for _, snssai := range snssais {
+				match := bson.M{query.attr + ".sst": snssai.Sst}
+				if snssai.Sd != "" {
+					match[query.attr+".sd"] = snssai.Sd
+				}
+				matches = append(matches, match)
+			}
+			filter["$or"] = matches
+		}
+	}
+	return filter, nil
+}
+
+func splitQueryValues(values []string) []string {
+	var split []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				split = append(split, v)
+			}
+		}
+	}
+	return split
+}
+
+func parseQueryBool(value string) (bool, error) {
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", value)
+}
+
+func parseQuerySnssais(values []string) ([]models.Snssai, error) {
+	var snssais []models.Snssai
+	for _, value := range values {
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			var list []models.Snssai
+			if err := json.Unmarshal([]byte(value), &list); err != nil {
+				return nil, err
+			}
+			snssais = append(snssais, list...)
+		} else {
+			var snssai models.Snssai
+			if err := json.Unmarshal([]byte(value), &snssai); err != nil {
+				return nil, err
+			}
+			snssais = append(snssais, snssai)
+		}
+	}
+	return snssais, nil
+}
+
+// responseDocument removes the attributes added by the UDR from doc.
+func (resource *ApplicationDataResource) responseDocument(doc map[string]interface{}) map[string]interface{} {
+	delete(doc, "_id")
+	delete(doc, resource.idKey)
+	return doc
+}
+
+func HandleApplicationDataResourceGet(resource *ApplicationDataResource,
+	queryParams url.Values,
+) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationData%sGet: queryParams=%#v", resource.Path, queryParams)
+
+	filter, err := resource.queryFilter(queryParams)
+	if err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrApplicationDataStats("get", resource.metric, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	docs, err := CommonDBClient.RestfulAPIGetMany(resource.collName, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrApplicationDataStats("get", resource.metric, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	response := make([]map[string]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		response = append(response, resource.responseDocument(doc))
+	}
+	stats.IncrementUdrApplicationDataStats("get", resource.metric, "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, response)
+}
+
+func HandleApplicationDataResourceIdGet(resource *ApplicationDataResource, id string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationData%sIdGet: id=%q", resource.Path, id)
+
+	doc, problemDetails := getDataFromDB(resource.collName, bson.M{resource.idKey: id})
+	if problemDetails != nil {
+		stats.IncrementUdrApplicationDataStats("get", resource.metric, "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrApplicationDataStats("get", resource.metric, "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, resource.responseDocument(doc))
+}
+
+func HandleApplicationDataResourceIdPut(resource *ApplicationDataResource, id string,
+	body []byte,
+) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationData%sIdPut: id=%q", resource.Path, id)
+
+	data := resource.newData()
+	if pd := ValidateRequestBody(data, body); pd != nil {
+		stats.IncrementUdrApplicationDataStats("update", resource.metric, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := decodeStrict(body, data); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrApplicationDataStats("update", resource.metric, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := resource.validate(data); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrApplicationDataStats("update", resource.metric, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	putData := util.ToBsonM(data)
+	putData[resource.idKey] = id
+	isExisted, err := CommonDBClient.RestfulAPIPutOne(resource.collName, bson.M{resource.idKey: id}, putData)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrApplicationDataStats("update", resource.metric, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrApplicationDataStats("update", resource.metric, "SUCCESS")
+	notifyApplicationDataChange(resource, id, data, putData)
+
+	if isExisted {
+		return httpwrapper.NewResponse(http.StatusOK, nil, data)
+	}
+	headers := http.Header{}
+	headers.Set("Location", resource.resourceUri(id))
+	return httpwrapper.NewResponse(http.StatusCreated, headers, data)
+}
+
+// HandleApplicationDataResourceIdPatch applies the JSON merge patch in body.
+func HandleApplicationDataResourceIdPatch(resource *ApplicationDataResource, id string,
+	body []byte,
+) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationData%sIdPatch: id=%q", resource.Path, id)
+
+	data := resource.newData()
+	problemDetails := mergePatchDocument(resource.collName, bson.M{resource.idKey: id}, body, data,
+		func() error { return resource.validate(data) })
+	if problemDetails != nil {
+		stats.IncrementUdrApplicationDataStats("update", resource.metric, "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrApplicationDataStats("update", resource.metric, "SUCCESS")
+	notifyApplicationDataChange(resource, id, data, util.ToBsonM(data))
+	return httpwrapper.NewResponse(http.StatusOK, nil, data)
+}
+
+func HandleApplicationDataResourceIdDelete(resource *ApplicationDataResource, id string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationData%sIdDelete: id=%q", resource.Path, id)
+
+	filter := bson.M{resource.idKey: id}
+	oldData, _ := CommonDBClient.RestfulAPIGetOne(resource.collName, filter)
+	problemDetails := deleteExistingDocument(resource.collName, filter)
+	if problemDetails != nil {
+		stats.IncrementUdrApplicationDataStats("delete", resource.metric, "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrApplicationDataStats("delete", resource.metric, "SUCCESS")
+	notifyApplicationDataChange(resource, id, nil, oldData)
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
+
+// notifyApplicationDataChange notifies the subscribers whose data filters
+// match doc of the change of the resource id, a deletion if data is nil.
+func notifyApplicationDataChange(resource *ApplicationDataResource, id string, data interface{},
+	doc map[string]interface{},
+) {
+	subscriptions, err := CommonDBClient.RestfulAPIGetMany(APPDATA_SUBS_DB_COLLECTION_NAME, bson.M{})
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		return
+	}
+
+	notif := ApplicationDataChangeNotif{ResUri: resource.resourceUri(id)}
+	if data == nil {
+		notif.DelResources = []string{notif.ResUri}
+	} else {
+		resource.setNotifData(&notif, data)
+	}
+	doc = normalizeDocument(doc)
+	now := time.Now()
+	for _, subscriptionDoc := range subscriptions {
+		var subscription ApplicationDataSubs
+		if err := json.Unmarshal(util.MapToByte(subscriptionDoc), &subscription); err != nil {
+			logger.DataRepoLog.Warnf("application data subscription %v: %v", subscriptionDoc["subsId"], err)
+			continue
+		}
+		if subscription.Expiry != nil && !now.Before(*subscription.Expiry) {
+			continue
+		}
+		if resource.subscribed(subscription.DataFilters, doc) {
+			go callback.SendApplicationDataChangeNotif(subscription.NotificationUri,
+				[]ApplicationDataChangeNotif{notif})
+		}
+	}
+}
+
+// subscribed reports whether one of dataFilters selects doc. No filter
+// selects any application data.
+func (resource *ApplicationDataResource) subscribed(dataFilters []DataFilter, doc map[string]interface{}) bool {
+	if len(dataFilters) == 0 {
+		return true
+	}
+	for _, dataFilter := range dataFilters {
+		if dataFilter.DataSub != resource.dataSub {
+			continue
+		}
+		if matchesAny(dataFilter.Dnns, attributeValues(doc, resource.dnnAttr)) &&
+			matchesAny(dataFilter.InternalGroupIds, attributeValues(doc, "interGroupId")) &&
+			matchesAny(dataFilter.Supis, attributeValues(doc, "supi")) &&
+			(resource.appIdAttr == "" || matchesAny(dataFilter.AppIds, attributeValues(doc, resource.appIdAttr))) &&
+			matchesSnssai(dataFilter.Snssais, attributeValues(doc, resource.snssaiAttr)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(wanted []string, values []interface{}) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, value := range values {
+		for _, w := range wanted {
+			if value == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesSnssai(wanted []models.Snssai, values []interface{}) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, value := range values {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var snssai models.Snssai
+		if err := json.Unmarshal(util.MapToByte(object), &snssai); err != nil {
+			continue
+		}
+		for _, w := range wanted {
+			if w.Sst == snssai.Sst && w.Sd == snssai.Sd {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// attributeValues returns the values at the dotted path in doc, looking into
+// the elements of the arrays on the way.
+func attributeValues(doc map[string]interface{}, path string) []interface{} {
+	if doc == nil || path == "" {
+		return nil
+	}
+	values := []interface{}{doc}
+	for _, key := range strings.Split(path, ".") {
+		var next []interface{}
+		for _, value := range values {
+			if object, ok := value.(map[string]interface{}); ok {
+				next = append(next, object[key])
+			}
+		}
+		values = nil
+		for _, value := range next {
+			if array, ok := value.([]interface{}); ok {
+				values = append(values, array...)
+			} else if value != nil {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// normalizeDocument turns the BSON types of doc into plain JSON types.
+func normalizeDocument(doc map[string]interface{}) map[string]interface{} {
+	normalized := map[string]interface{}{}
+	if doc != nil {
+		if err := json.Unmarshal(util.MapToByte(doc), &normalized); err != nil {
+			logger.DataRepoLog.Warnln(err)
+		}
+	}
+	return normalized
+}
+
+func validateApplicationDataSubs(subscription *ApplicationDataSubs) error {
+	if subscription.NotificationUri == "" {
+		return fmt.Errorf("notificationUri is missing")
+	}
+	for _, dataFilter := range subscription.DataFilters {
+		switch dataFilter.DataSub {
+		case DataSubType_BDT_POLICY_DATA, DataSubType_IPTV_CONFIGURATION_DATA, DataSubType_SERVICE_PARAMETER_DATA,
+			DataSubType_AM_INFLUENCE_DATA, DataSubType_EVENT_EXPOSURE_DATA:
+		case DataSubType_INFLUENCE_DATA, DataSubType_PFD_DATA:
+			// their changes are notified to the subscriptions of their own
+			// subs-to-notify resources only
+			return fmt.Errorf("dataSub %q is not supported", dataFilter.DataSub)
+		default:
+			return fmt.Errorf("unknown dataSub %q", dataFilter.DataSub)
+		}
+	}
+	return nil
+}
+
+func HandleApplicationDataSubsToNotifyPost(subscription *ApplicationDataSubs) *httpwrapper.Response {
+	logger.DataRepoLog.Infoln("handle ApplicationDataSubsToNotifyPost")
+
+	if err := validateApplicationDataSubs(subscription); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrApplicationDataStats("create", "app-data-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	subsId := uuid.New().String()
+	data := util.ToBsonM(*subscription)
+	data["subsId"] = subsId
+	if _, err := CommonDBClient.RestfulAPIPutOne(APPDATA_SUBS_DB_COLLECTION_NAME, bson.M{"subsId": subsId},
+		data); err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrApplicationDataStats("create", "app-data-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrApplicationDataStats("create", "app-data-subscription", "SUCCESS")
+
+	/* Contains the URI of the newly created resource, according
+	   to the structure: {apiRoot}/application-data/subs-to-notify/{subsId} */
+	headers := http.Header{}
+	headers.Set("Location", fmt.Sprintf("%s/application-data/subs-to-notify/%s",
+		udr_context.UDR_Self().GetIPv4GroupUri(udr_context.NUDR_DR), subsId))
+	return httpwrapper.NewResponse(http.StatusCreated, headers, subscription)
+}
+
+func HandleApplicationDataSubsToNotifySubsIdGet(subsId string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationDataSubsToNotifySubsIdGet: subsId=%q", subsId)
+
+	data, problemDetails := getDataFromDB(APPDATA_SUBS_DB_COLLECTION_NAME, bson.M{"subsId": subsId})
+	if problemDetails != nil {
+		stats.IncrementUdrApplicationDataStats("get", "app-data-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	delete(data, "_id")
+	delete(data, "subsId")
+	stats.IncrementUdrApplicationDataStats("get", "app-data-subscription", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, data)
+}
+
+func HandleApplicationDataSubsToNotifySubsIdPut(subsId string,
+	subscription *ApplicationDataSubs,
+) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationDataSubsToNotifySubsIdPut: subsId=%q", subsId)
+
+	if err := validateApplicationDataSubs(subscription); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrApplicationDataStats("update", "app-data-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	filter := bson.M{"subsId": subsId}
+	if _, problemDetails := getDataFromDB(APPDATA_SUBS_DB_COLLECTION_NAME, filter); problemDetails != nil {
+		stats.IncrementUdrApplicationDataStats("update", "app-data-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	data := util.ToBsonM(*subscription)
+	data["subsId"] = subsId
+	if _, err := CommonDBClient.RestfulAPIPutOne(APPDATA_SUBS_DB_COLLECTION_NAME, filter, data); err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrApplicationDataStats("update", "app-data-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrApplicationDataStats("update", "app-data-subscription", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, subscription)
+}
+
+func HandleApplicationDataSubsToNotifySubsIdDelete(subsId string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationDataSubsToNotifySubsIdDelete: subsId=%q", subsId)
+
+	problemDetails := deleteExistingDocument(APPDATA_SUBS_DB_COLLECTION_NAME, bson.M{"subsId": subsId})
+	if problemDetails != nil {
+		stats.IncrementUdrApplicationDataStats("delete", "app-data-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrApplicationDataStats("delete", "app-data-subscription", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"time"
+
+	"github.com/omec-project/openapi/models"
+)
+
+// Application data of TS 29.519 which the openapi models do not provide.
+
+type DataSubType string
+
+const (
+	DataSubType_INFLUENCE_DATA          DataSubType = "INFLUENCE_DATA"
+	DataSubType_BDT_POLICY_DATA         DataSubType = "BDT_POLICY_DATA"
+	DataSubType_IPTV_CONFIGURATION_DATA DataSubType = "IPTV_CONFIGURATION_DATA"
+	DataSubType_SERVICE_PARAMETER_DATA  DataSubType = "SERVICE_PARAMETER_DATA"
+	DataSubType_AM_INFLUENCE_DATA       DataSubType = "AM_INFLUENCE_DATA"
+	DataSubType_EVENT_EXPOSURE_DATA     DataSubType = "EVENT_EXPOSURE_DATA"
+	DataSubType_PFD_DATA                DataSubType = "PFD_DATA"
+)
+
+// ApplicationBdtPolicyData is the background data transfer policy negotiated
+// for an AF, not to be confused with the PCF BdtPolicyData.
+type ApplicationBdtPolicyData struct {
+	InterGroupId string         `json:"interGroupId,omitempty" bson:"interGroupId"`
+	Supi         string         `json:"supi,omitempty" bson:"supi"`
+	BdtRefId     string         `json:"bdtRefId" bson:"bdtRefId"`
+	Dnn          string         `json:"dnn,omitempty" bson:"dnn"`
+	Snssai       *models.Snssai `json:"snssai,omitempty" bson:"snssai"`
+	ResUri       string         `json:"resUri,omitempty" bson:"resUri"`
+	ResetIds     []string       `json:"resetIds,omitempty" bson:"resetIds"`
+}
+
+type IptvConfigData struct {
+	Supi          string                            `json:"supi,omitempty" bson:"supi"`
+	InterGroupId  string                            `json:"interGroupId,omitempty" bson:"interGroupId"`
+	Dnn           string                            `json:"dnn,omitempty" bson:"dnn"`
+	Snssai        *models.Snssai                    `json:"snssai,omitempty" bson:"snssai"`
+	AfAppId       string                            `json:"afAppId" bson:"afAppId"`
+	MultiAccCtrls map[string]MulticastAccessControl `json:"multiAccCtrls" bson:"multiAccCtrls"`
+	SuppFeat      string                            `json:"suppFeat,omitempty" bson:"suppFeat"`
+	ResUri        string                            `json:"resUri,omitempty" bson:"resUri"`
+	ResetIds      []string                          `json:"resetIds,omitempty" bson:"resetIds"`
+}
+
+type MulticastAccessControl struct {
+	SrcIpv4Addr     string `json:"srcIpv4Addr,omitempty" bson:"srcIpv4Addr"`
+	SrcIpv6Addr     string `json:"srcIpv6Addr,omitempty" bson:"srcIpv6Addr"`
+	MulticastV4Addr string `json:"multicastV4Addr,omitempty" bson:"multicastV4Addr"`
+	MulticastV6Addr string `json:"multicastV6Addr,omitempty" bson:"multicastV6Addr"`
+	// ALLOWED or NOT_ALLOWED
+	AccStatus string `json:"accStatus" bson:"accStatus"`
+}
+
+type ServiceParameterData struct {
+	AppId        string         `json:"appId,omitempty" bson:"appId"`
+	Dnn          string         `json:"dnn,omitempty" bson:"dnn"`
+	Snssai       *models.Snssai `json:"snssai,omitempty" bson:"snssai"`
+	InterGroupId string         `json:"interGroupId,omitempty" bson:"interGroupId"`
+	Supi         string         `json:"supi,omitempty" bson:"supi"`
+	UeIpv4       string         `json:"ueIpv4,omitempty" bson:"ueIpv4"`
+	UeIpv6       string         `json:"ueIpv6,omitempty" bson:"ueIpv6"`
+	UeMac        string         `json:"ueMac,omitempty" bson:"ueMac"`
+	AnyUeInd     bool           `json:"anyUeInd,omitempty" bson:"anyUeInd"`
+	// base64 encoded service parameters of TS 24.587 and TS 24.588
+	ParamOverPc5 string   `json:"paramOverPc5,omitempty" bson:"paramOverPc5"`
+	ParamOverUu  string   `json:"paramOverUu,omitempty" bson:"paramOverUu"`
+	SuppFeat     string   `json:"suppFeat,omitempty" bson:"suppFeat"`
+	ResUri       string   `json:"resUri,omitempty" bson:"resUri"`
+	ResetIds     []string `json:"resetIds,omitempty" bson:"resetIds"`
+}
+
+type DnnSnssaiInformation struct {
+	Dnn    string         `json:"dnn,omitempty" bson:"dnn"`
+	Snssai *models.Snssai `json:"snssai,omitempty" bson:"snssai"`
+}
+
+type AmInfluData struct {
+	AppIds         []string               `json:"appIds,omitempty" bson:"appIds"`
+	DnnSnssaiInfos []DnnSnssaiInformation `json:"dnnSnssaiInfos,omitempty" bson:"dnnSnssaiInfos"`
+	InterGroupId   string                 `json:"interGroupId,omitempty" bson:"interGroupId"`
+	Supi           string                 `json:"supi,omitempty" bson:"supi"`
+	AnyUeInd       bool                   `json:"anyUeInd,omitempty" bson:"anyUeInd"`
+	// in seconds
+	PolicyDuration int32    `json:"policyDuration,omitempty" bson:"policyDuration"`
+	EvSubs         []string `json:"evSubs,omitempty" bson:"evSubs"`
+	NotifUri       string   `json:"notifUri,omitempty" bson:"notifUri"`
+	NotifCorrId    string   `json:"notifCorrId,omitempty" bson:"notifCorrId"`
+	ThruReq        bool     `json:"thruReq,omitempty" bson:"thruReq"`
+	SuppFeat       string   `json:"suppFeat,omitempty" bson:"suppFeat"`
+	ResUri         string   `json:"resUri,omitempty" bson:"resUri"`
+	ResetIds       []string `json:"resetIds,omitempty" bson:"resetIds"`
+}
+
+// EventExposureData is the event exposure subscription of an AF.
+type EventExposureData struct {
+	AfAppId      string         `json:"afAppId,omitempty" bson:"afAppId"`
+	AfId         string         `json:"afId,omitempty" bson:"afId"`
+	Dnn          string         `json:"dnn,omitempty" bson:"dnn"`
+	Snssai       *models.Snssai `json:"snssai,omitempty" bson:"snssai"`
+	InterGroupId string         `json:"interGroupId,omitempty" bson:"interGroupId"`
+	Supi         string         `json:"supi,omitempty" bson:"supi"`
+	AnyUeInd     bool           `json:"anyUeInd,omitempty" bson:"anyUeInd"`
+	Events       []string       `json:"events" bson:"events"`
+	NotifUri     string         `json:"notifUri" bson:"notifUri"`
+	NotifId      string         `json:"notifId,omitempty" bson:"notifId"`
+	SuppFeat     string         `json:"suppFeat,omitempty" bson:"suppFeat"`
+	ResUri       string         `json:"resUri,omitempty" bson:"resUri"`
+	ResetIds     []string       `json:"resetIds,omitempty" bson:"resetIds"`
+}
+
+// ApplicationDataSubs is a subscription to application data changes.
+type ApplicationDataSubs struct {
+	NotificationUri   string       `json:"notificationUri" bson:"notificationUri"`
+	DataFilters       []DataFilter `json:"dataFilters,omitempty" bson:"dataFilters"`
+	Expiry            *time.Time   `json:"expiry,omitempty" bson:"expiry"`
+	SupportedFeatures string       `json:"supportedFeatures,omitempty" bson:"supportedFeatures"`
+}
+
+// DataFilter selects the application data of a subscription, an empty list
+// matches any value.
+type DataFilter struct {
+	DataSub          DataSubType     `json:"dataSub" bson:"dataSub"`
+	Dnns             []string        `json:"dnns,omitempty" bson:"dnns"`
+	Snssais          []models.Snssai `json:"snssais,omitempty" bson:"snssais"`
+	InternalGroupIds []string        `json:"internalGroupIds,omitempty" bson:"internalGroupIds"`
+	Supis            []string        `json:"supis,omitempty" bson:"supis"`
+	AppIds           []string        `json:"appIds,omitempty" bson:"appIds"`
+}
+
+type ApplicationDataChangeNotif struct {
+	IptvConfigData    *IptvConfigData           `json:"iptvConfigData,omitempty"`
+	BdtPolicyData     *ApplicationBdtPolicyData `json:"bdtPolicyData,omitempty"`
+	SerParamData      *ServiceParameterData     `json:"serParamData,omitempty"`
+	AmInfluData       *AmInfluData              `json:"amInfluData,omitempty"`
+	EventExposureData *EventExposureData        `json:"eventExposureData,omitempty"`
+	DelResources      []string                  `json:"delResources,omitempty"`
+	ResUri            string                    `json:"resUri"`
+}
@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR application data resources
+ */
+
+package producer
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestApplicationDataQueryFilter(t *testing.T) {
+	filter, err := AppDataIptvConfigData.queryFilter(url.Values{
+		"dnns":    {"internet,ims", "iot"},
+		"snssais": {`[{"sst":1,"sd":"010203"},{"sst":2}]`},
+		"ignored": {"x"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{
+		"dnn": bson.M{"$in": []string{"internet", "ims", "iot"}},
+		"$or": []bson.M{
+			{"snssai.sst": int32(1), "snssai.sd": "010203"},
+			{"snssai.sst": int32(2)},
+		},
+	}, filter)
+
+	filter, err = AppDataAmInfluenceData.queryFilter(url.Values{"any-ue": {"true"}})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"anyUeInd": true}, filter)
+
+	_, err = AppDataAmInfluenceData.queryFilter(url.Values{"any-ue": {"yes"}})
+	assert.Error(t, err)
+	_, err = AppDataIptvConfigData.queryFilter(url.Values{"snssais": {"1"}})
+	assert.Error(t, err)
+}
+
+func TestApplicationDataSubscribed(t *testing.T) {
+	doc := normalizeDocument(bson.M{
+		"supi":   "imsi-1",
+		"appIds": []string{"app-1"},
+		"dnnSnssaiInfos": []bson.M{
+			{"dnn": "internet", "snssai": bson.M{"sst": 1, "sd": "010203"}},
+		},
+	})
+
+	assert.True(t, AppDataAmInfluenceData.subscribed(nil, doc))
+	assert.True(t, AppDataAmInfluenceData.subscribed([]DataFilter{{
+		DataSub: DataSubType_AM_INFLUENCE_DATA,
+		Dnns:    []string{"ims", "internet"},
+		Snssais: []models.Snssai{{Sst: 1, Sd: "010203"}},
+		AppIds:  []string{"app-1"},
+	}}, doc))
+	assert.False(t, AppDataAmInfluenceData.subscribed([]DataFilter{{
+		DataSub: DataSubType_IPTV_CONFIGURATION_DATA,
+	}}, doc), "other data subscribed")
+	assert.False(t, AppDataAmInfluenceData.subscribed([]DataFilter{{
+		DataSub: DataSubType_AM_INFLUENCE_DATA,
+		Snssais: []models.Snssai{{Sst: 1}},
+	}}, doc))
+	assert.False(t, AppDataAmInfluenceData.subscribed([]DataFilter{{
+		DataSub: DataSubType_AM_INFLUENCE_DATA,
+		Supis:   []string{"imsi-2"},
+	}}, doc))
+	// an S-NSSAI stored in a wrong shape matches no filter instead of panicking
+	assert.False(t, matchesSnssai([]models.Snssai{{Sst: 1}}, []interface{}{"1-010203"}))
+}
+
+func TestApplicationDataValidation(t *testing.T) {
+	assert.NoError(t, AppDataAmInfluenceData.validate(&AmInfluData{AnyUeInd: true}))
+	assert.Error(t, AppDataAmInfluenceData.validate(&AmInfluData{}))
+	assert.Error(t, AppDataAmInfluenceData.validate(&AmInfluData{Supi: "imsi-1", AnyUeInd: true}))
+
+	assert.NoError(t, AppDataServiceParamData.validate(&ServiceParameterData{UeIpv4: "10.0.0.1"}))
+	assert.Error(t, AppDataServiceParamData.validate(&ServiceParameterData{Dnn: "internet"}))
+
+	assert.Error(t, AppDataIptvConfigData.validate(&IptvConfigData{AfAppId: "app-1",
+		MultiAccCtrls: map[string]MulticastAccessControl{"1": {AccStatus: "DENIED"}}}))
+	assert.NoError(t, AppDataIptvConfigData.validate(&IptvConfigData{AfAppId: "app-1",
+		MultiAccCtrls: map[string]MulticastAccessControl{"1": {AccStatus: "ALLOWED"}}}))
+
+	assert.NoError(t, validateApplicationDataSubs(&ApplicationDataSubs{NotificationUri: "http://af",
+		DataFilters: []DataFilter{{DataSub: DataSubType_AM_INFLUENCE_DATA}}}))
+	for _, dataSub := range []DataSubType{DataSubType_PFD_DATA, DataSubType_INFLUENCE_DATA, "UNKNOWN"} {
+		assert.Error(t, validateApplicationDataSubs(&ApplicationDataSubs{NotificationUri: "http://af",
+			DataFilters: []DataFilter{{DataSub: dataSub}}}), dataSub)
+	}
+}
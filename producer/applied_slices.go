@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"fmt"
+
+	protos "github.com/5GC-DEV/config5g-cdac/proto/sdcoreConfig"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/proto"
+)
+
+// CONFIGPOD_APPLIED_SLICES keeps the config pod network slices applied to the
+// DB, so that the slices deleted while the UDR was down are reconciled once
+// it is back.
+const CONFIGPOD_APPLIED_SLICES = "configPod.appliedSlices"
+
+// SaveAppliedSlice records that the data of nwSlice is in the DB.
+func SaveAppliedSlice(nwSlice *protos.NetworkSlice) error {
+	data, err := proto.Marshal(nwSlice)
+	if err != nil {
+		return fmt.Errorf("encoding network slice %s: %w", nwSlice.Name, err)
+	}
+	filter := bson.M{"name": nwSlice.Name}
+	_, err = CommonDBClient.RestfulAPIPutOne(CONFIGPOD_APPLIED_SLICES, filter, bson.M{"name": nwSlice.Name, "slice": data})
+	return err
+}
+
+// RemoveAppliedSlice records that the data of the network slice name is no
+// longer in the DB.
+func RemoveAppliedSlice(name string) error {
+	return CommonDBClient.RestfulAPIDeleteOne(CONFIGPOD_APPLIED_SLICES, bson.M{"name": name})
+}
+
+// AppliedSlices returns the network slices applied to the DB.
+func AppliedSlices() ([]*protos.NetworkSlice, error) {
+	docs, err := CommonDBClient.RestfulAPIGetMany(CONFIGPOD_APPLIED_SLICES, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	slices := make([]*protos.NetworkSlice, 0, len(docs))
+	for _, doc := range docs {
+		var data []byte
+		switch slice := doc["slice"].(type) {
+		case primitive.Binary:
+			data = slice.Data
+		case []byte:
+			data = slice
+		default:
+			return nil, fmt.Errorf("network slice %v is not encoded", doc["name"])
+		}
+		nwSlice := &protos.NetworkSlice{}
+		if err := proto.Unmarshal(data, nwSlice); err != nil {
+			return nil, fmt.Errorf("decoding network slice %v: %w", doc["name"], err)
+		}
+		slices = append(slices, nwSlice)
+	}
+	return slices, nil
+}
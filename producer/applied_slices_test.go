@@ -0,0 +1,31 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR config pod applied network slices
+ */
+
+package producer
+
+import (
+	"testing"
+
+	protos "github.com/5GC-DEV/config5g-cdac/proto/sdcoreConfig"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAppliedSlices(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &filteringDB{docs: map[string][]map[string]interface{}{}}
+	slice1 := &protos.NetworkSlice{Name: "slice1", Nssai: &protos.NSSAI{Sst: "1", Sd: "010203"}}
+	slice2 := &protos.NetworkSlice{Name: "slice2", Nssai: &protos.NSSAI{Sst: "2"}}
+
+	assert.NoError(t, SaveAppliedSlice(slice1))
+	assert.NoError(t, SaveAppliedSlice(slice2))
+	assert.NoError(t, RemoveAppliedSlice("slice1"))
+
+	slices, err := AppliedSlices()
+	assert.NoError(t, err)
+	if assert.Len(t, slices, 1) {
+		assert.True(t, proto.Equal(slice2, slices[0]))
+	}
+}
@@ -6,13 +6,63 @@
 package producer
 
 import (
+	"strings"
+
 	"github.com/omec-project/openapi/models"
+	udr_context "github.com/omec-project/udr/context"
 	"github.com/omec-project/udr/producer/callback"
 )
 
+// subscriptionDataResources maps the watched subscription data collections to
+// the template of their resource URI, see TS 29.505.
+var subscriptionDataResources = map[string]string{
+	SUBSCDATA_PROVISIONED_AMDATA:                   "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/am-data",
+	SUBSCDATA_PROVISIONED_SMFSELDATA:               "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/smf-selection-subscription-data",
+	SUBSCDATA_PROVISIONED_SMDATA:                   "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/sm-data",
+	SUBSCDATA_PROVISIONED_SMSDATA:                  "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/sms-data",
+	SUBSCDATA_PROVISIONED_TRACEDATA:                "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/trace-data",
+	SUBSCDATA_PROVISIONED_SMSMNGDATA:               "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/sms-mng-data",
+	SUBSCDATA_PROVISIONED_LCSPRIVACYDATA:           "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/lcs-privacy-data",
+	SUBSCDATA_PROVISIONED_LCSMODATA:                "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/lcs-mo-data",
+	SUBSCDATA_PROVISIONED_LCSBCADATA:               "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/lcs-bca-data",
+	SUBSCDATA_PROVISIONED_V2XDATA:                  "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/v2x-data",
+	SUBSCDATA_PROVISIONED_PROSEDATA:                "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/prose-data",
+	SUBSCDATA_PROVISIONED_MBSDATA:                  "/subscription-data/{ueId}/{servingPlmnId}/provisioned-data/mbs-data",
+	SUBSCDATA_SHAREDDATA:                           "/subscription-data/shared-data/{sharedDataId}",
+	SUBSCDATA_AUTHENTICATION_SUBSCRIPTION:          "/subscription-data/{ueId}/authentication-data/authentication-subscription",
+	SUBSCDATA_PPDATA:                               "/subscription-data/{ueId}/pp-data",
+	SUBSCDATA_OPERATORSPECIFICDATA:                 "/subscription-data/{ueId}/operator-specific-data",
+	SUBSCDATA_CTXDATA_AMF_3GPPACCESS:               "/subscription-data/{ueId}/context-data/amf-3gpp-access",
+	SUBSCDATA_CTXDATA_AMF_NON3GPPACCESS:            "/subscription-data/{ueId}/context-data/amf-non-3gpp-access",
+	SUBSCDATA_CTXDATA_IPSMGW:                       "/subscription-data/{ueId}/context-data/ip-sm-gw-access",
+	SUBSCDATA_CTXDATA_MWD:                          "/subscription-data/{ueId}/context-data/mwd",
+	SUBSCDATA_CTXDATA_LOCATION:                     "/subscription-data/{ueId}/context-data/location",
+	SUBSCDATA_CTXDATA_NIDDAUTHORIZATION:            "/subscription-data/{ueId}/context-data/nidd-authorizations",
+	SUBSCDATA_CTXDATA_SERVICESPECIFICAUTHORIZATION: "/subscription-data/{ueId}/context-data/service-specific-authorizations/{serviceType}",
+}
+
+// subscriptionDataResourceUri returns the URI of the subscription data
+// resource stored in collName. Without servingPlmnId, the URI of the UE
+// subscription data is returned for the provisioned data sets.
+func subscriptionDataResourceUri(collName string, ueId string, servingPlmnId string) string {
+	resourceUri, ok := subscriptionDataResources[collName]
+	if !ok {
+		resourceUri = "/subscription-data/{ueId}"
+	}
+	if servingPlmnId == "" {
+		resourceUri, _, _ = strings.Cut(resourceUri, "/{servingPlmnId}")
+	}
+	resourceUri = strings.ReplaceAll(resourceUri, "{ueId}", ueId)
+	resourceUri = strings.ReplaceAll(resourceUri, "{servingPlmnId}", servingPlmnId)
+	return udr_context.UDR_Self().GetIPv4GroupUri(udr_context.NUDR_DR) + resourceUri
+}
+
 func PreHandleOnDataChangeNotify(ueId string, resourceId string, patchItems []models.PatchItem,
 	origValue interface{}, newValue interface{},
 ) {
+	if changeStreamNotifications.Load() {
+		return
+	}
 	notifyItems := []models.NotifyItem{}
 	changes := []models.ChangeItem{}
 
@@ -64,5 +114,22 @@ func PreHandlePolicyDataChangeNotification(ueId string, dataId string, value int
 		return
 	}
 
-	go callback.SendPolicyDataChangeNotification(policyDataChangeNotification)
+	notifyPolicyDataChange(callback.PolicyDataResourcePath(policyDataChangeNotification), policyDataChangeNotification)
+}
+
+// notifyPolicyDataChange notifies the change of the policy data resource at
+// resourcePath.
+func notifyPolicyDataChange(resourcePath string, notification models.PolicyDataChangeNotification) {
+	if changeStreamNotifications.Load() {
+		return
+	}
+	go callback.SendPolicyDataChangeNotification(resourcePath, notification)
+}
+
+func sponsorConnectivityDataPath(sponsorId string) string {
+	return "/policy-data/sponsor-connectivity-data/" + sponsorId
+}
+
+func plmnUePolicySetPath(plmnId string) string {
+	return "/policy-data/plmns/" + plmnId + "/ue-policy-set"
 }
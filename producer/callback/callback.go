@@ -6,7 +6,13 @@
 package callback
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/omec-project/openapi/Nudr_DataRepository"
 	"github.com/omec-project/openapi/models"
@@ -14,36 +20,119 @@ import (
 	"github.com/omec-project/udr/logger"
 )
 
+// notificationTimeout bounds a notification posted to a subscriber, so that
+// one which does not answer holds no connection for long.
+const notificationTimeout = 10 * time.Second
+
+var notificationClient = &http.Client{Timeout: notificationTimeout}
+
 func SendOnDataChangeNotify(ueId string, notifyItems []models.NotifyItem) {
 	udrSelf := udr_context.UDR_Self()
 	configuration := Nudr_DataRepository.NewConfiguration()
 	client := Nudr_DataRepository.NewAPIClient(configuration)
 
-	for _, subscriptionDataSubscription := range udrSelf.SubscriptionDataSubscriptions {
-		if ueId == subscriptionDataSubscription.UeId {
-			onDataChangeNotifyUrl := subscriptionDataSubscription.CallbackReference
-
-			dataChangeNotify := models.DataChangeNotify{}
-			dataChangeNotify.UeId = ueId
-			dataChangeNotify.OriginalCallbackReference = []string{subscriptionDataSubscription.OriginalCallbackReference}
-			dataChangeNotify.NotifyItems = notifyItems
-			httpResponse, err := client.DataChangeNotifyCallbackDocumentApi.OnDataChangeNotify(context.TODO(),
-				onDataChangeNotifyUrl, dataChangeNotify)
-			if err != nil {
-				if httpResponse == nil {
-					logger.HttpLog.Errorln(err.Error())
-				} else if err.Error() != httpResponse.Status {
-					logger.HttpLog.Errorln(err.Error())
-				}
+	for _, subscriptionDataSubscription := range udrSelf.SubscriptionDataSubscribers(ueId, time.Now()) {
+		monitoredItems := MonitoredNotifyItems(subscriptionDataSubscription.MonitoredResourceUri, notifyItems)
+		if len(monitoredItems) == 0 {
+			continue
+		}
+		onDataChangeNotifyUrl := subscriptionDataSubscription.CallbackReference
+
+		dataChangeNotify := models.DataChangeNotify{}
+		dataChangeNotify.UeId = ueId
+		dataChangeNotify.OriginalCallbackReference = []string{subscriptionDataSubscription.OriginalCallbackReference}
+		dataChangeNotify.NotifyItems = monitoredItems
+		httpResponse, err := client.DataChangeNotifyCallbackDocumentApi.OnDataChangeNotify(context.TODO(),
+			onDataChangeNotifyUrl, dataChangeNotify)
+		if err != nil {
+			if httpResponse == nil {
+				logger.HttpLog.Errorln(err.Error())
+			} else if err.Error() != httpResponse.Status {
+				logger.HttpLog.Errorln(err.Error())
 			}
 		}
 	}
 }
 
-func SendPolicyDataChangeNotification(policyDataChangeNotification models.PolicyDataChangeNotification) {
+// MonitoredNotifyItems returns the notify items of the resources monitored
+// through monitoredResourceUris, which covers the resources under each URI.
+// No monitored URI means every resource of the UE is monitored.
+func MonitoredNotifyItems(monitoredResourceUris []string, notifyItems []models.NotifyItem) []models.NotifyItem {
+	if len(monitoredResourceUris) == 0 {
+		return notifyItems
+	}
+	var monitoredItems []models.NotifyItem
+	for _, notifyItem := range notifyItems {
+		if resourceMonitored(monitoredResourceUris, dataResourcePath(notifyItem.ResourceId)) {
+			monitoredItems = append(monitoredItems, notifyItem)
+		}
+	}
+	return monitoredItems
+}
+
+// PolicyDataResourcePath returns the path of the policy data resource which
+// changed, see TS 29.519.
+func PolicyDataResourcePath(notification models.PolicyDataChangeNotification) string {
+	switch {
+	case notification.SponsorConnectivityData != nil:
+		return "/policy-data/sponsor-connectivity-data/" + notification.SponsorId
+	case notification.BdtData != nil:
+		return "/policy-data/bdt-data/" + notification.BdtRefId
+	case notification.AmPolicyData != nil:
+		return "/policy-data/ues/" + notification.UeId + "/am-data"
+	case notification.UePolicySet != nil:
+		return "/policy-data/ues/" + notification.UeId + "/ue-policy-set"
+	case notification.UsageMonData != nil:
+		return "/policy-data/ues/" + notification.UeId + "/sm-data/" + notification.UsageMonId
+	case notification.SmPolicyData != nil:
+		return "/policy-data/ues/" + notification.UeId + "/sm-data"
+	}
+	return "/policy-data/ues/" + notification.UeId
+}
+
+// resourceMonitored reports whether one of monitoredResourceUris is the
+// resource at resourcePath or one of its parents. No monitored URI means
+// every resource is monitored.
+func resourceMonitored(monitoredResourceUris []string, resourcePath string) bool {
+	if len(monitoredResourceUris) == 0 {
+		return true
+	}
+	for _, monitoredResourceUri := range monitoredResourceUris {
+		monitoredPath := dataResourcePath(monitoredResourceUri)
+		if resourcePath == monitoredPath || strings.HasPrefix(resourcePath, monitoredPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// dataResourcePath returns the path of uri from /subscription-data or
+// /policy-data on, so that URIs with different API roots compare equal.
+func dataResourcePath(uri string) string {
+	path := uri
+	if u, err := url.Parse(uri); err == nil {
+		path = u.Path
+	}
+	path = strings.TrimSuffix(path, "/")
+	for _, root := range []string{"/subscription-data", "/policy-data"} {
+		if i := strings.Index(path+"/", root+"/"); i >= 0 {
+			return path[i:]
+		}
+	}
+	return path
+}
+
+// SendPolicyDataChangeNotification notifies the subscribers monitoring the
+// policy data resource at resourcePath.
+func SendPolicyDataChangeNotification(resourcePath string,
+	policyDataChangeNotification models.PolicyDataChangeNotification,
+) {
 	udrSelf := udr_context.UDR_Self()
 
-	for _, policyDataSubscription := range udrSelf.PolicyDataSubscriptions {
+	for _, policyDataSubscription := range udrSelf.PolicyDataSubscribers() {
+		if !resourceMonitored(policyDataSubscription.MonitoredResourceUris, resourcePath) {
+			continue
+		}
 		policyDataChangeNotificationUrl := policyDataSubscription.NotificationUri
 
 		configuration := Nudr_DataRepository.NewConfiguration()
@@ -59,3 +148,35 @@ func SendPolicyDataChangeNotification(policyDataChangeNotification models.Policy
 		}
 	}
 }
+
+// SendApplicationDataChangeNotif posts the application data change
+// notifications to notificationUri.
+func SendApplicationDataChangeNotif(notificationUri string, notifications interface{}) {
+	postNotification("application data change", notificationUri, notifications)
+}
+
+// SendPfdChangeNotification posts the PFD change notifications to notifyUri.
+func SendPfdChangeNotification(notifyUri string, notifications []models.PfdChangeNotification) {
+	postNotification("PFD change", notifyUri, notifications)
+}
+
+func postNotification(kind string, uri string, notifications interface{}) {
+	body, err := json.Marshal(notifications)
+	if err != nil {
+		logger.HttpLog.Errorln(err.Error())
+		return
+	}
+	httpResponse, err := notificationClient.Post(uri, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.HttpLog.Errorln(err.Error())
+		return
+	}
+	defer func() {
+		if rspCloseErr := httpResponse.Body.Close(); rspCloseErr != nil {
+			logger.HttpLog.Errorf("%s notification response body cannot close: %+v", kind, rspCloseErr)
+		}
+	}()
+	if httpResponse.StatusCode != http.StatusNoContent && httpResponse.StatusCode != http.StatusOK {
+		logger.HttpLog.Errorf("%s notification to %s: %s", kind, uri, httpResponse.Status)
+	}
+}
@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR data change notification filtering
+ */
+
+package callback
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	udr_context "github.com/omec-project/udr/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitoredNotifyItems(t *testing.T) {
+	amData := models.NotifyItem{ResourceId: "http://udr:8000/nudr-dr/v1/subscription-data/imsi-1/20893/provisioned-data/am-data"}
+	ppData := models.NotifyItem{ResourceId: "http://udr:8000/nudr-dr/v1/subscription-data/imsi-1/pp-data"}
+	notifyItems := []models.NotifyItem{amData, ppData}
+
+	assert.Equal(t, notifyItems, MonitoredNotifyItems(nil, notifyItems))
+	assert.Equal(t, []models.NotifyItem{amData}, MonitoredNotifyItems(
+		[]string{"https://udr.example/nudr-dr/v1/subscription-data/imsi-1/20893/provisioned-data"}, notifyItems))
+	assert.Equal(t, []models.NotifyItem{ppData}, MonitoredNotifyItems(
+		[]string{"/subscription-data/imsi-1/pp-data/"}, notifyItems))
+	assert.Equal(t, notifyItems, MonitoredNotifyItems(
+		[]string{"http://udr:8000/nudr-dr/v1/subscription-data/imsi-1"}, notifyItems))
+	assert.Empty(t, MonitoredNotifyItems(
+		[]string{"http://udr:8000/nudr-dr/v1/subscription-data/imsi-1/pp"}, notifyItems),
+		"prefixes match whole path segments only")
+}
+
+func TestSubscriptionDataSubscriptionExpiry(t *testing.T) {
+	udrSelf := udr_context.UDR_Self()
+	defer udrSelf.Reset()
+	now := time.Now()
+	expiry := now.Add(time.Minute)
+	expiring := udrSelf.NewSubscriptionDataSubscription(&models.SubscriptionDataSubscriptions{UeId: "imsi-1", Expiry: &expiry})
+	udrSelf.NewSubscriptionDataSubscription(&models.SubscriptionDataSubscriptions{UeId: "imsi-1"})
+
+	assert.Len(t, udrSelf.SubscriptionDataSubscribers("imsi-1", now), 2)
+	assert.Len(t, udrSelf.SubscriptionDataSubscribers("imsi-1", expiry), 1)
+	assert.Empty(t, udrSelf.DeleteExpiredSubscriptionDataSubscriptions(now))
+	assert.Equal(t, []string{expiring}, udrSelf.DeleteExpiredSubscriptionDataSubscriptions(expiry))
+	assert.False(t, udrSelf.DeleteSubscriptionDataSubscription(expiring))
+}
+
+func TestPolicyDataResourceMonitored(t *testing.T) {
+	smData := PolicyDataResourcePath(models.PolicyDataChangeNotification{
+		UeId: "imsi-1", SmPolicyData: &models.SmPolicyData{},
+	})
+	usageMonData := PolicyDataResourcePath(models.PolicyDataChangeNotification{
+		UeId: "imsi-1", UsageMonId: "mon-1", UsageMonData: &models.UsageMonData{},
+	})
+	bdtData := PolicyDataResourcePath(models.PolicyDataChangeNotification{
+		BdtRefId: "bdt-1", BdtData: &models.BdtData{},
+	})
+	assert.Equal(t, "/policy-data/ues/imsi-1/sm-data", smData)
+	assert.Equal(t, "/policy-data/ues/imsi-1/sm-data/mon-1", usageMonData)
+
+	perUe := []string{"http://pcf/nudr-dr/v1/policy-data/ues/imsi-1"}
+	assert.True(t, resourceMonitored(perUe, smData))
+	assert.True(t, resourceMonitored(perUe, usageMonData))
+	assert.False(t, resourceMonitored(perUe, bdtData))
+	assert.False(t, resourceMonitored([]string{"/policy-data/ues/imsi-2/sm-data"}, smData))
+	assert.False(t, resourceMonitored([]string{"/policy-data/ues/imsi-1/am-data"}, smData))
+	assert.True(t, resourceMonitored([]string{"/policy-data/ues/imsi-1/sm-data"}, usageMonData))
+	assert.True(t, resourceMonitored([]string{"http://udr/nudr-dr/v1/policy-data/bdt-data/"}, bdtData))
+	assert.True(t, resourceMonitored(nil, bdtData))
+}
+
+func TestPolicyDataSubscriptionsConcurrentAccess(t *testing.T) {
+	udrSelf := udr_context.UDR_Self()
+	defer udrSelf.Reset()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			subsId := udrSelf.NewPolicyDataSubscription(&models.PolicyDataSubscription{NotificationUri: "http://pcf"})
+			assert.True(t, udrSelf.ReplacePolicyDataSubscription(subsId, &models.PolicyDataSubscription{}))
+			assert.True(t, udrSelf.DeletePolicyDataSubscription(subsId))
+		}()
+		go func() {
+			defer wg.Done()
+			for _, subscription := range udrSelf.PolicyDataSubscribers() {
+				_ = subscription.NotificationUri
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Empty(t, udrSelf.PolicyDataSubscribers())
+	assert.False(t, udrSelf.DeletePolicyDataSubscription("1"))
+}
+
+func TestPostNotificationTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	defer func(client *http.Client) { notificationClient = client }(notificationClient)
+	notificationClient = &http.Client{Timeout: 50 * time.Millisecond}
+
+	done := make(chan struct{})
+	go func() {
+		postNotification("PFD change", server.URL, []models.PfdChangeNotification{})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification to an unresponsive subscriber did not time out")
+	}
+}
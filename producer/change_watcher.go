@@ -0,0 +1,424 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+	"github.com/omec-project/udr/producer/callback"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// CHANGE_STREAM_RESUME_TOKENS holds, per database, the resume token of the
+	// last change handled by the watcher.
+	CHANGE_STREAM_RESUME_TOKENS = "udr.changeStreamResumeTokens"
+
+	// CHANGE_WATCHER_LEASE holds the lease of the UDR replica running the
+	// change streams.
+	CHANGE_WATCHER_LEASE = "udr.changeWatcherLease"
+
+	changeWatchRetryInterval = 5 * time.Second
+	// the lease is renewed well before it expires so that a replica which
+	// stopped is taken over within changeWatcherLeaseTtl
+	changeWatcherLeaseTtl           = 30 * time.Second
+	changeWatcherLeaseRenewInterval = 10 * time.Second
+
+	// MongoDB error codes of a resume token that can no longer be used
+	mongoErrInvalidResumeToken      = 260
+	mongoErrChangeStreamHistoryLost = 286
+	mongoErrNamespaceExists         = 48
+)
+
+// changeStreamNotifications is set on every replica when the change watcher
+// sends the notifications, the handlers then leave it to the watcher so that
+// subscribers are not notified twice.
+var changeStreamNotifications atomic.Bool
+
+// policyDataCollections are the watched policy data collections.
+var policyDataCollections = map[string]bool{
+	POLICYDATA_UES_AMDATA:              true,
+	POLICYDATA_UES_UEPOLICYSET:         true,
+	POLICYDATA_PLMNS_UEPOLICYSET:       true,
+	POLICYDATA_UES_SMDATA:              true,
+	POLICYDATA_UES_SMDATA_USAGEMONDATA: true,
+	POLICYDATA_SPONSORCONNECTIVITYDATA: true,
+	POLICYDATA_BDTDATA:                 true,
+}
+
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	Ns            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	FullDocument             bson.M `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.M `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        struct {
+		UpdatedFields bson.M   `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+// StartChangeWatcher watches the subscription data and policy data
+// collections and notifies the subscribers of every insert, update and
+// delete, whoever made it. It resumes after the last change handled, also
+// across restarts. Every UDR replica calls it and leaves the notifications to
+// the watcher; the replica holding the watcher lease runs the change streams.
+// The deleted documents come from the pre-images of the changes, which need
+// MongoDB 6.0 or later.
+func StartChangeWatcher() {
+	changeStreamNotifications.Store(true)
+	owner := uuid.New().String()
+	if hostname, err := os.Hostname(); err == nil {
+		owner = hostname + "/" + owner
+	}
+	go runChangeWatcherLease(context.Background(), owner)
+}
+
+// runChangeWatcherLease runs the change streams while owner holds the watcher
+// lease, and stops them once it is lost.
+func runChangeWatcherLease(ctx context.Context, owner string) {
+	var stopWatching context.CancelFunc
+	ticker := time.NewTicker(changeWatcherLeaseRenewInterval)
+	defer ticker.Stop()
+	for {
+		held, err := acquireChangeWatcherLease(ctx, owner, time.Now())
+		if err != nil {
+			logger.DataRepoLog.Warnf("change watcher lease: %v", err)
+		}
+		switch {
+		case held && stopWatching == nil:
+			logger.DataRepoLog.Infof("%s holds the change watcher lease", owner)
+			var watchCtx context.Context
+			watchCtx, stopWatching = context.WithCancel(ctx)
+			startChangeStreams(watchCtx)
+		case !held && stopWatching != nil:
+			logger.DataRepoLog.Warnf("%s lost the change watcher lease", owner)
+			stopWatching()
+			stopWatching = nil
+		}
+		select {
+		case <-ctx.Done():
+			if stopWatching != nil {
+				stopWatching()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// acquireChangeWatcherLease takes or renews the watcher lease for owner,
+// which succeeds unless another replica holds a lease not yet expired.
+func acquireChangeWatcherLease(ctx context.Context, owner string, now time.Time) (bool, error) {
+	collClient, ok := uncached(CommonDBClient).(collectionDBInterface)
+	if !ok {
+		return false, errors.New("DB client does not support leases")
+	}
+	filter := bson.M{"_id": "watcher", "$or": bson.A{
+		bson.M{"owner": owner},
+		bson.M{"expiresAt": bson.M{"$lt": now}},
+	}}
+	update := bson.M{"$set": bson.M{"owner": owner, "expiresAt": now.Add(changeWatcherLeaseTtl)}}
+	_, err := collClient.GetCollection(CHANGE_WATCHER_LEASE).UpdateOne(ctx, filter, update,
+		options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// the upsert collided with the lease of another replica
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func startChangeStreams(ctx context.Context) {
+	var commonColls, authColls []string
+	for collName := range subscriptionDataResources {
+		if collName == SUBSCDATA_AUTHENTICATION_SUBSCRIPTION {
+			authColls = append(authColls, collName)
+		} else {
+			commonColls = append(commonColls, collName)
+		}
+	}
+	for collName := range policyDataCollections {
+		commonColls = append(commonColls, collName)
+	}
+	go watchChanges(ctx, "common", CommonDBClient, commonColls)
+	go watchChanges(ctx, "auth", AuthDBClient, authColls)
+}
+
+func watchChanges(ctx context.Context, name string, db DBInterface, collNames []string) {
+	collClient, ok := uncached(db).(collectionDBInterface)
+	if !ok {
+		logger.DataRepoLog.Errorf("%s DB client does not support change streams", name)
+		return
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"ns.coll": bson.M{"$in": collNames}}}}}
+	database := collClient.GetCollection(CHANGE_STREAM_RESUME_TOKENS).Database()
+	for {
+		// without pre-images the deletes could not be notified, so changes
+		// are only watched once every collection keeps them
+		err := enableChangeStreamPreImages(ctx, database, collNames)
+		if err != nil && ctx.Err() == nil {
+			logger.DataRepoLog.Errorf("change stream of %s DB: enable pre-images: %v", name, err)
+		} else if err = watchChangeStream(ctx, name, collClient, pipeline); err != nil && ctx.Err() == nil {
+			logger.DataRepoLog.Warnf("change stream of %s DB: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(changeWatchRetryInterval):
+		}
+	}
+}
+
+// enableChangeStreamPreImages makes the collections collNames of database
+// keep the pre-images of their changes, creating the collections not there
+// yet, so that their change events carry the documents deleted.
+func enableChangeStreamPreImages(ctx context.Context, database *mongo.Database, collNames []string) error {
+	preImages := bson.M{"enabled": true}
+	for _, collName := range collNames {
+		collMod := bson.D{{Key: "collMod", Value: collName}, {Key: "changeStreamPreAndPostImages", Value: preImages}}
+		err := database.RunCommand(ctx, collMod).Err()
+		if commandFailed(err, mongoErrNamespaceNotFound) {
+			err = database.CreateCollection(ctx, collName,
+				options.CreateCollection().SetChangeStreamPreAndPostImages(preImages))
+			if commandFailed(err, mongoErrNamespaceExists) {
+				// created concurrently, without the pre-images
+				err = database.RunCommand(ctx, collMod).Err()
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", collName, err)
+		}
+	}
+	return nil
+}
+
+func watchChangeStream(ctx context.Context, name string, collClient collectionDBInterface,
+	pipeline mongo.Pipeline,
+) error {
+	tokens := uncached(CommonDBClient).(collectionDBInterface).GetCollection(CHANGE_STREAM_RESUME_TOKENS)
+	database := collClient.GetCollection(CHANGE_STREAM_RESUME_TOKENS).Database()
+
+	streamOptions := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	var saved struct {
+		Token bson.Raw `bson:"token"`
+	}
+	if err := tokens.FindOne(ctx, bson.M{"_id": name}).Decode(&saved); err == nil && saved.Token != nil {
+		streamOptions.SetStartAfter(saved.Token)
+	}
+
+	stream, err := database.Watch(ctx, pipeline, streamOptions)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) &&
+		(cmdErr.Code == mongoErrInvalidResumeToken || cmdErr.Code == mongoErrChangeStreamHistoryLost) {
+		logger.DataRepoLog.Warnf("change stream of %s DB cannot resume, changes were missed: %v", name, err)
+		if _, err := tokens.DeleteOne(ctx, bson.M{"_id": name}); err != nil {
+			return err
+		}
+		stream, err = database.Watch(ctx, pipeline, streamOptions.SetStartAfter(nil))
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := stream.Close(context.Background()); err != nil {
+			logger.DataRepoLog.Debugln(err)
+		}
+	}()
+	logger.DataRepoLog.Infof("watching changes of %s DB", name)
+
+	for stream.Next(ctx) {
+		var event changeEvent
+		if err := stream.Decode(&event); err != nil {
+			logger.DataRepoLog.Warnf("change stream of %s DB: %v", name, err)
+		} else {
+			notifyChange(&event)
+		}
+		if _, err := tokens.UpdateOne(ctx, bson.M{"_id": name},
+			bson.M{"$set": bson.M{"token": stream.ResumeToken()}}, options.Update().SetUpsert(true)); err != nil {
+			logger.DataRepoLog.Warnf("save resume token of %s DB: %v", name, err)
+		}
+	}
+	return stream.Err()
+}
+
+func notifyChange(event *changeEvent) {
+	if _, ok := subscriptionDataResources[event.Ns.Coll]; ok {
+		if ueId, notifyItem := dataChangeNotifyItem(event); notifyItem != nil {
+			go callback.SendOnDataChangeNotify(ueId, []models.NotifyItem{*notifyItem})
+		}
+		return
+	}
+	if resourcePath, notification := policyDataChangeNotification(event); notification != nil {
+		go callback.SendPolicyDataChangeNotification(resourcePath, *notification)
+	}
+}
+
+// changedDocument returns the document of a change without its internal
+// fields, the document after the change unless it was deleted.
+func (event *changeEvent) changedDocument() bson.M {
+	doc := event.FullDocument
+	if doc == nil {
+		doc = event.FullDocumentBeforeChange
+	}
+	return withoutInternalFields(doc)
+}
+
+func withoutInternalFields(doc bson.M) bson.M {
+	if doc == nil {
+		return nil
+	}
+	stripped := make(bson.M, len(doc))
+	for key, value := range doc {
+		if key != "_id" && key != documentRevisionField && key != smfRegistrationRefreshField {
+			stripped[key] = value
+		}
+	}
+	return stripped
+}
+
+// dataChangeNotifyItem turns a change of a subscription data document into
+// the notify item of the UE owning it, nil if it has nothing to report.
+func dataChangeNotifyItem(event *changeEvent) (string, *models.NotifyItem) {
+	doc := event.changedDocument()
+	ueId, _ := doc["ueId"].(string)
+	// the shared data belong to no UE
+	if ueId == "" && strings.Contains(subscriptionDataResources[event.Ns.Coll], "{ueId}") {
+		if event.OperationType == "delete" && event.FullDocumentBeforeChange == nil {
+			logger.DataRepoLog.Warnf("delete of %s without pre-image not notified", event.Ns.Coll)
+			return "", nil
+		}
+		logger.DataRepoLog.Debugf("%s change of %s without ueId not notified", event.OperationType, event.Ns.Coll)
+		return "", nil
+	}
+
+	var changes []models.ChangeItem
+	switch event.OperationType {
+	case "insert":
+		changes = append(changes, models.ChangeItem{Op: models.ChangeType_ADD, Path: "/", NewValue: doc})
+	case "replace":
+		changes = append(changes, models.ChangeItem{
+			Op:        models.ChangeType_REPLACE,
+			Path:      "/",
+			OrigValue: withoutInternalFields(event.FullDocumentBeforeChange),
+			NewValue:  doc,
+		})
+	case "update":
+		for field, value := range withoutInternalFields(event.UpdateDescription.UpdatedFields) {
+			changes = append(changes, models.ChangeItem{
+				Op: models.ChangeType_REPLACE, Path: fieldPath(field), NewValue: value,
+			})
+		}
+		for _, field := range event.UpdateDescription.RemovedFields {
+			if field != documentRevisionField {
+				changes = append(changes, models.ChangeItem{Op: models.ChangeType_REMOVE, Path: fieldPath(field)})
+			}
+		}
+	case "delete":
+		changes = append(changes, models.ChangeItem{Op: models.ChangeType_REMOVE, Path: "/", OrigValue: doc})
+	}
+	if len(changes) == 0 {
+		return "", nil
+	}
+
+	servingPlmnId, _ := doc["servingPlmnId"].(string)
+	resourceUri := subscriptionDataResourceUri(event.Ns.Coll, ueId, servingPlmnId)
+	for _, key := range []string{"serviceType", "sharedDataId"} {
+		if value, ok := doc[key].(string); ok {
+			resourceUri = strings.ReplaceAll(resourceUri, "{"+key+"}", value)
+		}
+	}
+	return ueId, &models.NotifyItem{
+		ResourceId: resourceUri,
+		Changes:    changes,
+	}
+}
+
+// fieldPath turns a dotted MongoDB field name into a JSON pointer.
+func fieldPath(field string) string {
+	return "/" + strings.ReplaceAll(field, ".", "/")
+}
+
+// policyDataChangeNotification turns a change of a policy data document into
+// a notification and the path of the resource changed, nil if it has nothing
+// to report. The notification cannot tell a deletion apart, only deletions of
+// the resources identified by the notification itself are notified, without
+// any data.
+func policyDataChangeNotification(event *changeEvent) (string, *models.PolicyDataChangeNotification) {
+	if event.OperationType == "delete" {
+		return policyDataDeletionNotification(event.Ns.Coll, withoutInternalFields(event.FullDocumentBeforeChange))
+	}
+	if event.FullDocument == nil {
+		return "", nil
+	}
+	doc := withoutInternalFields(event.FullDocument)
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		logger.DataRepoLog.Warnf("notify %s change: %v", event.Ns.Coll, err)
+		return "", nil
+	}
+
+	notification := &models.PolicyDataChangeNotification{}
+	notification.UeId, _ = doc["ueId"].(string)
+	var target interface{}
+	switch event.Ns.Coll {
+	case POLICYDATA_UES_AMDATA:
+		notification.AmPolicyData = &models.AmPolicyData{}
+		target = notification.AmPolicyData
+	case POLICYDATA_UES_UEPOLICYSET, POLICYDATA_PLMNS_UEPOLICYSET:
+		notification.UePolicySet = &models.UePolicySet{}
+		target = notification.UePolicySet
+	case POLICYDATA_UES_SMDATA:
+		notification.SmPolicyData = &models.SmPolicyData{}
+		target = notification.SmPolicyData
+	case POLICYDATA_UES_SMDATA_USAGEMONDATA:
+		notification.UsageMonId, _ = doc["usageMonId"].(string)
+		notification.UsageMonData = &models.UsageMonData{}
+		target = notification.UsageMonData
+	case POLICYDATA_SPONSORCONNECTIVITYDATA:
+		notification.SponsorId, _ = doc["sponsorId"].(string)
+		notification.SponsorConnectivityData = &models.SponsorConnectivityData{}
+		target = notification.SponsorConnectivityData
+	case POLICYDATA_BDTDATA:
+		notification.BdtRefId, _ = doc["bdtReferenceId"].(string)
+		notification.BdtData = &models.BdtData{}
+		target = notification.BdtData
+	default:
+		return "", nil
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		logger.DataRepoLog.Warnf("notify %s change: %v", event.Ns.Coll, err)
+		return "", nil
+	}
+	if event.Ns.Coll == POLICYDATA_PLMNS_UEPOLICYSET {
+		plmnId, _ := doc["plmnId"].(string)
+		return plmnUePolicySetPath(plmnId), notification
+	}
+	return callback.PolicyDataResourcePath(*notification), notification
+}
+
+// policyDataDeletionNotification returns the notification of the deletion of
+// doc from collName, nil if it cannot be notified. A deleted PLMN UE policy
+// set is not notified, nothing in the notification would identify the PLMN.
+func policyDataDeletionNotification(collName string, doc bson.M) (string, *models.PolicyDataChangeNotification) {
+	if collName == POLICYDATA_SPONSORCONNECTIVITYDATA {
+		if sponsorId, _ := doc["sponsorId"].(string); sponsorId != "" {
+			return sponsorConnectivityDataPath(sponsorId), &models.PolicyDataChangeNotification{SponsorId: sponsorId}
+		}
+	}
+	return "", nil
+}
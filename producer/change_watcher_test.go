@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR notifications of DB change events
+ */
+
+package producer
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestDataChangeNotifyItem(t *testing.T) {
+	event := &changeEvent{OperationType: "update"}
+	event.Ns.Coll = SUBSCDATA_PROVISIONED_AMDATA
+	event.FullDocument = bson.M{"_id": "x", "ueId": "imsi-1", "servingPlmnId": "20893", "gpsis": []string{"msisdn-2"}}
+	event.UpdateDescription.UpdatedFields = bson.M{"gpsis": []string{"msisdn-2"}, documentRevisionField: int64(2)}
+	event.UpdateDescription.RemovedFields = []string{"nssai.defaultSingleNssais"}
+
+	ueId, notifyItem := dataChangeNotifyItem(event)
+	assert.Equal(t, "imsi-1", ueId)
+	if assert.NotNil(t, notifyItem) {
+		assert.True(t, strings.HasSuffix(notifyItem.ResourceId, "/nudr-dr/v1/subscription-data/imsi-1/20893/provisioned-data/am-data"))
+		assert.ElementsMatch(t, []models.ChangeItem{
+			{Op: models.ChangeType_REPLACE, Path: "/gpsis", NewValue: []string{"msisdn-2"}},
+			{Op: models.ChangeType_REMOVE, Path: "/nssai/defaultSingleNssais"},
+		}, notifyItem.Changes)
+	}
+
+	// only the revision changed
+	event.UpdateDescription.UpdatedFields = bson.M{documentRevisionField: int64(3)}
+	event.UpdateDescription.RemovedFields = nil
+	_, notifyItem = dataChangeNotifyItem(event)
+	assert.Nil(t, notifyItem)
+
+	event = &changeEvent{OperationType: "delete"}
+	event.Ns.Coll = SUBSCDATA_PPDATA
+	event.FullDocumentBeforeChange = bson.M{"_id": "x", "ueId": "imsi-1"}
+	_, notifyItem = dataChangeNotifyItem(event)
+	if assert.NotNil(t, notifyItem) {
+		assert.True(t, strings.HasSuffix(notifyItem.ResourceId, "/nudr-dr/v1/subscription-data/imsi-1/pp-data"))
+		assert.Equal(t, []models.ChangeItem{
+			{Op: models.ChangeType_REMOVE, Path: "/", OrigValue: bson.M{"ueId": "imsi-1"}},
+		}, notifyItem.Changes)
+	}
+}
+
+func TestEnableChangeStreamPreImages(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("collMod or create", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: mongoErrNamespaceNotFound, Message: "ns not found"}),
+			mtest.CreateSuccessResponse(),
+		)
+		err := enableChangeStreamPreImages(context.Background(), mt.DB,
+			[]string{SUBSCDATA_PPDATA, SUBSCDATA_PROVISIONED_AMDATA})
+		assert.NoError(mt, err)
+
+		var commands []string
+		for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+			coll, _ := event.Command.Lookup(event.CommandName).StringValueOK()
+			enabled, _ := event.Command.Lookup("changeStreamPreAndPostImages", "enabled").BooleanOK()
+			assert.True(mt, enabled, event.CommandName)
+			commands = append(commands, event.CommandName+" "+coll)
+		}
+		assert.Equal(mt, []string{
+			"collMod " + SUBSCDATA_PPDATA,
+			"collMod " + SUBSCDATA_PROVISIONED_AMDATA,
+			"create " + SUBSCDATA_PROVISIONED_AMDATA,
+		}, commands)
+	})
+
+	mt.Run("unsupported", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 72, Message: "invalid option"}))
+		err := enableChangeStreamPreImages(context.Background(), mt.DB, []string{SUBSCDATA_PPDATA})
+		assert.ErrorContains(mt, err, SUBSCDATA_PPDATA)
+	})
+}
+
+func TestPolicyDataChangeNotification(t *testing.T) {
+	event := &changeEvent{OperationType: "insert"}
+	event.Ns.Coll = POLICYDATA_UES_SMDATA_USAGEMONDATA
+	event.FullDocument = bson.M{"_id": "x", "ueId": "imsi-1", "usageMonId": "mon-1", "limitId": "limit-1"}
+
+	resourcePath, notification := policyDataChangeNotification(event)
+	assert.Equal(t, "/policy-data/ues/imsi-1/sm-data/mon-1", resourcePath)
+	if assert.NotNil(t, notification) {
+		assert.Equal(t, "imsi-1", notification.UeId)
+		assert.Equal(t, "mon-1", notification.UsageMonId)
+		if assert.NotNil(t, notification.UsageMonData) {
+			assert.Equal(t, "limit-1", notification.UsageMonData.LimitId)
+		}
+	}
+
+	event.OperationType = "delete"
+	event.FullDocumentBeforeChange = event.FullDocument
+	_, notification = policyDataChangeNotification(event)
+	assert.Nil(t, notification)
+
+	event.Ns.Coll = POLICYDATA_SPONSORCONNECTIVITYDATA
+	event.FullDocumentBeforeChange = bson.M{"_id": "x", "sponsorId": "sponsor-1", "aspIds": []string{"asp-1"}}
+	resourcePath, notification = policyDataChangeNotification(event)
+	assert.Equal(t, "/policy-data/sponsor-connectivity-data/sponsor-1", resourcePath)
+	assert.Equal(t, &models.PolicyDataChangeNotification{SponsorId: "sponsor-1"}, notification)
+
+	// nothing in the notification would tell which PLMN lost its UE policy set
+	event.Ns.Coll = POLICYDATA_PLMNS_UEPOLICYSET
+	event.FullDocumentBeforeChange = bson.M{"_id": "x", "plmnId": "20893"}
+	_, notification = policyDataChangeNotification(event)
+	assert.Nil(t, notification)
+}
+
+func TestChangeWatcherLeaseNeedsCollections(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &fakeDB{}
+
+	held, err := acquireChangeWatcherLease(context.Background(), "udr-0", time.Now())
+	assert.Error(t, err)
+	assert.False(t, held)
+
+	// without the lease no change stream is started and the loop ends with ctx
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runChangeWatcherLease(ctx, "udr-0")
+}
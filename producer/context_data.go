@@ -0,0 +1,267 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"github.com/omec-project/udr/producer/callback"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ContextDataResource describes a context data resource of a UE of
+// TS 29.505, stored one document per UE, or per UE and KeyParam.
+type ContextDataResource struct {
+	// Path of the resource under /subscription-data/{ueId}/context-data
+	Path string
+	// KeyParam is the path parameter identifying the resources of a UE, if
+	// there are several
+	KeyParam string
+	collName string
+	newData  func() interface{}
+	validate func(data interface{}) error
+}
+
+var ContextDataIpSmGwAccess = &ContextDataResource{
+	Path:     "ip-sm-gw-access",
+	collName: SUBSCDATA_CTXDATA_IPSMGW,
+	newData:  func() interface{} { return &IpSmGwRegistration{} },
+	validate: func(data interface{}) error {
+		ipSmGwRegistration := data.(*IpSmGwRegistration)
+		if ipSmGwRegistration.IpSmGwMapAddress == "" && ipSmGwRegistration.IpSmGwDiameterAddress == nil &&
+			ipSmGwRegistration.IpsmgwIpv4 == "" && ipSmGwRegistration.IpsmgwIpv6 == "" &&
+			ipSmGwRegistration.IpsmgwFqdn == "" {
+			return fmt.Errorf("no IP-SM-GW address")
+		}
+		return nil
+	},
+}
+
+var ContextDataMessageWaitingData = &ContextDataResource{
+	Path:     "mwd",
+	collName: SUBSCDATA_CTXDATA_MWD,
+	newData:  func() interface{} { return &MessageWaitingData{} },
+	validate: func(data interface{}) error {
+		for i, smscData := range data.(*MessageWaitingData).MwdList {
+			if smscData.SmscMapAddress == "" && smscData.SmscDiameterAddress == nil {
+				return fmt.Errorf("mwdList[%d]: no SMS-SC address", i)
+			}
+		}
+		return nil
+	},
+}
+
+var ContextDataLocation = &ContextDataResource{
+	Path:     "location",
+	collName: SUBSCDATA_CTXDATA_LOCATION,
+	newData:  func() interface{} { return &LocationInfo{} },
+	validate: func(data interface{}) error {
+		for i, registrationLocationInfo := range data.(*LocationInfo).RegistrationLocationInfoList {
+			if registrationLocationInfo.AmfInstanceId == "" {
+				return fmt.Errorf("registrationLocationInfoList[%d]: amfInstanceId is missing", i)
+			}
+			if len(registrationLocationInfo.AccessTypeList) == 0 {
+				return fmt.Errorf("registrationLocationInfoList[%d]: accessTypeList must not be empty", i)
+			}
+		}
+		return nil
+	},
+}
+
+var ContextDataNiddAuthorizations = &ContextDataResource{
+	Path:     "nidd-authorizations",
+	collName: SUBSCDATA_CTXDATA_NIDDAUTHORIZATION,
+	newData:  func() interface{} { return &NiddAuthorizationInfo{} },
+	validate: func(data interface{}) error {
+		return validateAuthorizationInfos(data.(*NiddAuthorizationInfo).NiddAuthorizationList)
+	},
+}
+
+var ContextDataServiceSpecificAuthorizations = &ContextDataResource{
+	Path:     "service-specific-authorizations",
+	KeyParam: "serviceType",
+	collName: SUBSCDATA_CTXDATA_SERVICESPECIFICAUTHORIZATION,
+	newData:  func() interface{} { return &ServiceSpecificAuthorizationInfo{} },
+	validate: func(data interface{}) error {
+		return validateAuthorizationInfos(data.(*ServiceSpecificAuthorizationInfo).ServiceSpecificAuthorizationList)
+	},
+}
+
+func validateAuthorizationInfos(authorizationInfos []AuthorizationInfo) error {
+	if len(authorizationInfos) == 0 {
+		return fmt.Errorf("the authorization list must not be empty")
+	}
+	for i, authorizationInfo := range authorizationInfos {
+		switch {
+		case authorizationInfo.Snssai == nil:
+			return fmt.Errorf("authorization %d: snssai is missing", i)
+		case authorizationInfo.Dnn == "":
+			return fmt.Errorf("authorization %d: dnn is missing", i)
+		case authorizationInfo.AfId == "":
+			return fmt.Errorf("authorization %d: afId is missing", i)
+		case authorizationInfo.AuthUpdateCallbackUri == "":
+			return fmt.Errorf("authorization %d: authUpdateCallbackUri is missing", i)
+		}
+	}
+	return nil
+}
+
+func (resource *ContextDataResource) filter(ueId string, key string) bson.M {
+	filter := bson.M{"ueId": ueId}
+	if resource.KeyParam != "" {
+		filter[resource.KeyParam] = key
+	}
+	return filter
+}
+
+// requestKeys returns the ueId and the key of the context data a request is
+// made for.
+func (resource *ContextDataResource) requestKeys(request *httpwrapper.Request) (ueId string, key string) {
+	if resource.KeyParam != "" {
+		key = request.Params[resource.KeyParam]
+	}
+	return request.Params["ueId"], key
+}
+
+func (resource *ContextDataResource) resourceUri(ueId string, key string) string {
+	resourceUri := subscriptionDataResourceUri(resource.collName, ueId, "")
+	if resource.KeyParam != "" {
+		resourceUri = strings.ReplaceAll(resourceUri, "{"+resource.KeyParam+"}", key)
+	}
+	return resourceUri
+}
+
+func HandleGetContextData(resource *ContextDataResource, request *httpwrapper.Request) *httpwrapper.Response {
+	ueId, key := resource.requestKeys(request)
+	logger.DataRepoLog.Infof("handle GetContextData %s: ueId=%q", resource.Path, ueId)
+
+	data, problemDetails := getDataFromDB(resource.collName, resource.filter(ueId, key))
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("get", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	for attr := range resource.filter(ueId, key) {
+		delete(data, attr)
+	}
+	stats.IncrementUdrSubscriptionDataStats("get", resource.Path, "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, data)
+}
+
+// HandleCreateContextData creates or replaces the context data of ueId.
+func HandleCreateContextData(resource *ContextDataResource, request *httpwrapper.Request) *httpwrapper.Response {
+	ueId, key := resource.requestKeys(request)
+	body := request.Body.([]byte)
+	logger.DataRepoLog.Infof("handle CreateContextData %s: ueId=%q", resource.Path, ueId)
+
+	data := resource.newData()
+	if pd := ValidateRequestBody(data, body); pd != nil {
+		stats.IncrementUdrSubscriptionDataStats("create", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := decodeStrict(body, data); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("create", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := resource.validate(data); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("create", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	filter := resource.filter(ueId, key)
+	origValue, err := CommonDBClient.RestfulAPIGetOne(resource.collName, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("create", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	putData := util.ToBsonM(data)
+	for attr, value := range filter {
+		putData[attr] = value
+	}
+	if _, err := CommonDBClient.RestfulAPIPutOne(resource.collName, filter, putData); err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("create", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrSubscriptionDataStats("create", resource.Path, "SUCCESS")
+
+	resourceUri := resource.resourceUri(ueId, key)
+	if origValue != nil {
+		notifySubscriptionDataChange(ueId, resourceUri, models.ChangeItem{
+			Op: models.ChangeType_REPLACE, Path: "/", OrigValue: withoutInternalFields(origValue), NewValue: data,
+		})
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	}
+	notifySubscriptionDataChange(ueId, resourceUri, models.ChangeItem{Op: models.ChangeType_ADD, Path: "/", NewValue: data})
+	headers := http.Header{}
+	headers.Set("Location", resourceUri)
+	return httpwrapper.NewResponse(http.StatusCreated, headers, data)
+}
+
+// HandleModifyContextData applies the JSON merge patch in body to the
+// context data of ueId.
+func HandleModifyContextData(resource *ContextDataResource, request *httpwrapper.Request) *httpwrapper.Response {
+	ueId, key := resource.requestKeys(request)
+	body := request.Body.([]byte)
+	logger.DataRepoLog.Infof("handle ModifyContextData %s: ueId=%q", resource.Path, ueId)
+
+	data := resource.newData()
+	problemDetails := mergePatchDocument(resource.collName, resource.filter(ueId, key), body, data,
+		func() error { return resource.validate(data) })
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("update", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("update", resource.Path, "SUCCESS")
+	notifySubscriptionDataChange(ueId, resource.resourceUri(ueId, key),
+		models.ChangeItem{Op: models.ChangeType_REPLACE, Path: "/", NewValue: data})
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
+
+func HandleDeleteContextData(resource *ContextDataResource, request *httpwrapper.Request) *httpwrapper.Response {
+	ueId, key := resource.requestKeys(request)
+	logger.DataRepoLog.Infof("handle DeleteContextData %s: ueId=%q", resource.Path, ueId)
+
+	filter := resource.filter(ueId, key)
+	origValue, err := CommonDBClient.RestfulAPIGetOne(resource.collName, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("delete", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	problemDetails := deleteExistingDocument(resource.collName, filter)
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("delete", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("delete", resource.Path, "SUCCESS")
+	notifySubscriptionDataChange(ueId, resource.resourceUri(ueId, key), models.ChangeItem{
+		Op: models.ChangeType_REMOVE, Path: "/", OrigValue: withoutInternalFields(origValue),
+	})
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
+
+// notifySubscriptionDataChange notifies the subscribers to the data of ueId,
+// or to the data of no UE if empty, of the change of the subscription data at
+// resourceUri, unless the change watcher does.
+func notifySubscriptionDataChange(ueId string, resourceUri string, change models.ChangeItem) {
+	if changeStreamNotifications.Load() {
+		return
+	}
+	go callback.SendOnDataChangeNotify(ueId, []models.NotifyItem{{
+		ResourceId: resourceUri,
+		Changes:    []models.ChangeItem{change},
+	}})
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"time"
+
+	"github.com/omec-project/openapi/models"
+)
+
+// Context data of TS 29.503 and TS 29.505 which the openapi models do not
+// provide.
+
+type NetworkNodeDiameterAddress struct {
+	Name  string `json:"name" bson:"name"`
+	Realm string `json:"realm" bson:"realm"`
+}
+
+type IpSmGwRegistration struct {
+	IpSmGwMapAddress      string                      `json:"ipSmGwMapAddress,omitempty" bson:"ipSmGwMapAddress"`
+	IpSmGwDiameterAddress *NetworkNodeDiameterAddress `json:"ipSmGwDiameterAddress,omitempty" bson:"ipSmGwDiameterAddress"`
+	IpsmgwIpv4            string                      `json:"ipsmgwIpv4,omitempty" bson:"ipsmgwIpv4"`
+	IpsmgwIpv6            string                      `json:"ipsmgwIpv6,omitempty" bson:"ipsmgwIpv6"`
+	IpsmgwFqdn            string                      `json:"ipsmgwFqdn,omitempty" bson:"ipsmgwFqdn"`
+	NfInstanceId          string                      `json:"nfInstanceId,omitempty" bson:"nfInstanceId"`
+	UnriIndicator         bool                        `json:"unriIndicator,omitempty" bson:"unriIndicator"`
+	ResetIds              []string                    `json:"resetIds,omitempty" bson:"resetIds"`
+	IpSmGwSbiSupInd       bool                        `json:"ipSmGwSbiSupInd,omitempty" bson:"ipSmGwSbiSupInd"`
+}
+
+type MessageWaitingData struct {
+	MwdList []SmscData `json:"mwdList,omitempty" bson:"mwdList"`
+}
+
+type SmscData struct {
+	SmscMapAddress      string                      `json:"smscMapAddress,omitempty" bson:"smscMapAddress"`
+	SmscDiameterAddress *NetworkNodeDiameterAddress `json:"smscDiameterAddress,omitempty" bson:"smscDiameterAddress"`
+}
+
+// LocationInfo locates a UE through the AMFs it is registered with.
+type LocationInfo struct {
+	Supi                         string                     `json:"supi,omitempty" bson:"supi"`
+	Gpsi                         string                     `json:"gpsi,omitempty" bson:"gpsi"`
+	RegistrationLocationInfoList []RegistrationLocationInfo `json:"registrationLocationInfoList,omitempty" bson:"registrationLocationInfoList"`
+	SupportedFeatures            string                     `json:"supportedFeatures,omitempty" bson:"supportedFeatures"`
+}
+
+type RegistrationLocationInfo struct {
+	AmfInstanceId  string              `json:"amfInstanceId" bson:"amfInstanceId"`
+	Guami          *models.Guami       `json:"guami,omitempty" bson:"guami"`
+	PlmnId         *models.PlmnId      `json:"plmnId,omitempty" bson:"plmnId"`
+	VgmlcAddress   *VgmlcAddress       `json:"vgmlcAddress,omitempty" bson:"vgmlcAddress"`
+	AccessTypeList []models.AccessType `json:"accessTypeList" bson:"accessTypeList"`
+}
+
+type VgmlcAddress struct {
+	VgmlcAddressIpv4 string `json:"vgmlcAddressIpv4,omitempty" bson:"vgmlcAddressIpv4"`
+	VgmlcAddressIpv6 string `json:"vgmlcAddressIpv6,omitempty" bson:"vgmlcAddressIpv6"`
+	VgmlcFqdn        string `json:"vgmlcFqdn,omitempty" bson:"vgmlcFqdn"`
+}
+
+type NiddAuthorizationInfo struct {
+	NiddAuthorizationList []AuthorizationInfo `json:"niddAuthorizationList" bson:"niddAuthorizationList"`
+}
+
+type ServiceSpecificAuthorizationInfo struct {
+	ServiceSpecificAuthorizationList []AuthorizationInfo `json:"serviceSpecificAuthorizationList" bson:"serviceSpecificAuthorizationList"`
+}
+
+// AuthorizationInfo is the authorization of an AF to use a service of a UE.
+type AuthorizationInfo struct {
+	Snssai                 *models.Snssai `json:"snssai" bson:"snssai"`
+	Dnn                    string         `json:"dnn" bson:"dnn"`
+	MtcProviderInformation string         `json:"mtcProviderInformation,omitempty" bson:"mtcProviderInformation"`
+	AuthUpdateCallbackUri  string         `json:"authUpdateCallbackUri" bson:"authUpdateCallbackUri"`
+	AfId                   string         `json:"afId" bson:"afId"`
+	NefId                  string         `json:"nefId,omitempty" bson:"nefId"`
+	ValidityTime           *time.Time     `json:"validityTime,omitempty" bson:"validityTime"`
+	ContextInfo            interface{}    `json:"contextInfo,omitempty" bson:"contextInfo"`
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR context data resources
+ */
+
+package producer
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/omec-project/util/httpwrapper"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func mwdRequest(body interface{}) *httpwrapper.Request {
+	return dbRequest(body, map[string]string{"ueId": "imsi-1"}, nil)
+}
+
+func TestCreateContextData(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &countingDB{fakeDB: fakeDB{docs: map[string][]map[string]interface{}{}}}
+	CommonDBClient = db
+
+	rsp := HandleCreateContextData(ContextDataMessageWaitingData, mwdRequest([]byte(`{"mwdList":[{}]}`)))
+	assert.Equal(t, http.StatusBadRequest, rsp.Status)
+	rsp = HandleCreateContextData(ContextDataMessageWaitingData, mwdRequest([]byte(`{"unknown":1}`)))
+	assert.Equal(t, http.StatusBadRequest, rsp.Status)
+
+	rsp = HandleCreateContextData(ContextDataMessageWaitingData,
+		mwdRequest([]byte(`{"mwdList":[{"smscMapAddress":"1234"}]}`)))
+	assert.Equal(t, http.StatusCreated, rsp.Status)
+	assert.True(t, strings.HasSuffix(rsp.Header.Get("Location"), "/subscription-data/imsi-1/context-data/mwd"))
+	assert.Equal(t, "imsi-1", db.docs[SUBSCDATA_CTXDATA_MWD][0]["ueId"])
+
+	rsp = HandleCreateContextData(ContextDataMessageWaitingData,
+		mwdRequest([]byte(`{"mwdList":[{"smscMapAddress":"5678"}]}`)))
+	assert.Equal(t, http.StatusNoContent, rsp.Status)
+
+	rsp = HandleGetContextData(ContextDataMessageWaitingData, mwdRequest(nil))
+	assert.Equal(t, http.StatusOK, rsp.Status)
+	assert.NotContains(t, rsp.Body, "ueId")
+}
+
+func TestContextDataResourceUri(t *testing.T) {
+	assert.Equal(t, bson.M{"ueId": "imsi-1", "serviceType": "AF_GUIDANCE_FOR_URSP"},
+		ContextDataServiceSpecificAuthorizations.filter("imsi-1", "AF_GUIDANCE_FOR_URSP"))
+	assert.True(t, strings.HasSuffix(
+		ContextDataServiceSpecificAuthorizations.resourceUri("imsi-1", "AF_GUIDANCE_FOR_URSP"),
+		"/subscription-data/imsi-1/context-data/service-specific-authorizations/AF_GUIDANCE_FOR_URSP"))
+	ueId, key := ContextDataServiceSpecificAuthorizations.requestKeys(dbRequest(nil,
+		map[string]string{"ueId": "imsi-1", "serviceType": "AF_GUIDANCE_FOR_URSP"}, nil))
+	assert.Equal(t, "imsi-1", ueId)
+	assert.Equal(t, "AF_GUIDANCE_FOR_URSP", key)
+
+	event := &changeEvent{OperationType: "insert"}
+	event.Ns.Coll = SUBSCDATA_CTXDATA_SERVICESPECIFICAUTHORIZATION
+	event.FullDocument = bson.M{"ueId": "imsi-1", "serviceType": "AF_GUIDANCE_FOR_URSP"}
+	_, notifyItem := dataChangeNotifyItem(event)
+	if assert.NotNil(t, notifyItem) {
+		assert.Equal(t, ContextDataServiceSpecificAuthorizations.resourceUri("imsi-1", "AF_GUIDANCE_FOR_URSP"),
+			notifyItem.ResourceId)
+	}
+}
@@ -5,16 +5,17 @@
 package producer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"reflect"
 	"strconv"
-	"strings"
+	"sync"
+	"time"
 
 	protos "github.com/5GC-DEV/config5g-cdac/proto/sdcoreConfig"
 	jsonpatch "github.com/evanphx/json-patch"
-	"github.com/mitchellh/mapstructure"
 	"github.com/omec-project/openapi/models"
 	udr_context "github.com/omec-project/udr/context"
 	"github.com/omec-project/udr/logger"
@@ -26,26 +27,59 @@ import (
 )
 
 const (
-	APPDATA_INFLUDATA_DB_COLLECTION_NAME       = "applicationData.influenceData"
-	APPDATA_INFLUDATA_SUBSC_DB_COLLECTION_NAME = "applicationData.influenceData.subsToNotify"
-	APPDATA_PFD_DB_COLLECTION_NAME             = "applicationData.pfds"
-	POLICYDATA_BDTDATA                         = "policyData.bdtData"
-	POLICYDATA_UES_OPSPECDATA                  = "policyData.ues.operatorSpecificData"
-	POLICYDATA_UES_SMDATA_USAGEMONDATA         = "policyData.ues.smData.usageMonData"
-	POLICYDATA_UES_UEPOLICYSET                 = "policyData.ues.uePolicySet"
-	SUBSCDATA_CTXDATA_AMF_3GPPACCESS           = "subscriptionData.contextData.amf3gppAccess"
-	SUBSCDATA_CTXDATA_AMF_NON3GPPACCESS        = "subscriptionData.contextData.amfNon3gppAccess"
-	SUBSCDATA_CTXDATA_SMF_REGISTRATION         = "subscriptionData.contextData.smfRegistrations"
-	SUBSCDATA_CTXDATA_SMSF_3GPPACCESS          = "subscriptionData.contextData.smsf3gppAccess"
-	SUBSCDATA_CTXDATA_SMSF_NON3GPPACCESS       = "subscriptionData.contextData.smsfNon3gppAccess"
+	APPDATA_INFLUDATA_DB_COLLECTION_NAME           = "applicationData.influenceData"
+	APPDATA_INFLUDATA_SUBSC_DB_COLLECTION_NAME     = "applicationData.influenceData.subsToNotify"
+	APPDATA_PFD_DB_COLLECTION_NAME                 = "applicationData.pfds"
+	APPDATA_PFD_SUBSC_DB_COLLECTION_NAME           = "applicationData.pfds.subsToNotify"
+	APPDATA_BDTPOLICYDATA_DB_COLLECTION_NAME       = "applicationData.bdtPolicyData"
+	APPDATA_IPTVCONFIGDATA_DB_COLLECTION_NAME      = "applicationData.iptvConfigData"
+	APPDATA_SERVICEPARAMDATA_DB_COLLECTION_NAME    = "applicationData.serviceParamData"
+	APPDATA_AMINFLUDATA_DB_COLLECTION_NAME         = "applicationData.amInfluenceData"
+	APPDATA_EVENTEXPOSUREDATA_DB_COLLECTION_NAME   = "applicationData.eventExposureData"
+	APPDATA_SUBS_DB_COLLECTION_NAME                = "applicationData.subsToNotify"
+	POLICYDATA_BDTDATA                             = "policyData.bdtData"
+	POLICYDATA_UES_AMDATA                          = "policyData.ues.amData"
+	POLICYDATA_UES_OPSPECDATA                      = "policyData.ues.operatorSpecificData"
+	POLICYDATA_UES_SMDATA_USAGEMONDATA             = "policyData.ues.smData.usageMonData"
+	POLICYDATA_UES_UEPOLICYSET                     = "policyData.ues.uePolicySet"
+	POLICYDATA_SPONSORCONNECTIVITYDATA             = "policyData.sponsorConnectivityData"
+	POLICYDATA_PLMNS_UEPOLICYSET                   = "policyData.plmns.uePolicySet"
+	SUBSCDATA_CTXDATA_AMF_3GPPACCESS               = "subscriptionData.contextData.amf3gppAccess"
+	SUBSCDATA_CTXDATA_AMF_NON3GPPACCESS            = "subscriptionData.contextData.amfNon3gppAccess"
+	SUBSCDATA_CTXDATA_SMF_REGISTRATION             = "subscriptionData.contextData.smfRegistrations"
+	SUBSCDATA_CTXDATA_SMSF_3GPPACCESS              = "subscriptionData.contextData.smsf3gppAccess"
+	SUBSCDATA_CTXDATA_SMSF_NON3GPPACCESS           = "subscriptionData.contextData.smsfNon3gppAccess"
+	SUBSCDATA_CTXDATA_IPSMGW                       = "subscriptionData.contextData.ipSmGwAccess"
+	SUBSCDATA_CTXDATA_MWD                          = "subscriptionData.contextData.mwd"
+	SUBSCDATA_CTXDATA_LOCATION                     = "subscriptionData.contextData.location"
+	SUBSCDATA_CTXDATA_NIDDAUTHORIZATION            = "subscriptionData.contextData.niddAuthorizations"
+	SUBSCDATA_CTXDATA_SERVICESPECIFICAUTHORIZATION = "subscriptionData.contextData.serviceSpecificAuthorizations"
+	SUBSCDATA_PROVISIONED_AMDATA                   = "subscriptionData.provisionedData.amData"
+	SUBSCDATA_PROVISIONED_SMDATA                   = "subscriptionData.provisionedData.smData"
+	SUBSCDATA_PROVISIONED_SMFSELDATA               = "subscriptionData.provisionedData.smfSelectionSubscriptionData"
+	SUBSCDATA_PROVISIONED_SMSDATA                  = "subscriptionData.provisionedData.smsData"
+	SUBSCDATA_PROVISIONED_TRACEDATA                = "subscriptionData.provisionedData.traceData"
+	SUBSCDATA_PROVISIONED_SMSMNGDATA               = "subscriptionData.provisionedData.smsMngData"
+	SUBSCDATA_PROVISIONED_LCSPRIVACYDATA           = "subscriptionData.provisionedData.lcsPrivacyData"
+	SUBSCDATA_PROVISIONED_LCSMODATA                = "subscriptionData.provisionedData.lcsMoData"
+	SUBSCDATA_PROVISIONED_LCSBCADATA               = "subscriptionData.provisionedData.lcsBcaData"
+	SUBSCDATA_PROVISIONED_V2XDATA                  = "subscriptionData.provisionedData.v2xData"
+	SUBSCDATA_PROVISIONED_PROSEDATA                = "subscriptionData.provisionedData.proseData"
+	SUBSCDATA_SHAREDDATA                           = "subscriptionData.sharedData"
+	SUBSCDATA_IDENTITYDATA                         = "subscriptionData.identityData"
+	SUBSCDATA_PROVISIONED_MBSDATA                  = "subscriptionData.provisionedData.mbsData"
+	SUBSCDATA_AUTHENTICATION_SUBSCRIPTION          = "subscriptionData.authenticationData.authenticationSubscription"
+	SUBSCDATA_PPDATA                               = "subscriptionData.ppData"
+	SUBSCDATA_GROUPDATA                            = "subscriptionData.groupData"
+	SUBSCDATA_GROUPDATA_5GVNGROUPS                 = "subscriptionData.groupData.5gVnGroups"
+	SUBSCDATA_OPERATORSPECIFICDATA                 = "subscriptionData.operatorSpecificData"
 )
 
-var CurrentResourceUri string
-
 func getDataFromDB(collName string, filter bson.M) (map[string]interface{}, *models.ProblemDetails) {
 	data, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 	if data == nil {
 		return nil, util.ProblemDetailsNotFound("DATA_NOT_FOUND")
@@ -83,73 +117,18 @@ func toBsonM(data interface{}) (ret bson.M) {
 
 // AddEntrySmPolicyTable ... write table entries into policyData.ues.smData
 func AddEntrySmPolicyTable(imsi string, dnn string, snssai *protos.NSSAI) error {
-	logger.CfgLog.Infoln("AddEntrySmPolicyTable")
-	collName := "policyData.ues.smData"
-	var addUeId bool
-	logger.CfgLog.Infoln("collname, imsi, dnn, sst, sd:", collName, imsi, dnn, snssai.Sst, snssai.Sd)
-	ueID := "imsi-" + imsi
-
-	sval, err := strconv.ParseUint(snssai.Sst, 10, 32)
-	if err != nil {
-		logger.CfgLog.Errorln("parse fail for sst", err)
-		return err
-	}
-	filter := bson.M{"ueId": ueID}
-	modelNssai := models.Snssai{
-		Sd:  snssai.Sd,
-		Sst: int32(sval),
-	}
-	smPolicyData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-	if errGetOne != nil {
-		logger.DataRepoLog.Warnln(errGetOne)
-	}
-	var smPolicyDataWrite models.SmPolicyData
-	if smPolicyData != nil {
-		err := json.Unmarshal(util.MapToByte(smPolicyData), &smPolicyDataWrite)
-		if err != nil {
-			logger.DataRepoLog.Warnln(err)
-			return err
-		}
-	} else {
-		smPolicyDataWrite.SmPolicySnssaiData = make(map[string]models.SmPolicySnssaiData)
-		addUeId = true
-	}
-
-	smPolicySnssaiData := models.SmPolicySnssaiData{
-		Snssai: &modelNssai,
-		SmPolicyDnnData: map[string]models.SmPolicyDnnData{
-			dnn: {
-				Dnn: dnn,
-			},
-		},
-	}
-
-	// Check if the DNN already exists for this SNSSAI and IMSI
-	hexSnssai := util.SnssaiModelsToHex(modelNssai)
-	if existingData, exists := smPolicyDataWrite.SmPolicySnssaiData[hexSnssai]; exists {
-		// DNN already exists, append to the existing DNN data
-		existingData.SmPolicyDnnData[dnn] = models.SmPolicyDnnData{
-			Dnn: dnn,
-		}
-		smPolicyDataWrite.SmPolicySnssaiData[hexSnssai] = existingData
-	} else {
-		// New DNN for this SNSSAI, so add the new entry
-		smPolicyDataWrite.SmPolicySnssaiData[hexSnssai] = smPolicySnssaiData
-	}
-
-	// Convert the updated data to BSON format for database insertion
-	smPolicyDataBsonM := toBsonM(smPolicyDataWrite)
-	if addUeId {
-		smPolicyDataBsonM["ueId"] = ueID
-	}
-	logger.CfgLog.Infof("Data to be sent to database - smPolicyData: %+v", smPolicyDataBsonM)
+	logger.CfgLog.Infoln("collname, imsi, dnn, sst, sd:", POLICYDATA_UES_SMDATA, imsi, dnn, snssai.Sst, snssai.Sd)
+	_, err := ApplySmPolicyUpdates([]SmPolicyUpdate{{Imsi: imsi, Dnn: dnn, Snssai: snssai}})
+	return err
+}
 
-	// Post the data to the database
-	_, errPost := CommonDBClient.RestfulAPIPost(collName, filter, smPolicyDataBsonM)
-	if errPost != nil {
-		logger.DataRepoLog.Warnln(errPost)
-	}
-	return nil
+// RemoveEntrySmPolicyTable ... remove a table entry from policyData.ues.smData.
+// The S-NSSAI is dropped once it has no DNN left, and the UE document once it
+// has no S-NSSAI left.
+func RemoveEntrySmPolicyTable(imsi string, dnn string, snssai *protos.NSSAI) error {
+	logger.CfgLog.Infoln("collname, imsi, dnn, sst, sd:", POLICYDATA_UES_SMDATA, imsi, dnn, snssai.Sst, snssai.Sd)
+	_, err := ApplySmPolicyUpdates([]SmPolicyUpdate{{Imsi: imsi, Dnn: dnn, Snssai: snssai, Remove: true}})
+	return err
 }
 
 func HandleDeleteAccessAndMobilityData(request *httpwrapper.Request) *httpwrapper.Response {
@@ -166,7 +145,12 @@ func HandleQueryAmData(request *httpwrapper.Request) *httpwrapper.Response {
 	collName := "subscriptionData.provisionedData.amData"
 	ueId := request.Params["ueId"]
 	servingPlmnId := request.Params["servingPlmnId"]
-	response, problemDetails := QueryAmDataProcedure(collName, ueId, servingPlmnId)
+	opts, err := parseQueryOptions(request.Query)
+	if err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	response, problemDetails := QueryAmDataProcedure(collName, ueId, servingPlmnId, opts)
 
 	if problemDetails == nil {
 		return httpwrapper.NewResponse(http.StatusOK, nil, response)
@@ -175,15 +159,21 @@ func HandleQueryAmData(request *httpwrapper.Request) *httpwrapper.Response {
 	}
 }
 
-func QueryAmDataProcedure(collName string, ueId string, servingPlmnId string) (*map[string]interface{},
-	*models.ProblemDetails,
+func QueryAmDataProcedure(collName string, ueId string, servingPlmnId string, opts *queryOptions) (
+	*map[string]interface{}, *models.ProblemDetails,
 ) {
 	filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-	accessAndMobilitySubscriptionData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
+	docs, errGetOne := opts.findDocuments(context.TODO(), CommonDBClient, collName, filter, true)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
-	if accessAndMobilitySubscriptionData != nil {
+	if len(docs) > 0 {
+		accessAndMobilitySubscriptionData := docs[0]
+		opts.stripUnsupported(collName, accessAndMobilitySubscriptionData)
+		if opts.SupportedFeatures != nil && len(opts.Fields) == 0 {
+			accessAndMobilitySubscriptionData["supportedFeatures"] = opts.negotiatedFeatures()
+		}
 		return &accessAndMobilitySubscriptionData, nil
 	} else {
 		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
@@ -196,39 +186,30 @@ func HandleAmfContext3gpp(request *httpwrapper.Request) *httpwrapper.Response {
 	patchItem := request.Body.([]models.PatchItem)
 	ueId := request.Params["ueId"]
 
-	problemDetails := AmfContext3gppProcedure(collName, ueId, patchItem)
+	etag, problemDetails := AmfContext3gppProcedure(collName, ueId, patchItem, request.Header)
 	if problemDetails == nil {
 		stats.IncrementUdrSubscriptionDataStats("update", "amf-3gpp-access", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+		return httpwrapper.NewResponse(http.StatusNoContent, etagHeader(etag), map[string]interface{}{})
 	} else {
 		stats.IncrementUdrSubscriptionDataStats("update", "amf-3gpp-access", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	}
 }
 
-func AmfContext3gppProcedure(collName string, ueId string, patchItem []models.PatchItem) *models.ProblemDetails {
-	filter := bson.M{"ueId": ueId}
-	origValue, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-	if errGetOne != nil {
-		logger.DataRepoLog.Warnln(errGetOne)
-	}
-
-	patchJSON, err := json.Marshal(patchItem)
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
+func AmfContext3gppProcedure(collName string, ueId string, patchItem []models.PatchItem,
+	header http.Header,
+) (string, *models.ProblemDetails) {
+	patchItem, problemDetails := purgeFlagPatch(patchItem)
+	if problemDetails != nil {
+		return "", problemDetails
 	}
-	failure := CommonDBClient.RestfulAPIJSONPatch(collName, filter, patchJSON)
-
-	if failure == nil {
-		newValue, errGetOneNew := CommonDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOneNew != nil {
-			logger.DataRepoLog.Warnln(errGetOneNew)
-		}
-		PreHandleOnDataChangeNotify(ueId, CurrentResourceUri, patchItem, origValue, newValue)
-		return nil
-	} else {
-		return util.ProblemDetailsModifyNotAllowed("")
+	filter := bson.M{"ueId": ueId}
+	origValue, newValue, etag, problemDetails := patchVersionedDocument(CommonDBClient, collName, filter, patchItem, header)
+	if problemDetails != nil {
+		return "", problemDetails
 	}
+	PreHandleOnDataChangeNotify(ueId, subscriptionDataResourceUri(collName, ueId, ""), patchItem, origValue, newValue)
+	return etag, nil
 }
 
 func HandleCreateAmfContext3gpp(request *httpwrapper.Request) *httpwrapper.Response {
@@ -238,28 +219,34 @@ func HandleCreateAmfContext3gpp(request *httpwrapper.Request) *httpwrapper.Respo
 	ueId := request.Params["ueId"]
 	collName := SUBSCDATA_CTXDATA_AMF_3GPPACCESS
 
-	err := CreateAmfContext3gppProcedure(collName, ueId, Amf3GppAccessRegistration)
-	if err == nil {
-		stats.IncrementUdrSubscriptionDataStats("create", "amf-3gpp-access", "SUCCESS")
-	} else {
+	etag, problemDetails := CreateAmfContext3gppProcedure(collName, ueId, Amf3GppAccessRegistration, request.Header)
+	if problemDetails != nil {
 		stats.IncrementUdrSubscriptionDataStats("create", "amf-3gpp-access", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	}
+	stats.IncrementUdrSubscriptionDataStats("create", "amf-3gpp-access", "SUCCESS")
 
-	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	return httpwrapper.NewResponse(http.StatusNoContent, etagHeader(etag), map[string]interface{}{})
 }
 
 func CreateAmfContext3gppProcedure(collName string, ueId string,
-	Amf3GppAccessRegistration models.Amf3GppAccessRegistration,
-) error {
+	Amf3GppAccessRegistration models.Amf3GppAccessRegistration, header http.Header,
+) (string, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId}
 	putData := util.ToBsonM(Amf3GppAccessRegistration)
 	putData["ueId"] = ueId
+	// a new registration clears the purge flag of the previous one
+	putData["purgeFlag"] = Amf3GppAccessRegistration.PurgeFlag
 
-	_, errPutOne := CommonDBClient.RestfulAPIPutOne(collName, filter, putData)
-	if errPutOne != nil {
-		logger.DataRepoLog.Warnln(errPutOne)
+	previous := previousAmfRegistration(collName, filter)
+	etag, problemDetails := putVersionedDocument(CommonDBClient, collName, filter, putData, header)
+	if problemDetails != nil {
+		logger.DataRepoLog.Warnln(problemDetails.Detail)
+		return etag, problemDetails
 	}
-	return errPutOne
+	recordAmfRegistrationHistory(ueId, models.AccessType__3_GPP_ACCESS, previous,
+		Amf3GppAccessRegistration.AmfInstanceId)
+	return etag, nil
 }
 
 func HandleQueryAmfContext3gpp(request *httpwrapper.Request) *httpwrapper.Response {
@@ -272,7 +259,7 @@ func HandleQueryAmfContext3gpp(request *httpwrapper.Request) *httpwrapper.Respon
 
 	if response != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "amf-3gpp-access", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+		return documentResponse(request.Header, *response)
 	} else if problemDetails != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "amf-3gpp-access", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
@@ -287,6 +274,7 @@ func QueryAmfContext3gppProcedure(collName string, ueId string) (*map[string]int
 	amf3GppAccessRegistration, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if amf3GppAccessRegistration != nil {
@@ -302,13 +290,12 @@ func HandleAmfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Response
 	ueId := request.Params["ueId"]
 	collName := SUBSCDATA_CTXDATA_AMF_NON3GPPACCESS
 	patchItem := request.Body.([]models.PatchItem)
-	filter := bson.M{"ueId": ueId}
 
-	problemDetails := AmfContextNon3gppProcedure(ueId, collName, patchItem, filter)
+	etag, problemDetails := AmfContextNon3gppProcedure(ueId, collName, patchItem, request.Header)
 
 	if problemDetails == nil {
 		stats.IncrementUdrSubscriptionDataStats("update", "amf-non-3gpp-access", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+		return httpwrapper.NewResponse(http.StatusNoContent, etagHeader(etag), map[string]interface{}{})
 	} else {
 		stats.IncrementUdrSubscriptionDataStats("update", "amf-non-3gpp-access", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
@@ -316,28 +303,19 @@ func HandleAmfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Response
 }
 
 func AmfContextNon3gppProcedure(ueId string, collName string, patchItem []models.PatchItem,
-	filter bson.M,
-) *models.ProblemDetails {
-	origValue, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-	if errGetOne != nil {
-		logger.DataRepoLog.Warnln(errGetOne)
-	}
-
-	patchJSON, err := json.Marshal(patchItem)
-	if err != nil {
-		logger.DataRepoLog.Error(err)
+	header http.Header,
+) (string, *models.ProblemDetails) {
+	patchItem, problemDetails := purgeFlagPatch(patchItem)
+	if problemDetails != nil {
+		return "", problemDetails
 	}
-	failure := CommonDBClient.RestfulAPIJSONPatch(collName, filter, patchJSON)
-	if failure == nil {
-		newValue, errGetOneNew := CommonDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOneNew != nil {
-			logger.DataRepoLog.Warnln(errGetOneNew)
-		}
-		PreHandleOnDataChangeNotify(ueId, CurrentResourceUri, patchItem, origValue, newValue)
-		return nil
-	} else {
-		return util.ProblemDetailsModifyNotAllowed("")
+	filter := bson.M{"ueId": ueId}
+	origValue, newValue, etag, problemDetails := patchVersionedDocument(CommonDBClient, collName, filter, patchItem, header)
+	if problemDetails != nil {
+		return "", problemDetails
 	}
+	PreHandleOnDataChangeNotify(ueId, subscriptionDataResourceUri(collName, ueId, ""), patchItem, origValue, newValue)
+	return etag, nil
 }
 
 func HandleCreateAmfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Response {
@@ -347,28 +325,35 @@ func HandleCreateAmfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Re
 	collName := SUBSCDATA_CTXDATA_AMF_NON3GPPACCESS
 	ueId := request.Params["ueId"]
 
-	err := CreateAmfContextNon3gppProcedure(AmfNon3GppAccessRegistration, collName, ueId)
-	if err == nil {
-		stats.IncrementUdrSubscriptionDataStats("create", "amf-non-3gpp-access", "SUCCESS")
-	} else {
+	etag, problemDetails := CreateAmfContextNon3gppProcedure(AmfNon3GppAccessRegistration, collName, ueId,
+		request.Header)
+	if problemDetails != nil {
 		stats.IncrementUdrSubscriptionDataStats("create", "amf-non-3gpp-access", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	}
+	stats.IncrementUdrSubscriptionDataStats("create", "amf-non-3gpp-access", "SUCCESS")
 
-	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	return httpwrapper.NewResponse(http.StatusNoContent, etagHeader(etag), map[string]interface{}{})
 }
 
 func CreateAmfContextNon3gppProcedure(AmfNon3GppAccessRegistration models.AmfNon3GppAccessRegistration,
-	collName string, ueId string,
-) error {
+	collName string, ueId string, header http.Header,
+) (string, *models.ProblemDetails) {
 	putData := util.ToBsonM(AmfNon3GppAccessRegistration)
 	putData["ueId"] = ueId
+	// a new registration clears the purge flag of the previous one
+	putData["purgeFlag"] = AmfNon3GppAccessRegistration.PurgeFlag
 	filter := bson.M{"ueId": ueId}
 
-	_, errPutOne := CommonDBClient.RestfulAPIPutOne(collName, filter, putData)
-	if errPutOne != nil {
-		logger.DataRepoLog.Warnln(errPutOne)
+	previous := previousAmfRegistration(collName, filter)
+	etag, problemDetails := putVersionedDocument(CommonDBClient, collName, filter, putData, header)
+	if problemDetails != nil {
+		logger.DataRepoLog.Warnln(problemDetails.Detail)
+		return etag, problemDetails
 	}
-	return errPutOne
+	recordAmfRegistrationHistory(ueId, models.AccessType_NON_3_GPP_ACCESS, previous,
+		AmfNon3GppAccessRegistration.AmfInstanceId)
+	return etag, nil
 }
 
 func HandleQueryAmfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Response {
@@ -381,7 +366,7 @@ func HandleQueryAmfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Res
 
 	if response != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "amf-non-3gpp-access", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+		return documentResponse(request.Header, *response)
 	} else if problemDetails != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "amf-non-3gpp-access", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
@@ -397,6 +382,7 @@ func QueryAmfContextNon3gppProcedure(collName string, ueId string) (*map[string]
 	response, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if response != nil {
@@ -409,57 +395,44 @@ func QueryAmfContextNon3gppProcedure(collName string, ueId string) (*map[string]
 func HandleModifyAuthentication(request *httpwrapper.Request) *httpwrapper.Response {
 	logger.DataRepoLog.Infoln("handle ModifyAuthentication")
 
-	collName := "subscriptionData.authenticationData.authenticationSubscription"
+	collName := SUBSCDATA_AUTHENTICATION_SUBSCRIPTION
 	ueId := request.Params["ueId"]
 	patchItem := request.Body.([]models.PatchItem)
 
-	problemDetails := ModifyAuthenticationProcedure(collName, ueId, patchItem)
+	etag, problemDetails := ModifyAuthenticationProcedure(collName, ueId, patchItem, request.Header)
 
 	if problemDetails == nil {
 		stats.IncrementUdrSubscriptionDataStats("update", "authentication-subscription", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+		return httpwrapper.NewResponse(http.StatusNoContent, etagHeader(etag), map[string]interface{}{})
 	} else {
 		stats.IncrementUdrSubscriptionDataStats("update", "authentication-subscription", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	}
 }
 
-func ModifyAuthenticationProcedure(collName string, ueId string, patchItem []models.PatchItem) *models.ProblemDetails {
+func ModifyAuthenticationProcedure(collName string, ueId string, patchItem []models.PatchItem,
+	header http.Header,
+) (string, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId}
-	origValue, errGetOne := AuthDBClient.RestfulAPIGetOne(collName, filter)
-	if errGetOne != nil {
-		logger.DataRepoLog.Warnln(errGetOne)
-	}
-
-	patchJSON, err := json.Marshal(patchItem)
-	if err != nil {
-		logger.DataRepoLog.Error(err)
-	}
-	failure := AuthDBClient.RestfulAPIJSONPatch(collName, filter, patchJSON)
-
-	if failure == nil {
-		newValue, errGetOneNew := AuthDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOneNew != nil {
-			logger.DataRepoLog.Warnln(errGetOneNew)
-		}
-		PreHandleOnDataChangeNotify(ueId, CurrentResourceUri, patchItem, origValue, newValue)
-		return nil
-	} else {
-		return util.ProblemDetailsModifyNotAllowed("")
+	origValue, newValue, etag, problemDetails := patchVersionedDocument(AuthDBClient, collName, filter, patchItem, header)
+	if problemDetails != nil {
+		return "", problemDetails
 	}
+	PreHandleOnDataChangeNotify(ueId, subscriptionDataResourceUri(collName, ueId, ""), patchItem, origValue, newValue)
+	return etag, nil
 }
 
 func HandleQueryAuthSubsData(request *httpwrapper.Request) *httpwrapper.Response {
 	logger.DataRepoLog.Infoln("handle QueryAuthSubsData")
 
-	collName := "subscriptionData.authenticationData.authenticationSubscription"
+	collName := SUBSCDATA_AUTHENTICATION_SUBSCRIPTION
 	ueId := request.Params["ueId"]
 
 	response, problemDetails := QueryAuthSubsDataProcedure(collName, ueId)
 
 	if response != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "authentication-subscription", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+		return documentResponse(request.Header, response)
 	} else if problemDetails != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "authentication-subscription", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
@@ -476,6 +449,7 @@ func QueryAuthSubsDataProcedure(collName string, ueId string) (map[string]interf
 	authenticationSubscription, errGetOne := AuthDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if authenticationSubscription != nil {
@@ -491,12 +465,12 @@ func HandleCreateAuthenticationSoR(request *httpwrapper.Request) *httpwrapper.Re
 	ueId := request.Params["ueId"]
 	collName := "subscriptionData.ueUpdateConfirmationData.sorData"
 
-	err := CreateAuthenticationSoRProcedure(collName, ueId, putData)
-	if err == nil {
-		stats.IncrementUdrSubscriptionDataStats("create", "sor-data", "SUCCESS")
-	} else {
+	if err := CreateAuthenticationSoRProcedure(collName, ueId, putData); err != nil {
 		stats.IncrementUdrSubscriptionDataStats("create", "sor-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
+	stats.IncrementUdrSubscriptionDataStats("create", "sor-data", "SUCCESS")
 
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
@@ -539,6 +513,7 @@ func QueryAuthSoRProcedure(collName string, ueId string) (map[string]interface{}
 	sorData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if sorData != nil {
@@ -555,12 +530,12 @@ func HandleCreateAuthenticationStatus(request *httpwrapper.Request) *httpwrapper
 	ueId := request.Params["ueId"]
 	collName := "subscriptionData.authenticationData.authenticationStatus"
 
-	err := CreateAuthenticationStatusProcedure(collName, ueId, putData)
-	if err == nil {
-		stats.IncrementUdrSubscriptionDataStats("create", "authentication-status", "SUCCESS")
-	} else {
+	if err := CreateAuthenticationStatusProcedure(collName, ueId, putData); err != nil {
 		stats.IncrementUdrSubscriptionDataStats("create", "authentication-status", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
+	stats.IncrementUdrSubscriptionDataStats("create", "authentication-status", "SUCCESS")
 
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
@@ -605,6 +580,7 @@ func QueryAuthenticationStatusProcedure(collName string, ueId string) (*map[stri
 	authEvent, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if authEvent != nil {
@@ -628,7 +604,12 @@ func HandleApplicationDataInfluenceDataGet(queryParams map[string][]string) *htt
 		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
 
-	response := getApplicationDataInfluenceDatafromDB(influIDs, dnns, snssais, intGroupIDs, supis)
+	response, err := getApplicationDataInfluenceDatafromDB(influIDs, dnns, snssais, intGroupIDs, supis)
+	if err != nil {
+		stats.IncrementUdrApplicationDataStats("get", "influence-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 	stats.IncrementUdrApplicationDataStats("get", "influence-data", "SUCCESS")
 
 	return httpwrapper.NewResponse(http.StatusOK, nil, response)
@@ -636,11 +617,12 @@ func HandleApplicationDataInfluenceDataGet(queryParams map[string][]string) *htt
 
 func getApplicationDataInfluenceDatafromDB(influIDs, dnns, snssais,
 	intGroupIDs, supis []string,
-) []map[string]interface{} {
+) ([]map[string]interface{}, error) {
 	filter := bson.M{}
 	allInfluDatas, errGetMany := CommonDBClient.RestfulAPIGetMany(APPDATA_INFLUDATA_DB_COLLECTION_NAME, filter)
 	if errGetMany != nil {
 		logger.DataRepoLog.Warnln(errGetMany)
+		return nil, errGetMany
 	}
 	var matchedInfluDatas []map[string]interface{}
 	matchedInfluDatas = filterDataByString("influenceId", influIDs, allInfluDatas)
@@ -654,7 +636,7 @@ func getApplicationDataInfluenceDatafromDB(influIDs, dnns, snssais,
 		// Delete "influenceId" entry which is added by us
 		delete(matchedInfluDatas[i], "influenceId")
 	}
-	return matchedInfluDatas
+	return matchedInfluDatas, nil
 }
 
 func filterDataByString(filterName string, filterValues []string,
@@ -709,19 +691,19 @@ func filterDataBySnssai(snssaiValues []string,
 func HandleApplicationDataInfluenceDataInfluenceIdDelete(influID string) *httpwrapper.Response {
 	logger.DataRepoLog.Infof("handle ApplicationDataInfluenceDataInfluenceIdDelete: influID=%q", influID)
 
-	deleteApplicationDataIndividualInfluenceDataFromDB(influID)
+	if err := deleteApplicationDataIndividualInfluenceDataFromDB(influID); err != nil {
+		stats.IncrementUdrApplicationDataStats("delete", "influence-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrApplicationDataStats("delete", "influence-data", "SUCCESS")
 
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
 
-func deleteApplicationDataIndividualInfluenceDataFromDB(influID string) {
+func deleteApplicationDataIndividualInfluenceDataFromDB(influID string) error {
 	filter := bson.M{"influenceId": influID}
-	err := deleteDataFromDB(APPDATA_INFLUDATA_DB_COLLECTION_NAME, filter)
-	if err == nil {
-		stats.IncrementUdrApplicationDataStats("delete", "influence-data", "SUCCESS")
-	} else {
-		stats.IncrementUdrApplicationDataStats("delete", "influence-data", "FAILURE")
-	}
+	return deleteDataFromDB(APPDATA_INFLUDATA_DB_COLLECTION_NAME, filter)
 }
 
 func HandleApplicationDataInfluenceDataInfluenceIdPatch(influID string,
@@ -729,23 +711,28 @@ func HandleApplicationDataInfluenceDataInfluenceIdPatch(influID string,
 ) *httpwrapper.Response {
 	logger.DataRepoLog.Infof("handle ApplicationDataInfluenceDataInfluenceIdPatch: influID=%q", influID)
 
-	response, status := patchApplicationDataIndividualInfluenceDataToDB(influID, trInfluDataPatch)
+	response, problemDetails := patchApplicationDataIndividualInfluenceDataToDB(influID, trInfluDataPatch)
+	if problemDetails != nil {
+		stats.IncrementUdrApplicationDataStats("update", "influence-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
 	stats.IncrementUdrApplicationDataStats("update", "influence-data", "SUCCESS")
 
-	return httpwrapper.NewResponse(status, nil, response)
+	return httpwrapper.NewResponse(http.StatusOK, nil, response)
 }
 
 func patchApplicationDataIndividualInfluenceDataToDB(influID string,
 	trInfluDataPatch *models.TrafficInfluDataPatch,
-) (bson.M, int) {
+) (bson.M, *models.ProblemDetails) {
 	filter := bson.M{"influenceId": influID}
 
 	oldData, errGetOne := CommonDBClient.RestfulAPIGetOne(APPDATA_INFLUDATA_DB_COLLECTION_NAME, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 	if oldData == nil {
-		return nil, http.StatusNotFound
+		return nil, util.ProblemDetailsNotFound("DATA_NOT_FOUND")
 	}
 
 	trInfluData := models.TrafficInfluData{
@@ -771,11 +758,12 @@ func patchApplicationDataIndividualInfluenceDataToDB(influID string,
 	_, errPutOne := CommonDBClient.RestfulAPIPutOne(APPDATA_INFLUDATA_DB_COLLECTION_NAME, filter, newData)
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, dbProblemDetails(errPutOne)
 	}
 	// Roll back to origin data before return
 	delete(newData, "influenceId")
 
-	return newData, http.StatusOK
+	return newData, nil
 }
 
 func HandleApplicationDataInfluenceDataInfluenceIdPut(influID string,
@@ -783,14 +771,20 @@ func HandleApplicationDataInfluenceDataInfluenceIdPut(influID string,
 ) *httpwrapper.Response {
 	logger.DataRepoLog.Infof("handle ApplicationDataInfluenceDataInfluenceIdPut: influID=%q", influID)
 
-	response, status := putApplicationDataIndividualInfluenceDataToDB(influID, trInfluData)
+	response, status, err := putApplicationDataIndividualInfluenceDataToDB(influID, trInfluData)
+	if err != nil {
+		stats.IncrementUdrApplicationDataStats("create", "influence-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrApplicationDataStats("create", "influence-data", "SUCCESS")
 
 	return httpwrapper.NewResponse(status, nil, response)
 }
 
 func putApplicationDataIndividualInfluenceDataToDB(influID string,
 	trInfluData *models.TrafficInfluData,
-) (bson.M, int) {
+) (bson.M, int, error) {
 	filter := bson.M{"influenceId": influID}
 	data := util.ToBsonM(*trInfluData)
 
@@ -799,14 +793,15 @@ func putApplicationDataIndividualInfluenceDataToDB(influID string,
 	isExisted, errPutOne := CommonDBClient.RestfulAPIPutOne(APPDATA_INFLUDATA_DB_COLLECTION_NAME, filter, data)
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, 0, errPutOne
 	}
 	// Roll back to origin data before return
 	delete(data, "influenceId")
 
 	if isExisted {
-		return data, http.StatusOK
+		return data, http.StatusOK, nil
 	}
-	return data, http.StatusCreated
+	return data, http.StatusCreated, nil
 }
 
 func HandleApplicationDataInfluenceDataSubsToNotifyGet(queryParams map[string][]string) *httpwrapper.Response {
@@ -842,7 +837,12 @@ func HandleApplicationDataInfluenceDataSubsToNotifyGet(queryParams map[string][]
 		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
 
-	response := getApplicationDataInfluenceDataSubsToNotifyfromDB(dnn, snssai, intGroupID, supi)
+	response, err := getApplicationDataInfluenceDataSubsToNotifyfromDB(dnn, snssai, intGroupID, supi)
+	if err != nil {
+		stats.IncrementUdrApplicationDataStats("get", "influence-data-notify", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 	stats.IncrementUdrApplicationDataStats("get", "influence-data-notify", "SUCCESS")
 
 	return httpwrapper.NewResponse(http.StatusOK, nil, response)
@@ -850,7 +850,7 @@ func HandleApplicationDataInfluenceDataSubsToNotifyGet(queryParams map[string][]
 
 func getApplicationDataInfluenceDataSubsToNotifyfromDB(dnn, snssai, intGroupID,
 	supi []string,
-) []map[string]interface{} {
+) ([]map[string]interface{}, error) {
 	filter := bson.M{}
 	if len(dnn) != 0 {
 		filter["dnns"] = dnn[0]
@@ -864,6 +864,7 @@ func getApplicationDataInfluenceDataSubsToNotifyfromDB(dnn, snssai, intGroupID,
 	matchedSubs, errGetMany := CommonDBClient.RestfulAPIGetMany(APPDATA_INFLUDATA_SUBSC_DB_COLLECTION_NAME, filter)
 	if errGetMany != nil {
 		logger.DataRepoLog.Warnln(errGetMany)
+		return nil, errGetMany
 	}
 	if len(snssai) != 0 {
 		matchedSubs = filterDataBySnssais(snssai[0], matchedSubs)
@@ -874,7 +875,7 @@ func getApplicationDataInfluenceDataSubsToNotifyfromDB(dnn, snssai, intGroupID,
 		// Delete "subscriptionId" entry which is added by us
 		delete(matchedSubs[i], "subscriptionId")
 	}
-	return matchedSubs
+	return matchedSubs, nil
 }
 
 func filterDataBySnssais(snssaiValue string,
@@ -909,7 +910,13 @@ func HandleApplicationDataInfluenceDataSubsToNotifyPost(trInfluSub *models.Traff
 	udrSelf := udr_context.UDR_Self()
 
 	newSubscID := strconv.FormatUint(udrSelf.NewAppDataInfluDataSubscriptionID(), 10)
-	response, status := postApplicationDataInfluenceDataSubsToNotifyToDB(newSubscID, trInfluSub)
+	response, status, err := postApplicationDataInfluenceDataSubsToNotifyToDB(newSubscID, trInfluSub)
+	if err != nil {
+		stats.IncrementUdrApplicationDataStats("create", "influence-data-subscription", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrApplicationDataStats("create", "influence-data-subscription", "SUCCESS")
 
 	/* Contains the URI of the newly created resource, according
 	   to the structure: {apiRoot}/application-data/influenceData/subs-to-notify/{subscID} */
@@ -923,7 +930,7 @@ func HandleApplicationDataInfluenceDataSubsToNotifyPost(trInfluSub *models.Traff
 
 func postApplicationDataInfluenceDataSubsToNotifyToDB(subscID string,
 	trInfluSub *models.TrafficInfluSub,
-) (bson.M, int) {
+) (bson.M, int, error) {
 	filter := bson.M{"subscriptionId": subscID}
 	data := util.ToBsonM(*trInfluSub)
 
@@ -932,22 +939,23 @@ func postApplicationDataInfluenceDataSubsToNotifyToDB(subscID string,
 	_, errPutOne := CommonDBClient.RestfulAPIPutOne(APPDATA_INFLUDATA_SUBSC_DB_COLLECTION_NAME, filter, data)
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, 0, errPutOne
 	}
 	// Revert back to origin data before return
 	delete(data, "subscriptionId")
-	return data, http.StatusCreated
+	return data, http.StatusCreated, nil
 }
 
 func HandleApplicationDataInfluenceDataSubsToNotifySubscriptionIdDelete(subscID string) *httpwrapper.Response {
 	logger.DataRepoLog.Infof(
 		"handle ApplicationDataInfluenceDataSubsToNotifySubscriptionIdDelete: subscID=%q", subscID)
 
-	err := deleteApplicationDataIndividualInfluenceDataSubsToNotifyFromDB(subscID)
-	if err == nil {
-		stats.IncrementUdrApplicationDataStats("delete", "influence-data-subscription", "SUCCESS")
-	} else {
+	if err := deleteApplicationDataIndividualInfluenceDataSubsToNotifyFromDB(subscID); err != nil {
 		stats.IncrementUdrApplicationDataStats("delete", "influence-data-subscription", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
+	stats.IncrementUdrApplicationDataStats("delete", "influence-data-subscription", "SUCCESS")
 
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
@@ -988,28 +996,29 @@ func HandleApplicationDataInfluenceDataSubsToNotifySubscriptionIdPut(
 	logger.DataRepoLog.Infof(
 		"handle HandleApplicationDataInfluenceDataSubsToNotifySubscriptionIdPut: subscID=%q", subscID)
 
-	response, status := putApplicationDataIndividualInfluenceDataSubsToNotifyToDB(subscID, trInfluSub)
-	if response != nil {
-		stats.IncrementUdrApplicationDataStats("update", "influence-data-subscription", "SUCCESS")
-	} else {
+	response, problemDetails := putApplicationDataIndividualInfluenceDataSubsToNotifyToDB(subscID, trInfluSub)
+	if problemDetails != nil {
 		stats.IncrementUdrApplicationDataStats("update", "influence-data-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	}
+	stats.IncrementUdrApplicationDataStats("update", "influence-data-subscription", "SUCCESS")
 
-	return httpwrapper.NewResponse(status, nil, response)
+	return httpwrapper.NewResponse(http.StatusOK, nil, response)
 }
 
 func putApplicationDataIndividualInfluenceDataSubsToNotifyToDB(subscID string,
 	trInfluSub *models.TrafficInfluSub,
-) (bson.M, int) {
+) (bson.M, *models.ProblemDetails) {
 	filter := bson.M{"subscriptionId": subscID}
 	newData := util.ToBsonM(*trInfluSub)
 
 	oldData, errGetOne := CommonDBClient.RestfulAPIGetOne(APPDATA_INFLUDATA_SUBSC_DB_COLLECTION_NAME, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 	if oldData == nil {
-		return nil, http.StatusNotFound
+		return nil, util.ProblemDetailsNotFound("SUBSCRIPTION_NOT_FOUND")
 	}
 	// Add "subscriptionId" entry to DB
 	newData["subscriptionId"] = subscID
@@ -1017,21 +1026,32 @@ func putApplicationDataIndividualInfluenceDataSubsToNotifyToDB(subscID string,
 	_, errPutOne := CommonDBClient.RestfulAPIPutOne(APPDATA_INFLUDATA_SUBSC_DB_COLLECTION_NAME, filter, newData)
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, dbProblemDetails(errPutOne)
 	}
 	// Roll back to origin data before return
 	delete(newData, "subscriptionId")
-	return newData, http.StatusOK
+	return newData, nil
 }
 
 func HandleApplicationDataPfdsAppIdDelete(appID string) *httpwrapper.Response {
 	logger.DataRepoLog.Infof("handle ApplicationDataPfdsAppIdDelete: appID=%s", appID)
 
-	err := deleteApplicationDataIndividualPfdFromDB(appID)
-	if err == nil {
-		stats.IncrementUdrApplicationDataStats("delete", "pfds", "SUCCESS")
-	} else {
+	filter := bson.M{"applicationId": appID}
+	oldData, err := CommonDBClient.RestfulAPIGetOne(APPDATA_PFD_DB_COLLECTION_NAME, filter)
+	if err == nil && oldData != nil {
+		err = deleteApplicationDataIndividualPfdFromDB(appID)
+		if err == nil {
+			notifyPfdChange(models.PfdChangeNotification{ApplicationId: appID, RemovalFlag: true})
+		}
+	} else if err != nil {
+		logger.DataRepoLog.Warnln(err)
+	}
+	if err != nil {
 		stats.IncrementUdrApplicationDataStats("delete", "pfds", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
+	stats.IncrementUdrApplicationDataStats("delete", "pfds", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
 
@@ -1061,68 +1081,78 @@ func getApplicationDataIndividualPfdFromDB(appID string) (map[string]interface{}
 func HandleApplicationDataPfdsAppIdPut(appID string, pfdDataForApp *models.PfdDataForApp) *httpwrapper.Response {
 	logger.DataRepoLog.Infof("handle ApplicationDataPfdsAppIdPut: appID=%s", appID)
 
-	response, status := putApplicationDataIndividualPfdToDB(appID, pfdDataForApp)
-	if response != nil {
-		stats.IncrementUdrApplicationDataStats("update", "pfds", "SUCCESS")
-	} else {
+	// The PFDs may be cached by the consumers until cachingTime. One already
+	// past, e.g. echoed back from an earlier GET, is dropped.
+	if pfdDataForApp.CachingTime != nil && !pfdDataForApp.CachingTime.After(time.Now()) {
+		pfdDataForApp.CachingTime = nil
+	}
+	pfdDataForApp.ApplicationId = appID
+
+	response, status, err := putApplicationDataIndividualPfdToDB(appID, pfdDataForApp)
+	if err != nil {
 		stats.IncrementUdrApplicationDataStats("update", "pfds", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
+	stats.IncrementUdrApplicationDataStats("update", "pfds", "SUCCESS")
 	return httpwrapper.NewResponse(status, nil, response)
 }
 
-func putApplicationDataIndividualPfdToDB(appID string, pfdDataForApp *models.PfdDataForApp) (bson.M, int) {
+func putApplicationDataIndividualPfdToDB(appID string, pfdDataForApp *models.PfdDataForApp) (bson.M, int, error) {
 	filter := bson.M{"applicationId": appID}
 	data := util.ToBsonM(*pfdDataForApp)
 
+	oldData, errGetOne := CommonDBClient.RestfulAPIGetOne(APPDATA_PFD_DB_COLLECTION_NAME, filter)
+	if errGetOne != nil {
+		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, 0, errGetOne
+	}
 	isExisted, errPutOne := CommonDBClient.RestfulAPIPutOne(APPDATA_PFD_DB_COLLECTION_NAME, filter, data)
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, 0, errPutOne
+	}
+
+	// A new cachingTime alone does not change the PFDs of the application.
+	if oldData == nil || !reflect.DeepEqual(normalizeDocument(oldData)["pfds"], normalizeDocument(data)["pfds"]) {
+		notifyPfdChange(models.PfdChangeNotification{ApplicationId: appID, Pfds: pfdDataForApp.Pfds})
 	}
 
 	if isExisted {
-		return data, http.StatusOK
+		return data, http.StatusOK, nil
 	}
-	return data, http.StatusCreated
+	return data, http.StatusCreated, nil
 }
 
 func HandleApplicationDataPfdsGet(pfdsAppIDs []string) *httpwrapper.Response {
 	logger.DataRepoLog.Infof("handle ApplicationDataPfdsGet: pfdsAppIDs=%#v", pfdsAppIDs)
 
-	// TODO: Parse appID with separator ','
-	// Ex: "app1,app2,..."
-	response := getApplicationDataPfdsFromDB(pfdsAppIDs)
+	response, err := getApplicationDataPfdsFromDB(splitQueryValues(pfdsAppIDs))
+	if err != nil {
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrApplicationDataStats("get", "pfds", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 	stats.IncrementUdrApplicationDataStats("get", "pfds", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusOK, nil, response)
 }
 
-func getApplicationDataPfdsFromDB(pfdsAppIDs []string) (response []map[string]interface{}) {
+func getApplicationDataPfdsFromDB(pfdsAppIDs []string) ([]map[string]interface{}, error) {
 	filter := bson.M{}
+	if len(pfdsAppIDs) != 0 {
+		filter["applicationId"] = bson.M{"$in": pfdsAppIDs}
+	}
 
-	var matchedPfds []map[string]interface{}
-	var errGetMany error
-	if len(pfdsAppIDs) == 0 {
-		matchedPfds, errGetMany = CommonDBClient.RestfulAPIGetMany(APPDATA_PFD_DB_COLLECTION_NAME, filter)
-		if errGetMany != nil {
-			logger.DataRepoLog.Warnln(errGetMany)
-		}
-		for i := 0; i < len(matchedPfds); i++ {
-			delete(matchedPfds[i], "_id")
-		}
-	} else {
-		for _, v := range pfdsAppIDs {
-			filter := bson.M{"applicationId": v}
-			data, errGetOne := CommonDBClient.RestfulAPIGetOne(APPDATA_PFD_DB_COLLECTION_NAME, filter)
-			if errGetOne != nil {
-				logger.DataRepoLog.Warnln(errGetOne)
-			}
-			if data != nil {
-				// Delete "_id" entry which is auto-inserted by MongoDB
-				delete(data, "_id")
-				matchedPfds = append(matchedPfds, data)
-			}
-		}
+	matchedPfds, errGetMany := CommonDBClient.RestfulAPIGetMany(APPDATA_PFD_DB_COLLECTION_NAME, filter)
+	if errGetMany != nil {
+		logger.DataRepoLog.Warnln(errGetMany)
+		return nil, errGetMany
+	}
+	for i := 0; i < len(matchedPfds); i++ {
+		// Delete "_id" entry which is auto-inserted by MongoDB
+		delete(matchedPfds[i], "_id")
 	}
-	return matchedPfds
+	return matchedPfds, nil
 }
 
 func HandleExposureDataSubsToNotifyPost(request *httpwrapper.Request) *httpwrapper.Response {
@@ -1143,12 +1173,12 @@ func HandlePolicyDataBdtDataBdtReferenceIdDelete(request *httpwrapper.Request) *
 	collName := POLICYDATA_BDTDATA
 	bdtReferenceId := request.Params["bdtReferenceId"]
 
-	err := PolicyDataBdtDataBdtReferenceIdDeleteProcedure(collName, bdtReferenceId)
-	if err == nil {
-		stats.IncrementUdrPolicyDataStats("delete", "bdt-data", "SUCCESS")
-	} else {
+	if err := PolicyDataBdtDataBdtReferenceIdDeleteProcedure(collName, bdtReferenceId); err != nil {
 		stats.IncrementUdrPolicyDataStats("delete", "bdt-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
+	stats.IncrementUdrPolicyDataStats("delete", "bdt-data", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
 
@@ -1189,6 +1219,7 @@ func PolicyDataBdtDataBdtReferenceIdGetProcedure(collName string, bdtReferenceId
 	bdtData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if bdtData != nil {
@@ -1205,35 +1236,30 @@ func HandlePolicyDataBdtDataBdtReferenceIdPut(request *httpwrapper.Request) *htt
 	bdtReferenceId := request.Params["bdtReferenceId"]
 	bdtData := request.Body.(models.BdtData)
 
-	response := PolicyDataBdtDataBdtReferenceIdPutProcedure(collName, bdtReferenceId, bdtData)
-	if response != nil {
-		stats.IncrementUdrPolicyDataStats("update", "bdt-data", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+	response, err := PolicyDataBdtDataBdtReferenceIdPutProcedure(collName, bdtReferenceId, bdtData)
+	if err != nil {
+		stats.IncrementUdrPolicyDataStats("update", "bdt-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
-
-	pd := util.ProblemDetailsUnspecified("")
-	stats.IncrementUdrPolicyDataStats("update", "bdt-data", "FAILURE")
-	return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	stats.IncrementUdrPolicyDataStats("update", "bdt-data", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, response)
 }
 
 func PolicyDataBdtDataBdtReferenceIdPutProcedure(collName string, bdtReferenceId string,
 	bdtData models.BdtData,
-) bson.M {
+) (bson.M, error) {
 	putData := util.ToBsonM(bdtData)
 	putData["bdtReferenceId"] = bdtReferenceId
 	filter := bson.M{"bdtReferenceId": bdtReferenceId}
 
-	isExisted, errPutOne := CommonDBClient.RestfulAPIPutOne(collName, filter, putData)
+	_, errPutOne := CommonDBClient.RestfulAPIPutOne(collName, filter, putData)
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, errPutOne
 	}
-
-	if isExisted {
-		PreHandlePolicyDataChangeNotification("", bdtReferenceId, bdtData)
-		return putData
-	} else {
-		return putData
-	}
+	PreHandlePolicyDataChangeNotification("", bdtReferenceId, bdtData)
+	return putData, nil
 }
 
 func HandlePolicyDataBdtDataGet(request *httpwrapper.Request) *httpwrapper.Response {
@@ -1241,24 +1267,35 @@ func HandlePolicyDataBdtDataGet(request *httpwrapper.Request) *httpwrapper.Respo
 
 	collName := POLICYDATA_BDTDATA
 
-	response := PolicyDataBdtDataGetProcedure(collName)
+	response, err := PolicyDataBdtDataGetProcedure(collName)
+	if err != nil {
+		stats.IncrementUdrPolicyDataStats("get", "bdt-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 	stats.IncrementUdrPolicyDataStats("get", "bdt-data", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusOK, nil, response)
 }
 
-func PolicyDataBdtDataGetProcedure(collName string) (response *[]map[string]interface{}) {
+// PolicyDataBdtDataGetProcedure returns all the BDT data, an empty list if
+// there are none.
+func PolicyDataBdtDataGetProcedure(collName string) ([]map[string]interface{}, error) {
 	filter := bson.M{}
 	bdtDataArray, errGetMany := CommonDBClient.RestfulAPIGetMany(collName, filter)
 	if errGetMany != nil {
 		logger.DataRepoLog.Warnln(errGetMany)
+		return nil, errGetMany
+	}
+	if bdtDataArray == nil {
+		bdtDataArray = []map[string]interface{}{}
 	}
-	return &bdtDataArray
+	return bdtDataArray, nil
 }
 
 func HandlePolicyDataPlmnsPlmnIdUePolicySetGet(request *httpwrapper.Request) *httpwrapper.Response {
 	logger.DataRepoLog.Infoln("handle PolicyDataPlmnsPlmnIdUePolicySetGet")
 
-	collName := "policyData.plmns.uePolicySet"
+	collName := POLICYDATA_PLMNS_UEPOLICYSET
 	plmnId := request.Params["plmnId"]
 
 	response, problemDetails := PolicyDataPlmnsPlmnIdUePolicySetGetProcedure(collName, plmnId)
@@ -1283,52 +1320,301 @@ func PolicyDataPlmnsPlmnIdUePolicySetGetProcedure(collName string,
 	uePolicySet, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if uePolicySet != nil {
 		return &uePolicySet, nil
 	} else {
-		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
+		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
+	}
+}
+
+func HandlePolicyDataPlmnsPlmnIdUePolicySetPut(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.DataRepoLog.Infoln("handle PolicyDataPlmnsPlmnIdUePolicySetPut")
+
+	collName := POLICYDATA_PLMNS_UEPOLICYSET
+	plmnId := request.Params["plmnId"]
+
+	var uePolicySet models.UePolicySet
+	if pd := ValidateRequestBody(&uePolicySet, request.Body.([]byte)); pd != nil {
+		stats.IncrementUdrPolicyDataStats("create", "plmn-ue-policy-set", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := decodeStrict(request.Body.([]byte), &uePolicySet); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrPolicyDataStats("create", "plmn-ue-policy-set", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := validateUePolicySet(&uePolicySet); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrPolicyDataStats("create", "plmn-ue-policy-set", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	response, status, err := PolicyDataPlmnsPlmnIdUePolicySetPutProcedure(collName, plmnId, uePolicySet)
+	if err != nil {
+		stats.IncrementUdrPolicyDataStats("create", "plmn-ue-policy-set", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	switch status {
+	case http.StatusNoContent:
+		stats.IncrementUdrPolicyDataStats("create", "plmn-ue-policy-set", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	case http.StatusCreated:
+		stats.IncrementUdrPolicyDataStats("create", "plmn-ue-policy-set", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusCreated, nil, response)
+	}
+
+	pd := util.ProblemDetailsUnspecified("")
+	stats.IncrementUdrPolicyDataStats("create", "plmn-ue-policy-set", "FAILURE")
+	return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+}
+
+func PolicyDataPlmnsPlmnIdUePolicySetPutProcedure(collName string, plmnId string,
+	uePolicySet models.UePolicySet,
+) (bson.M, int, error) {
+	putData := util.ToBsonM(uePolicySet)
+	putData["plmnId"] = plmnId
+	filter := bson.M{"plmnId": plmnId}
+
+	isExisted, errPutOne := CommonDBClient.RestfulAPIPutOne(collName, filter, putData)
+	if errPutOne != nil {
+		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, 0, errPutOne
+	}
+	notifyPolicyDataChange(plmnUePolicySetPath(plmnId), models.PolicyDataChangeNotification{UePolicySet: &uePolicySet})
+	if !isExisted {
+		return putData, http.StatusCreated, nil
+	} else {
+		return nil, http.StatusNoContent, nil
+	}
+}
+
+func HandlePolicyDataPlmnsPlmnIdUePolicySetPatch(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.DataRepoLog.Infoln("handle PolicyDataPlmnsPlmnIdUePolicySetPatch")
+
+	collName := POLICYDATA_PLMNS_UEPOLICYSET
+	plmnId := request.Params["plmnId"]
+
+	problemDetails := PolicyDataPlmnsPlmnIdUePolicySetPatchProcedure(collName, plmnId, request.Body.([]byte))
+
+	if problemDetails == nil {
+		stats.IncrementUdrPolicyDataStats("update", "plmn-ue-policy-set", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	} else {
+		stats.IncrementUdrPolicyDataStats("update", "plmn-ue-policy-set", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+}
+
+func PolicyDataPlmnsPlmnIdUePolicySetPatchProcedure(collName string, plmnId string,
+	patch []byte,
+) *models.ProblemDetails {
+	var uePolicySet models.UePolicySet
+	problemDetails := mergePatchDocument(collName, bson.M{"plmnId": plmnId}, patch, &uePolicySet, func() error {
+		return validateUePolicySet(&uePolicySet)
+	})
+	if problemDetails != nil {
+		return problemDetails
+	}
+	notifyPolicyDataChange(plmnUePolicySetPath(plmnId), models.PolicyDataChangeNotification{UePolicySet: &uePolicySet})
+	return nil
+}
+
+func HandlePolicyDataPlmnsPlmnIdUePolicySetDelete(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.DataRepoLog.Infoln("handle PolicyDataPlmnsPlmnIdUePolicySetDelete")
+
+	collName := POLICYDATA_PLMNS_UEPOLICYSET
+	plmnId := request.Params["plmnId"]
+
+	problemDetails := PolicyDataPlmnsPlmnIdUePolicySetDeleteProcedure(collName, plmnId)
+
+	if problemDetails == nil {
+		stats.IncrementUdrPolicyDataStats("delete", "plmn-ue-policy-set", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	} else {
+		stats.IncrementUdrPolicyDataStats("delete", "plmn-ue-policy-set", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+}
+
+func PolicyDataPlmnsPlmnIdUePolicySetDeleteProcedure(collName string, plmnId string) *models.ProblemDetails {
+	// the deletion is not notified: a notification without the data set has
+	// nothing identifying the PLMN
+	return deleteExistingDocument(collName, bson.M{"plmnId": plmnId})
+}
+
+func HandlePolicyDataSponsorConnectivityDataSponsorIdGet(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.DataRepoLog.Infoln("handle PolicyDataSponsorConnectivityDataSponsorIdGet")
+
+	collName := POLICYDATA_SPONSORCONNECTIVITYDATA
+	sponsorId := request.Params["sponsorId"]
+
+	response, status, err := PolicyDataSponsorConnectivityDataSponsorIdGetProcedure(collName, sponsorId)
+	if err != nil {
+		stats.IncrementUdrPolicyDataStats("get", "sponsor-connectivity-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	switch status {
+	case http.StatusOK:
+		stats.IncrementUdrPolicyDataStats("get", "sponsor-connectivity-data", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+	case http.StatusNoContent:
+		stats.IncrementUdrPolicyDataStats("get", "sponsor-connectivity-data", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	}
+
+	pd := util.ProblemDetailsUnspecified("")
+	stats.IncrementUdrPolicyDataStats("get", "sponsor-connectivity-data", "FAILURE")
+	return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+}
+
+func PolicyDataSponsorConnectivityDataSponsorIdGetProcedure(collName string,
+	sponsorId string,
+) (*map[string]interface{}, int, error) {
+	filter := bson.M{"sponsorId": sponsorId}
+
+	sponsorConnectivityData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
+	if errGetOne != nil {
+		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, 0, errGetOne
+	}
+
+	if sponsorConnectivityData != nil {
+		return &sponsorConnectivityData, http.StatusOK, nil
+	} else {
+		return nil, http.StatusNoContent, nil
+	}
+}
+
+func HandlePolicyDataSponsorConnectivityDataSponsorIdPut(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.DataRepoLog.Infoln("handle PolicyDataSponsorConnectivityDataSponsorIdPut")
+
+	collName := POLICYDATA_SPONSORCONNECTIVITYDATA
+	sponsorId := request.Params["sponsorId"]
+
+	var sponsorConnectivityData models.SponsorConnectivityData
+	if pd := ValidateRequestBody(&sponsorConnectivityData, request.Body.([]byte)); pd != nil {
+		stats.IncrementUdrPolicyDataStats("create", "sponsor-connectivity-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := decodeStrict(request.Body.([]byte), &sponsorConnectivityData); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrPolicyDataStats("create", "sponsor-connectivity-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := validateSponsorConnectivityData(&sponsorConnectivityData); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrPolicyDataStats("create", "sponsor-connectivity-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	response, status, err := PolicyDataSponsorConnectivityDataSponsorIdPutProcedure(collName, sponsorId,
+		sponsorConnectivityData)
+	if err != nil {
+		stats.IncrementUdrPolicyDataStats("create", "sponsor-connectivity-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	switch status {
+	case http.StatusNoContent:
+		stats.IncrementUdrPolicyDataStats("create", "sponsor-connectivity-data", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	case http.StatusCreated:
+		stats.IncrementUdrPolicyDataStats("create", "sponsor-connectivity-data", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusCreated, nil, response)
+	}
+
+	pd := util.ProblemDetailsUnspecified("")
+	stats.IncrementUdrPolicyDataStats("create", "sponsor-connectivity-data", "FAILURE")
+	return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+}
+
+func PolicyDataSponsorConnectivityDataSponsorIdPutProcedure(collName string, sponsorId string,
+	sponsorConnectivityData models.SponsorConnectivityData,
+) (bson.M, int, error) {
+	putData := util.ToBsonM(sponsorConnectivityData)
+	putData["sponsorId"] = sponsorId
+	filter := bson.M{"sponsorId": sponsorId}
+
+	isExisted, errPutOne := CommonDBClient.RestfulAPIPutOne(collName, filter, putData)
+	if errPutOne != nil {
+		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, 0, errPutOne
+	}
+	PreHandlePolicyDataChangeNotification("", sponsorId, sponsorConnectivityData)
+	if !isExisted {
+		return putData, http.StatusCreated, nil
+	} else {
+		return nil, http.StatusNoContent, nil
 	}
 }
 
-func HandlePolicyDataSponsorConnectivityDataSponsorIdGet(request *httpwrapper.Request) *httpwrapper.Response {
-	logger.DataRepoLog.Infoln("handle PolicyDataSponsorConnectivityDataSponsorIdGet")
+func HandlePolicyDataSponsorConnectivityDataSponsorIdPatch(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.DataRepoLog.Infoln("handle PolicyDataSponsorConnectivityDataSponsorIdPatch")
 
-	collName := "policyData.sponsorConnectivityData"
+	collName := POLICYDATA_SPONSORCONNECTIVITYDATA
 	sponsorId := request.Params["sponsorId"]
 
-	response, status := PolicyDataSponsorConnectivityDataSponsorIdGetProcedure(collName, sponsorId)
+	problemDetails := PolicyDataSponsorConnectivityDataSponsorIdPatchProcedure(collName, sponsorId,
+		request.Body.([]byte))
 
-	switch status {
-	case http.StatusOK:
-		stats.IncrementUdrPolicyDataStats("get", "sponsor-connectivity-data", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusOK, nil, response)
-	case http.StatusNoContent:
-		stats.IncrementUdrPolicyDataStats("get", "sponsor-connectivity-data", "SUCCESS")
+	if problemDetails == nil {
+		stats.IncrementUdrPolicyDataStats("update", "sponsor-connectivity-data", "SUCCESS")
 		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	} else {
+		stats.IncrementUdrPolicyDataStats("update", "sponsor-connectivity-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	}
+}
 
-	pd := util.ProblemDetailsUnspecified("")
-	stats.IncrementUdrPolicyDataStats("get", "sponsor-connectivity-data", "FAILURE")
-	return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+func PolicyDataSponsorConnectivityDataSponsorIdPatchProcedure(collName string, sponsorId string,
+	patch []byte,
+) *models.ProblemDetails {
+	var sponsorConnectivityData models.SponsorConnectivityData
+	problemDetails := mergePatchDocument(collName, bson.M{"sponsorId": sponsorId}, patch, &sponsorConnectivityData,
+		func() error { return validateSponsorConnectivityData(&sponsorConnectivityData) })
+	if problemDetails != nil {
+		return problemDetails
+	}
+	PreHandlePolicyDataChangeNotification("", sponsorId, sponsorConnectivityData)
+	return nil
 }
 
-func PolicyDataSponsorConnectivityDataSponsorIdGetProcedure(collName string,
-	sponsorId string,
-) (*map[string]interface{}, int) {
-	filter := bson.M{"sponsorId": sponsorId}
+func HandlePolicyDataSponsorConnectivityDataSponsorIdDelete(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.DataRepoLog.Infoln("handle PolicyDataSponsorConnectivityDataSponsorIdDelete")
 
-	sponsorConnectivityData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-	if errGetOne != nil {
-		logger.DataRepoLog.Warnln(errGetOne)
-	}
+	collName := POLICYDATA_SPONSORCONNECTIVITYDATA
+	sponsorId := request.Params["sponsorId"]
 
-	if sponsorConnectivityData != nil {
-		return &sponsorConnectivityData, http.StatusOK
+	problemDetails := PolicyDataSponsorConnectivityDataSponsorIdDeleteProcedure(collName, sponsorId)
+
+	if problemDetails == nil {
+		stats.IncrementUdrPolicyDataStats("delete", "sponsor-connectivity-data", "SUCCESS")
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 	} else {
-		return nil, http.StatusNoContent
+		stats.IncrementUdrPolicyDataStats("delete", "sponsor-connectivity-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+}
+
+func PolicyDataSponsorConnectivityDataSponsorIdDeleteProcedure(collName string,
+	sponsorId string,
+) *models.ProblemDetails {
+	problemDetails := deleteExistingDocument(collName, bson.M{"sponsorId": sponsorId})
+	if problemDetails != nil {
+		return problemDetails
 	}
+	// the deletion is told by a notification without the data set
+	notifyPolicyDataChange(sponsorConnectivityDataPath(sponsorId),
+		models.PolicyDataChangeNotification{SponsorId: sponsorId})
+	return nil
 }
 
 func HandlePolicyDataSubsToNotifyPost(request *httpwrapper.Request) *httpwrapper.Response {
@@ -1347,9 +1633,7 @@ func HandlePolicyDataSubsToNotifyPost(request *httpwrapper.Request) *httpwrapper
 func PolicyDataSubsToNotifyPostProcedure(PolicyDataSubscription models.PolicyDataSubscription) string {
 	udrSelf := udr_context.UDR_Self()
 
-	newSubscriptionID := strconv.Itoa(udrSelf.PolicyDataSubscriptionIDGenerator)
-	udrSelf.PolicyDataSubscriptions[newSubscriptionID] = &PolicyDataSubscription
-	udrSelf.PolicyDataSubscriptionIDGenerator++
+	newSubscriptionID := udrSelf.NewPolicyDataSubscription(&PolicyDataSubscription)
 
 	/* Contains the URI of the newly created resource, according
 	   to the structure: {apiRoot}/subscription-data/subs-to-notify/{subsId} */
@@ -1377,12 +1661,9 @@ func HandlePolicyDataSubsToNotifySubsIdDelete(request *httpwrapper.Request) *htt
 
 func PolicyDataSubsToNotifySubsIdDeleteProcedure(subsId string) (problemDetails *models.ProblemDetails) {
 	udrSelf := udr_context.UDR_Self()
-	_, ok := udrSelf.PolicyDataSubscriptions[subsId]
-	if !ok {
+	if !udrSelf.DeletePolicyDataSubscription(subsId) {
 		return util.ProblemDetailsNotFound("SUBSCRIPTION_NOT_FOUND")
 	}
-	delete(udrSelf.PolicyDataSubscriptions, subsId)
-
 	return nil
 }
 
@@ -1407,13 +1688,9 @@ func PolicyDataSubsToNotifySubsIdPutProcedure(subsId string,
 	policyDataSubscription models.PolicyDataSubscription,
 ) (*models.PolicyDataSubscription, *models.ProblemDetails) {
 	udrSelf := udr_context.UDR_Self()
-	_, ok := udrSelf.PolicyDataSubscriptions[subsId]
-	if !ok {
+	if !udrSelf.ReplacePolicyDataSubscription(subsId, &policyDataSubscription) {
 		return nil, util.ProblemDetailsNotFound("SUBSCRIPTION_NOT_FOUND")
 	}
-
-	udrSelf.PolicyDataSubscriptions[subsId] = &policyDataSubscription
-
 	return &policyDataSubscription, nil
 }
 
@@ -1446,6 +1723,7 @@ func PolicyDataUesUeIdAmDataGetProcedure(collName string,
 	amPolicyData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if amPolicyData != nil {
@@ -1461,11 +1739,11 @@ func HandlePolicyDataUesUeIdOperatorSpecificDataGet(request *httpwrapper.Request
 	collName := POLICYDATA_UES_OPSPECDATA
 	ueId := request.Params["ueId"]
 
-	response, problemDetails := PolicyDataUesUeIdOperatorSpecificDataGetProcedure(collName, ueId)
+	response, etag, problemDetails := PolicyDataUesUeIdOperatorSpecificDataGetProcedure(collName, ueId)
 
 	if response != nil {
 		stats.IncrementUdrPolicyDataStats("get", "operator-specific-data", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+		return etagResponse(request.Header, etag, response)
 	} else if problemDetails != nil {
 		stats.IncrementUdrPolicyDataStats("get", "operator-specific-data", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
@@ -1478,19 +1756,20 @@ func HandlePolicyDataUesUeIdOperatorSpecificDataGet(request *httpwrapper.Request
 
 func PolicyDataUesUeIdOperatorSpecificDataGetProcedure(collName string,
 	ueId string,
-) (*interface{}, *models.ProblemDetails) {
+) (*interface{}, string, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId}
 
 	operatorSpecificDataContainerMapCover, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, "", dbProblemDetails(errGetOne)
 	}
 
 	if operatorSpecificDataContainerMapCover != nil {
-		operatorSpecificDataContainerMap := operatorSpecificDataContainerMapCover["operatorSpecificDataContainerMap"]
-		return &operatorSpecificDataContainerMap, nil
+		operatorSpecificDataContainerMap := operatorSpecificDataContainerMapCover[operatorSpecificDataContainerMapField]
+		return &operatorSpecificDataContainerMap, documentETag(operatorSpecificDataContainerMapCover), nil
 	} else {
-		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
+		return nil, "", util.ProblemDetailsNotFound("USER_NOT_FOUND")
 	}
 }
 
@@ -1501,11 +1780,12 @@ func HandlePolicyDataUesUeIdOperatorSpecificDataPatch(request *httpwrapper.Reque
 	ueId := request.Params["ueId"]
 	patchItem := request.Body.([]models.PatchItem)
 
-	problemDetails := PolicyDataUesUeIdOperatorSpecificDataPatchProcedure(collName, ueId, patchItem)
+	etag, problemDetails := PolicyDataUesUeIdOperatorSpecificDataPatchProcedure(collName, ueId, patchItem,
+		request.Header)
 
 	if problemDetails == nil {
 		stats.IncrementUdrPolicyDataStats("update", "operator-specific-data", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+		return httpwrapper.NewResponse(http.StatusNoContent, etagHeader(etag), map[string]interface{}{})
 	} else {
 		stats.IncrementUdrPolicyDataStats("update", "operator-specific-data", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
@@ -1513,23 +1793,21 @@ func HandlePolicyDataUesUeIdOperatorSpecificDataPatch(request *httpwrapper.Reque
 }
 
 func PolicyDataUesUeIdOperatorSpecificDataPatchProcedure(collName string, ueId string,
-	patchItem []models.PatchItem,
-) *models.ProblemDetails {
+	patchItem []models.PatchItem, header http.Header,
+) (string, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId}
 
-	patchJSON, err := json.Marshal(patchItem)
-	if err != nil {
-		logger.DataRepoLog.Warnln(err)
-	}
-
-	failure := CommonDBClient.RestfulAPIJSONPatchExtend(collName, filter, patchJSON,
-		"operatorSpecificDataContainerMap")
-
-	if failure == nil {
-		return nil
-	} else {
-		return util.ProblemDetailsModifyNotAllowed("")
+	// the patch applies to the container map stored in the document
+	containerPatch := make([]models.PatchItem, len(patchItem))
+	for i, item := range patchItem {
+		item.Path = "/" + operatorSpecificDataContainerMapField + item.Path
+		if item.From != "" {
+			item.From = "/" + operatorSpecificDataContainerMapField + item.From
+		}
+		containerPatch[i] = item
 	}
+	_, _, etag, problemDetails := patchVersionedDocument(CommonDBClient, collName, filter, containerPatch, header)
+	return etag, problemDetails
 }
 
 func HandlePolicyDataUesUeIdOperatorSpecificDataPut(request *httpwrapper.Request) *httpwrapper.Response {
@@ -1541,29 +1819,30 @@ func HandlePolicyDataUesUeIdOperatorSpecificDataPut(request *httpwrapper.Request
 	ueId := request.Params["ueId"]
 	OperatorSpecificDataContainer := request.Body.(map[string]models.OperatorSpecificDataContainer)
 
-	err := PolicyDataUesUeIdOperatorSpecificDataPutProcedure(collName, ueId, OperatorSpecificDataContainer)
-	if err == nil {
-		stats.IncrementUdrPolicyDataStats("create", "operator-specific-data", "SUCCESS")
-	} else {
+	etag, problemDetails := PolicyDataUesUeIdOperatorSpecificDataPutProcedure(collName, ueId,
+		OperatorSpecificDataContainer, request.Header)
+	if problemDetails != nil {
 		stats.IncrementUdrPolicyDataStats("create", "operator-specific-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	}
+	stats.IncrementUdrPolicyDataStats("create", "operator-specific-data", "SUCCESS")
 
-	return httpwrapper.NewResponse(http.StatusOK, nil, map[string]interface{}{})
+	return httpwrapper.NewResponse(http.StatusOK, etagHeader(etag), map[string]interface{}{})
 }
 
 func PolicyDataUesUeIdOperatorSpecificDataPutProcedure(collName string, ueId string,
-	OperatorSpecificDataContainer map[string]models.OperatorSpecificDataContainer,
-) error {
+	OperatorSpecificDataContainer map[string]models.OperatorSpecificDataContainer, header http.Header,
+) (string, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId}
 
-	putData := map[string]interface{}{"operatorSpecificDataContainerMap": OperatorSpecificDataContainer}
+	putData := map[string]interface{}{operatorSpecificDataContainerMapField: OperatorSpecificDataContainer}
 	putData["ueId"] = ueId
 
-	_, errPutOne := CommonDBClient.RestfulAPIPutOne(collName, filter, putData)
-	if errPutOne != nil {
-		logger.DataRepoLog.Warnln(errPutOne)
+	etag, problemDetails := putVersionedDocument(CommonDBClient, collName, filter, putData, header)
+	if problemDetails != nil {
+		logger.DataRepoLog.Warnln(problemDetails.Detail)
 	}
-	return errPutOne
+	return etag, problemDetails
 }
 
 func HandlePolicyDataUesUeIdSmDataGet(request *httpwrapper.Request) *httpwrapper.Response {
@@ -1608,6 +1887,7 @@ func PolicyDataUesUeIdSmDataGetProcedure(collName string, ueId string, snssai mo
 	smPolicyData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 	if smPolicyData != nil {
 		var smPolicyDataResp models.SmPolicyData
@@ -1621,6 +1901,7 @@ func PolicyDataUesUeIdSmDataGetProcedure(collName string, ueId string, snssai mo
 			usageMonDataMapArray, errGetMany := CommonDBClient.RestfulAPIGetMany(collName, filter)
 			if errGetMany != nil {
 				logger.DataRepoLog.Warnln(errGetMany)
+				return nil, dbProblemDetails(errGetMany)
 			}
 
 			if !reflect.DeepEqual(usageMonDataMapArray, []map[string]interface{}{}) {
@@ -1663,13 +1944,13 @@ func PolicyDataUesUeIdSmDataPatchProcedure(collName string, ueId string,
 ) *models.ProblemDetails {
 	filter := bson.M{"ueId": ueId}
 
-	successAll := true
+	var failure error
 	for k, usageMonData := range UsageMonData {
 		limitId := k
 		filterTmp := bson.M{"ueId": ueId, "limitId": limitId}
-		failure := CommonDBClient.RestfulAPIMergePatch(collName, filterTmp, util.ToBsonM(usageMonData))
-		if failure != nil {
-			successAll = false
+		if err := CommonDBClient.RestfulAPIMergePatch(collName, filterTmp, util.ToBsonM(usageMonData)); err != nil {
+			logger.DataRepoLog.Warnln(err)
+			failure = err
 		} else {
 			var usageMonData models.UsageMonData
 			usageMonDataBsonM, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
@@ -1684,7 +1965,7 @@ func PolicyDataUesUeIdSmDataPatchProcedure(collName string, ueId string,
 		}
 	}
 
-	if successAll {
+	if failure == nil {
 		smPolicyDataBsonM, errGetOneNew := CommonDBClient.RestfulAPIGetOne(collName, filter)
 		if errGetOneNew != nil {
 			logger.DataRepoLog.Warnln(errGetOneNew)
@@ -1717,7 +1998,7 @@ func PolicyDataUesUeIdSmDataPatchProcedure(collName string, ueId string,
 		PreHandlePolicyDataChangeNotification(ueId, "", smPolicyData)
 		return nil
 	} else {
-		return util.ProblemDetailsModifyNotAllowed("")
+		return dbProblemDetails(failure)
 	}
 }
 
@@ -1728,12 +2009,12 @@ func HandlePolicyDataUesUeIdSmDataUsageMonIdDelete(request *httpwrapper.Request)
 	ueId := request.Params["ueId"]
 	usageMonId := request.Params["usageMonId"]
 
-	err := PolicyDataUesUeIdSmDataUsageMonIdDeleteProcedure(collName, ueId, usageMonId)
-	if err == nil {
-		stats.IncrementUdrPolicyDataStats("delete", "sm-data", "SUCCESS")
-	} else {
+	if err := PolicyDataUesUeIdSmDataUsageMonIdDeleteProcedure(collName, ueId, usageMonId); err != nil {
 		stats.IncrementUdrPolicyDataStats("delete", "sm-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
+	stats.IncrementUdrPolicyDataStats("delete", "sm-data", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
 
@@ -1753,7 +2034,12 @@ func HandlePolicyDataUesUeIdSmDataUsageMonIdGet(request *httpwrapper.Request) *h
 	ueId := request.Params["ueId"]
 	usageMonId := request.Params["usageMonId"]
 
-	response := PolicyDataUesUeIdSmDataUsageMonIdGetProcedure(collName, usageMonId, ueId)
+	response, err := PolicyDataUesUeIdSmDataUsageMonIdGetProcedure(collName, usageMonId, ueId)
+	if err != nil {
+		stats.IncrementUdrPolicyDataStats("get", "sm-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 
 	if response != nil {
 		stats.IncrementUdrPolicyDataStats("get", "sm-data", "SUCCESS")
@@ -1766,15 +2052,18 @@ func HandlePolicyDataUesUeIdSmDataUsageMonIdGet(request *httpwrapper.Request) *h
 
 func PolicyDataUesUeIdSmDataUsageMonIdGetProcedure(collName string, usageMonId string,
 	ueId string,
-) *map[string]interface{} {
+) (*map[string]interface{}, error) {
 	filter := bson.M{"ueId": ueId, "usageMonId": usageMonId}
 
 	usageMonData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, errGetOne
 	}
-
-	return &usageMonData
+	if usageMonData == nil {
+		return nil, nil
+	}
+	return &usageMonData, nil
 }
 
 func HandlePolicyDataUesUeIdSmDataUsageMonIdPut(request *httpwrapper.Request) *httpwrapper.Response {
@@ -1785,7 +2074,12 @@ func HandlePolicyDataUesUeIdSmDataUsageMonIdPut(request *httpwrapper.Request) *h
 	usageMonData := request.Body.(models.UsageMonData)
 	collName := POLICYDATA_UES_SMDATA_USAGEMONDATA
 
-	response := PolicyDataUesUeIdSmDataUsageMonIdPutProcedure(collName, ueId, usageMonId, usageMonData)
+	response, err := PolicyDataUesUeIdSmDataUsageMonIdPutProcedure(collName, ueId, usageMonId, usageMonData)
+	if err != nil {
+		stats.IncrementUdrPolicyDataStats("create", "sm-data", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 	stats.IncrementUdrPolicyDataStats("create", "sm-data", "SUCCESS")
 
 	return httpwrapper.NewResponse(http.StatusCreated, nil, response)
@@ -1793,7 +2087,7 @@ func HandlePolicyDataUesUeIdSmDataUsageMonIdPut(request *httpwrapper.Request) *h
 
 func PolicyDataUesUeIdSmDataUsageMonIdPutProcedure(collName string, ueId string, usageMonId string,
 	usageMonData models.UsageMonData,
-) *bson.M {
+) (*bson.M, error) {
 	putData := util.ToBsonM(usageMonData)
 	putData["ueId"] = ueId
 	putData["usageMonId"] = usageMonId
@@ -1802,8 +2096,9 @@ func PolicyDataUesUeIdSmDataUsageMonIdPutProcedure(collName string, ueId string,
 	_, errPutOne := CommonDBClient.RestfulAPIPutOne(collName, filter, putData)
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, errPutOne
 	}
-	return &putData
+	return &putData, nil
 }
 
 func HandlePolicyDataUesUeIdUePolicySetGet(request *httpwrapper.Request) *httpwrapper.Response {
@@ -1835,6 +2130,7 @@ func PolicyDataUesUeIdUePolicySetGetProcedure(collName string, ueId string) (*ma
 	uePolicySet, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if uePolicySet != nil {
@@ -1884,7 +2180,8 @@ func PolicyDataUesUeIdUePolicySetPatchProcedure(collName string, ueId string,
 		PreHandlePolicyDataChangeNotification(ueId, "", uePolicySet)
 		return nil
 	} else {
-		return util.ProblemDetailsModifyNotAllowed("")
+		logger.DataRepoLog.Warnln(failure)
+		return dbProblemDetails(failure)
 	}
 }
 
@@ -1895,7 +2192,12 @@ func HandlePolicyDataUesUeIdUePolicySetPut(request *httpwrapper.Request) *httpwr
 	ueId := request.Params["ueId"]
 	UePolicySet := request.Body.(models.UePolicySet)
 
-	response, status := PolicyDataUesUeIdUePolicySetPutProcedure(collName, ueId, UePolicySet)
+	response, status, err := PolicyDataUesUeIdUePolicySetPutProcedure(collName, ueId, UePolicySet)
+	if err != nil {
+		stats.IncrementUdrPolicyDataStats("create", "ue-policy-set", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 
 	switch status {
 	case http.StatusNoContent:
@@ -1913,7 +2215,7 @@ func HandlePolicyDataUesUeIdUePolicySetPut(request *httpwrapper.Request) *httpwr
 
 func PolicyDataUesUeIdUePolicySetPutProcedure(collName string, ueId string,
 	UePolicySet models.UePolicySet,
-) (bson.M, int) {
+) (bson.M, int, error) {
 	putData := util.ToBsonM(UePolicySet)
 	putData["ueId"] = ueId
 	filter := bson.M{"ueId": ueId}
@@ -1921,11 +2223,12 @@ func PolicyDataUesUeIdUePolicySetPutProcedure(collName string, ueId string,
 	isExisted, errPutOne := CommonDBClient.RestfulAPIPutOne(collName, filter, putData)
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, 0, errPutOne
 	}
 	if !isExisted {
-		return putData, http.StatusCreated
+		return putData, http.StatusCreated, nil
 	} else {
-		return nil, http.StatusNoContent
+		return nil, http.StatusNoContent, nil
 	}
 }
 
@@ -2066,6 +2369,10 @@ func ModifyAmfSubscriptionInfoProcedure(ueId string, subsId string,
 		return util.ProblemDetailsModifyNotAllowed("Occur error when applying PatchItem")
 	}
 	var modifiedData []models.AmfSubscriptionInfo
+	if problemDetails := validateJSON(&modifiedData, modified,
+		"the patched resource does not match its schema"); problemDetails != nil {
+		return problemDetails
+	}
 	err = json.Unmarshal(modified, &modifiedData)
 	if err != nil {
 		logger.DataRepoLog.Error(err)
@@ -2144,6 +2451,7 @@ func QueryEEDataProcedure(collName string, ueId string) (*map[string]interface{}
 	eeProfileData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if eeProfileData != nil {
@@ -2434,19 +2742,32 @@ func HandleQueryeesubscriptions(request *httpwrapper.Request) *httpwrapper.Respo
 	return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 }
 
+// QueryeesubscriptionsProcedure returns the EE subscriptions of ueId,
+// including those to the groups of ueId.
 func QueryeesubscriptionsProcedure(ueId string) ([]models.EeSubscription, *models.ProblemDetails) {
 	udrSelf := udr_context.UDR_Self()
 
+	var eeSubscriptionSlice []models.EeSubscription
 	value, ok := udrSelf.UESubsCollection.Load(ueId)
-	if !ok {
-		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
+	if ok {
+		UESubsData := value.(*udr_context.UESubsData)
+		for _, v := range UESubsData.EeSubscriptionCollection {
+			eeSubscriptionSlice = append(eeSubscriptionSlice, *v.EeSubscriptions)
+		}
 	}
 
-	UESubsData := value.(*udr_context.UESubsData)
-	var eeSubscriptionSlice []models.EeSubscription
-
-	for _, v := range UESubsData.EeSubscriptionCollection {
-		eeSubscriptionSlice = append(eeSubscriptionSlice, *v.EeSubscriptions)
+	for _, ueGroupId := range ueGroupIds(ueId) {
+		value, found := udrSelf.UEGroupCollection.Load(ueGroupId)
+		if !found {
+			continue
+		}
+		ok = true
+		for _, v := range value.(*udr_context.UEGroupSubsData).EeSubscriptions {
+			eeSubscriptionSlice = append(eeSubscriptionSlice, *v)
+		}
+	}
+	if !ok {
+		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
 	}
 	return eeSubscriptionSlice, nil
 }
@@ -2458,42 +2779,27 @@ func HandlePatchOperSpecData(request *httpwrapper.Request) *httpwrapper.Response
 	ueId := request.Params["ueId"]
 	patchItem := request.Body.([]models.PatchItem)
 
-	problemDetails := PatchOperSpecDataProcedure(collName, ueId, patchItem)
+	etag, problemDetails := PatchOperSpecDataProcedure(collName, ueId, patchItem, request.Header)
 
 	if problemDetails == nil {
 		stats.IncrementUdrPolicyDataStats("update", "operator-specific-data", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+		return httpwrapper.NewResponse(http.StatusNoContent, etagHeader(etag), map[string]interface{}{})
 	} else {
 		stats.IncrementUdrPolicyDataStats("update", "operator-specific-data", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	}
 }
 
-func PatchOperSpecDataProcedure(collName string, ueId string, patchItem []models.PatchItem) *models.ProblemDetails {
+func PatchOperSpecDataProcedure(collName string, ueId string, patchItem []models.PatchItem,
+	header http.Header,
+) (string, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId}
-
-	origValue, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-	if errGetOne != nil {
-		logger.DataRepoLog.Errorln(errGetOne)
-	}
-
-	patchJSON, err := json.Marshal(patchItem)
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-	}
-
-	failure := CommonDBClient.RestfulAPIJSONPatch(collName, filter, patchJSON)
-
-	if failure == nil {
-		newValue, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOne != nil {
-			logger.DataRepoLog.Errorln(errGetOne)
-		}
-		PreHandleOnDataChangeNotify(ueId, CurrentResourceUri, patchItem, origValue, newValue)
-		return nil
-	} else {
-		return util.ProblemDetailsModifyNotAllowed("")
+	origValue, newValue, etag, problemDetails := patchVersionedDocument(CommonDBClient, collName, filter, patchItem, header)
+	if problemDetails != nil {
+		return "", problemDetails
 	}
+	PreHandleOnDataChangeNotify(ueId, subscriptionDataResourceUri(collName, ueId, ""), patchItem, origValue, newValue)
+	return etag, nil
 }
 
 func HandleQueryOperSpecData(request *httpwrapper.Request) *httpwrapper.Response {
@@ -2506,7 +2812,7 @@ func HandleQueryOperSpecData(request *httpwrapper.Request) *httpwrapper.Response
 
 	if response != nil {
 		stats.IncrementUdrPolicyDataStats("get", "operator-specific-data", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+		return documentResponse(request.Header, *response)
 	} else if problemDetails != nil {
 		stats.IncrementUdrPolicyDataStats("get", "operator-specific-data", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
@@ -2523,6 +2829,7 @@ func QueryOperSpecDataProcedure(collName string, ueId string) (*map[string]inter
 	operatorSpecificDataContainer, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	// The key of the map is operator specific data element name and the value is the operator specific data of the UE.
@@ -2544,7 +2851,7 @@ func HandleGetppData(request *httpwrapper.Request) *httpwrapper.Response {
 
 	if response != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "pp-data", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusOK, nil, response)
+		return documentResponse(request.Header, *response)
 	} else if problemDetails != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "pp-data", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
@@ -2561,6 +2868,7 @@ func GetppDataProcedure(collName string, ueId string) (*map[string]interface{},
 	ppData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if ppData != nil {
@@ -2582,14 +2890,20 @@ func HandleQuerySessionManagementData(request *httpwrapper.Request) *httpwrapper
 	return httpwrapper.NewResponse(http.StatusOK, nil, map[string]interface{}{})
 }
 
-func HandleQueryProvisionedData(request *httpwrapper.Request) *httpwrapper.Response {
+func HandleQueryProvisionedData(ctx context.Context, request *httpwrapper.Request) *httpwrapper.Response {
 	logger.DataRepoLog.Infoln("handle QueryProvisionedData")
 
-	var provisionedDataSets models.ProvisionedDataSets
+	var provisionedDataSets ProvisionedDataSets
 	ueId := request.Params["ueId"]
 	servingPlmnId := request.Params["servingPlmnId"]
+	opts, err := parseQueryOptions(request.Query)
+	if err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("get", "provisioned-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 
-	response, problemDetails := QueryProvisionedDataProcedure(ueId, servingPlmnId, provisionedDataSets)
+	response, problemDetails := QueryProvisionedDataProcedure(ctx, ueId, servingPlmnId, provisionedDataSets, opts)
 
 	if response != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "provisioned-data", "SUCCESS")
@@ -2604,112 +2918,60 @@ func HandleQueryProvisionedData(request *httpwrapper.Request) *httpwrapper.Respo
 	return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 }
 
-func QueryProvisionedDataProcedure(ueId string, servingPlmnId string,
-	provisionedDataSets models.ProvisionedDataSets,
-) (*models.ProvisionedDataSets, *models.ProblemDetails) {
-	{
-		collName := "subscriptionData.provisionedData.amData"
-		filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-		accessAndMobilitySubscriptionData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOne != nil {
-			logger.DataRepoLog.Warnln(errGetOne)
-		}
-		if accessAndMobilitySubscriptionData != nil {
-			var tmp models.AccessAndMobilitySubscriptionData
-			err := mapstructure.Decode(accessAndMobilitySubscriptionData, &tmp)
-			if err != nil {
-				panic(err)
-			}
-			provisionedDataSets.AmData = &tmp
-		}
-	}
+func QueryProvisionedDataProcedure(ctx context.Context, ueId string, servingPlmnId string,
+	provisionedDataSets ProvisionedDataSets, opts *queryOptions,
+) (*ProvisionedDataSets, *models.ProblemDetails) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	{
-		collName := "subscriptionData.provisionedData.smfSelectionSubscriptionData"
-		filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-		smfSelectionSubscriptionData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOne != nil {
-			logger.DataRepoLog.Warnln(errGetOne)
+	filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var dbErr, decodeErr error
+	for _, dataSet := range provisionedDataSetDecoders {
+		if !opts.wants(dataSet.Name) {
+			continue
 		}
-		if smfSelectionSubscriptionData != nil {
-			var tmp models.SmfSelectionSubscriptionData
-			err := mapstructure.Decode(smfSelectionSubscriptionData, &tmp)
-			if err != nil {
-				panic(err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			collName := provisionedDataSetCollections[dataSet.Name]
+			docs, errGet := opts.findDocuments(ctx, CommonDBClient, collName, filter, !dataSet.Many)
+			if errGet != nil {
+				logger.DataRepoLog.Warnln(errGet)
+				mu.Lock()
+				defer mu.Unlock()
+				if dbErr == nil && decodeErr == nil {
+					dbErr = errGet
+					cancel()
+				}
+				return
 			}
-			provisionedDataSets.SmfSelData = &tmp
-		}
-	}
-
-	{
-		collName := "subscriptionData.provisionedData.smsData"
-		filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-		smsSubscriptionData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOne != nil {
-			logger.DataRepoLog.Warnln(errGetOne)
-		}
-		if smsSubscriptionData != nil {
-			var tmp models.SmsSubscriptionData
-			err := mapstructure.Decode(smsSubscriptionData, &tmp)
-			if err != nil {
-				panic(err)
+			if len(docs) == 0 {
+				return
 			}
-			provisionedDataSets.SmsSubsData = &tmp
-		}
-	}
-
-	{
-		collName := "subscriptionData.provisionedData.smData"
-		filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-		sessionManagementSubscriptionDatas, errGetMany := CommonDBClient.RestfulAPIGetMany(collName, filter)
-		if errGetMany != nil {
-			logger.DataRepoLog.Warnln(errGetMany)
-		}
-		if sessionManagementSubscriptionDatas != nil {
-			var tmp []models.SessionManagementSubscriptionData
-			err := mapstructure.Decode(sessionManagementSubscriptionDatas, &tmp)
-			if err != nil {
-				panic(err)
+			for _, doc := range docs {
+				opts.stripUnsupported(collName, doc)
 			}
-			provisionedDataSets.SmData = tmp
-		}
-	}
-
-	{
-		collName := "subscriptionData.provisionedData.traceData"
-		filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-		traceData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOne != nil {
-			logger.DataRepoLog.Warnln(errGetOne)
-		}
-		if traceData != nil {
-			var tmp models.TraceData
-			err := mapstructure.Decode(traceData, &tmp)
-			if err != nil {
-				panic(err)
+			mu.Lock()
+			defer mu.Unlock()
+			if err := decodeProvisionedDataSet(dataSet, docs, &provisionedDataSets); err != nil && dbErr == nil &&
+				decodeErr == nil {
+				decodeErr = err
+				cancel()
 			}
-			provisionedDataSets.TraceData = &tmp
-		}
+		}()
 	}
+	wg.Wait()
 
-	{
-		collName := "subscriptionData.provisionedData.smsMngData"
-		filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-		smsManagementSubscriptionData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOne != nil {
-			logger.DataRepoLog.Warnln(errGetOne)
-		}
-		if smsManagementSubscriptionData != nil {
-			var tmp models.SmsManagementSubscriptionData
-			err := mapstructure.Decode(smsManagementSubscriptionData, &tmp)
-			if err != nil {
-				panic(err)
-			}
-			provisionedDataSets.SmsMngData = &tmp
-		}
+	if dbErr != nil {
+		return nil, dbProblemDetails(dbErr)
 	}
-
-	if !reflect.DeepEqual(provisionedDataSets, models.ProvisionedDataSets{}) {
+	if decodeErr != nil {
+		logger.DataRepoLog.Errorf("provisioned data of %s: %v", ueId, decodeErr)
+		return nil, util.ProblemDetailsSystemFailure(decodeErr.Error())
+	}
+	if !reflect.DeepEqual(provisionedDataSets, ProvisionedDataSets{}) {
 		return &provisionedDataSets, nil
 	} else {
 		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
@@ -2723,48 +2985,33 @@ func HandleModifyPpData(request *httpwrapper.Request) *httpwrapper.Response {
 	patchItem := request.Body.([]models.PatchItem)
 	ueId := request.Params["ueId"]
 
-	problemDetails := ModifyPpDataProcedure(collName, ueId, patchItem)
+	etag, problemDetails := ModifyPpDataProcedure(collName, ueId, patchItem, request.Header)
 	if problemDetails == nil {
 		stats.IncrementUdrSubscriptionDataStats("update", "pp-data", "SUCCESS")
-		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+		return httpwrapper.NewResponse(http.StatusNoContent, etagHeader(etag), map[string]interface{}{})
 	} else {
 		stats.IncrementUdrSubscriptionDataStats("update", "pp-data", "FAILURE")
 		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
 	}
 }
 
-func ModifyPpDataProcedure(collName string, ueId string, patchItem []models.PatchItem) *models.ProblemDetails {
+func ModifyPpDataProcedure(collName string, ueId string, patchItem []models.PatchItem,
+	header http.Header,
+) (string, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId}
-
-	origValue, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-	if errGetOne != nil {
-		logger.DataRepoLog.Warnln(errGetOne)
-	}
-
-	patchJSON, err := json.Marshal(patchItem)
-	if err != nil {
-		logger.DataRepoLog.Errorln(err)
-	}
-
-	failure := CommonDBClient.RestfulAPIJSONPatch(collName, filter, patchJSON)
-
-	if failure == nil {
-		newValue, errGetOneNew := CommonDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOneNew != nil {
-			logger.DataRepoLog.Warnln(errGetOneNew)
-		}
-		PreHandleOnDataChangeNotify(ueId, CurrentResourceUri, patchItem, origValue, newValue)
-		return nil
-	} else {
-		return util.ProblemDetailsModifyNotAllowed("")
+	origValue, newValue, etag, problemDetails := patchVersionedDocument(CommonDBClient, collName, filter, patchItem, header)
+	if problemDetails != nil {
+		return "", problemDetails
 	}
+	PreHandleOnDataChangeNotify(ueId, subscriptionDataResourceUri(collName, ueId, ""), patchItem, origValue, newValue)
+	return etag, nil
 }
 
 func HandleGetIdentityData(request *httpwrapper.Request) *httpwrapper.Response {
 	logger.DataRepoLog.Infoln("handle GetIdentityData")
 
 	ueId := request.Params["ueId"]
-	collName := "subscriptionData.identityData"
+	collName := SUBSCDATA_IDENTITYDATA
 
 	response, problemDetails := GetIdentityDataProcedure(collName, ueId)
 
@@ -2787,6 +3034,7 @@ func GetIdentityDataProcedure(collName string, ueId string) (*map[string]interfa
 	identityData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if identityData != nil {
@@ -2823,6 +3071,7 @@ func GetOdbDataProcedure(collName string, ueId string) (*map[string]interface{},
 	operatorDeterminedBarringData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if operatorDeterminedBarringData != nil {
@@ -2835,16 +3084,14 @@ func GetOdbDataProcedure(collName string, ueId string) (*map[string]interface{},
 func HandleGetSharedData(request *httpwrapper.Request) *httpwrapper.Response {
 	logger.DataRepoLog.Infoln("handle GetSharedData")
 
-	var sharedDataIds []string
-	if len(request.Query["shared-data-ids"]) != 0 {
-		sharedDataIds = request.Query["shared-data-ids"]
-		if strings.Contains(sharedDataIds[0], ",") {
-			sharedDataIds = strings.Split(sharedDataIds[0], ",")
-		}
+	sharedDataIds := splitQueryValues(request.Query["shared-data-ids"])
+	if len(sharedDataIds) == 0 {
+		pd := util.ProblemDetailsMalformedReqSyntax("shared-data-ids is missing")
+		stats.IncrementUdrSubscriptionDataStats("get", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
-	collName := "subscriptionData.sharedData"
 
-	response, problemDetails := GetSharedDataProcedure(collName, sharedDataIds)
+	response, problemDetails := GetSharedDataProcedure(SUBSCDATA_SHAREDDATA, sharedDataIds)
 
 	if response != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "shared-data", "SUCCESS")
@@ -2859,18 +3106,28 @@ func HandleGetSharedData(request *httpwrapper.Request) *httpwrapper.Response {
 	return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 }
 
+// GetSharedDataProcedure fetches the shared data of sharedDataIds in one
+// query, in the order of sharedDataIds.
 func GetSharedDataProcedure(collName string, sharedDataIds []string) (*[]map[string]interface{},
 	*models.ProblemDetails,
 ) {
+	filter := bson.M{"sharedDataId": bson.M{"$in": sharedDataIds}}
+	docs, err := CommonDBClient.RestfulAPIGetMany(collName, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		return nil, dbProblemDetails(err)
+	}
+	bySharedDataId := make(map[string]map[string]interface{}, len(docs))
+	for _, doc := range docs {
+		if sharedDataId, ok := doc["sharedDataId"].(string); ok {
+			bySharedDataId[sharedDataId] = withoutInternalFields(doc)
+		}
+	}
 	var sharedDataArray []map[string]interface{}
 	for _, sharedDataId := range sharedDataIds {
-		filter := bson.M{"sharedDataId": sharedDataId}
-		sharedData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
-		if errGetOne != nil {
-			logger.DataRepoLog.Warnln(errGetOne)
-		}
-		if sharedData != nil {
+		if sharedData, ok := bySharedDataId[sharedDataId]; ok {
 			sharedDataArray = append(sharedDataArray, sharedData)
+			delete(bySharedDataId, sharedDataId)
 		}
 	}
 
@@ -3049,14 +3306,25 @@ func HandleQuerySmData(request *httpwrapper.Request) *httpwrapper.Response {
 	}
 
 	dnn := request.Query.Get("dnn")
-	response := QuerySmDataProcedure(collName, ueId, servingPlmnId, singleNssai, dnn)
+	opts, err := parseQueryOptions(request.Query)
+	if err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("get", "sm-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	response, err := QuerySmDataProcedure(collName, ueId, servingPlmnId, singleNssai, dnn, opts)
+	if err != nil {
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("get", "sm-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 	stats.IncrementUdrSubscriptionDataStats("get", "sm-data", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusOK, nil, response)
 }
 
 func QuerySmDataProcedure(collName string, ueId string, servingPlmnId string,
-	singleNssai models.Snssai, dnn string,
-) *[]map[string]interface{} {
+	singleNssai models.Snssai, dnn string, opts *queryOptions,
+) (*[]map[string]interface{}, error) {
 	filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
 
 	if !reflect.DeepEqual(singleNssai, models.Snssai{}) {
@@ -3072,12 +3340,16 @@ func QuerySmDataProcedure(collName string, ueId string, servingPlmnId string,
 		filter["dnnConfigurations."+dnn] = bson.M{"$exists": true}
 	}
 
-	sessionManagementSubscriptionDatas, errGetMany := CommonDBClient.RestfulAPIGetMany(collName, filter)
+	sessionManagementSubscriptionDatas, errGetMany := opts.findDocuments(context.TODO(), CommonDBClient, collName, filter, false)
 	if errGetMany != nil {
 		logger.DataRepoLog.Warnln(errGetMany)
+		return nil, errGetMany
+	}
+	for _, sessionManagementSubscriptionData := range sessionManagementSubscriptionDatas {
+		opts.stripUnsupported(collName, sessionManagementSubscriptionData)
 	}
 
-	return &sessionManagementSubscriptionDatas
+	return &sessionManagementSubscriptionDatas, nil
 }
 
 func HandleCreateSmfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Response {
@@ -3086,12 +3358,20 @@ func HandleCreateSmfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Re
 	SmfRegistration := request.Body.(models.SmfRegistration)
 	collName := SUBSCDATA_CTXDATA_SMF_REGISTRATION
 	ueId := request.Params["ueId"]
-	pduSessionId, err := strconv.ParseInt(request.Params["pduSessionId"], 10, 64)
+	pduSessionId, err := strconv.ParseInt(request.Params["pduSessionId"], 10, 32)
 	if err != nil {
 		logger.DataRepoLog.Warnln(err)
+		pd := util.ProblemDetailsMalformedReqSyntax("pduSessionId must be an integer")
+		stats.IncrementUdrSubscriptionDataStats("create", "smf-registrations", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
 	}
 
-	response, status := CreateSmfContextNon3gppProcedure(SmfRegistration, collName, ueId, pduSessionId)
+	response, status, err := CreateSmfContextNon3gppProcedure(SmfRegistration, collName, ueId, pduSessionId)
+	if err != nil {
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("create", "smf-registrations", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 
 	switch status {
 	case http.StatusCreated:
@@ -3109,21 +3389,24 @@ func HandleCreateSmfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Re
 
 func CreateSmfContextNon3gppProcedure(SmfRegistration models.SmfRegistration,
 	collName string, ueId string, pduSessionIdInt int64,
-) (bson.M, int) {
+) (bson.M, int, error) {
 	putData := util.ToBsonM(SmfRegistration)
 	putData["ueId"] = ueId
 	putData["pduSessionId"] = int32(pduSessionIdInt)
+	putData[smfRegistrationRefreshField] = time.Now().UTC()
 
 	filter := bson.M{"ueId": ueId, "pduSessionId": pduSessionIdInt}
 	isExisted, errPutOne := CommonDBClient.RestfulAPIPutOne(collName, filter, putData)
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
+		return nil, 0, errPutOne
 	}
+	withoutRefreshTime(putData)
 
 	if !isExisted {
-		return putData, http.StatusCreated
+		return putData, http.StatusCreated, nil
 	} else {
-		return putData, http.StatusOK
+		return putData, http.StatusOK, nil
 	}
 }
 
@@ -3134,22 +3417,28 @@ func HandleDeleteSmfContext(request *httpwrapper.Request) *httpwrapper.Response
 	ueId := request.Params["ueId"]
 	pduSessionId := request.Params["pduSessionId"]
 
-	DeleteSmfContextProcedure(collName, ueId, pduSessionId)
+	if problemDetails := DeleteSmfContextProcedure(collName, ueId, pduSessionId); problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("delete", "smf-registrations", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
 	stats.IncrementUdrSubscriptionDataStats("delete", "smf-registrations", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
 
-func DeleteSmfContextProcedure(collName string, ueId string, pduSessionId string) {
+func DeleteSmfContextProcedure(collName string, ueId string, pduSessionId string) *models.ProblemDetails {
 	pduSessionIdInt, err := strconv.ParseInt(pduSessionId, 10, 32)
 	if err != nil {
 		logger.DataRepoLog.Error(err)
+		return util.ProblemDetailsMalformedReqSyntax("pduSessionId must be an integer")
 	}
 	filter := bson.M{"ueId": ueId, "pduSessionId": pduSessionIdInt}
 
 	errDelOne := CommonDBClient.RestfulAPIDeleteOne(collName, filter)
 	if errDelOne != nil {
 		logger.DataRepoLog.Warnln(errDelOne)
+		return dbProblemDetails(errDelOne)
 	}
+	return nil
 }
 
 func HandleQuerySmfRegistration(request *httpwrapper.Request) *httpwrapper.Response {
@@ -3179,6 +3468,7 @@ func QuerySmfRegistrationProcedure(collName string, ueId string,
 	pduSessionIdInt, err := strconv.ParseInt(pduSessionId, 10, 32)
 	if err != nil {
 		logger.DataRepoLog.Error(err)
+		return nil, util.ProblemDetailsMalformedReqSyntax("pduSessionId must be an integer")
 	}
 
 	filter := bson.M{"ueId": ueId, "pduSessionId": pduSessionIdInt}
@@ -3186,9 +3476,11 @@ func QuerySmfRegistrationProcedure(collName string, ueId string,
 	smfRegistration, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if smfRegistration != nil {
+		withoutRefreshTime(smfRegistration)
 		return &smfRegistration, nil
 	} else {
 		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
@@ -3200,7 +3492,12 @@ func HandleQuerySmfRegList(request *httpwrapper.Request) *httpwrapper.Response {
 
 	collName := SUBSCDATA_CTXDATA_SMF_REGISTRATION
 	ueId := request.Params["ueId"]
-	response := QuerySmfRegListProcedure(collName, ueId)
+	response, err := QuerySmfRegListProcedure(collName, ueId)
+	if err != nil {
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("get", "smf-registrations", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 
 	stats.IncrementUdrSubscriptionDataStats("get", "smf-registrations", "SUCCESS")
 	if response == nil {
@@ -3210,18 +3507,22 @@ func HandleQuerySmfRegList(request *httpwrapper.Request) *httpwrapper.Response {
 	}
 }
 
-func QuerySmfRegListProcedure(collName string, ueId string) *[]map[string]interface{} {
+func QuerySmfRegListProcedure(collName string, ueId string) (*[]map[string]interface{}, error) {
 	filter := bson.M{"ueId": ueId}
 	smfRegList, errGetMany := CommonDBClient.RestfulAPIGetMany(collName, filter)
 	if errGetMany != nil {
 		logger.DataRepoLog.Warnln(errGetMany)
+		return nil, errGetMany
 	}
 
 	if smfRegList != nil {
-		return &smfRegList
+		for _, smfRegistration := range smfRegList {
+			withoutRefreshTime(smfRegistration)
+		}
+		return &smfRegList, nil
 	} else {
 		// Return empty array instead
-		return nil
+		return nil, nil
 	}
 }
 
@@ -3231,7 +3532,13 @@ func HandleQuerySmfSelectData(request *httpwrapper.Request) *httpwrapper.Respons
 	collName := "subscriptionData.provisionedData.smfSelectionSubscriptionData"
 	ueId := request.Params["ueId"]
 	servingPlmnId := request.Params["servingPlmnId"]
-	response, problemDetails := QuerySmfSelectDataProcedure(collName, ueId, servingPlmnId)
+	opts, err := parseQueryOptions(request.Query)
+	if err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("get", "provisioned-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	response, problemDetails := QuerySmfSelectDataProcedure(collName, ueId, servingPlmnId, opts)
 
 	if problemDetails == nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "provisioned-data", "SUCCESS")
@@ -3243,15 +3550,18 @@ func HandleQuerySmfSelectData(request *httpwrapper.Request) *httpwrapper.Respons
 }
 
 func QuerySmfSelectDataProcedure(collName string, ueId string,
-	servingPlmnId string,
+	servingPlmnId string, opts *queryOptions,
 ) (*map[string]interface{}, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-	smfSelectionSubscriptionData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
+	docs, errGetOne := opts.findDocuments(context.TODO(), CommonDBClient, collName, filter, true)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
-	if smfSelectionSubscriptionData != nil {
+	if len(docs) > 0 {
+		smfSelectionSubscriptionData := docs[0]
+		opts.stripUnsupported(collName, smfSelectionSubscriptionData)
 		return &smfSelectionSubscriptionData, nil
 	} else {
 		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
@@ -3265,12 +3575,16 @@ func HandleCreateSmsfContext3gpp(request *httpwrapper.Request) *httpwrapper.Resp
 	collName := SUBSCDATA_CTXDATA_SMSF_3GPPACCESS
 	ueId := request.Params["ueId"]
 
-	CreateSmsfContext3gppProcedure(collName, ueId, SmsfRegistration)
+	if err := CreateSmsfContext3gppProcedure(collName, ueId, SmsfRegistration); err != nil {
+		stats.IncrementUdrSubscriptionDataStats("create", "smsf-3gpp-access", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 	stats.IncrementUdrSubscriptionDataStats("create", "smsf-3gpp-access", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
 
-func CreateSmsfContext3gppProcedure(collName string, ueId string, SmsfRegistration models.SmsfRegistration) {
+func CreateSmsfContext3gppProcedure(collName string, ueId string, SmsfRegistration models.SmsfRegistration) error {
 	putData := util.ToBsonM(SmsfRegistration)
 	putData["ueId"] = ueId
 	filter := bson.M{"ueId": ueId}
@@ -3279,6 +3593,7 @@ func CreateSmsfContext3gppProcedure(collName string, ueId string, SmsfRegistrati
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
 	}
+	return errPutOne
 }
 
 func HandleDeleteSmsfContext3gpp(request *httpwrapper.Request) *httpwrapper.Response {
@@ -3287,17 +3602,22 @@ func HandleDeleteSmsfContext3gpp(request *httpwrapper.Request) *httpwrapper.Resp
 	collName := SUBSCDATA_CTXDATA_SMSF_3GPPACCESS
 	ueId := request.Params["ueId"]
 
-	DeleteSmsfContext3gppProcedure(collName, ueId)
+	if err := DeleteSmsfContext3gppProcedure(collName, ueId); err != nil {
+		stats.IncrementUdrSubscriptionDataStats("delete", "smsf-3gpp-access", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 	stats.IncrementUdrSubscriptionDataStats("delete", "smsf-3gpp-access", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
 
-func DeleteSmsfContext3gppProcedure(collName string, ueId string) {
+func DeleteSmsfContext3gppProcedure(collName string, ueId string) error {
 	filter := bson.M{"ueId": ueId}
 	errDelOne := CommonDBClient.RestfulAPIDeleteOne(collName, filter)
 	if errDelOne != nil {
 		logger.DataRepoLog.Warnln(errDelOne)
 	}
+	return errDelOne
 }
 
 func HandleQuerySmsfContext3gpp(request *httpwrapper.Request) *httpwrapper.Response {
@@ -3326,6 +3646,7 @@ func QuerySmsfContext3gppProcedure(collName string, ueId string) (*map[string]in
 	smsfRegistration, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if smsfRegistration != nil {
@@ -3342,12 +3663,16 @@ func HandleCreateSmsfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.R
 	collName := SUBSCDATA_CTXDATA_SMSF_NON3GPPACCESS
 	ueId := request.Params["ueId"]
 
-	CreateSmsfContextNon3gppProcedure(SmsfRegistration, collName, ueId)
+	if err := CreateSmsfContextNon3gppProcedure(SmsfRegistration, collName, ueId); err != nil {
+		stats.IncrementUdrSubscriptionDataStats("create", "smsf-non-3gpp-access", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 	stats.IncrementUdrSubscriptionDataStats("create", "smsf-non-3gpp-access", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
 
-func CreateSmsfContextNon3gppProcedure(SmsfRegistration models.SmsfRegistration, collName string, ueId string) {
+func CreateSmsfContextNon3gppProcedure(SmsfRegistration models.SmsfRegistration, collName string, ueId string) error {
 	putData := util.ToBsonM(SmsfRegistration)
 	putData["ueId"] = ueId
 	filter := bson.M{"ueId": ueId}
@@ -3356,6 +3681,7 @@ func CreateSmsfContextNon3gppProcedure(SmsfRegistration models.SmsfRegistration,
 	if errPutOne != nil {
 		logger.DataRepoLog.Warnln(errPutOne)
 	}
+	return errPutOne
 }
 
 func HandleDeleteSmsfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Response {
@@ -3364,17 +3690,22 @@ func HandleDeleteSmsfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.R
 	collName := SUBSCDATA_CTXDATA_SMSF_NON3GPPACCESS
 	ueId := request.Params["ueId"]
 
-	DeleteSmsfContextNon3gppProcedure(collName, ueId)
+	if err := DeleteSmsfContextNon3gppProcedure(collName, ueId); err != nil {
+		stats.IncrementUdrSubscriptionDataStats("delete", "smsf-non-3gpp-access", "FAILURE")
+		pd := dbProblemDetails(err)
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
 	stats.IncrementUdrSubscriptionDataStats("delete", "smsf-non-3gpp-access", "SUCCESS")
 	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
 }
 
-func DeleteSmsfContextNon3gppProcedure(collName string, ueId string) {
+func DeleteSmsfContextNon3gppProcedure(collName string, ueId string) error {
 	filter := bson.M{"ueId": ueId}
 	errDelOne := CommonDBClient.RestfulAPIDeleteOne(collName, filter)
 	if errDelOne != nil {
 		logger.DataRepoLog.Warnln(errDelOne)
 	}
+	return errDelOne
 }
 
 func HandleQuerySmsfContextNon3gpp(request *httpwrapper.Request) *httpwrapper.Response {
@@ -3403,6 +3734,7 @@ func QuerySmsfContextNon3gppProcedure(collName string, ueId string) (*map[string
 	smsfRegistration, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
 	if smsfRegistration != nil {
@@ -3418,7 +3750,13 @@ func HandleQuerySmsMngData(request *httpwrapper.Request) *httpwrapper.Response {
 	collName := "subscriptionData.provisionedData.smsMngData"
 	ueId := request.Params["ueId"]
 	servingPlmnId := request.Params["servingPlmnId"]
-	response, problemDetails := QuerySmsMngDataProcedure(collName, ueId, servingPlmnId)
+	opts, err := parseQueryOptions(request.Query)
+	if err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("get", "sms-mng-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	response, problemDetails := QuerySmsMngDataProcedure(collName, ueId, servingPlmnId, opts)
 
 	if response != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "sms-mng-data", "SUCCESS")
@@ -3434,15 +3772,18 @@ func HandleQuerySmsMngData(request *httpwrapper.Request) *httpwrapper.Response {
 }
 
 func QuerySmsMngDataProcedure(collName string, ueId string,
-	servingPlmnId string,
+	servingPlmnId string, opts *queryOptions,
 ) (*map[string]interface{}, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-	smsManagementSubscriptionData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
+	docs, errGetOne := opts.findDocuments(context.TODO(), CommonDBClient, collName, filter, true)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
-	if smsManagementSubscriptionData != nil {
+	if len(docs) > 0 {
+		smsManagementSubscriptionData := docs[0]
+		opts.stripUnsupported(collName, smsManagementSubscriptionData)
 		return &smsManagementSubscriptionData, nil
 	} else {
 		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
@@ -3456,7 +3797,13 @@ func HandleQuerySmsData(request *httpwrapper.Request) *httpwrapper.Response {
 	servingPlmnId := request.Params["servingPlmnId"]
 	collName := "subscriptionData.provisionedData.smsData"
 
-	response, problemDetails := QuerySmsDataProcedure(collName, ueId, servingPlmnId)
+	opts, err := parseQueryOptions(request.Query)
+	if err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("get", "sms-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	response, problemDetails := QuerySmsDataProcedure(collName, ueId, servingPlmnId, opts)
 
 	if response != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "sms-data", "SUCCESS")
@@ -3472,16 +3819,18 @@ func HandleQuerySmsData(request *httpwrapper.Request) *httpwrapper.Response {
 }
 
 func QuerySmsDataProcedure(collName string, ueId string,
-	servingPlmnId string,
+	servingPlmnId string, opts *queryOptions,
 ) (*map[string]interface{}, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-
-	smsSubscriptionData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
+	docs, errGetOne := opts.findDocuments(context.TODO(), CommonDBClient, collName, filter, true)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
-	if smsSubscriptionData != nil {
+	if len(docs) > 0 {
+		smsSubscriptionData := docs[0]
+		opts.stripUnsupported(collName, smsSubscriptionData)
 		return &smsSubscriptionData, nil
 	} else {
 		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
@@ -3493,7 +3842,11 @@ func HandlePostSubscriptionDataSubscriptions(request *httpwrapper.Request) *http
 
 	SubscriptionDataSubscriptions := request.Body.(models.SubscriptionDataSubscriptions)
 
-	locationHeader := PostSubscriptionDataSubscriptionsProcedure(SubscriptionDataSubscriptions)
+	locationHeader, problemDetails := PostSubscriptionDataSubscriptionsProcedure(SubscriptionDataSubscriptions)
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("create", "subs-to-notify", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
 
 	headers := http.Header{}
 	headers.Set("Location", locationHeader)
@@ -3503,19 +3856,33 @@ func HandlePostSubscriptionDataSubscriptions(request *httpwrapper.Request) *http
 
 func PostSubscriptionDataSubscriptionsProcedure(
 	SubscriptionDataSubscriptions models.SubscriptionDataSubscriptions,
-) string {
+) (string, *models.ProblemDetails) {
 	udrSelf := udr_context.UDR_Self()
 
-	newSubscriptionID := strconv.Itoa(udrSelf.SubscriptionDataSubscriptionIDGenerator)
-	udrSelf.SubscriptionDataSubscriptions[newSubscriptionID] = &SubscriptionDataSubscriptions
-	udrSelf.SubscriptionDataSubscriptionIDGenerator++
+	if expiry := SubscriptionDataSubscriptions.Expiry; expiry != nil && !time.Now().Before(*expiry) {
+		return "", util.ProblemDetailsInvalidParams("subscription already expired", []models.InvalidParam{
+			{Param: "/expiry", Reason: "expiry is not in the future"},
+		})
+	}
+
+	// the notifications are sent under the SUPI, a UE subscribed to by GPSI
+	// is stored under its SUPI too
+	if SubscriptionDataSubscriptions.UeId != "" {
+		supi, err := ResolveUeId(SubscriptionDataSubscriptions.UeId)
+		if err != nil {
+			logger.DataRepoLog.Errorln(err)
+			return "", dbProblemDetails(err)
+		}
+		SubscriptionDataSubscriptions.UeId = supi
+	}
+	newSubscriptionID := udrSelf.NewSubscriptionDataSubscription(&SubscriptionDataSubscriptions)
 
 	/* Contains the URI of the newly created resource, according
 	   to the structure: {apiRoot}/subscription-data/subs-to-notify/{subsId} */
 	locationHeader := fmt.Sprintf("%s/subscription-data/subs-to-notify/%s",
 		udrSelf.GetIPv4GroupUri(udr_context.NUDR_DR), newSubscriptionID)
 
-	return locationHeader
+	return locationHeader, nil
 }
 
 func HandleRemovesubscriptionDataSubscriptions(request *httpwrapper.Request) *httpwrapper.Response {
@@ -3536,14 +3903,29 @@ func HandleRemovesubscriptionDataSubscriptions(request *httpwrapper.Request) *ht
 
 func RemovesubscriptionDataSubscriptionsProcedure(subsId string) *models.ProblemDetails {
 	udrSelf := udr_context.UDR_Self()
-	_, ok := udrSelf.SubscriptionDataSubscriptions[subsId]
-	if !ok {
+	if !udrSelf.DeleteSubscriptionDataSubscription(subsId) {
 		return util.ProblemDetailsNotFound("SUBSCRIPTION_NOT_FOUND")
 	}
-	delete(udrSelf.SubscriptionDataSubscriptions, subsId)
 	return nil
 }
 
+// subscriptionReaperInterval is how often expired subscriptions are deleted.
+const subscriptionReaperInterval = time.Minute
+
+// StartSubscriptionReaper deletes the expired subscription data
+// subscriptions in the background.
+func StartSubscriptionReaper() {
+	go func() {
+		ticker := time.NewTicker(subscriptionReaperInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			for _, subsId := range udr_context.UDR_Self().DeleteExpiredSubscriptionDataSubscriptions(now) {
+				logger.DataRepoLog.Infof("subscription data subscription %s expired", subsId)
+			}
+		}
+	}()
+}
+
 func HandleQueryTraceData(request *httpwrapper.Request) *httpwrapper.Response {
 	logger.DataRepoLog.Infoln("handle QueryTraceData")
 
@@ -3551,7 +3933,13 @@ func HandleQueryTraceData(request *httpwrapper.Request) *httpwrapper.Response {
 	ueId := request.Params["ueId"]
 	servingPlmnId := request.Params["servingPlmnId"]
 
-	response, problemDetails := QueryTraceDataProcedure(collName, ueId, servingPlmnId)
+	opts, err := parseQueryOptions(request.Query)
+	if err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("get", "trace-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	response, problemDetails := QueryTraceDataProcedure(collName, ueId, servingPlmnId, opts)
 
 	if response != nil {
 		stats.IncrementUdrSubscriptionDataStats("get", "trace-data", "SUCCESS")
@@ -3567,16 +3955,18 @@ func HandleQueryTraceData(request *httpwrapper.Request) *httpwrapper.Response {
 }
 
 func QueryTraceDataProcedure(collName string, ueId string,
-	servingPlmnId string,
+	servingPlmnId string, opts *queryOptions,
 ) (*map[string]interface{}, *models.ProblemDetails) {
 	filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
-
-	traceData, errGetOne := CommonDBClient.RestfulAPIGetOne(collName, filter)
+	docs, errGetOne := opts.findDocuments(context.TODO(), CommonDBClient, collName, filter, true)
 	if errGetOne != nil {
 		logger.DataRepoLog.Warnln(errGetOne)
+		return nil, dbProblemDetails(errGetOne)
 	}
 
-	if traceData != nil {
+	if len(docs) > 0 {
+		traceData := docs[0]
+		opts.stripUnsupported(collName, traceData)
 		return &traceData, nil
 	} else {
 		return nil, util.ProblemDetailsNotFound("USER_NOT_FOUND")
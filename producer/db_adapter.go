@@ -34,6 +34,7 @@ var (
 	AuthDBClient   DBInterface
 )
 
+// MongoDBClient is the DBInterface of MongoDB, its errors are DBErrors.
 type MongoDBClient struct {
 	mongoapi.MongoClient
 }
@@ -41,9 +42,8 @@ type MongoDBClient struct {
 // Set CommonDBClient
 func setCommonDBClient(url string, dbname string) error {
 	mClient, errConnect := mongoapi.NewMongoClient(url, dbname)
-	if mClient.Client != nil {
-		CommonDBClient = mClient
-		CommonDBClient.(*mongoapi.MongoClient).Client.Database(dbname)
+	if mClient != nil && mClient.Client != nil {
+		CommonDBClient = &MongoDBClient{MongoClient: *mClient}
 	}
 	return errConnect
 }
@@ -51,9 +51,8 @@ func setCommonDBClient(url string, dbname string) error {
 // Set AuthDBClient
 func setAuthDBClient(authurl string, authkeysdbname string) error {
 	mClient, errConnect := mongoapi.NewMongoClient(authurl, authkeysdbname)
-	if mClient.Client != nil {
-		AuthDBClient = mClient
-		AuthDBClient.(*mongoapi.MongoClient).Client.Database(authkeysdbname)
+	if mClient != nil && mClient.Client != nil {
+		AuthDBClient = &MongoDBClient{MongoClient: *mClient}
 	}
 	return errConnect
 }
@@ -83,11 +82,13 @@ ConnectMongo:
 }
 
 func (db *MongoDBClient) RestfulAPIGetOne(collName string, filter bson.M) (map[string]interface{}, error) {
-	return db.MongoClient.RestfulAPIGetOne(collName, filter)
+	data, err := db.MongoClient.RestfulAPIGetOne(collName, filter)
+	return data, newDBError(err)
 }
 
 func (db *MongoDBClient) RestfulAPIGetMany(collName string, filter bson.M) ([]map[string]interface{}, error) {
-	return db.MongoClient.RestfulAPIGetMany(collName, filter)
+	data, err := db.MongoClient.RestfulAPIGetMany(collName, filter)
+	return data, newDBError(err)
 }
 
 func (db *MongoDBClient) RestfulAPIPutOneTimeout(collName string, filter bson.M, putData map[string]interface{}, timeout int32, timeField string) bool {
@@ -95,41 +96,44 @@ func (db *MongoDBClient) RestfulAPIPutOneTimeout(collName string, filter bson.M,
 }
 
 func (db *MongoDBClient) RestfulAPIPutOne(collName string, filter bson.M, putData map[string]interface{}) (bool, error) {
-	return db.MongoClient.RestfulAPIPutOne(collName, filter, putData)
+	found, err := db.MongoClient.RestfulAPIPutOne(collName, filter, putData)
+	return found, newDBError(err)
 }
 
 func (db *MongoDBClient) RestfulAPIPutOneNotUpdate(collName string, filter bson.M, putData map[string]interface{}) (bool, error) {
-	return db.MongoClient.RestfulAPIPutOneNotUpdate(collName, filter, putData)
+	found, err := db.MongoClient.RestfulAPIPutOneNotUpdate(collName, filter, putData)
+	return found, newDBError(err)
 }
 
 func (db *MongoDBClient) RestfulAPIPutMany(collName string, filterArray []primitive.M, putDataArray []map[string]interface{}) error {
-	return db.MongoClient.RestfulAPIPutMany(collName, filterArray, putDataArray)
+	return newDBError(db.MongoClient.RestfulAPIPutMany(collName, filterArray, putDataArray))
 }
 
 func (db *MongoDBClient) RestfulAPIDeleteOne(collName string, filter bson.M) error {
-	return db.MongoClient.RestfulAPIDeleteOne(collName, filter)
+	return newDBError(db.MongoClient.RestfulAPIDeleteOne(collName, filter))
 }
 
 func (db *MongoDBClient) RestfulAPIDeleteMany(collName string, filter bson.M) error {
-	return db.MongoClient.RestfulAPIDeleteMany(collName, filter)
+	return newDBError(db.MongoClient.RestfulAPIDeleteMany(collName, filter))
 }
 
 func (db *MongoDBClient) RestfulAPIMergePatch(collName string, filter bson.M, patchData map[string]interface{}) error {
-	return db.MongoClient.RestfulAPIMergePatch(collName, filter, patchData)
+	return newDBError(db.MongoClient.RestfulAPIMergePatch(collName, filter, patchData))
 }
 
 func (db *MongoDBClient) RestfulAPIJSONPatch(collName string, filter bson.M, patchJSON []byte) error {
-	return db.MongoClient.RestfulAPIJSONPatch(collName, filter, patchJSON)
+	return newDBError(db.MongoClient.RestfulAPIJSONPatch(collName, filter, patchJSON))
 }
 
 func (db *MongoDBClient) RestfulAPIJSONPatchExtend(collName string, filter bson.M, patchJSON []byte, dataName string) error {
-	return db.MongoClient.RestfulAPIJSONPatchExtend(collName, filter, patchJSON, dataName)
+	return newDBError(db.MongoClient.RestfulAPIJSONPatchExtend(collName, filter, patchJSON, dataName))
 }
 
 func (db *MongoDBClient) RestfulAPIPost(collName string, filter bson.M, postData map[string]interface{}) (bool, error) {
-	return db.MongoClient.RestfulAPIPost(collName, filter, postData)
+	found, err := db.MongoClient.RestfulAPIPost(collName, filter, postData)
+	return found, newDBError(err)
 }
 
 func (db *MongoDBClient) RestfulAPIPostMany(collName string, filter bson.M, postDataArray []interface{}) error {
-	return db.MongoClient.RestfulAPIPostMany(collName, filter, postDataArray)
+	return newDBError(db.MongoClient.RestfulAPIPostMany(collName, filter, postDataArray))
 }
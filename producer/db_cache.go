@@ -0,0 +1,392 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const cacheWatchRetryInterval = 5 * time.Second
+
+// cachedCollections are the hot data sets read on every UE registration, and
+// the identity data used to resolve the GPSIs of the requests.
+var cachedCollections = map[string]bool{
+	SUBSCDATA_IDENTITYDATA:                true,
+	SUBSCDATA_PROVISIONED_AMDATA:          true,
+	SUBSCDATA_PROVISIONED_SMFSELDATA:      true,
+	SUBSCDATA_PROVISIONED_SMDATA:          true,
+	SUBSCDATA_AUTHENTICATION_SUBSCRIPTION: true,
+}
+
+type cacheEntry struct {
+	key      string
+	collName string
+	ueId     string // ueId of the filter, empty if the filter has none
+	docs     []map[string]interface{}
+	expires  time.Time
+}
+
+// docCache is a bounded LRU cache of query results with a time to live.
+type docCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	lru        *list.List // front is most recently used
+	entries    map[string]*list.Element
+	// generation of each collection, bumped on invalidation so that a result
+	// read before a concurrent write is not stored after it
+	generation map[string]uint64
+}
+
+func newDocCache(maxEntries int, ttl time.Duration) *docCache {
+	return &docCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+		generation: make(map[string]uint64),
+	}
+}
+
+func (c *docCache) get(key string) ([]map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return copyDocuments(entry.docs), true
+}
+
+func (c *docCache) currentGeneration(collName string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation[collName]
+}
+
+// put stores docs unless collName was invalidated since generation.
+func (c *docCache) put(key, collName, ueId string, docs []map[string]interface{}, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation[collName] != generation {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{
+		key:      key,
+		collName: collName,
+		ueId:     ueId,
+		docs:     copyDocuments(docs),
+		expires:  time.Now().Add(c.ttl),
+	})
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *docCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+// invalidate drops the entries of collName affected by a write matching
+// filter: those of the same UE, or all of them if filter has no ueId.
+func (c *docCache) invalidate(collName string, filter bson.M) {
+	ueId, _ := filter["ueId"].(string)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation[collName]++
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		entry := elem.Value.(*cacheEntry)
+		if entry.collName == collName && (ueId == "" || entry.ueId == "" || entry.ueId == ueId) {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+func cacheKey(collName string, filter, projection bson.M, one bool) (string, error) {
+	// encoding/json sorts map keys, so equal filters give equal keys
+	key, err := json.Marshal([]interface{}{collName, filter, projection, one})
+	return string(key), err
+}
+
+func copyDocuments(docs []map[string]interface{}) []map[string]interface{} {
+	if docs == nil {
+		return nil
+	}
+	copied := make([]map[string]interface{}, len(docs))
+	for i, doc := range docs {
+		copied[i] = copyValue(doc).(map[string]interface{})
+	}
+	return copied
+}
+
+// copyValue deep copies the maps and slices of a decoded document, callers
+// are free to modify the documents they get.
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			copied[key] = copyValue(elem)
+		}
+		return copied
+	case primitive.M:
+		return primitive.M(copyValue(map[string]interface{}(v)).(map[string]interface{}))
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, elem := range v {
+			copied[i] = copyValue(elem)
+		}
+		return copied
+	case primitive.A:
+		return primitive.A(copyValue([]interface{}(v)).([]interface{}))
+	case primitive.D:
+		copied := make(primitive.D, len(v))
+		for i, elem := range v {
+			copied[i] = primitive.E{Key: elem.Key, Value: copyValue(elem.Value)}
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// cachedDBClient is a DBInterface serving reads of cachedCollections from a
+// docCache. Writes through it invalidate the affected entries.
+type cachedDBClient struct {
+	DBInterface
+	name  string
+	cache *docCache
+}
+
+// EnableDBCache puts a read-through cache of maxEntries query results per
+// database in front of CommonDBClient and AuthDBClient. With changeStreams
+// set, entries are also invalidated on writes made by other UDR replicas or
+// by tools writing to MongoDB directly.
+func EnableDBCache(maxEntries int, ttl time.Duration, changeStreams bool) {
+	ctx := context.Background()
+	CommonDBClient = newCachedDBClient(ctx, "common", CommonDBClient, maxEntries, ttl, changeStreams)
+	AuthDBClient = newCachedDBClient(ctx, "auth", AuthDBClient, maxEntries, ttl, changeStreams)
+}
+
+func newCachedDBClient(ctx context.Context, name string, db DBInterface, maxEntries int, ttl time.Duration,
+	changeStreams bool,
+) DBInterface {
+	if _, ok := db.(collectionDBInterface); !ok {
+		logger.DataRepoLog.Warnf("%s DB client does not support caching", name)
+		return db
+	}
+	cached := &cachedDBClient{DBInterface: db, name: name, cache: newDocCache(maxEntries, ttl)}
+	logger.DataRepoLog.Infof("cache enabled for %s DB: %d entries, ttl %v", name, maxEntries, ttl)
+	if changeStreams {
+		for collName := range cachedCollections {
+			go cached.watchInvalidations(ctx, collName)
+		}
+	}
+	return cached
+}
+
+func (c *cachedDBClient) GetCollection(collName string) *mongo.Collection {
+	return c.DBInterface.(collectionDBInterface).GetCollection(collName)
+}
+
+// findDocuments is the cached counterpart of findDocuments.
+func (c *cachedDBClient) findDocuments(ctx context.Context, collName string, filter bson.M, projection bson.M,
+	one bool,
+) ([]map[string]interface{}, error) {
+	return c.cachedRead(collName, filter, projection, one, func() ([]map[string]interface{}, error) {
+		return findDocuments(ctx, c.DBInterface, collName, filter, projection, one)
+	})
+}
+
+func (c *cachedDBClient) cachedRead(collName string, filter bson.M, projection bson.M, one bool,
+	read func() ([]map[string]interface{}, error),
+) ([]map[string]interface{}, error) {
+	if !cachedCollections[collName] {
+		return read()
+	}
+	key, err := cacheKey(collName, filter, projection, one)
+	if err != nil {
+		return read()
+	}
+	if docs, ok := c.cache.get(key); ok {
+		stats.IncrementUdrDbCacheStats(collName, "hit")
+		return docs, nil
+	}
+	stats.IncrementUdrDbCacheStats(collName, "miss")
+	generation := c.cache.currentGeneration(collName)
+	docs, err := read()
+	// empty results are not cached, a document may be inserted any time
+	if err == nil && len(docs) > 0 {
+		ueId, _ := filter["ueId"].(string)
+		c.cache.put(key, collName, ueId, docs, generation)
+	}
+	return docs, err
+}
+
+func (c *cachedDBClient) RestfulAPIGetOne(collName string, filter bson.M) (map[string]interface{}, error) {
+	docs, err := c.cachedRead(collName, filter, nil, true, func() ([]map[string]interface{}, error) {
+		doc, err := c.DBInterface.RestfulAPIGetOne(collName, filter)
+		if err != nil || doc == nil {
+			return nil, err
+		}
+		return []map[string]interface{}{doc}, nil
+	})
+	if len(docs) == 0 {
+		return nil, err
+	}
+	return docs[0], err
+}
+
+func (c *cachedDBClient) RestfulAPIGetMany(collName string, filter bson.M) ([]map[string]interface{}, error) {
+	return c.cachedRead(collName, filter, nil, false, func() ([]map[string]interface{}, error) {
+		return c.DBInterface.RestfulAPIGetMany(collName, filter)
+	})
+}
+
+func (c *cachedDBClient) invalidate(collName string, filter bson.M) {
+	if cachedCollections[collName] {
+		c.cache.invalidate(collName, filter)
+	}
+}
+
+// uncached returns the DB client behind the cache of db, for reads that must
+// see the latest version of a document.
+func uncached(db DBInterface) DBInterface {
+	if cached, ok := db.(*cachedDBClient); ok {
+		return cached.DBInterface
+	}
+	return db
+}
+
+// invalidateCachedDocuments invalidates the cache of db for a write made
+// without going through its DBInterface methods, e.g. on the collection.
+func invalidateCachedDocuments(db DBInterface, collName string, filter bson.M) {
+	if cached, ok := db.(*cachedDBClient); ok {
+		cached.invalidate(collName, filter)
+	}
+}
+
+// watchInvalidations invalidates the entries of collName changed by any
+// writer, until ctx is done. The whole collection is invalidated when the
+// change stream (re)starts since changes may have been missed meanwhile.
+func (c *cachedDBClient) watchInvalidations(ctx context.Context, collName string) {
+	streamOptions := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	for {
+		stream, err := c.GetCollection(collName).Watch(ctx, mongo.Pipeline{}, streamOptions)
+		if err != nil {
+			logger.DataRepoLog.Warnf("cache change stream of %s DB %s: %v", c.name, collName, err)
+		} else {
+			c.invalidate(collName, nil)
+			for stream.Next(ctx) {
+				var event struct {
+					FullDocument struct {
+						UeId string `bson:"ueId"`
+					} `bson:"fullDocument"`
+				}
+				if err := stream.Decode(&event); err != nil || event.FullDocument.UeId == "" {
+					c.invalidate(collName, nil)
+					continue
+				}
+				c.invalidate(collName, bson.M{"ueId": event.FullDocument.UeId})
+			}
+			if err := stream.Err(); err != nil && ctx.Err() == nil {
+				logger.DataRepoLog.Warnf("cache change stream of %s DB %s: %v", c.name, collName, err)
+			}
+			if err := stream.Close(context.Background()); err != nil {
+				logger.DataRepoLog.Debugln(err)
+			}
+			c.invalidate(collName, nil)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cacheWatchRetryInterval):
+		}
+	}
+}
+
+func (c *cachedDBClient) RestfulAPIPutOneTimeout(collName string, filter bson.M, putData map[string]interface{},
+	timeout int32, timeField string,
+) bool {
+	defer c.invalidate(collName, filter)
+	return c.DBInterface.RestfulAPIPutOneTimeout(collName, filter, putData, timeout, timeField)
+}
+
+func (c *cachedDBClient) RestfulAPIPutOne(collName string, filter bson.M, putData map[string]interface{}) (bool, error) {
+	defer c.invalidate(collName, filter)
+	return c.DBInterface.RestfulAPIPutOne(collName, filter, putData)
+}
+
+func (c *cachedDBClient) RestfulAPIPutOneNotUpdate(collName string, filter bson.M, putData map[string]interface{}) (
+	bool, error,
+) {
+	defer c.invalidate(collName, filter)
+	return c.DBInterface.RestfulAPIPutOneNotUpdate(collName, filter, putData)
+}
+
+func (c *cachedDBClient) RestfulAPIPutMany(collName string, filterArray []primitive.M,
+	putDataArray []map[string]interface{},
+) error {
+	defer c.invalidate(collName, nil)
+	return c.DBInterface.RestfulAPIPutMany(collName, filterArray, putDataArray)
+}
+
+func (c *cachedDBClient) RestfulAPIDeleteOne(collName string, filter bson.M) error {
+	defer c.invalidate(collName, filter)
+	return c.DBInterface.RestfulAPIDeleteOne(collName, filter)
+}
+
+func (c *cachedDBClient) RestfulAPIDeleteMany(collName string, filter bson.M) error {
+	defer c.invalidate(collName, filter)
+	return c.DBInterface.RestfulAPIDeleteMany(collName, filter)
+}
+
+func (c *cachedDBClient) RestfulAPIMergePatch(collName string, filter bson.M, patchData map[string]interface{}) error {
+	defer c.invalidate(collName, filter)
+	return c.DBInterface.RestfulAPIMergePatch(collName, filter, patchData)
+}
+
+func (c *cachedDBClient) RestfulAPIJSONPatch(collName string, filter bson.M, patchJSON []byte) error {
+	defer c.invalidate(collName, filter)
+	return c.DBInterface.RestfulAPIJSONPatch(collName, filter, patchJSON)
+}
+
+func (c *cachedDBClient) RestfulAPIJSONPatchExtend(collName string, filter bson.M, patchJSON []byte,
+	dataName string,
+) error {
+	defer c.invalidate(collName, filter)
+	return c.DBInterface.RestfulAPIJSONPatchExtend(collName, filter, patchJSON, dataName)
+}
+
+func (c *cachedDBClient) RestfulAPIPost(collName string, filter bson.M, postData map[string]interface{}) (bool, error) {
+	defer c.invalidate(collName, filter)
+	return c.DBInterface.RestfulAPIPost(collName, filter, postData)
+}
+
+func (c *cachedDBClient) RestfulAPIPostMany(collName string, filter bson.M, postDataArray []interface{}) error {
+	defer c.invalidate(collName, filter)
+	return c.DBInterface.RestfulAPIPostMany(collName, filter, postDataArray)
+}
@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for the UDR subscription data cache
+ */
+
+package producer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// countingDB counts the reads reaching the DB.
+type countingDB struct {
+	fakeDB
+	reads int
+}
+
+func (db *countingDB) RestfulAPIGetOne(collName string, filter bson.M) (map[string]interface{}, error) {
+	db.reads++
+	return db.fakeDB.RestfulAPIGetOne(collName, filter)
+}
+
+func (db *countingDB) RestfulAPIPutOne(collName string, filter bson.M, putData map[string]interface{}) (bool, error) {
+	db.docs[collName] = []map[string]interface{}{putData}
+	return true, nil
+}
+
+func TestCachedDBClient(t *testing.T) {
+	db := &countingDB{fakeDB: fakeDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_PROVISIONED_AMDATA: {{"ueId": "imsi-1", "gpsis": []interface{}{"msisdn-1"}}},
+	}}}
+	cached := &cachedDBClient{DBInterface: db, name: "common", cache: newDocCache(10, time.Minute)}
+	filter := bson.M{"ueId": "imsi-1", "servingPlmnId": "20893"}
+
+	doc, err := cached.RestfulAPIGetOne(SUBSCDATA_PROVISIONED_AMDATA, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, "imsi-1", doc["ueId"])
+	// callers may modify what they get without corrupting the cache
+	doc["gpsis"].([]interface{})[0] = "changed"
+	delete(doc, "ueId")
+
+	doc, _ = cached.RestfulAPIGetOne(SUBSCDATA_PROVISIONED_AMDATA, bson.M{"servingPlmnId": "20893", "ueId": "imsi-1"})
+	assert.Equal(t, 1, db.reads)
+	assert.Equal(t, "imsi-1", doc["ueId"])
+	assert.Equal(t, []interface{}{"msisdn-1"}, doc["gpsis"])
+
+	_, err = cached.RestfulAPIPutOne(SUBSCDATA_PROVISIONED_AMDATA, bson.M{"ueId": "imsi-1"},
+		map[string]interface{}{"ueId": "imsi-1", "gpsis": []interface{}{"msisdn-2"}})
+	assert.NoError(t, err)
+	doc, _ = cached.RestfulAPIGetOne(SUBSCDATA_PROVISIONED_AMDATA, filter)
+	assert.Equal(t, 2, db.reads)
+	assert.Equal(t, []interface{}{"msisdn-2"}, doc["gpsis"])
+
+	// collections outside of the hot data sets are never cached
+	_, _ = cached.RestfulAPIGetOne(SUBSCDATA_CTXDATA_AMF_3GPPACCESS, filter)
+	_, _ = cached.RestfulAPIGetOne(SUBSCDATA_CTXDATA_AMF_3GPPACCESS, filter)
+	assert.Equal(t, 4, db.reads)
+}
+
+func TestDocCacheEviction(t *testing.T) {
+	cache := newDocCache(2, time.Minute)
+	docs := []map[string]interface{}{{"ueId": "imsi-1"}}
+	cache.put("a", "coll", "imsi-1", docs, 0)
+	cache.put("b", "coll", "imsi-2", docs, 0)
+	_, _ = cache.get("a")
+	cache.put("c", "coll", "imsi-3", docs, 0)
+	_, ok := cache.get("b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+
+	cache.invalidate("coll", bson.M{"ueId": "imsi-1"})
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+
+	// a result read before an invalidation is not stored
+	cache.put("d", "coll", "imsi-4", docs, 0)
+	_, ok = cache.get("d")
+	assert.False(t, ok)
+
+	cache = newDocCache(2, time.Nanosecond)
+	cache.put("a", "coll", "imsi-1", docs, 0)
+	time.Sleep(time.Millisecond)
+	_, ok = cache.get("a")
+	assert.False(t, ok, "expired entry is not served")
+}
@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/util"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DBErrorKind classifies the failures of the database to answer them
+// consistently.
+type DBErrorKind int
+
+const (
+	DBErrorInternal DBErrorKind = iota
+	DBErrorNotFound
+	DBErrorConflict
+	DBErrorTimeout
+	DBErrorUnavailable
+	DBErrorInvalid
+)
+
+func (kind DBErrorKind) String() string {
+	switch kind {
+	case DBErrorNotFound:
+		return "not found"
+	case DBErrorConflict:
+		return "conflict"
+	case DBErrorTimeout:
+		return "timeout"
+	case DBErrorUnavailable:
+		return "unavailable"
+	case DBErrorInvalid:
+		return "invalid"
+	default:
+		return "internal"
+	}
+}
+
+// DBError is a failure of the database of a known kind.
+type DBError struct {
+	Kind DBErrorKind
+	Err  error
+}
+
+func (e *DBError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// MongoDB server error codes of the invalid requests.
+var invalidServerErrorCodes = []int{
+	2,   // BadValue
+	9,   // FailedToParse
+	52,  // DollarPrefixedFieldName
+	121, // DocumentValidationFailure
+}
+
+// The mongoapi client flattens the driver errors into their messages, these
+// fragments identify them.
+var (
+	unavailableMessages = []string{
+		"server selection error", "connection refused", "client is disconnected", "no reachable servers",
+		"connection(", "socket was unexpectedly closed", "i/o timeout",
+	}
+	timeoutMessages  = []string{"context deadline exceeded", "timed out", "operation exceeded time limit"}
+	conflictMessages = []string{"E11000", "duplicate key"}
+	notFoundMessages = []string{"no documents in result"}
+	invalidMessages  = []string{
+		"Document failed validation", "unknown operator", "DecodePatch err", "Apply err", "MergePatch err",
+		"Unmarshal err",
+	}
+)
+
+func containsAny(s string, fragments []string) bool {
+	for _, fragment := range fragments {
+		if strings.Contains(s, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyDBError returns the kind of a database failure.
+func classifyDBError(err error) DBErrorKind {
+	var dbError *DBError
+	if errors.As(err, &dbError) {
+		return dbError.Kind
+	}
+	var serverError mongo.ServerError
+	message := err.Error()
+	switch {
+	case mongo.IsDuplicateKeyError(err) || containsAny(message, conflictMessages):
+		return DBErrorConflict
+	case mongo.IsNetworkError(err) || errors.Is(err, mongo.ErrClientDisconnected) ||
+		containsAny(message, unavailableMessages):
+		// a server selection failing on its deadline is an unavailable
+		// database, not a slow one
+		return DBErrorUnavailable
+	case mongo.IsTimeout(err) || errors.Is(err, context.DeadlineExceeded) || containsAny(message, timeoutMessages):
+		return DBErrorTimeout
+	case errors.Is(err, mongo.ErrNoDocuments) || containsAny(message, notFoundMessages):
+		return DBErrorNotFound
+	case errors.As(err, &serverError) && hasAnyErrorCode(serverError, invalidServerErrorCodes):
+		return DBErrorInvalid
+	case containsAny(message, invalidMessages):
+		return DBErrorInvalid
+	}
+	return DBErrorInternal
+}
+
+func hasAnyErrorCode(serverError mongo.ServerError, codes []int) bool {
+	for _, code := range codes {
+		if serverError.HasErrorCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// newDBError returns err as a DBError, nil if err is nil.
+func newDBError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbError *DBError
+	if errors.As(err, &dbError) {
+		return err
+	}
+	return &DBError{Kind: classifyDBError(err), Err: err}
+}
+
+// DBProblemDetails answers a failure of the database met outside the
+// handlers of this package, like dbProblemDetails.
+func DBProblemDetails(err error) *models.ProblemDetails {
+	return dbProblemDetails(err)
+}
+
+// dbProblemDetails answers a failure of the database.
+func dbProblemDetails(err error) *models.ProblemDetails {
+	switch classifyDBError(err) {
+	case DBErrorNotFound:
+		return util.ProblemDetailsNotFound("DATA_NOT_FOUND")
+	case DBErrorConflict:
+		return util.ProblemDetailsConflict(err.Error())
+	case DBErrorTimeout:
+		return util.ProblemDetailsTimeout(err.Error())
+	case DBErrorUnavailable:
+		return util.ProblemDetailsUnavailable(err.Error())
+	case DBErrorInvalid:
+		return util.ProblemDetailsInvalidData(err.Error())
+	default:
+		return util.ProblemDetailsSystemFailure(err.Error())
+	}
+}
@@ -0,0 +1,486 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR database error classification and their HTTP answers
+ */
+
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/util/httpwrapper"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestClassifyDBError(t *testing.T) {
+	testCases := []struct {
+		err  error
+		kind DBErrorKind
+	}{
+		{mongo.ErrNoDocuments, DBErrorNotFound},
+		{errors.New("RestfulAPIGetOne err: mongo: no documents in result"), DBErrorNotFound},
+		{mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, DBErrorConflict},
+		{errors.New("RestfulAPIPutOne UpdateOne err: E11000 duplicate key error collection"), DBErrorConflict},
+		{context.DeadlineExceeded, DBErrorTimeout},
+		{errors.New("RestfulAPIGetMany err: context deadline exceeded"), DBErrorTimeout},
+		{mongo.ErrClientDisconnected, DBErrorUnavailable},
+		{
+			errors.New("RestfulAPIGetOne err: server selection error: context deadline exceeded"),
+			DBErrorUnavailable,
+		},
+		{mongo.CommandError{Code: 121, Message: "Document failed validation"}, DBErrorInvalid},
+		{errors.New("RestfulAPIJSONPatch DecodePatch err: unexpected end of JSON input"), DBErrorInvalid},
+		{errors.New("something else"), DBErrorInternal},
+		{fmt.Errorf("identities: %w", &DBError{Kind: DBErrorTimeout, Err: errors.New("slow")}), DBErrorTimeout},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.kind, classifyDBError(tc.err), tc.err.Error())
+		var dbError *DBError
+		if assert.ErrorAs(t, newDBError(tc.err), &dbError) {
+			assert.Equal(t, tc.kind, dbError.Kind)
+		}
+	}
+	assert.NoError(t, newDBError(nil))
+}
+
+func TestDBProblemDetails(t *testing.T) {
+	testCases := []struct {
+		kind   DBErrorKind
+		status int
+		cause  string
+	}{
+		{DBErrorNotFound, http.StatusNotFound, "DATA_NOT_FOUND"},
+		{DBErrorConflict, http.StatusConflict, ""},
+		{DBErrorTimeout, http.StatusGatewayTimeout, "TIMED_OUT_REQUEST"},
+		{DBErrorUnavailable, http.StatusServiceUnavailable, "NF_CONGESTION"},
+		{DBErrorInvalid, http.StatusBadRequest, "MANDATORY_IE_INCORRECT"},
+		{DBErrorInternal, http.StatusInternalServerError, "SYSTEM_FAILURE"},
+	}
+	for _, tc := range testCases {
+		pd := dbProblemDetails(&DBError{Kind: tc.kind, Err: errors.New(tc.kind.String())})
+		assert.Equal(t, int32(tc.status), pd.Status, tc.kind.String())
+		assert.Equal(t, tc.cause, pd.Cause, tc.kind.String())
+	}
+
+	// a patch that does not apply to the document is invalid like any other write
+	pd := dbProblemDetails(&DBError{Kind: DBErrorInvalid, Err: errors.New("Apply err")})
+	assert.Equal(t, int32(http.StatusBadRequest), pd.Status)
+}
+
+// failingDB fails every operation with err.
+type failingDB struct {
+	err error
+}
+
+func (db *failingDB) RestfulAPIGetOne(collName string, filter bson.M) (map[string]interface{}, error) {
+	return nil, db.err
+}
+
+func (db *failingDB) RestfulAPIGetMany(collName string, filter bson.M) ([]map[string]interface{}, error) {
+	return nil, db.err
+}
+
+func (db *failingDB) RestfulAPIPutOneTimeout(collName string, filter bson.M, putData map[string]interface{},
+	timeout int32, timeField string,
+) bool {
+	return false
+}
+
+func (db *failingDB) RestfulAPIPutOne(collName string, filter bson.M, putData map[string]interface{}) (bool, error) {
+	return false, db.err
+}
+
+func (db *failingDB) RestfulAPIPutOneNotUpdate(collName string, filter bson.M, putData map[string]interface{},
+) (bool, error) {
+	return false, db.err
+}
+
+func (db *failingDB) RestfulAPIPutMany(collName string, filterArray []primitive.M,
+	putDataArray []map[string]interface{},
+) error {
+	return db.err
+}
+
+func (db *failingDB) RestfulAPIDeleteOne(collName string, filter bson.M) error {
+	return db.err
+}
+
+func (db *failingDB) RestfulAPIDeleteMany(collName string, filter bson.M) error {
+	return db.err
+}
+
+func (db *failingDB) RestfulAPIMergePatch(collName string, filter bson.M, patchData map[string]interface{}) error {
+	return db.err
+}
+
+func (db *failingDB) RestfulAPIJSONPatch(collName string, filter bson.M, patchJSON []byte) error {
+	return db.err
+}
+
+func (db *failingDB) RestfulAPIJSONPatchExtend(collName string, filter bson.M, patchJSON []byte,
+	dataName string,
+) error {
+	return db.err
+}
+
+func (db *failingDB) RestfulAPIPost(collName string, filter bson.M, postData map[string]interface{}) (bool, error) {
+	return false, db.err
+}
+
+func (db *failingDB) RestfulAPIPostMany(collName string, filter bson.M, postDataArray []interface{}) error {
+	return db.err
+}
+
+// versionedFailingDB is a failingDB supporting versioned writes, which read
+// the document before using the collection.
+type versionedFailingDB struct {
+	failingDB
+}
+
+func (db *versionedFailingDB) GetCollection(collName string) *mongo.Collection {
+	return nil
+}
+
+func dbRequest(body interface{}, params map[string]string, query url.Values) *httpwrapper.Request {
+	request := httpwrapper.NewRequest(httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil), body)
+	for key, value := range params {
+		request.Params[key] = value
+	}
+	return request
+}
+
+func TestHandlersAnswerDBErrors(t *testing.T) {
+	defer func(common, auth DBInterface) { CommonDBClient, AuthDBClient = common, auth }(CommonDBClient, AuthDBClient)
+
+	ue := map[string]string{
+		"ueId": "imsi-1", "servingPlmnId": "20893", "pduSessionId": "1", "usageMonId": "um-1",
+		"bdtReferenceId": "bdt-1", "plmnId": "20893", "sponsorId": "sponsor-1",
+	}
+	patch := []models.PatchItem{{Op: models.PatchOperation_REPLACE, Path: "/pei", Value: "imeisv-1"}}
+	request := func(body interface{}) *httpwrapper.Request { return dbRequest(body, ue, nil) }
+	group := func(body interface{}) *httpwrapper.Request {
+		return dbRequest(body, map[string]string{"extGroupId": "extgroupid-1@example.com"}, nil)
+	}
+	handlers := []struct {
+		name      string
+		versioned bool
+		handle    func() *httpwrapper.Response
+	}{
+		// subscription data
+		{"QueryAmData", false, func() *httpwrapper.Response { return HandleQueryAmData(request(nil)) }},
+		{"AmfContext3gpp", true, func() *httpwrapper.Response { return HandleAmfContext3gpp(request(patch)) }},
+		{"CreateAmfContext3gpp", true, func() *httpwrapper.Response {
+			return HandleCreateAmfContext3gpp(request(models.Amf3GppAccessRegistration{AmfInstanceId: "amf-1"}))
+		}},
+		{"QueryAmfContext3gpp", false, func() *httpwrapper.Response { return HandleQueryAmfContext3gpp(request(nil)) }},
+		{"AmfContextNon3gpp", true, func() *httpwrapper.Response { return HandleAmfContextNon3gpp(request(patch)) }},
+		{"CreateAmfContextNon3gpp", true, func() *httpwrapper.Response {
+			return HandleCreateAmfContextNon3gpp(request(models.AmfNon3GppAccessRegistration{AmfInstanceId: "amf-1"}))
+		}},
+		{"QueryAmfContextNon3gpp", false, func() *httpwrapper.Response {
+			return HandleQueryAmfContextNon3gpp(request(nil))
+		}},
+		{"ModifyAuthentication", true, func() *httpwrapper.Response { return HandleModifyAuthentication(request(patch)) }},
+		{"QueryAuthSubsData", false, func() *httpwrapper.Response { return HandleQueryAuthSubsData(request(nil)) }},
+		{"CreateAuthenticationSoR", false, func() *httpwrapper.Response {
+			return HandleCreateAuthenticationSoR(request(models.SorData{}))
+		}},
+		{"QueryAuthSoR", false, func() *httpwrapper.Response { return HandleQueryAuthSoR(request(nil)) }},
+		{"CreateAuthenticationStatus", false, func() *httpwrapper.Response {
+			return HandleCreateAuthenticationStatus(request(models.AuthEvent{}))
+		}},
+		{"QueryAuthenticationStatus", false, func() *httpwrapper.Response {
+			return HandleQueryAuthenticationStatus(request(nil))
+		}},
+		{"QueryEEData", false, func() *httpwrapper.Response { return HandleQueryEEData(request(nil)) }},
+		{"PatchOperSpecData", true, func() *httpwrapper.Response { return HandlePatchOperSpecData(request(patch)) }},
+		{"QueryOperSpecData", false, func() *httpwrapper.Response { return HandleQueryOperSpecData(request(nil)) }},
+		{"GetppData", false, func() *httpwrapper.Response { return HandleGetppData(request(nil)) }},
+		{"QueryProvisionedData", false, func() *httpwrapper.Response {
+			return HandleQueryProvisionedData(context.Background(), request(nil))
+		}},
+		{"ModifyPpData", true, func() *httpwrapper.Response { return HandleModifyPpData(request(patch)) }},
+		{"GetIdentityData", false, func() *httpwrapper.Response { return HandleGetIdentityData(request(nil)) }},
+		{"GetOdbData", false, func() *httpwrapper.Response { return HandleGetOdbData(request(nil)) }},
+		{"GetSharedData", false, func() *httpwrapper.Response {
+			return HandleGetSharedData(dbRequest(nil, ue, url.Values{"shared-data-ids": {"shared-1"}}))
+		}},
+		{"QuerySmData", false, func() *httpwrapper.Response { return HandleQuerySmData(request(nil)) }},
+		{"CreateSmfContextNon3gpp", false, func() *httpwrapper.Response {
+			return HandleCreateSmfContextNon3gpp(request(models.SmfRegistration{SmfInstanceId: "smf-1"}))
+		}},
+		{"DeleteSmfContext", false, func() *httpwrapper.Response { return HandleDeleteSmfContext(request(nil)) }},
+		{"QuerySmfRegistration", false, func() *httpwrapper.Response {
+			return HandleQuerySmfRegistration(request(nil))
+		}},
+		{"QuerySmfRegList", false, func() *httpwrapper.Response { return HandleQuerySmfRegList(request(nil)) }},
+		{"QuerySmfSelectData", false, func() *httpwrapper.Response { return HandleQuerySmfSelectData(request(nil)) }},
+		{"CreateSmsfContext3gpp", false, func() *httpwrapper.Response {
+			return HandleCreateSmsfContext3gpp(request(models.SmsfRegistration{}))
+		}},
+		{"DeleteSmsfContext3gpp", false, func() *httpwrapper.Response {
+			return HandleDeleteSmsfContext3gpp(request(nil))
+		}},
+		{"QuerySmsfContext3gpp", false, func() *httpwrapper.Response { return HandleQuerySmsfContext3gpp(request(nil)) }},
+		{"CreateSmsfContextNon3gpp", false, func() *httpwrapper.Response {
+			return HandleCreateSmsfContextNon3gpp(request(models.SmsfRegistration{}))
+		}},
+		{"DeleteSmsfContextNon3gpp", false, func() *httpwrapper.Response {
+			return HandleDeleteSmsfContextNon3gpp(request(nil))
+		}},
+		{"QuerySmsfContextNon3gpp", false, func() *httpwrapper.Response {
+			return HandleQuerySmsfContextNon3gpp(request(nil))
+		}},
+		{"QuerySmsMngData", false, func() *httpwrapper.Response { return HandleQuerySmsMngData(request(nil)) }},
+		{"QuerySmsData", false, func() *httpwrapper.Response { return HandleQuerySmsData(request(nil)) }},
+		{"QueryTraceData", false, func() *httpwrapper.Response { return HandleQueryTraceData(request(nil)) }},
+		{"GetContextData", false, func() *httpwrapper.Response {
+			return HandleGetContextData(ContextDataMessageWaitingData, request(nil))
+		}},
+		{"CreateContextData", false, func() *httpwrapper.Response {
+			return HandleCreateContextData(ContextDataMessageWaitingData, request([]byte(`{"mwdList":[]}`)))
+		}},
+		{"ModifyContextData", false, func() *httpwrapper.Response {
+			return HandleModifyContextData(ContextDataMessageWaitingData, request([]byte(`{"mwdList":[]}`)))
+		}},
+		{"DeleteContextData", false, func() *httpwrapper.Response {
+			return HandleDeleteContextData(ContextDataMessageWaitingData, request(nil))
+		}},
+		{"QueryProvisionedDataResource", false, func() *httpwrapper.Response {
+			return HandleQueryProvisionedDataResource(context.Background(), ProvisionedDataProse, request(nil))
+		}},
+		{"QueryGroupIdentifiers", false, func() *httpwrapper.Response {
+			return HandleQueryGroupIdentifiers(dbRequest(nil, nil,
+				url.Values{"ue-id-ind": {"true"}, "ext-group-id": {"group-1"}}))
+		}},
+		{"QueryAmfRegistrationHistory", false, func() *httpwrapper.Response {
+			return HandleQueryAmfRegistrationHistory(request(nil))
+		}},
+		{"GetGroupData", false, func() *httpwrapper.Response { return HandleGetGroupData(group(nil)) }},
+		{"PutGroupData", false, func() *httpwrapper.Response {
+			return HandlePutGroupData(group(GroupIdentifiers{
+				ExtGroupId: "extgroupid-1@example.com", IntGroupId: "20893001-001-01-01",
+			}))
+		}},
+		{"DeleteGroupData", false, func() *httpwrapper.Response { return HandleDeleteGroupData(group(nil)) }},
+		{"PutIdentityData", false, func() *httpwrapper.Response {
+			return HandlePutIdentityData(dbRequest(IdentityData{GpsiList: []string{"msisdn-33612345678"}},
+				map[string]string{"ueId": "imsi-208930000000001"}, nil))
+		}},
+		{"DeleteIdentityData", false, func() *httpwrapper.Response {
+			return HandleDeleteIdentityData(dbRequest(nil, map[string]string{"ueId": "imsi-208930000000001"}, nil))
+		}},
+		{"GetIndividualSharedData", false, func() *httpwrapper.Response {
+			return HandleGetIndividualSharedData("shared-1")
+		}},
+		{"PutSharedData", false, func() *httpwrapper.Response {
+			return HandlePutSharedData("shared-1", []byte(`{"sharedDataId":"shared-1","sharedAmData":{}}`))
+		}},
+		{"ModifySharedData", false, func() *httpwrapper.Response {
+			return HandleModifySharedData("shared-1", []byte(`{}`))
+		}},
+		{"DeleteSharedData", false, func() *httpwrapper.Response { return HandleDeleteSharedData("shared-1") }},
+		{"Query5GVnGroups", false, func() *httpwrapper.Response {
+			return HandleQuery5GVnGroups(url.Values{"ue-ids": {"imsi-1"}})
+		}},
+		{"Get5GVnGroup", false, func() *httpwrapper.Response { return HandleGet5GVnGroup("extgroupid-vn@example.com") }},
+		{"Create5GVnGroup", false, func() *httpwrapper.Response {
+			return HandleCreate5GVnGroup("extgroupid-vn@example.com", &VnGroupConfiguration{
+				VnGroupData: &VnGroupData{Dnn: "internet", SNssai: &models.Snssai{Sst: 1}},
+			})
+		}},
+		{"Modify5GVnGroup", false, func() *httpwrapper.Response {
+			return HandleModify5GVnGroup("extgroupid-vn@example.com", []byte(`{}`))
+		}},
+		{"Delete5GVnGroup", false, func() *httpwrapper.Response {
+			return HandleDelete5GVnGroup("extgroupid-vn@example.com")
+		}},
+		// policy data
+		{"PolicyDataBdtDataBdtReferenceIdDelete", false, func() *httpwrapper.Response {
+			return HandlePolicyDataBdtDataBdtReferenceIdDelete(request(nil))
+		}},
+		{"PolicyDataBdtDataBdtReferenceIdGet", false, func() *httpwrapper.Response {
+			return HandlePolicyDataBdtDataBdtReferenceIdGet(request(nil))
+		}},
+		{"PolicyDataBdtDataBdtReferenceIdPut", false, func() *httpwrapper.Response {
+			return HandlePolicyDataBdtDataBdtReferenceIdPut(request(models.BdtData{}))
+		}},
+		{"PolicyDataBdtDataGet", false, func() *httpwrapper.Response { return HandlePolicyDataBdtDataGet(request(nil)) }},
+		{"PolicyDataPlmnsPlmnIdUePolicySetGet", false, func() *httpwrapper.Response {
+			return HandlePolicyDataPlmnsPlmnIdUePolicySetGet(request(nil))
+		}},
+		{"PolicyDataPlmnsPlmnIdUePolicySetPut", false, func() *httpwrapper.Response {
+			return HandlePolicyDataPlmnsPlmnIdUePolicySetPut(request([]byte(`{}`)))
+		}},
+		{"PolicyDataPlmnsPlmnIdUePolicySetPatch", false, func() *httpwrapper.Response {
+			return HandlePolicyDataPlmnsPlmnIdUePolicySetPatch(request([]byte(`{}`)))
+		}},
+		{"PolicyDataPlmnsPlmnIdUePolicySetDelete", false, func() *httpwrapper.Response {
+			return HandlePolicyDataPlmnsPlmnIdUePolicySetDelete(request(nil))
+		}},
+		{"PolicyDataSponsorConnectivityDataSponsorIdGet", false, func() *httpwrapper.Response {
+			return HandlePolicyDataSponsorConnectivityDataSponsorIdGet(request(nil))
+		}},
+		{"PolicyDataSponsorConnectivityDataSponsorIdPut", false, func() *httpwrapper.Response {
+			return HandlePolicyDataSponsorConnectivityDataSponsorIdPut(request([]byte(`{"aspIds":["asp-1"]}`)))
+		}},
+		{"PolicyDataSponsorConnectivityDataSponsorIdPatch", false, func() *httpwrapper.Response {
+			return HandlePolicyDataSponsorConnectivityDataSponsorIdPatch(request([]byte(`{}`)))
+		}},
+		{"PolicyDataSponsorConnectivityDataSponsorIdDelete", false, func() *httpwrapper.Response {
+			return HandlePolicyDataSponsorConnectivityDataSponsorIdDelete(request(nil))
+		}},
+		{"PolicyDataUesUeIdAmDataGet", false, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdAmDataGet(request(nil))
+		}},
+		{"PolicyDataUesUeIdOperatorSpecificDataGet", false, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdOperatorSpecificDataGet(request(nil))
+		}},
+		{"PolicyDataUesUeIdOperatorSpecificDataPatch", true, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdOperatorSpecificDataPatch(request(patch))
+		}},
+		{"PolicyDataUesUeIdOperatorSpecificDataPut", true, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdOperatorSpecificDataPut(
+				request(map[string]models.OperatorSpecificDataContainer{}))
+		}},
+		{"PolicyDataUesUeIdSmDataGet", false, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdSmDataGet(request(nil))
+		}},
+		{"PolicyDataUesUeIdSmDataPatch", false, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdSmDataPatch(request(map[string]models.UsageMonData{"um-1": {}}))
+		}},
+		{"PolicyDataUesUeIdSmDataUsageMonIdDelete", false, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdSmDataUsageMonIdDelete(request(nil))
+		}},
+		{"PolicyDataUesUeIdSmDataUsageMonIdGet", false, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdSmDataUsageMonIdGet(request(nil))
+		}},
+		{"PolicyDataUesUeIdSmDataUsageMonIdPut", false, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdSmDataUsageMonIdPut(request(models.UsageMonData{}))
+		}},
+		{"PolicyDataUesUeIdUePolicySetGet", false, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdUePolicySetGet(request(nil))
+		}},
+		{"PolicyDataUesUeIdUePolicySetPatch", false, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdUePolicySetPatch(request(models.UePolicySet{}))
+		}},
+		{"PolicyDataUesUeIdUePolicySetPut", false, func() *httpwrapper.Response {
+			return HandlePolicyDataUesUeIdUePolicySetPut(request(models.UePolicySet{}))
+		}},
+		// application data
+		{"ApplicationDataInfluenceDataGet", false, func() *httpwrapper.Response {
+			return HandleApplicationDataInfluenceDataGet(map[string][]string{"supis": {"imsi-1"}})
+		}},
+		{"ApplicationDataInfluenceDataInfluenceIdDelete", false, func() *httpwrapper.Response {
+			return HandleApplicationDataInfluenceDataInfluenceIdDelete("influ-1")
+		}},
+		{"ApplicationDataInfluenceDataInfluenceIdPatch", false, func() *httpwrapper.Response {
+			return HandleApplicationDataInfluenceDataInfluenceIdPatch("influ-1", &models.TrafficInfluDataPatch{})
+		}},
+		{"ApplicationDataInfluenceDataInfluenceIdPut", false, func() *httpwrapper.Response {
+			return HandleApplicationDataInfluenceDataInfluenceIdPut("influ-1", &models.TrafficInfluData{})
+		}},
+		{"ApplicationDataInfluenceDataSubsToNotifyGet", false, func() *httpwrapper.Response {
+			return HandleApplicationDataInfluenceDataSubsToNotifyGet(map[string][]string{"supi": {"imsi-1"}})
+		}},
+		{"ApplicationDataInfluenceDataSubsToNotifyPost", false, func() *httpwrapper.Response {
+			return HandleApplicationDataInfluenceDataSubsToNotifyPost(&models.TrafficInfluSub{})
+		}},
+		{"ApplicationDataInfluenceDataSubsToNotifySubscriptionIdDelete", false, func() *httpwrapper.Response {
+			return HandleApplicationDataInfluenceDataSubsToNotifySubscriptionIdDelete("subs-1")
+		}},
+		{"ApplicationDataInfluenceDataSubsToNotifySubscriptionIdGet", false, func() *httpwrapper.Response {
+			return HandleApplicationDataInfluenceDataSubsToNotifySubscriptionIdGet("subs-1")
+		}},
+		{"ApplicationDataInfluenceDataSubsToNotifySubscriptionIdPut", false, func() *httpwrapper.Response {
+			return HandleApplicationDataInfluenceDataSubsToNotifySubscriptionIdPut("subs-1", &models.TrafficInfluSub{})
+		}},
+		{"ApplicationDataPfdsAppIdDelete", false, func() *httpwrapper.Response {
+			return HandleApplicationDataPfdsAppIdDelete("app-1")
+		}},
+		{"ApplicationDataPfdsAppIdGet", false, func() *httpwrapper.Response {
+			return HandleApplicationDataPfdsAppIdGet("app-1")
+		}},
+		{"ApplicationDataPfdsAppIdPut", false, func() *httpwrapper.Response {
+			return HandleApplicationDataPfdsAppIdPut("app-1", &models.PfdDataForApp{ApplicationId: "app-1"})
+		}},
+		{"ApplicationDataPfdsGet", false, func() *httpwrapper.Response {
+			return HandleApplicationDataPfdsGet([]string{"app-1"})
+		}},
+		{"ApplicationDataPfdsSubsToNotifyPost", false, func() *httpwrapper.Response {
+			return HandleApplicationDataPfdsSubsToNotifyPost(&models.PfdSubscription{NotifyUri: "http://nef/pfd"})
+		}},
+		{"ApplicationDataPfdsSubsToNotifySubsIdGet", false, func() *httpwrapper.Response {
+			return HandleApplicationDataPfdsSubsToNotifySubsIdGet("subs-1")
+		}},
+		{"ApplicationDataPfdsSubsToNotifySubsIdPut", false, func() *httpwrapper.Response {
+			return HandleApplicationDataPfdsSubsToNotifySubsIdPut("subs-1",
+				&models.PfdSubscription{NotifyUri: "http://nef/pfd"})
+		}},
+		{"ApplicationDataPfdsSubsToNotifySubsIdDelete", false, func() *httpwrapper.Response {
+			return HandleApplicationDataPfdsSubsToNotifySubsIdDelete("subs-1")
+		}},
+		{"ApplicationDataResourceGet", false, func() *httpwrapper.Response {
+			return HandleApplicationDataResourceGet(AppDataBdtPolicyData, nil)
+		}},
+		{"ApplicationDataResourceIdGet", false, func() *httpwrapper.Response {
+			return HandleApplicationDataResourceIdGet(AppDataBdtPolicyData, "bdt-1")
+		}},
+		{"ApplicationDataResourceIdPatch", false, func() *httpwrapper.Response {
+			return HandleApplicationDataResourceIdPatch(AppDataBdtPolicyData, "bdt-1", []byte(`{}`))
+		}},
+		{"ApplicationDataResourceIdDelete", false, func() *httpwrapper.Response {
+			return HandleApplicationDataResourceIdDelete(AppDataBdtPolicyData, "bdt-1")
+		}},
+		{"ApplicationDataSubsToNotifyPost", false, func() *httpwrapper.Response {
+			return HandleApplicationDataSubsToNotifyPost(&ApplicationDataSubs{NotificationUri: "http://nef/app"})
+		}},
+		{"ApplicationDataSubsToNotifySubsIdGet", false, func() *httpwrapper.Response {
+			return HandleApplicationDataSubsToNotifySubsIdGet("subs-1")
+		}},
+		{"ApplicationDataSubsToNotifySubsIdPut", false, func() *httpwrapper.Response {
+			return HandleApplicationDataSubsToNotifySubsIdPut("subs-1",
+				&ApplicationDataSubs{NotificationUri: "http://nef/app"})
+		}},
+		{"ApplicationDataSubsToNotifySubsIdDelete", false, func() *httpwrapper.Response {
+			return HandleApplicationDataSubsToNotifySubsIdDelete("subs-1")
+		}},
+	}
+
+	kinds := []struct {
+		kind   DBErrorKind
+		status int
+	}{
+		{DBErrorUnavailable, http.StatusServiceUnavailable},
+		{DBErrorTimeout, http.StatusGatewayTimeout},
+		{DBErrorConflict, http.StatusConflict},
+	}
+	for _, kind := range kinds {
+		db := failingDB{err: &DBError{Kind: kind.kind, Err: errors.New(kind.kind.String())}}
+		for _, handler := range handlers {
+			if handler.versioned {
+				CommonDBClient = &versionedFailingDB{db}
+			} else {
+				CommonDBClient = &db
+			}
+			AuthDBClient = CommonDBClient
+			rsp := handler.handle()
+			if assert.Equal(t, kind.status, rsp.Status, "%s on %s", handler.name, kind.kind) {
+				pd, ok := rsp.Body.(*models.ProblemDetails)
+				if assert.True(t, ok, "%s on %s", handler.name, kind.kind) {
+					assert.Equal(t, int32(kind.status), pd.Status)
+				}
+			}
+		}
+	}
+}
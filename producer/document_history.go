@@ -0,0 +1,540 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SUBSCDATA_DOCUMENT_HISTORY keeps the prior versions of the subscriber data
+// documents, in the database of the documents.
+const SUBSCDATA_DOCUMENT_HISTORY = "subscriptionData.documentHistory"
+
+// configPodActor is the actor of the writes provisioning the subscriber data
+// of the config pod.
+const configPodActor = "config-pod"
+
+// documentHistoryKeys maps the collections whose prior versions are kept to
+// the attributes identifying a document.
+var documentHistoryKeys = map[string][]string{
+	SUBSCDATA_PROVISIONED_AMDATA:          {"ueId", "servingPlmnId"},
+	SUBSCDATA_PROVISIONED_SMFSELDATA:      {"ueId", "servingPlmnId"},
+	SUBSCDATA_AUTHENTICATION_SUBSCRIPTION: {"ueId"},
+	SUBSCDATA_PPDATA:                      {"ueId"},
+	SUBSCDATA_OPERATORSPECIFICDATA:        {"ueId"},
+}
+
+// DocumentVersion is a prior version of a subscriber data document, replaced
+// or removed at RecordedAt by Actor.
+type DocumentVersion struct {
+	VersionId  string            `json:"versionId" bson:"versionId"`
+	UeId       string            `json:"ueId" bson:"ueId"`
+	Resource   string            `json:"resource" bson:"resource"`
+	Operation  models.ChangeType `json:"operation" bson:"operation"`
+	Actor      string            `json:"actor" bson:"actor"`
+	RecordedAt time.Time         `json:"recordedAt" bson:"recordedAt"`
+	Revision   int64             `json:"revision" bson:"revision"`
+	Document   bson.M            `json:"document,omitempty" bson:"document"`
+	Collection string            `json:"-" bson:"collection"`
+	Key        bson.M            `json:"-" bson:"key"`
+}
+
+// DocumentVersionDiff is the JSON merge patch turning the document of version
+// From into the document of version To.
+type DocumentVersionDiff struct {
+	From       string                 `json:"from"`
+	To         string                 `json:"to"`
+	MergePatch map[string]interface{} `json:"mergePatch"`
+}
+
+type documentHistoryLimits struct {
+	maxVersions int
+}
+
+// documentHistory holds the limits of the history, nil while it is not kept.
+var documentHistory atomic.Pointer[documentHistoryLimits]
+
+// documentRestoreEnabled is set when the admin API may write prior versions
+// back.
+var documentRestoreEnabled atomic.Bool
+
+const (
+	// recordedAtTTLIndex names the index dropping the versions past their
+	// maximum age.
+	recordedAtTTLIndex = "recordedAt_ttl"
+
+	// MongoDB error codes of the index commands
+	mongoErrNamespaceNotFound    = 26
+	mongoErrIndexNotFound        = 27
+	mongoErrIndexOptionsConflict = 85
+)
+
+// EnableDocumentHistory keeps the last maxVersions prior versions of every
+// subscriber data document written through the UDR.
+func EnableDocumentHistory(maxVersions int) {
+	documentHistory.Store(&documentHistoryLimits{maxVersions: maxVersions})
+}
+
+// EnableDocumentRestore lets the admin API write prior versions back.
+func EnableDocumentRestore() {
+	documentRestoreEnabled.Store(true)
+}
+
+// CreateDocumentHistoryIndexes creates the indexes to fetch the versions of
+// the documents of a UE and, unless maxAge is zero, the TTL index dropping
+// the versions older than maxAge.
+func CreateDocumentHistoryIndexes(maxAge time.Duration) {
+	for _, db := range documentHistoryDBs() {
+		collClient, ok := uncached(db).(collectionDBInterface)
+		if !ok {
+			continue
+		}
+		indexes := collClient.GetCollection(SUBSCDATA_DOCUMENT_HISTORY).Indexes()
+		_, err := indexes.CreateMany(context.Background(), []mongo.IndexModel{
+			{Keys: bson.D{{Key: "versionId", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "ueId", Value: 1}, {Key: "collection", Value: 1}}},
+		})
+		if err != nil {
+			logger.DataRepoLog.Warnf("create indexes of %s: %v", SUBSCDATA_DOCUMENT_HISTORY, err)
+		}
+		if err := createRecordedAtTTLIndex(indexes, maxAge); err != nil {
+			logger.DataRepoLog.Warnf("create TTL index of %s: %v", SUBSCDATA_DOCUMENT_HISTORY, err)
+		}
+	}
+}
+
+// createRecordedAtTTLIndex makes the TTL index on recordedAt expire the
+// versions after maxAge, and drops it if maxAge is zero. An index created
+// with another maximum age is replaced.
+func createRecordedAtTTLIndex(indexes mongo.IndexView, maxAge time.Duration) error {
+	if maxAge <= 0 {
+		_, err := indexes.DropOne(context.Background(), recordedAtTTLIndex)
+		if commandFailed(err, mongoErrNamespaceNotFound) || commandFailed(err, mongoErrIndexNotFound) {
+			return nil
+		}
+		return err
+	}
+	expireAfterSeconds := int32(max(1, (maxAge+time.Second-1)/time.Second))
+	model := mongo.IndexModel{
+		Keys:    bson.D{{Key: "recordedAt", Value: 1}},
+		Options: options.Index().SetName(recordedAtTTLIndex).SetExpireAfterSeconds(expireAfterSeconds),
+	}
+	_, err := indexes.CreateOne(context.Background(), model)
+	if commandFailed(err, mongoErrIndexOptionsConflict) {
+		if _, err := indexes.DropOne(context.Background(), recordedAtTTLIndex); err != nil {
+			return err
+		}
+		_, err = indexes.CreateOne(context.Background(), model)
+		return err
+	}
+	return err
+}
+
+// commandFailed reports whether err is the failure code of a database
+// command.
+func commandFailed(err error, code int32) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == code
+}
+
+// documentHistoryDBs returns the databases keeping versions.
+func documentHistoryDBs() []DBInterface {
+	var dbs []DBInterface
+	for _, db := range []DBInterface{CommonDBClient, AuthDBClient} {
+		if db != nil && (len(dbs) == 0 || dbs[0] != db) {
+			dbs = append(dbs, db)
+		}
+	}
+	return dbs
+}
+
+// documentHistoryDB returns the database of the documents of collName and of
+// their versions.
+func documentHistoryDB(collName string) DBInterface {
+	if collName == SUBSCDATA_AUTHENTICATION_SUBSCRIPTION {
+		return AuthDBClient
+	}
+	return CommonDBClient
+}
+
+// documentHistoryKept reports whether the versions of the documents of
+// collName are kept.
+func documentHistoryKept(collName string) bool {
+	_, ok := documentHistoryKeys[collName]
+	return ok && documentHistory.Load() != nil
+}
+
+// requestActor returns the actor of a request, the NF of its User-Agent, see
+// TS 29.500.
+func requestActor(header http.Header) string {
+	if userAgent := header.Get("User-Agent"); userAgent != "" {
+		return userAgent
+	}
+	return "unknown"
+}
+
+// historyFilter returns the filter of the versions of the document of
+// collName identified by key.
+func historyFilter(collName string, key bson.M) bson.M {
+	filter := bson.M{"collection": collName}
+	for attr, value := range key {
+		filter["key."+attr] = value
+	}
+	return filter
+}
+
+// recordDocumentVersion appends prior, the version of a document of collName
+// a write replaced or removed, to the history of the document and drops the
+// versions beyond the limits.
+func recordDocumentVersion(db DBInterface, collName string, prior map[string]interface{}, revision int64,
+	operation models.ChangeType, actor string,
+) {
+	keyAttrs, ok := documentHistoryKeys[collName]
+	limits := documentHistory.Load()
+	if !ok || limits == nil || prior == nil {
+		return
+	}
+	key := bson.M{}
+	for _, attr := range keyAttrs {
+		key[attr] = prior[attr]
+	}
+	ueId, _ := prior["ueId"].(string)
+	servingPlmnId, _ := prior["servingPlmnId"].(string)
+	now := time.Now().UTC()
+	versionId := primitive.NewObjectID().Hex()
+	version := bson.M{
+		"versionId":  versionId,
+		"ueId":       ueId,
+		"resource":   subscriptionDataResourceUri(collName, ueId, servingPlmnId),
+		"operation":  operation,
+		"actor":      actor,
+		"recordedAt": now,
+		"revision":   revision,
+		"document":   withoutInternalFields(prior),
+		"collection": collName,
+		"key":        key,
+	}
+	if _, err := db.RestfulAPIPutOne(SUBSCDATA_DOCUMENT_HISTORY, bson.M{"versionId": versionId}, version); err != nil {
+		logger.DataRepoLog.Warnf("keep version of %s of %s: %v", collName, ueId, err)
+		return
+	}
+	if err := pruneDocumentHistory(db, collName, key, limits); err != nil {
+		logger.DataRepoLog.Warnf("prune versions of %s of %s: %v", collName, ueId, err)
+	}
+}
+
+// pruneDocumentHistory drops the oldest versions of the document of collName
+// identified by key beyond the maximum count. The versions past their maximum
+// age are dropped by the TTL index on recordedAt.
+func pruneDocumentHistory(db DBInterface, collName string, key bson.M, limits *documentHistoryLimits) error {
+	docs, err := db.RestfulAPIGetMany(SUBSCDATA_DOCUMENT_HISTORY, historyFilter(collName, key))
+	if err != nil {
+		return err
+	}
+	if len(docs) <= limits.maxVersions {
+		return nil
+	}
+	versions, err := decodeDocumentVersions(docs)
+	if err != nil {
+		return err
+	}
+	dropped := bson.A{}
+	for _, version := range versions[limits.maxVersions:] {
+		dropped = append(dropped, version.VersionId)
+	}
+	return db.RestfulAPIDeleteMany(SUBSCDATA_DOCUMENT_HISTORY, bson.M{"versionId": bson.M{"$in": dropped}})
+}
+
+// decodeDocumentVersions decodes stored versions, the latest first.
+func decodeDocumentVersions(docs []map[string]interface{}) ([]DocumentVersion, error) {
+	versions := make([]DocumentVersion, 0, len(docs))
+	for _, doc := range docs {
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		var version DocumentVersion
+		if err := bson.Unmarshal(data, &version); err != nil {
+			return nil, fmt.Errorf("malformed version %v: %w", doc["versionId"], err)
+		}
+		versions = append(versions, version)
+	}
+	// the ObjectID counter in versionId orders the versions of the same
+	// millisecond
+	sort.Slice(versions, func(i, j int) bool {
+		if !versions[i].RecordedAt.Equal(versions[j].RecordedAt) {
+			return versions[i].RecordedAt.After(versions[j].RecordedAt)
+		}
+		return versions[i].VersionId > versions[j].VersionId
+	})
+	return versions, nil
+}
+
+// putDocumentWithHistory stores a document like putVersionedDocument, on
+// behalf of actor, unless it already holds the attributes of putData. The
+// version it changes is kept.
+func putDocumentWithHistory(db DBInterface, collName string, filter bson.M, putData map[string]interface{},
+	actor string,
+) error {
+	prior, err := uncached(db).RestfulAPIGetOne(collName, filter)
+	if err != nil {
+		return err
+	}
+	if prior != nil && !documentChangedBy(prior, putData) {
+		return nil
+	}
+	if _, problemDetails := putVersionedDocument(db, collName, filter, putData,
+		http.Header{"User-Agent": {actor}}); problemDetails != nil {
+		return fmt.Errorf("put %s: %s", collName, problemDetails.Detail)
+	}
+	return nil
+}
+
+// deleteDocumentsWithHistory deletes documents like RestfulAPIDeleteMany and
+// keeps the versions it removes.
+func deleteDocumentsWithHistory(db DBInterface, collName string, filter bson.M, actor string) error {
+	var priors []map[string]interface{}
+	if documentHistoryKept(collName) {
+		var err error
+		if priors, err = uncached(db).RestfulAPIGetMany(collName, filter); err != nil {
+			return err
+		}
+	}
+	if err := db.RestfulAPIDeleteMany(collName, filter); err != nil {
+		return err
+	}
+	for _, prior := range priors {
+		recordDocumentVersion(db, collName, prior, documentRevision(prior), models.ChangeType_REMOVE, actor)
+	}
+	return nil
+}
+
+// documentChangedBy reports whether setting the attributes of putData changes
+// the document doc, compared as JSON.
+func documentChangedBy(doc map[string]interface{}, putData map[string]interface{}) bool {
+	var current, updated map[string]interface{}
+	if json.Unmarshal(util.MapToByte(doc), &current) != nil ||
+		json.Unmarshal(util.MapToByte(putData), &updated) != nil {
+		return true
+	}
+	for attr, value := range updated {
+		if !reflect.DeepEqual(current[attr], value) {
+			return true
+		}
+	}
+	return false
+}
+
+// historyResourceCollection returns the collection of the documents of the
+// resource named like the last segment of its URI, e.g. am-data.
+func historyResourceCollection(resource string) (string, bool) {
+	for collName := range documentHistoryKeys {
+		if path.Base(subscriptionDataResources[collName]) == resource {
+			return collName, true
+		}
+	}
+	return "", false
+}
+
+// documentVersions returns the versions of the documents of ueId, of those of
+// collName only unless empty, the latest first.
+func documentVersions(ueId string, collName string) ([]DocumentVersion, error) {
+	filter := bson.M{"ueId": ueId}
+	if collName != "" {
+		filter["collection"] = collName
+	}
+	var docs []map[string]interface{}
+	for _, db := range documentHistoryDBs() {
+		dbDocs, err := db.RestfulAPIGetMany(SUBSCDATA_DOCUMENT_HISTORY, filter)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, dbDocs...)
+	}
+	return decodeDocumentVersions(docs)
+}
+
+// documentVersion returns the version versionId of a document of ueId.
+func documentVersion(ueId string, versionId string) (*DocumentVersion, *models.ProblemDetails) {
+	for _, db := range documentHistoryDBs() {
+		doc, err := db.RestfulAPIGetOne(SUBSCDATA_DOCUMENT_HISTORY, bson.M{"versionId": versionId, "ueId": ueId})
+		if err != nil {
+			logger.DataRepoLog.Warnln(err)
+			return nil, dbProblemDetails(err)
+		}
+		if doc == nil {
+			continue
+		}
+		versions, err := decodeDocumentVersions([]map[string]interface{}{doc})
+		if err != nil {
+			logger.DataRepoLog.Warnln(err)
+			return nil, util.ProblemDetailsSystemFailure(err.Error())
+		}
+		return &versions[0], nil
+	}
+	return nil, util.ProblemDetailsNotFound("DATA_NOT_FOUND")
+}
+
+// HandleListDocumentVersions lists the versions of the documents of ueId, of
+// the resource named resource only unless empty, without their document.
+func HandleListDocumentVersions(ueId string, resource string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ListDocumentVersions: ueId=%q resource=%q", ueId, resource)
+
+	collName := ""
+	if resource != "" {
+		var ok bool
+		if collName, ok = historyResourceCollection(resource); !ok {
+			pd := util.ProblemDetailsMalformedReqSyntax(fmt.Sprintf("no history of resource %s", resource))
+			stats.IncrementUdrSubscriptionDataStats("get", "document-history", "FAILURE")
+			return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+		}
+	}
+	versions, err := documentVersions(ueId, collName)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("get", "document-history", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	for i := range versions {
+		versions[i].Document = nil
+	}
+	stats.IncrementUdrSubscriptionDataStats("get", "document-history", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, versions)
+}
+
+func HandleGetDocumentVersion(ueId string, versionId string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle GetDocumentVersion: ueId=%q versionId=%q", ueId, versionId)
+
+	version, problemDetails := documentVersion(ueId, versionId)
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("get", "document-history", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("get", "document-history", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, version)
+}
+
+// HandleDiffDocumentVersions returns the changes from the version versionId of
+// a document to its version toVersionId, or to the current document if
+// toVersionId is empty or "current".
+func HandleDiffDocumentVersions(ueId string, versionId string, toVersionId string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle DiffDocumentVersions: ueId=%q versionId=%q to=%q", ueId, versionId,
+		toVersionId)
+
+	diff, problemDetails := diffDocumentVersions(ueId, versionId, toVersionId)
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("get", "document-history", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("get", "document-history", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, diff)
+}
+
+func diffDocumentVersions(ueId string, versionId string,
+	toVersionId string,
+) (*DocumentVersionDiff, *models.ProblemDetails) {
+	from, problemDetails := documentVersion(ueId, versionId)
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+	var toDocument map[string]interface{}
+	if toVersionId == "" || toVersionId == "current" {
+		toVersionId = "current"
+		current, err := documentHistoryDB(from.Collection).RestfulAPIGetOne(from.Collection, from.Key)
+		if err != nil {
+			logger.DataRepoLog.Warnln(err)
+			return nil, dbProblemDetails(err)
+		}
+		toDocument = withoutInternalFields(current)
+	} else {
+		to, problemDetails := documentVersion(ueId, toVersionId)
+		if problemDetails != nil {
+			return nil, problemDetails
+		}
+		if to.Collection != from.Collection || !reflect.DeepEqual(to.Key, from.Key) {
+			return nil, util.ProblemDetailsMalformedReqSyntax(
+				fmt.Sprintf("versions %s and %s are not versions of the same resource", versionId, toVersionId))
+		}
+		toDocument = to.Document
+	}
+	if toDocument == nil {
+		// the document was removed
+		toDocument = map[string]interface{}{}
+	}
+
+	mergePatch, err := jsonpatch.CreateMergePatch(util.MapToByte(from.Document), util.MapToByte(toDocument))
+	if err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+	diff := &DocumentVersionDiff{From: versionId, To: toVersionId}
+	if err := json.Unmarshal(mergePatch, &diff.MergePatch); err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+	return diff, nil
+}
+
+// HandleRestoreDocumentVersion writes the version versionId of a document
+// back, if the restore is enabled. The version it replaces is kept and the
+// subscribers are notified of the change.
+func HandleRestoreDocumentVersion(ueId string, versionId string, header http.Header) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle RestoreDocumentVersion: ueId=%q versionId=%q", ueId, versionId)
+
+	if !documentRestoreEnabled.Load() {
+		pd := util.ProblemDetailsModifyNotAllowed("the restore of document versions is not enabled")
+		stats.IncrementUdrSubscriptionDataStats("update", "document-history", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	version, problemDetails := documentVersion(ueId, versionId)
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("update", "document-history", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	db := documentHistoryDB(version.Collection)
+	origValue, err := uncached(db).RestfulAPIGetOne(version.Collection, version.Key)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("update", "document-history", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	var etag string
+	change := models.ChangeItem{Path: "/", NewValue: version.Document}
+	if origValue == nil {
+		change.Op = models.ChangeType_ADD
+		etag, problemDetails = putVersionedDocument(db, version.Collection, version.Key, version.Document, header)
+	} else {
+		change.Op = models.ChangeType_REPLACE
+		origValue, _, etag, problemDetails = replaceVersionedDocument(db, version.Collection, version.Key,
+			version.Document, header)
+		change.OrigValue = withoutInternalFields(origValue)
+	}
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("update", "document-history", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("update", "document-history", "SUCCESS")
+	notifySubscriptionDataChange(version.UeId, version.Resource, change)
+	return httpwrapper.NewResponse(http.StatusNoContent, etagHeader(etag), map[string]interface{}{})
+}
@@ -0,0 +1,468 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR document history
+ */
+
+package producer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	udr_context "github.com/omec-project/udr/context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// filteringDB serves documents from memory, keyed by collection, matching
+// equality filters, dotted attributes, $in, $lt, $and and $or.
+type filteringDB struct {
+	DBInterface
+	docs map[string][]map[string]interface{}
+}
+
+// filterValues returns the values of attr in doc, looking into the elements
+// of the arrays on the way like MongoDB does. A missing attribute is nil.
+func filterValues(doc map[string]interface{}, attr string) []interface{} {
+	values := []interface{}{doc}
+	for _, name := range strings.Split(attr, ".") {
+		var next []interface{}
+		for _, value := range values {
+			switch object := value.(type) {
+			case bson.M:
+				next = append(next, object[name])
+			case map[string]interface{}:
+				next = append(next, object[name])
+			}
+		}
+		values = nil
+		for _, value := range next {
+			switch array := value.(type) {
+			case []interface{}:
+				values = append(values, array...)
+			case bson.A:
+				values = append(values, array...)
+			case []string:
+				for _, item := range array {
+					values = append(values, item)
+				}
+			case []bson.M:
+				for _, item := range array {
+					values = append(values, item)
+				}
+			case []map[string]interface{}:
+				for _, item := range array {
+					values = append(values, item)
+				}
+			default:
+				values = append(values, value)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return []interface{}{nil}
+	}
+	return values
+}
+
+// filterEqual compares values like MongoDB, numbers by their value.
+func filterEqual(value interface{}, want interface{}) bool {
+	number := func(v interface{}) (float64, bool) {
+		switch n := v.(type) {
+		case int:
+			return float64(n), true
+		case int32:
+			return float64(n), true
+		case int64:
+			return float64(n), true
+		case float64:
+			return n, true
+		}
+		return 0, false
+	}
+	if a, ok := number(value); ok {
+		b, ok := number(want)
+		return ok && a == b
+	}
+	return value == want
+}
+
+func filterMatches(doc map[string]interface{}, filter bson.M) bool {
+	for attr, want := range filter {
+		switch attr {
+		case "$and", "$or":
+			matched := 0
+			for _, clause := range want.([]bson.M) {
+				if filterMatches(doc, clause) {
+					matched++
+				}
+			}
+			if (attr == "$and" && matched < len(want.([]bson.M))) || (attr == "$or" && matched == 0) {
+				return false
+			}
+			continue
+		}
+		found := false
+		for _, value := range filterValues(doc, attr) {
+			found = found || valueMatches(value, want)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func valueMatches(value interface{}, want interface{}) bool {
+	condition, ok := want.(bson.M)
+	switch {
+	case ok && condition["$in"] != nil:
+		items, isStrings := condition["$in"].([]string)
+		if !isStrings {
+			for _, item := range condition["$in"].(bson.A) {
+				if filterEqual(value, item) {
+					return true
+				}
+			}
+		}
+		for _, item := range items {
+			if filterEqual(value, item) {
+				return true
+			}
+		}
+		return false
+	case ok && condition["$ne"] != nil:
+		return !filterEqual(value, condition["$ne"])
+	case ok && condition["$lt"] != nil:
+		at, isTime := value.(time.Time)
+		return isTime && at.Before(condition["$lt"].(time.Time))
+	}
+	return filterEqual(value, want)
+}
+
+func (db *filteringDB) RestfulAPIGetOne(collName string, filter bson.M) (map[string]interface{}, error) {
+	for _, doc := range db.docs[collName] {
+		if filterMatches(doc, filter) {
+			return doc, nil
+		}
+	}
+	return nil, nil
+}
+
+func (db *filteringDB) RestfulAPIGetMany(collName string, filter bson.M) ([]map[string]interface{}, error) {
+	var docs []map[string]interface{}
+	for _, doc := range db.docs[collName] {
+		if filterMatches(doc, filter) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+func (db *filteringDB) RestfulAPIPutOne(collName string, filter bson.M, putData map[string]interface{}) (bool, error) {
+	for i, doc := range db.docs[collName] {
+		if filterMatches(doc, filter) {
+			// the documents read before stay unchanged
+			updated := map[string]interface{}{}
+			for attr, value := range doc {
+				updated[attr] = value
+			}
+			for attr, value := range putData {
+				updated[attr] = value
+			}
+			db.docs[collName][i] = updated
+			return true, nil
+		}
+	}
+	db.docs[collName] = append(db.docs[collName], putData)
+	return false, nil
+}
+
+func (db *filteringDB) RestfulAPIDeleteOne(collName string, filter bson.M) error {
+	for i, doc := range db.docs[collName] {
+		if filterMatches(doc, filter) {
+			db.docs[collName] = append(db.docs[collName][:i:i], db.docs[collName][i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (db *filteringDB) RestfulAPIDeleteMany(collName string, filter bson.M) error {
+	var kept []map[string]interface{}
+	for _, doc := range db.docs[collName] {
+		if !filterMatches(doc, filter) {
+			kept = append(kept, doc)
+		}
+	}
+	db.docs[collName] = kept
+	return nil
+}
+
+// versionedDB is a filteringDB whose versioned writes are sent to the mocked
+// database of mt, which answers them as matching one document. They are
+// applied to the documents in memory before the next read.
+type versionedDB struct {
+	*filteringDB
+	mt *mtest.T
+}
+
+func newVersionedDB(mt *mtest.T, docs map[string][]map[string]interface{}) *versionedDB {
+	return &versionedDB{filteringDB: &filteringDB{docs: docs}, mt: mt}
+}
+
+func (db *versionedDB) GetCollection(collName string) *mongo.Collection {
+	db.mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+	return db.mt.DB.Collection(collName)
+}
+
+// applyWrites applies the updates and replacements sent to the mocked
+// database since the last call.
+func (db *versionedDB) applyWrites() {
+	for event := db.mt.GetStartedEvent(); event != nil; event = db.mt.GetStartedEvent() {
+		collName, ok := event.Command.Lookup("update").StringValueOK()
+		if !ok {
+			continue
+		}
+		values, err := event.Command.Lookup("updates").Array().Values()
+		assert.NoError(db.mt, err)
+		for _, value := range values {
+			var update struct {
+				Q bson.M `bson:"q"`
+				U bson.M `bson:"u"`
+			}
+			assert.NoError(db.mt, bson.Unmarshal(value.Document(), &update))
+			delete(update.Q, documentRevisionField)
+			if set, ok := update.U["$set"].(bson.M); ok {
+				_, err = db.filteringDB.RestfulAPIPutOne(collName, update.Q, set)
+			} else {
+				assert.NoError(db.mt, db.filteringDB.RestfulAPIDeleteOne(collName, update.Q))
+				_, err = db.filteringDB.RestfulAPIPutOne(collName, update.Q, update.U)
+			}
+			assert.NoError(db.mt, err)
+		}
+	}
+}
+
+func (db *versionedDB) RestfulAPIGetOne(collName string, filter bson.M) (map[string]interface{}, error) {
+	db.applyWrites()
+	return db.filteringDB.RestfulAPIGetOne(collName, filter)
+}
+
+func (db *versionedDB) RestfulAPIGetMany(collName string, filter bson.M) ([]map[string]interface{}, error) {
+	db.applyWrites()
+	return db.filteringDB.RestfulAPIGetMany(collName, filter)
+}
+
+func TestPutDocumentWithHistory(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("put", func(mt *mtest.T) {
+		defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+		defer documentHistory.Store(nil)
+		db := newVersionedDB(mt, map[string][]map[string]interface{}{})
+		CommonDBClient = db
+		filter := bson.M{"ueId": "imsi-1", "servingPlmnId": "20893"}
+		amData := func(sst int) bson.M {
+			return bson.M{"ueId": "imsi-1", "servingPlmnId": "20893", "nssai": bson.M{
+				"defaultSingleNssais": bson.A{bson.M{"sst": sst}},
+			}}
+		}
+
+		// nothing is kept while the history is disabled
+		err := putDocumentWithHistory(db, SUBSCDATA_PROVISIONED_AMDATA, filter, amData(1), configPodActor)
+		assert.NoError(mt, err)
+		err = putDocumentWithHistory(db, SUBSCDATA_PROVISIONED_AMDATA, filter, amData(2), configPodActor)
+		assert.NoError(mt, err)
+		assert.Empty(mt, db.docs[SUBSCDATA_DOCUMENT_HISTORY])
+		// every change is a new revision
+		current, err := db.RestfulAPIGetOne(SUBSCDATA_PROVISIONED_AMDATA, filter)
+		assert.NoError(mt, err)
+		assert.Equal(mt, int64(2), documentRevision(current))
+
+		EnableDocumentHistory(2)
+		// rewriting the same data keeps no version
+		err = putDocumentWithHistory(db, SUBSCDATA_PROVISIONED_AMDATA, filter, amData(2), configPodActor)
+		assert.NoError(mt, err)
+		current, err = db.RestfulAPIGetOne(SUBSCDATA_PROVISIONED_AMDATA, filter)
+		assert.NoError(mt, err)
+		assert.Equal(mt, int64(2), documentRevision(current))
+		assert.Empty(mt, db.docs[SUBSCDATA_DOCUMENT_HISTORY])
+		for sst := 3; sst <= 5; sst++ {
+			err = putDocumentWithHistory(db, SUBSCDATA_PROVISIONED_AMDATA, filter, amData(sst), configPodActor)
+			assert.NoError(mt, err)
+		}
+
+		rsp := HandleListDocumentVersions("imsi-1", "am-data")
+		assert.Equal(mt, http.StatusOK, rsp.Status)
+		versions := rsp.Body.([]DocumentVersion)
+		if assert.Len(mt, versions, 2) {
+			assert.Equal(mt, models.ChangeType_REPLACE, versions[0].Operation)
+			assert.Equal(mt, configPodActor, versions[0].Actor)
+			assert.True(mt, strings.HasSuffix(versions[0].Resource,
+				"/subscription-data/imsi-1/20893/provisioned-data/am-data"))
+			assert.Nil(mt, versions[0].Document)
+		}
+
+		rsp = HandleGetDocumentVersion("imsi-1", versions[1].VersionId)
+		assert.Equal(mt, http.StatusOK, rsp.Status)
+		sst := filterValues(rsp.Body.(*DocumentVersion).Document, "nssai.defaultSingleNssais.sst")
+		assert.Equal(mt, []interface{}{int32(3)}, sst)
+		rsp = HandleGetDocumentVersion("imsi-2", versions[1].VersionId)
+		assert.Equal(mt, http.StatusNotFound, rsp.Status)
+
+		rsp = HandleListDocumentVersions("imsi-1", "unknown-data")
+		assert.Equal(mt, http.StatusBadRequest, rsp.Status)
+	})
+}
+
+func TestDeleteDocumentsWithHistory(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	defer documentHistory.Store(nil)
+	db := &filteringDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_PROVISIONED_AMDATA: {{"ueId": "imsi-1", "servingPlmnId": "20893"}},
+		SUBSCDATA_PROVISIONED_SMDATA: {{"ueId": "imsi-1", "servingPlmnId": "20893"}},
+	}}
+	CommonDBClient = db
+	EnableDocumentHistory(10)
+
+	for _, collName := range []string{SUBSCDATA_PROVISIONED_AMDATA, SUBSCDATA_PROVISIONED_SMDATA} {
+		assert.NoError(t, deleteDocumentsWithHistory(db, collName, bson.M{"ueId": "imsi-1"}, configPodActor))
+		assert.Empty(t, db.docs[collName])
+	}
+
+	// the versions of the SM data are not kept
+	versions, err := documentVersions("imsi-1", "")
+	assert.NoError(t, err)
+	if assert.Len(t, versions, 1) {
+		assert.Equal(t, models.ChangeType_REMOVE, versions[0].Operation)
+		assert.Equal(t, SUBSCDATA_PROVISIONED_AMDATA, versions[0].Collection)
+	}
+}
+
+func TestDiffDocumentVersions(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("diff", func(mt *mtest.T) {
+		defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+		defer documentHistory.Store(nil)
+		db := newVersionedDB(mt, map[string][]map[string]interface{}{})
+		CommonDBClient = db
+		EnableDocumentHistory(10)
+		filter := bson.M{"ueId": "imsi-1", "servingPlmnId": "20893"}
+
+		for _, putData := range []bson.M{
+			{"ueId": "imsi-1", "servingPlmnId": "20893", "gpsis": bson.A{"msisdn-1"}},
+			{"ueId": "imsi-1", "servingPlmnId": "20893", "gpsis": bson.A{"msisdn-2"}, "mpsPriority": true},
+			{"ueId": "imsi-1", "servingPlmnId": "20893", "gpsis": bson.A{"msisdn-3"}},
+		} {
+			err := putDocumentWithHistory(db, SUBSCDATA_PROVISIONED_AMDATA, filter, putData, "AMF-1")
+			assert.NoError(mt, err)
+		}
+		versions, err := documentVersions("imsi-1", SUBSCDATA_PROVISIONED_AMDATA)
+		assert.NoError(mt, err)
+		if !assert.Len(mt, versions, 2) {
+			return
+		}
+
+		rsp := HandleDiffDocumentVersions("imsi-1", versions[1].VersionId, versions[0].VersionId)
+		assert.Equal(mt, http.StatusOK, rsp.Status)
+		assert.Equal(mt, &DocumentVersionDiff{
+			From: versions[1].VersionId, To: versions[0].VersionId,
+			MergePatch: map[string]interface{}{"gpsis": []interface{}{"msisdn-2"}, "mpsPriority": true},
+		}, rsp.Body)
+
+		rsp = HandleDiffDocumentVersions("imsi-1", versions[1].VersionId, "")
+		assert.Equal(mt, http.StatusOK, rsp.Status)
+		assert.Equal(mt, "current", rsp.Body.(*DocumentVersionDiff).To)
+		assert.Equal(mt, map[string]interface{}{"gpsis": []interface{}{"msisdn-3"}, "mpsPriority": true},
+			rsp.Body.(*DocumentVersionDiff).MergePatch)
+
+		rsp = HandleDiffDocumentVersions("imsi-1", versions[1].VersionId, "unknown")
+		assert.Equal(mt, http.StatusNotFound, rsp.Status)
+	})
+}
+
+func TestRestoreDocumentVersion(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("restore", func(mt *mtest.T) {
+		defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+		defer documentHistory.Store(nil)
+		defer documentRestoreEnabled.Store(false)
+		db := newVersionedDB(mt, map[string][]map[string]interface{}{})
+		CommonDBClient = db
+		EnableDocumentHistory(10)
+		filter := bson.M{"ueId": "imsi-1", "servingPlmnId": "20893"}
+		for _, gpsi := range []string{"msisdn-1", "msisdn-2"} {
+			putData := bson.M{"ueId": "imsi-1", "servingPlmnId": "20893", "gpsis": bson.A{gpsi}}
+			err := putDocumentWithHistory(db, SUBSCDATA_PROVISIONED_AMDATA, filter, putData, "AMF-1")
+			assert.NoError(mt, err)
+		}
+		versions, err := documentVersions("imsi-1", SUBSCDATA_PROVISIONED_AMDATA)
+		assert.NoError(mt, err)
+		if !assert.Len(mt, versions, 1) {
+			return
+		}
+
+		// the restore is a write of the admin API, off unless enabled
+		rsp := HandleRestoreDocumentVersion("imsi-1", versions[0].VersionId, http.Header{})
+		assert.Equal(mt, http.StatusForbidden, rsp.Status)
+		EnableDocumentRestore()
+		rsp = HandleRestoreDocumentVersion("imsi-1", "unknown", http.Header{})
+		assert.Equal(mt, http.StatusNotFound, rsp.Status)
+
+		notifications := make(chan models.DataChangeNotify, 1)
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var dataChangeNotify models.DataChangeNotify
+			assert.NoError(mt, json.NewDecoder(r.Body).Decode(&dataChangeNotify))
+			notifications <- dataChangeNotify
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		// the notifications are sent over HTTP/2 without TLS
+		server.Config.Protocols = new(http.Protocols)
+		server.Config.Protocols.SetUnencryptedHTTP2(true)
+		server.Start()
+		defer server.Close()
+		udrSelf := udr_context.UDR_Self()
+		defer udrSelf.Reset()
+		udrSelf.NewSubscriptionDataSubscription(&models.SubscriptionDataSubscriptions{
+			UeId: "imsi-1", CallbackReference: server.URL,
+		})
+
+		rsp = HandleRestoreDocumentVersion("imsi-1", versions[0].VersionId, http.Header{})
+		assert.Equal(mt, http.StatusNoContent, rsp.Status)
+		current, err := db.RestfulAPIGetOne(SUBSCDATA_PROVISIONED_AMDATA, filter)
+		assert.NoError(mt, err)
+		assert.Equal(mt, []interface{}{"msisdn-1"}, filterValues(current, "gpsis"))
+		assert.Equal(mt, int64(3), documentRevision(current))
+
+		select {
+		case dataChangeNotify := <-notifications:
+			assert.Equal(mt, "imsi-1", dataChangeNotify.UeId)
+			if assert.Len(mt, dataChangeNotify.NotifyItems, 1) {
+				notifyItem := dataChangeNotify.NotifyItems[0]
+				assert.True(mt, strings.HasSuffix(notifyItem.ResourceId,
+					"/subscription-data/imsi-1/20893/provisioned-data/am-data"))
+				if assert.Len(mt, notifyItem.Changes, 1) {
+					assert.Equal(mt, models.ChangeType_REPLACE, notifyItem.Changes[0].Op)
+					newValue, _ := notifyItem.Changes[0].NewValue.(map[string]interface{})
+					assert.Equal(mt, []interface{}{"msisdn-1"}, newValue["gpsis"])
+					origValue, _ := notifyItem.Changes[0].OrigValue.(map[string]interface{})
+					assert.Equal(mt, []interface{}{"msisdn-2"}, origValue["gpsis"])
+				}
+			}
+		case <-time.After(5 * time.Second):
+			mt.Fatal("no notification of the restore")
+		}
+	})
+}
+
+func TestRequestActor(t *testing.T) {
+	assert.Equal(t, "AMF-1", requestActor(http.Header{"User-Agent": {"AMF-1"}}))
+	assert.Equal(t, "unknown", requestActor(nil))
+}
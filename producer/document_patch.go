@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"bytes"
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+	"github.com/omec-project/udr/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// decodeStrict decodes data into the model target, rejecting the attributes
+// the model does not know.
+func decodeStrict(data []byte, target interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+// mergePatchDocument applies the JSON merge patch to the document matching
+// filter and stores the result once decoded into target and validated. The
+// attributes of filter are kept in the stored document.
+func mergePatchDocument(collName string, filter bson.M, patch []byte, target interface{},
+	validate func() error,
+) *models.ProblemDetails {
+	origValue, err := CommonDBClient.RestfulAPIGetOne(collName, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		return dbProblemDetails(err)
+	}
+	if origValue == nil {
+		return util.ProblemDetailsNotFound("DATA_NOT_FOUND")
+	}
+	delete(origValue, "_id")
+	for key := range filter {
+		delete(origValue, key)
+	}
+
+	original, err := json.Marshal(origValue)
+	if err != nil {
+		return util.ProblemDetailsSystemFailure(err.Error())
+	}
+	modified, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return util.ProblemDetailsMalformedReqSyntax(err.Error())
+	}
+	if problemDetails := validateJSON(target, modified,
+		"the patched resource does not match its schema"); problemDetails != nil {
+		return problemDetails
+	}
+	if err := decodeStrict(modified, target); err != nil {
+		return util.ProblemDetailsMalformedReqSyntax(err.Error())
+	}
+	if err := validate(); err != nil {
+		return util.ProblemDetailsMalformedReqSyntax(err.Error())
+	}
+
+	putData := util.ToBsonM(target)
+	for key, value := range filter {
+		putData[key] = value
+	}
+	if _, err := CommonDBClient.RestfulAPIPutOne(collName, filter, putData); err != nil {
+		logger.DataRepoLog.Warnln(err)
+		return dbProblemDetails(err)
+	}
+	return nil
+}
+
+// deleteExistingDocument deletes the document matching filter, which must exist.
+func deleteExistingDocument(collName string, filter bson.M) *models.ProblemDetails {
+	existing, err := CommonDBClient.RestfulAPIGetOne(collName, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		return dbProblemDetails(err)
+	}
+	if existing == nil {
+		return util.ProblemDetailsNotFound("DATA_NOT_FOUND")
+	}
+	if err := CommonDBClient.RestfulAPIDeleteOne(collName, filter); err != nil {
+		logger.DataRepoLog.Warnln(err)
+		return dbProblemDetails(err)
+	}
+	return nil
+}
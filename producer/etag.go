@@ -0,0 +1,287 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// documentRevisionField holds the version of a stored document. It is
+	// bumped on every versioned write and exposed to clients as the ETag.
+	documentRevisionField = "_rev"
+	documentMaxAttempts   = 3
+)
+
+// documentRevision returns the revision of a stored document, 0 if it was
+// never written with a revision.
+func documentRevision(doc map[string]interface{}) int64 {
+	switch rev := doc[documentRevisionField].(type) {
+	case int64:
+		return rev
+	case int32:
+		return int64(rev)
+	case float64:
+		return int64(rev)
+	}
+	return 0
+}
+
+func revisionETag(revision int64) string {
+	return `"` + strconv.FormatInt(revision, 10) + `"`
+}
+
+// documentETag returns the ETag of a stored document. A document never
+// written with a revision is tagged by a hash of its content, so that a
+// change of it by a writer outside the UDR still changes its ETag.
+func documentETag(doc map[string]interface{}) string {
+	if revision := documentRevision(doc); revision != 0 || doc == nil {
+		return revisionETag(revision)
+	}
+	hash := fnv.New64a()
+	hash.Write(util.MapToByte(withoutInternalFields(doc)))
+	return `"0-` + strconv.FormatUint(hash.Sum64(), 16) + `"`
+}
+
+// etagMatches reports whether etag is in the comma separated list of entity
+// tags of an If-Match or If-None-Match header. Weak tags only match when weak
+// comparison is allowed (If-None-Match), see RFC 9110 section 8.8.3.2.
+func etagMatches(list string, etag string, weak bool) bool {
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writePrecondition evaluates If-Match and If-None-Match of a PUT, PATCH or
+// DELETE request against the current document, nil if it does not exist.
+func writePrecondition(header http.Header, doc map[string]interface{}) *models.ProblemDetails {
+	if ifMatch := header.Get("If-Match"); ifMatch != "" {
+		if doc == nil {
+			return util.ProblemDetailsPreconditionFailed("resource does not exist")
+		}
+		if !etagMatches(ifMatch, documentETag(doc), false) {
+			return util.ProblemDetailsPreconditionFailed("If-Match does not match " + documentETag(doc))
+		}
+	}
+	if ifNoneMatch := header.Get("If-None-Match"); ifNoneMatch != "" && doc != nil {
+		if etagMatches(ifNoneMatch, documentETag(doc), true) {
+			return util.ProblemDetailsPreconditionFailed("If-None-Match matches " + documentETag(doc))
+		}
+	}
+	return nil
+}
+
+// documentResponse builds the response of a GET of a versioned document: the
+// revision is replaced by an ETag header and If-None-Match is honored.
+func documentResponse(header http.Header, doc map[string]interface{}) *httpwrapper.Response {
+	etag := documentETag(doc)
+	delete(doc, documentRevisionField)
+	return etagResponse(header, etag, doc)
+}
+
+// etagResponse builds the response of a GET returning body, a representation
+// of a versioned document tagged etag, honoring If-None-Match.
+func etagResponse(header http.Header, etag string, body interface{}) *httpwrapper.Response {
+	rspHeader := etagHeader(etag)
+	if ifNoneMatch := header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag, true) {
+		return httpwrapper.NewResponse(http.StatusNotModified, rspHeader, nil)
+	}
+	return httpwrapper.NewResponse(http.StatusOK, rspHeader, body)
+}
+
+// etagHeader returns the response header carrying the ETag of a written document.
+func etagHeader(etag string) http.Header {
+	if etag == "" {
+		return nil
+	}
+	header := make(http.Header)
+	header.Set("ETag", etag)
+	return header
+}
+
+// patchVersionedDocument applies a JSON patch to a document and bumps its
+// revision. The write only succeeds if the document is unchanged since it was
+// read; without If-Match a lost race is retried, with If-Match it fails with
+// 412. It returns the document before and after the patch and the new ETag.
+func patchVersionedDocument(db DBInterface, collName string, filter bson.M, patchItem []models.PatchItem,
+	header http.Header,
+) (origValue, newValue map[string]interface{}, etag string, problemDetails *models.ProblemDetails) {
+	patchJSON, err := json.Marshal(patchItem)
+	if err != nil {
+		logger.DataRepoLog.Errorln(err)
+		return nil, nil, "", util.ProblemDetailsMalformedReqSyntax(err.Error())
+	}
+	patch, err := jsonpatch.DecodePatch(patchJSON)
+	if err != nil {
+		return nil, nil, "", util.ProblemDetailsMalformedReqSyntax(err.Error())
+	}
+	return rewriteVersionedDocument(db, collName, filter, header,
+		func(original []byte) ([]byte, *models.ProblemDetails) {
+			modified, err := patch.Apply(original)
+			if err != nil {
+				logger.DataRepoLog.Warnf("apply patch to %s: %v", collName, err)
+				return nil, util.ProblemDetailsModifyNotAllowed("")
+			}
+			return modified, nil
+		})
+}
+
+// replaceVersionedDocument replaces an existing document by replacement like
+// patchVersionedDocument.
+func replaceVersionedDocument(db DBInterface, collName string, filter bson.M, replacement map[string]interface{},
+	header http.Header,
+) (origValue, newValue map[string]interface{}, etag string, problemDetails *models.ProblemDetails) {
+	replacementJSON, err := json.Marshal(withoutInternalFields(replacement))
+	if err != nil {
+		return nil, nil, "", util.ProblemDetailsSystemFailure(err.Error())
+	}
+	return rewriteVersionedDocument(db, collName, filter, header,
+		func([]byte) ([]byte, *models.ProblemDetails) { return replacementJSON, nil })
+}
+
+// rewriteVersionedDocument replaces an existing document by the result of
+// rewrite and bumps its revision, retrying or failing on concurrent writes
+// like patchVersionedDocument. The replaced version is kept in the history.
+func rewriteVersionedDocument(db DBInterface, collName string, filter bson.M, header http.Header,
+	rewrite func(original []byte) ([]byte, *models.ProblemDetails),
+) (origValue, newValue map[string]interface{}, etag string, problemDetails *models.ProblemDetails) {
+	for attempt := 1; attempt <= documentMaxAttempts; attempt++ {
+		var err error
+		origValue, err = uncached(db).RestfulAPIGetOne(collName, filter)
+		if err != nil {
+			logger.DataRepoLog.Warnln(err)
+			return nil, nil, "", dbProblemDetails(err)
+		}
+		if problemDetails = writePrecondition(header, origValue); problemDetails != nil {
+			return nil, nil, "", problemDetails
+		}
+		if origValue == nil {
+			return nil, nil, "", util.ProblemDetailsModifyNotAllowed("")
+		}
+
+		revision := documentRevision(origValue)
+		delete(origValue, documentRevisionField)
+		original, err := json.Marshal(origValue)
+		if err != nil {
+			return nil, nil, "", util.ProblemDetailsSystemFailure(err.Error())
+		}
+		modified, rewriteProblem := rewrite(original)
+		if rewriteProblem != nil {
+			return nil, nil, "", rewriteProblem
+		}
+		newValue = make(map[string]interface{})
+		if err = json.Unmarshal(modified, &newValue); err != nil {
+			return nil, nil, "", util.ProblemDetailsModifyNotAllowed("")
+		}
+		if problemDetails = validateDocument(collName, newValue, filter); problemDetails != nil {
+			return nil, nil, "", problemDetails
+		}
+
+		collClient, ok := db.(collectionDBInterface)
+		if !ok {
+			return nil, nil, "", util.ProblemDetailsSystemFailure("DB client does not support versioned writes")
+		}
+		replacement := make(map[string]interface{}, len(newValue)+1)
+		for key, value := range newValue {
+			replacement[key] = value
+		}
+		replacement[documentRevisionField] = revision + 1
+		casFilter := bson.M{documentRevisionField: revisionFilter(revision)}
+		for key, value := range filter {
+			casFilter[key] = value
+		}
+		result, err := collClient.GetCollection(collName).ReplaceOne(context.TODO(), casFilter, replacement)
+		invalidateCachedDocuments(db, collName, filter)
+		if err != nil {
+			logger.DataRepoLog.Errorf("replace %s: %v", collName, err)
+			return nil, nil, "", dbProblemDetails(err)
+		}
+		if result.MatchedCount == 1 {
+			recordDocumentVersion(db, collName, origValue, revision, models.ChangeType_REPLACE, requestActor(header))
+			return origValue, newValue, revisionETag(revision + 1), nil
+		}
+		if header.Get("If-Match") != "" {
+			return nil, nil, "", util.ProblemDetailsPreconditionFailed("resource changed concurrently")
+		}
+		logger.DataRepoLog.Infof("%s changed concurrently, retrying patch (attempt %d)", collName, attempt)
+	}
+	return nil, nil, "", util.ProblemDetailsConflict(
+		fmt.Sprintf("concurrent updates after %d attempts", documentMaxAttempts))
+}
+
+// putVersionedDocument stores a document and bumps its revision, honoring the
+// preconditions of the request like patchVersionedDocument.
+func putVersionedDocument(db DBInterface, collName string, filter bson.M, putData map[string]interface{},
+	header http.Header,
+) (etag string, problemDetails *models.ProblemDetails) {
+	collClient, ok := db.(collectionDBInterface)
+	if !ok {
+		return "", util.ProblemDetailsSystemFailure("DB client does not support versioned writes")
+	}
+
+	for attempt := 1; attempt <= documentMaxAttempts; attempt++ {
+		origValue, err := uncached(db).RestfulAPIGetOne(collName, filter)
+		if err != nil {
+			logger.DataRepoLog.Warnln(err)
+			return "", dbProblemDetails(err)
+		}
+		if problemDetails = writePrecondition(header, origValue); problemDetails != nil {
+			return "", problemDetails
+		}
+
+		revision := documentRevision(origValue)
+		setData := make(bson.M, len(putData)+1)
+		for key, value := range putData {
+			setData[key] = value
+		}
+		setData[documentRevisionField] = revision + 1
+		casFilter := bson.M{documentRevisionField: revisionFilter(revision)}
+		for key, value := range filter {
+			casFilter[key] = value
+		}
+		result, err := collClient.GetCollection(collName).UpdateOne(context.TODO(), casFilter,
+			bson.M{"$set": setData}, options.Update().SetUpsert(origValue == nil))
+		invalidateCachedDocuments(db, collName, filter)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			logger.DataRepoLog.Errorf("put %s: %v", collName, err)
+			return "", dbProblemDetails(err)
+		}
+		if err == nil && (result.MatchedCount == 1 || result.UpsertedCount == 1) {
+			recordDocumentVersion(db, collName, origValue, revision, models.ChangeType_REPLACE, requestActor(header))
+			return revisionETag(revision + 1), nil
+		}
+		if header.Get("If-Match") != "" {
+			return "", util.ProblemDetailsPreconditionFailed("resource changed concurrently")
+		}
+		logger.DataRepoLog.Infof("%s changed concurrently, retrying put (attempt %d)", collName, attempt)
+	}
+	return "", util.ProblemDetailsConflict(fmt.Sprintf("concurrent updates after %d attempts", documentMaxAttempts))
+}
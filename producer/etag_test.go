@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR document ETags and conditional requests
+ */
+
+package producer
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestEtagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"3"`, `"3"`, false))
+	assert.True(t, etagMatches(`"1", "3"`, `"3"`, false))
+	assert.True(t, etagMatches(`*`, `"3"`, false))
+	assert.False(t, etagMatches(`"4"`, `"3"`, false))
+	assert.False(t, etagMatches(`W/"3"`, `"3"`, false))
+	assert.True(t, etagMatches(`W/"3"`, `"3"`, true))
+}
+
+func TestWritePrecondition(t *testing.T) {
+	doc := map[string]interface{}{"ueId": "imsi-1", documentRevisionField: int64(3)}
+
+	assert.Nil(t, writePrecondition(http.Header{}, doc))
+	assert.Nil(t, writePrecondition(http.Header{"If-Match": {`"3"`}}, doc))
+	pd := writePrecondition(http.Header{"If-Match": {`"2"`}}, doc)
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusPreconditionFailed), pd.Status)
+	}
+	assert.NotNil(t, writePrecondition(http.Header{"If-Match": {"*"}}, nil))
+	assert.NotNil(t, writePrecondition(http.Header{"If-None-Match": {"*"}}, doc))
+	assert.Nil(t, writePrecondition(http.Header{"If-None-Match": {"*"}}, nil))
+}
+
+func TestDocumentResponse(t *testing.T) {
+	rsp := documentResponse(http.Header{}, map[string]interface{}{"ueId": "imsi-1", documentRevisionField: int32(2)})
+	assert.Equal(t, http.StatusOK, rsp.Status)
+	assert.Equal(t, `"2"`, rsp.Header.Get("ETag"))
+	assert.Equal(t, map[string]interface{}{"ueId": "imsi-1"}, rsp.Body)
+
+	rsp = documentResponse(http.Header{"If-None-Match": {`"2"`}}, map[string]interface{}{documentRevisionField: int64(2)})
+	assert.Equal(t, http.StatusNotModified, rsp.Status)
+	assert.Nil(t, rsp.Body)
+
+	// documents never written with a revision are tagged by their content
+	rsp = documentResponse(http.Header{}, map[string]interface{}{"ueId": "imsi-1"})
+	etag := rsp.Header.Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `"0-`))
+	assert.Equal(t, etag, documentETag(map[string]interface{}{"_id": "id", "ueId": "imsi-1"}))
+	assert.NotEqual(t, etag, documentETag(map[string]interface{}{"ueId": "imsi-2"}))
+}
+
+// racingDB loses every compare-and-swap, as if another writer always changed
+// the document in between.
+type racingDB struct {
+	*filteringDB
+	mt *mtest.T
+}
+
+func (db *racingDB) GetCollection(collName string) *mongo.Collection {
+	db.mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+	return db.mt.DB.Collection(collName)
+}
+
+func TestVersionedWritesRetriesExhausted(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("conflict", func(mt *mtest.T) {
+		db := &racingDB{filteringDB: &filteringDB{docs: map[string][]map[string]interface{}{
+			SUBSCDATA_PROVISIONED_AMDATA: {{"ueId": "imsi-1", "servingPlmnId": "20893", documentRevisionField: 1}},
+		}}, mt: mt}
+		filter := bson.M{"ueId": "imsi-1", "servingPlmnId": "20893"}
+
+		_, pd := putVersionedDocument(db, SUBSCDATA_PROVISIONED_AMDATA, filter,
+			map[string]interface{}{"ueId": "imsi-1", "servingPlmnId": "20893"}, http.Header{})
+		if assert.NotNil(mt, pd) {
+			assert.Equal(mt, int32(http.StatusConflict), pd.Status)
+		}
+
+		_, _, _, pd = rewriteVersionedDocument(db, SUBSCDATA_PROVISIONED_AMDATA, filter, http.Header{},
+			func(original []byte) ([]byte, *models.ProblemDetails) { return original, nil })
+		if assert.NotNil(mt, pd) {
+			assert.Equal(mt, int32(http.StatusConflict), pd.Status)
+		}
+	})
+}
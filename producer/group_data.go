@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GroupIdentifiers maps an external group identifier to the internal one and
+// lists the members of the group.
+type GroupIdentifiers struct {
+	ExtGroupId string `json:"extGroupId,omitempty" bson:"extGroupId"`
+	IntGroupId string `json:"intGroupId,omitempty" bson:"intGroupId"`
+	UeIdList   []UeId `json:"ueIdList,omitempty" bson:"ueIdList"`
+}
+
+type UeId struct {
+	Supi     string   `json:"supi" bson:"supi"`
+	GpsiList []string `json:"gpsiList,omitempty" bson:"gpsiList"`
+}
+
+var (
+	// ExtGroupId and GroupId of TS 29.503 and TS 29.571
+	extGroupIdPattern = regexp.MustCompile(`^extgroupid-[^@]+@[^@]+$`)
+	intGroupIdPattern = regexp.MustCompile(`^[A-Fa-f0-9]{8}-[0-9]{3}-[0-9]{2,3}-([A-Fa-f0-9][A-Fa-f0-9]){1,10}$`)
+)
+
+// CreateGroupDataIndexes creates the indexes to look up the groups by their
+// identifiers and by their members.
+func CreateGroupDataIndexes() {
+	collClient, ok := uncached(CommonDBClient).(collectionDBInterface)
+	if !ok {
+		return
+	}
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "extGroupId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "intGroupId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "ueIdList.supi", Value: 1}}},
+		{Keys: bson.D{{Key: "ueIdList.gpsiList", Value: 1}}},
+	}
+	_, err := collClient.GetCollection(SUBSCDATA_GROUPDATA).Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		logger.DataRepoLog.Warnf("create indexes of %s: %v", SUBSCDATA_GROUPDATA, err)
+	}
+}
+
+func validateGroupIdentifiers(groupIdentifiers *GroupIdentifiers) error {
+	if !extGroupIdPattern.MatchString(groupIdentifiers.ExtGroupId) {
+		return fmt.Errorf("invalid extGroupId %q", groupIdentifiers.ExtGroupId)
+	}
+	if !intGroupIdPattern.MatchString(groupIdentifiers.IntGroupId) {
+		return fmt.Errorf("invalid intGroupId %q", groupIdentifiers.IntGroupId)
+	}
+	for _, ueId := range groupIdentifiers.UeIdList {
+		if ueId.Supi == "" {
+			return fmt.Errorf("ueIdList: supi is missing")
+		}
+	}
+	return nil
+}
+
+// groupMemberFilter selects the groups which ueId, a SUPI or a GPSI, is a
+// member of.
+func groupMemberFilter(ueId string) bson.M {
+	return bson.M{"$or": []bson.M{{"ueIdList.supi": ueId}, {"ueIdList.gpsiList": ueId}}}
+}
+
+func HandleQueryGroupIdentifiers(request *httpwrapper.Request) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle QueryGroupIdentifiers: queryParams=%#v", request.Query)
+
+	response, problemDetails := QueryGroupIdentifiersProcedure(request.Query)
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("get", "group-identifiers", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("get", "group-identifiers", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, response)
+}
+
+// QueryGroupIdentifiersProcedure returns the group identified by the
+// ext-group-id or int-group-id query parameter, or the groups of the ue-id
+// member. The members are only listed with ue-id-ind.
+func QueryGroupIdentifiersProcedure(queryParams url.Values) (interface{}, *models.ProblemDetails) {
+	filter := bson.M{}
+	if extGroupId := queryParams.Get("ext-group-id"); extGroupId != "" {
+		filter["extGroupId"] = extGroupId
+	}
+	if intGroupId := queryParams.Get("int-group-id"); intGroupId != "" {
+		filter["intGroupId"] = intGroupId
+	}
+	ueId := queryParams.Get("ue-id")
+	if len(filter) == 0 && ueId == "" {
+		return nil, util.ProblemDetailsMalformedReqSyntax("one of ext-group-id, int-group-id or ue-id is required")
+	}
+	ueIdInd := false
+	if value := queryParams.Get("ue-id-ind"); value != "" {
+		var err error
+		if ueIdInd, err = parseQueryBool(value); err != nil {
+			return nil, util.ProblemDetailsMalformedReqSyntax("ue-id-ind: " + err.Error())
+		}
+	}
+
+	if len(filter) == 0 {
+		docs, err := CommonDBClient.RestfulAPIGetMany(SUBSCDATA_GROUPDATA, groupMemberFilter(ueId))
+		if err != nil {
+			logger.DataRepoLog.Warnln(err)
+			return nil, dbProblemDetails(err)
+		}
+		groups := make([]GroupIdentifiers, 0, len(docs))
+		for _, doc := range docs {
+			group, err := decodeGroupIdentifiers(doc, ueIdInd)
+			if err != nil {
+				return nil, util.ProblemDetailsSystemFailure(err.Error())
+			}
+			groups = append(groups, *group)
+		}
+		return groups, nil
+	}
+
+	if ueId != "" {
+		filter = bson.M{"$and": []bson.M{filter, groupMemberFilter(ueId)}}
+	}
+	doc, err := CommonDBClient.RestfulAPIGetOne(SUBSCDATA_GROUPDATA, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		return nil, dbProblemDetails(err)
+	}
+	if doc == nil {
+		return nil, util.ProblemDetailsNotFound("GROUP_IDENTIFIER_NOT_FOUND")
+	}
+	group, err := decodeGroupIdentifiers(doc, ueIdInd)
+	if err != nil {
+		return nil, util.ProblemDetailsSystemFailure(err.Error())
+	}
+	return group, nil
+}
+
+func decodeGroupIdentifiers(doc map[string]interface{}, ueIdInd bool) (*GroupIdentifiers, error) {
+	var group GroupIdentifiers
+	if err := json.Unmarshal(util.MapToByte(doc), &group); err != nil {
+		logger.DataRepoLog.Warnf("group data %v: %v", doc["extGroupId"], err)
+		return nil, fmt.Errorf("malformed group data %v", doc["extGroupId"])
+	}
+	if !ueIdInd {
+		group.UeIdList = nil
+	}
+	return &group, nil
+}
+
+func HandleGetGroupData(request *httpwrapper.Request) *httpwrapper.Response {
+	extGroupId := request.Params["extGroupId"]
+	logger.DataRepoLog.Infof("handle GetGroupData: extGroupId=%q", extGroupId)
+
+	data, problemDetails := getDataFromDB(SUBSCDATA_GROUPDATA, bson.M{"extGroupId": extGroupId})
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("get", "group-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("get", "group-data", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, data)
+}
+
+// HandlePutGroupData creates or replaces the group extGroupId with its
+// members.
+func HandlePutGroupData(request *httpwrapper.Request) *httpwrapper.Response {
+	extGroupId := request.Params["extGroupId"]
+	groupIdentifiers := request.Body.(GroupIdentifiers)
+	logger.DataRepoLog.Infof("handle PutGroupData: extGroupId=%q", extGroupId)
+
+	if groupIdentifiers.ExtGroupId == "" {
+		groupIdentifiers.ExtGroupId = extGroupId
+	}
+	if groupIdentifiers.ExtGroupId != extGroupId {
+		pd := util.ProblemDetailsMalformedReqSyntax("extGroupId does not match the resource")
+		stats.IncrementUdrSubscriptionDataStats("update", "group-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := validateGroupIdentifiers(&groupIdentifiers); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("update", "group-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	isExisted, err := CommonDBClient.RestfulAPIPutOne(SUBSCDATA_GROUPDATA, bson.M{"extGroupId": extGroupId},
+		util.ToBsonM(groupIdentifiers))
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("update", "group-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrSubscriptionDataStats("update", "group-data", "SUCCESS")
+	if isExisted {
+		return httpwrapper.NewResponse(http.StatusOK, nil, &groupIdentifiers)
+	}
+	return httpwrapper.NewResponse(http.StatusCreated, nil, &groupIdentifiers)
+}
+
+func HandleDeleteGroupData(request *httpwrapper.Request) *httpwrapper.Response {
+	extGroupId := request.Params["extGroupId"]
+	logger.DataRepoLog.Infof("handle DeleteGroupData: extGroupId=%q", extGroupId)
+
+	problemDetails := deleteExistingDocument(SUBSCDATA_GROUPDATA, bson.M{"extGroupId": extGroupId})
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("delete", "group-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("delete", "group-data", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
+
+// ueGroupIds returns the external and internal identifiers of the groups of
+// ueId.
+func ueGroupIds(ueId string) []string {
+	docs, err := CommonDBClient.RestfulAPIGetMany(SUBSCDATA_GROUPDATA, groupMemberFilter(ueId))
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		return nil
+	}
+	var groupIds []string
+	for _, doc := range docs {
+		for _, key := range []string{"extGroupId", "intGroupId"} {
+			if groupId, ok := doc[key].(string); ok && groupId != "" {
+				groupIds = append(groupIds, groupId)
+			}
+		}
+	}
+	return groupIds
+}
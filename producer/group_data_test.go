@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR group data
+ */
+
+package producer
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	udr_context "github.com/omec-project/udr/context"
+	"github.com/omec-project/util/httpwrapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryGroupIdentifiers(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &filteringDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_GROUPDATA: {{
+			"_id":        "x",
+			"extGroupId": "extgroupid-lan@example.com",
+			"intGroupId": "20893001-001-01-01",
+			"ueIdList":   []interface{}{map[string]interface{}{"supi": "imsi-1", "gpsiList": []string{"msisdn-1"}}},
+		}},
+	}}
+
+	_, pd := QueryGroupIdentifiersProcedure(url.Values{"ue-id-ind": {"true"}})
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusBadRequest), pd.Status)
+	}
+
+	rsp, pd := QueryGroupIdentifiersProcedure(url.Values{"ext-group-id": {"extgroupid-lan@example.com"}})
+	assert.Nil(t, pd)
+	assert.Equal(t, &GroupIdentifiers{ExtGroupId: "extgroupid-lan@example.com", IntGroupId: "20893001-001-01-01"}, rsp)
+
+	rsp, pd = QueryGroupIdentifiersProcedure(url.Values{"ue-id": {"msisdn-1"}, "ue-id-ind": {"true"}})
+	assert.Nil(t, pd)
+	assert.Equal(t, []GroupIdentifiers{{
+		ExtGroupId: "extgroupid-lan@example.com",
+		IntGroupId: "20893001-001-01-01",
+		UeIdList:   []UeId{{Supi: "imsi-1", GpsiList: []string{"msisdn-1"}}},
+	}}, rsp)
+
+	// a UE outside of the group
+	rsp, pd = QueryGroupIdentifiersProcedure(url.Values{"ue-id": {"imsi-2"}})
+	assert.Nil(t, pd)
+	assert.Equal(t, []GroupIdentifiers{}, rsp)
+	_, pd = QueryGroupIdentifiersProcedure(url.Values{
+		"ext-group-id": {"extgroupid-lan@example.com"}, "ue-id": {"msisdn-2"},
+	})
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusNotFound), pd.Status)
+	}
+}
+
+func TestPutGroupData(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &filteringDB{docs: map[string][]map[string]interface{}{}}
+	group := func(body interface{}) *httpwrapper.Request {
+		return dbRequest(body, map[string]string{"extGroupId": "extgroupid-lan@example.com"}, nil)
+	}
+
+	rsp := HandlePutGroupData(group(GroupIdentifiers{
+		ExtGroupId: "extgroupid-wan@example.com", IntGroupId: "20893001-001-01-01",
+	}))
+	assert.Equal(t, http.StatusBadRequest, rsp.Status)
+	rsp = HandlePutGroupData(group(GroupIdentifiers{IntGroupId: "20893001-001-01-01"}))
+	assert.Equal(t, http.StatusCreated, rsp.Status)
+	assert.Equal(t, &GroupIdentifiers{ExtGroupId: "extgroupid-lan@example.com", IntGroupId: "20893001-001-01-01"},
+		rsp.Body)
+	rsp = HandleGetGroupData(group(nil))
+	assert.Equal(t, http.StatusOK, rsp.Status)
+}
+
+func TestValidateGroupIdentifiers(t *testing.T) {
+	group := &GroupIdentifiers{ExtGroupId: "extgroupid-lan@example.com", IntGroupId: "20893001-001-01-01"}
+	assert.NoError(t, validateGroupIdentifiers(group))
+	group.UeIdList = []UeId{{GpsiList: []string{"msisdn-1"}}}
+	assert.Error(t, validateGroupIdentifiers(group))
+	assert.Error(t, validateGroupIdentifiers(&GroupIdentifiers{ExtGroupId: "lan", IntGroupId: "20893001-001-01-01"}))
+	assert.Error(t, validateGroupIdentifiers(&GroupIdentifiers{ExtGroupId: "extgroupid-lan@example.com"}))
+}
+
+func TestQueryEeSubscriptionsOfGroupMember(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &filteringDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_GROUPDATA: {{
+			"extGroupId": "extgroupid-lan@example.com",
+			"intGroupId": "20893001-001-01-01",
+			"ueIdList":   []interface{}{map[string]interface{}{"supi": "imsi-1"}},
+		}},
+	}}
+	udrSelf := udr_context.UDR_Self()
+	defer udrSelf.UEGroupCollection.Delete("extgroupid-lan@example.com")
+	udrSelf.UEGroupCollection.Store("extgroupid-lan@example.com", &udr_context.UEGroupSubsData{
+		EeSubscriptions: map[string]*models.EeSubscription{"1": {CallbackReference: "http://nef/group"}},
+	})
+
+	rsp, pd := QueryeesubscriptionsProcedure("imsi-1")
+	assert.Nil(t, pd)
+	assert.Equal(t, []models.EeSubscription{{CallbackReference: "http://nef/group"}}, rsp)
+
+	// the subscriptions of the group do not apply to a UE outside of it
+	_, pd = QueryeesubscriptionsProcedure("imsi-2")
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusNotFound), pd.Status)
+	}
+}
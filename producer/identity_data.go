@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IdentityData lists the identities of a UE, stored one document per SUPI.
+// ImpuList holds the IMS public user identities, which TS 29.505 does not
+// return with the identity data but which identify the UE as well.
+type IdentityData struct {
+	SupiList []string `json:"supiList,omitempty" bson:"supiList"`
+	GpsiList []string `json:"gpsiList,omitempty" bson:"gpsiList"`
+	ImpuList []string `json:"impuList,omitempty" bson:"impuList"`
+}
+
+var (
+	// Supi, Gpsi and the IMPU form of VarUeId of TS 29.571 and TS 29.505
+	supiPattern = regexp.MustCompile(`^(imsi-[0-9]{5,15}|nai-.+|gci-.+|gli-.+)$`)
+	gpsiPattern = regexp.MustCompile(`^(msisdn-[0-9]{5,15}|extid-[^@]+@[^@]+)$`)
+	impuPattern = regexp.MustCompile(`^impu-.+$`)
+)
+
+// CreateIdentityDataIndexes creates the indexes to resolve the GPSIs and
+// IMPUs to the SUPI. They are unique so that an identity is never held by
+// two UEs.
+func CreateIdentityDataIndexes() {
+	collClient, ok := uncached(CommonDBClient).(collectionDBInterface)
+	if !ok {
+		return
+	}
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "ueId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// the UEs without GPSIs or IMPUs, whose lists are empty, are left out
+		{
+			Keys: bson.D{{Key: "gpsiList", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"gpsiList": bson.M{"$type": "string"}}),
+		},
+		{
+			Keys: bson.D{{Key: "impuList", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"impuList": bson.M{"$type": "string"}}),
+		},
+	}
+	_, err := collClient.GetCollection(SUBSCDATA_IDENTITYDATA).Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		logger.DataRepoLog.Warnf("create indexes of %s: %v", SUBSCDATA_IDENTITYDATA, err)
+	}
+}
+
+// ResolveUeId returns the SUPI of the UE identified by ueId, a SUPI, a GPSI
+// or an IMPU. A ueId which is not in the identity data is returned as is,
+// the subscription data of the UE may be stored under it.
+func ResolveUeId(ueId string) (string, error) {
+	var filter bson.M
+	switch {
+	case gpsiPattern.MatchString(ueId):
+		filter = bson.M{"gpsiList": ueId}
+	case impuPattern.MatchString(ueId):
+		filter = bson.M{"impuList": ueId}
+	default:
+		return ueId, nil
+	}
+	identityData, err := CommonDBClient.RestfulAPIGetOne(SUBSCDATA_IDENTITYDATA, filter)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", ueId, err)
+	}
+	if supi, ok := identityData["ueId"].(string); ok && supi != "" {
+		return supi, nil
+	}
+	return ueId, nil
+}
+
+func validateIdentityData(supi string, identityData *IdentityData) error {
+	for _, id := range identityData.SupiList {
+		if id != supi {
+			return fmt.Errorf("supiList: %q is not the SUPI of the resource", id)
+		}
+	}
+	for _, gpsi := range identityData.GpsiList {
+		if !gpsiPattern.MatchString(gpsi) {
+			return fmt.Errorf("gpsiList: invalid GPSI %q", gpsi)
+		}
+	}
+	for _, impu := range identityData.ImpuList {
+		if !impuPattern.MatchString(impu) {
+			return fmt.Errorf("impuList: invalid IMPU %q", impu)
+		}
+	}
+	return nil
+}
+
+// HandlePutIdentityData creates or replaces the identities of the UE of
+// SUPI ueId. The GPSIs and IMPUs are taken away from the UEs which held them
+// before, an identity moves to its new UE.
+func HandlePutIdentityData(request *httpwrapper.Request) *httpwrapper.Response {
+	ueId := request.Params["ueId"]
+	identityData := request.Body.(IdentityData)
+	logger.DataRepoLog.Infof("handle PutIdentityData: ueId=%q", ueId)
+
+	if !supiPattern.MatchString(ueId) {
+		pd := util.ProblemDetailsMalformedReqSyntax(fmt.Sprintf("%q is not a SUPI", ueId))
+		stats.IncrementUdrSubscriptionDataStats("update", "identity-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := validateIdentityData(ueId, &identityData); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("update", "identity-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	identityData.SupiList = []string{ueId}
+
+	restore, err := releaseIdentities(ueId, &identityData)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("update", "identity-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	// the lists are always written since the put only sets the attributes
+	putData := bson.M{
+		"ueId":     ueId,
+		"supiList": identityData.SupiList,
+		"gpsiList": withoutIdentities(identityData.GpsiList, nil),
+		"impuList": withoutIdentities(identityData.ImpuList, nil),
+	}
+	isExisted, err := CommonDBClient.RestfulAPIPutOne(SUBSCDATA_IDENTITYDATA, bson.M{"ueId": ueId}, putData)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		restore()
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("update", "identity-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrSubscriptionDataStats("update", "identity-data", "SUCCESS")
+	if isExisted {
+		return httpwrapper.NewResponse(http.StatusOK, nil, &identityData)
+	}
+	return httpwrapper.NewResponse(http.StatusCreated, nil, &identityData)
+}
+
+// releaseIdentities removes the GPSIs and IMPUs of identityData from the
+// identity data of the UEs other than supi. The returned restore gives them
+// back, for when the identities cannot be stored under supi; they are given
+// back already if the release fails.
+func releaseIdentities(supi string, identityData *IdentityData) (restore func(), err error) {
+	var released []map[string]interface{}
+	restore = func() {
+		for _, holder := range released {
+			holderSupi, _ := holder["ueId"].(string)
+			putData := bson.M{"gpsiList": holder["gpsiList"], "impuList": holder["impuList"]}
+			if _, err := CommonDBClient.RestfulAPIPutOne(SUBSCDATA_IDENTITYDATA, bson.M{"ueId": holderSupi},
+				putData); err != nil {
+				logger.DataRepoLog.Errorf("identities of %s cannot be given back: %v", holderSupi, err)
+			}
+		}
+	}
+
+	var held []bson.M
+	if len(identityData.GpsiList) > 0 {
+		held = append(held, bson.M{"gpsiList": bson.M{"$in": identityData.GpsiList}})
+	}
+	if len(identityData.ImpuList) > 0 {
+		held = append(held, bson.M{"impuList": bson.M{"$in": identityData.ImpuList}})
+	}
+	if len(held) == 0 {
+		return restore, nil
+	}
+	filter := bson.M{"ueId": bson.M{"$ne": supi}, "$or": held}
+	holders, err := CommonDBClient.RestfulAPIGetMany(SUBSCDATA_IDENTITYDATA, filter)
+	if err != nil {
+		return nil, fmt.Errorf("identities of %s: %w", supi, err)
+	}
+	for _, holder := range holders {
+		holderSupi, _ := holder["ueId"].(string)
+		logger.DataRepoLog.Infof("identities of %s move to %s", holderSupi, supi)
+		putData := bson.M{
+			"gpsiList": withoutIdentities(holder["gpsiList"], identityData.GpsiList),
+			"impuList": withoutIdentities(holder["impuList"], identityData.ImpuList),
+		}
+		if _, err := CommonDBClient.RestfulAPIPutOne(SUBSCDATA_IDENTITYDATA, bson.M{"ueId": holderSupi},
+			putData); err != nil {
+			restore()
+			return nil, fmt.Errorf("identities of %s: %w", holderSupi, err)
+		}
+		released = append(released, holder)
+	}
+	return restore, nil
+}
+
+// withoutIdentities returns the identities of list, a document attribute,
+// which are not in released, as a list never nil.
+func withoutIdentities(list interface{}, released []string) []string {
+	kept := []string{}
+	switch ids := list.(type) {
+	case []string:
+		for _, id := range ids {
+			if !slices.Contains(released, id) {
+				kept = append(kept, id)
+			}
+		}
+	case []interface{}:
+		for _, id := range ids {
+			if id, ok := id.(string); ok && !slices.Contains(released, id) {
+				kept = append(kept, id)
+			}
+		}
+	case bson.A:
+		return withoutIdentities([]interface{}(ids), released)
+	}
+	return kept
+}
+
+func HandleDeleteIdentityData(request *httpwrapper.Request) *httpwrapper.Response {
+	ueId := request.Params["ueId"]
+	logger.DataRepoLog.Infof("handle DeleteIdentityData: ueId=%q", ueId)
+
+	problemDetails := deleteExistingDocument(SUBSCDATA_IDENTITYDATA, bson.M{"ueId": ueId})
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("delete", "identity-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("delete", "identity-data", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
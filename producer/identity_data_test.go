@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR identity data and UE identity resolution
+ */
+
+package producer
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	udr_context "github.com/omec-project/udr/context"
+	"github.com/omec-project/util/httpwrapper"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestResolveUeId(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &countingDB{fakeDB: fakeDB{docs: map[string][]map[string]interface{}{}}}
+	CommonDBClient = db
+
+	supi, err := ResolveUeId("imsi-208930000000001")
+	assert.NoError(t, err)
+	assert.Equal(t, "imsi-208930000000001", supi)
+	assert.Equal(t, 0, db.reads)
+
+	// unknown GPSIs are kept, the data may be stored under them
+	supi, err = ResolveUeId("msisdn-33612345678")
+	assert.NoError(t, err)
+	assert.Equal(t, "msisdn-33612345678", supi)
+
+	db.docs[SUBSCDATA_IDENTITYDATA] = []map[string]interface{}{
+		{"ueId": "imsi-208930000000001", "gpsiList": []interface{}{"msisdn-33612345678"}},
+	}
+	for _, ueId := range []string{"msisdn-33612345678", "extid-ue1@example.com", "impu-sip:ue1@example.com"} {
+		supi, err = ResolveUeId(ueId)
+		assert.NoError(t, err)
+		assert.Equal(t, "imsi-208930000000001", supi)
+	}
+	assert.Equal(t, 4, db.reads)
+}
+
+// identityDB records the filters of the queries for many documents and
+// stores the last document put.
+type identityDB struct {
+	filterRecordingDB
+}
+
+func (db *identityDB) RestfulAPIPutOne(collName string, filter bson.M, putData map[string]interface{}) (bool, error) {
+	existed := len(db.docs[collName]) > 0
+	db.docs[collName] = []map[string]interface{}{putData}
+	return existed, nil
+}
+
+func putIdentityData(ueId string, identityData IdentityData) *httpwrapper.Response {
+	return HandlePutIdentityData(dbRequest(identityData, map[string]string{"ueId": ueId}, nil))
+}
+
+func TestPutIdentityData(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &identityDB{filterRecordingDB{fakeDB: fakeDB{docs: map[string][]map[string]interface{}{}}}}
+	CommonDBClient = db
+
+	rsp := putIdentityData("msisdn-33612345678", IdentityData{})
+	assert.Equal(t, http.StatusBadRequest, rsp.Status)
+	rsp = putIdentityData("imsi-208930000000001", IdentityData{GpsiList: []string{"33612345678"}})
+	assert.Equal(t, http.StatusBadRequest, rsp.Status)
+	rsp = putIdentityData("imsi-208930000000001", IdentityData{SupiList: []string{"imsi-208930000000002"}})
+	assert.Equal(t, http.StatusBadRequest, rsp.Status)
+
+	rsp = putIdentityData("imsi-208930000000001", IdentityData{GpsiList: []string{"msisdn-33612345678"}})
+	assert.Equal(t, http.StatusCreated, rsp.Status)
+	assert.Equal(t, &IdentityData{
+		SupiList: []string{"imsi-208930000000001"}, GpsiList: []string{"msisdn-33612345678"},
+	}, rsp.Body)
+	assert.Equal(t, []bson.M{{
+		"ueId": bson.M{"$ne": "imsi-208930000000001"},
+		"$or":  []bson.M{{"gpsiList": bson.M{"$in": []string{"msisdn-33612345678"}}}},
+	}}, db.filters)
+	assert.Equal(t, []string{"msisdn-33612345678"}, db.docs[SUBSCDATA_IDENTITYDATA][0]["gpsiList"])
+}
+
+func TestWithoutIdentities(t *testing.T) {
+	assert.Equal(t, []string{"msisdn-2"},
+		withoutIdentities(bson.A{"msisdn-1", "msisdn-2"}, []string{"msisdn-1"}))
+	assert.Equal(t, []string{}, withoutIdentities([]string{"msisdn-1"}, []string{"msisdn-1"}))
+	assert.Equal(t, []string{}, withoutIdentities(nil, nil))
+}
+
+// identityPutFailingDB fails to store the identity data of one UE.
+type identityPutFailingDB struct {
+	*filteringDB
+	failingUeId string
+}
+
+func (db *identityPutFailingDB) RestfulAPIPutOne(collName string, filter bson.M, putData map[string]interface{},
+) (bool, error) {
+	if filter["ueId"] == db.failingUeId {
+		return false, errors.New("connection reset")
+	}
+	return db.filteringDB.RestfulAPIPutOne(collName, filter, putData)
+}
+
+func TestPutIdentityDataGivesBackReleasedIdentities(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &identityPutFailingDB{filteringDB: &filteringDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_IDENTITYDATA: {{
+			"ueId": "imsi-208930000000001", "gpsiList": []string{"msisdn-33612345678", "msisdn-33612345679"},
+			"impuList": []string{},
+		}},
+	}}, failingUeId: "imsi-208930000000002"}
+	CommonDBClient = db
+
+	rsp := putIdentityData("imsi-208930000000002", IdentityData{GpsiList: []string{"msisdn-33612345678"}})
+	assert.Equal(t, http.StatusInternalServerError, rsp.Status)
+	holder, err := db.RestfulAPIGetOne(SUBSCDATA_IDENTITYDATA, bson.M{"ueId": "imsi-208930000000001"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"msisdn-33612345678", "msisdn-33612345679"}, holder["gpsiList"])
+}
+
+func TestSubscriptionDataSubscriptionByGpsi(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &filteringDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_IDENTITYDATA: {{"ueId": "imsi-208930000000001", "gpsiList": []interface{}{"msisdn-33612345678"}}},
+	}}
+	udrSelf := udr_context.UDR_Self()
+	defer udrSelf.Reset()
+
+	subscription := models.SubscriptionDataSubscriptions{UeId: "msisdn-33612345678", CallbackReference: "http://udm"}
+	rsp := HandlePostSubscriptionDataSubscriptions(&httpwrapper.Request{Body: subscription})
+	assert.Equal(t, http.StatusCreated, rsp.Status)
+	// the consumer gets its subscription back as sent
+	assert.Equal(t, subscription, rsp.Body)
+	// and is notified of the changes made under the SUPI
+	assert.Len(t, udrSelf.SubscriptionDataSubscribers("imsi-208930000000001", time.Now()), 1)
+}
+
+func TestPostSubscriptionDataSubscriptionsPastExpiry(t *testing.T) {
+	udrSelf := udr_context.UDR_Self()
+	defer udrSelf.Reset()
+
+	expiry := time.Now().Add(-time.Minute)
+	subscription := models.SubscriptionDataSubscriptions{
+		UeId: "imsi-208930000000001", CallbackReference: "http://udm", Expiry: &expiry,
+	}
+	rsp := HandlePostSubscriptionDataSubscriptions(&httpwrapper.Request{Body: subscription})
+	assert.Equal(t, http.StatusBadRequest, rsp.Status)
+	if pd, ok := rsp.Body.(*models.ProblemDetails); assert.True(t, ok) {
+		assert.Equal(t, "/expiry", pd.InvalidParams[0].Param)
+	}
+	assert.Empty(t, udrSelf.SubscriptionDataSubscribers("imsi-208930000000001", time.Now()))
+}
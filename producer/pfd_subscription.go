@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/omec-project/openapi/models"
+	udr_context "github.com/omec-project/udr/context"
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"github.com/omec-project/udr/producer/callback"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// notifyPfdChange notifies the subscribers to the PFDs of the application
+// of pfdChangeNotification.
+func notifyPfdChange(pfdChangeNotification models.PfdChangeNotification) {
+	filter := bson.M{"$or": []bson.M{
+		{"applicationIds": pfdChangeNotification.ApplicationId},
+		{"applicationIds": bson.M{"$in": []interface{}{nil, []string{}}}},
+	}}
+	subscriptions, err := CommonDBClient.RestfulAPIGetMany(APPDATA_PFD_SUBSC_DB_COLLECTION_NAME, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		return
+	}
+	for _, subscriptionDoc := range subscriptions {
+		var subscription models.PfdSubscription
+		if err := json.Unmarshal(util.MapToByte(subscriptionDoc), &subscription); err != nil {
+			logger.DataRepoLog.Warnf("PFD subscription %v: %v", subscriptionDoc["subsId"], err)
+			continue
+		}
+		go callback.SendPfdChangeNotification(subscription.NotifyUri,
+			[]models.PfdChangeNotification{pfdChangeNotification})
+	}
+}
+
+func HandleApplicationDataPfdsSubsToNotifyPost(pfdSubscription *models.PfdSubscription) *httpwrapper.Response {
+	logger.DataRepoLog.Infoln("handle ApplicationDataPfdsSubsToNotifyPost")
+
+	if pfdSubscription.NotifyUri == "" {
+		pd := util.ProblemDetailsMalformedReqSyntax("notifyUri is missing")
+		stats.IncrementUdrApplicationDataStats("create", "pfd-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	subsId := uuid.New().String()
+	data := util.ToBsonM(*pfdSubscription)
+	data["subsId"] = subsId
+	if _, err := CommonDBClient.RestfulAPIPutOne(APPDATA_PFD_SUBSC_DB_COLLECTION_NAME, bson.M{"subsId": subsId},
+		data); err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrApplicationDataStats("create", "pfd-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrApplicationDataStats("create", "pfd-subscription", "SUCCESS")
+
+	/* Contains the URI of the newly created resource, according
+	   to the structure: {apiRoot}/application-data/pfds/subs-to-notify/{subsId} */
+	headers := http.Header{}
+	headers.Set("Location", fmt.Sprintf("%s/application-data/pfds/subs-to-notify/%s",
+		udr_context.UDR_Self().GetIPv4GroupUri(udr_context.NUDR_DR), subsId))
+	return httpwrapper.NewResponse(http.StatusCreated, headers, pfdSubscription)
+}
+
+func HandleApplicationDataPfdsSubsToNotifySubsIdGet(subsId string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationDataPfdsSubsToNotifySubsIdGet: subsId=%q", subsId)
+
+	data, problemDetails := getDataFromDB(APPDATA_PFD_SUBSC_DB_COLLECTION_NAME, bson.M{"subsId": subsId})
+	if problemDetails != nil {
+		stats.IncrementUdrApplicationDataStats("get", "pfd-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	delete(data, "subsId")
+	stats.IncrementUdrApplicationDataStats("get", "pfd-subscription", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, data)
+}
+
+func HandleApplicationDataPfdsSubsToNotifySubsIdPut(subsId string,
+	pfdSubscription *models.PfdSubscription,
+) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationDataPfdsSubsToNotifySubsIdPut: subsId=%q", subsId)
+
+	if pfdSubscription.NotifyUri == "" {
+		pd := util.ProblemDetailsMalformedReqSyntax("notifyUri is missing")
+		stats.IncrementUdrApplicationDataStats("update", "pfd-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	filter := bson.M{"subsId": subsId}
+	if _, problemDetails := getDataFromDB(APPDATA_PFD_SUBSC_DB_COLLECTION_NAME, filter); problemDetails != nil {
+		stats.IncrementUdrApplicationDataStats("update", "pfd-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	data := util.ToBsonM(*pfdSubscription)
+	data["subsId"] = subsId
+	if _, err := CommonDBClient.RestfulAPIPutOne(APPDATA_PFD_SUBSC_DB_COLLECTION_NAME, filter, data); err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrApplicationDataStats("update", "pfd-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrApplicationDataStats("update", "pfd-subscription", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, pfdSubscription)
+}
+
+func HandleApplicationDataPfdsSubsToNotifySubsIdDelete(subsId string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ApplicationDataPfdsSubsToNotifySubsIdDelete: subsId=%q", subsId)
+
+	problemDetails := deleteExistingDocument(APPDATA_PFD_SUBSC_DB_COLLECTION_NAME, bson.M{"subsId": subsId})
+	if problemDetails != nil {
+		stats.IncrementUdrApplicationDataStats("delete", "pfd-subscription", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrApplicationDataStats("delete", "pfd-subscription", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR PFD data and PFD subscriptions
+ */
+
+package producer
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// filterRecordingDB records the filters of the queries for many documents.
+type filterRecordingDB struct {
+	fakeDB
+	filters []bson.M
+}
+
+func (db *filterRecordingDB) RestfulAPIGetMany(collName string, filter bson.M) ([]map[string]interface{}, error) {
+	db.filters = append(db.filters, filter)
+	return db.fakeDB.RestfulAPIGetMany(collName, filter)
+}
+
+func TestApplicationDataPfdsGet(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &filterRecordingDB{fakeDB: fakeDB{docs: map[string][]map[string]interface{}{
+		APPDATA_PFD_DB_COLLECTION_NAME: {{"_id": "x", "applicationId": "app1"}},
+	}}}
+	CommonDBClient = db
+
+	rsp := HandleApplicationDataPfdsGet([]string{"app1,app2", "app3"})
+	assert.Equal(t, http.StatusOK, rsp.Status)
+	assert.Equal(t, []map[string]interface{}{{"applicationId": "app1"}}, rsp.Body)
+	assert.Equal(t, []bson.M{{"applicationId": bson.M{"$in": []string{"app1", "app2", "app3"}}}}, db.filters)
+
+	db.filters = nil
+	HandleApplicationDataPfdsGet(nil)
+	assert.Equal(t, []bson.M{{}}, db.filters)
+}
+
+func TestApplicationDataPfdsAppIdPutCachingTime(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &filteringDB{docs: map[string][]map[string]interface{}{}}
+	CommonDBClient = db
+
+	// a body echoed back from a GET once the caching time passed is accepted
+	cachingTime := time.Now().Add(-time.Minute)
+	rsp := HandleApplicationDataPfdsAppIdPut("app1", &models.PfdDataForApp{CachingTime: &cachingTime})
+	assert.Equal(t, http.StatusCreated, rsp.Status)
+	if assert.Len(t, db.docs[APPDATA_PFD_DB_COLLECTION_NAME], 1) {
+		assert.Nil(t, db.docs[APPDATA_PFD_DB_COLLECTION_NAME][0]["cachingTime"])
+	}
+
+	cachingTime = time.Now().Add(time.Hour)
+	rsp = HandleApplicationDataPfdsAppIdPut("app1", &models.PfdDataForApp{CachingTime: &cachingTime})
+	assert.Equal(t, http.StatusOK, rsp.Status)
+	assert.NotNil(t, db.docs[APPDATA_PFD_DB_COLLECTION_NAME][0]["cachingTime"])
+}
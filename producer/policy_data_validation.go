@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"fmt"
+
+	"github.com/omec-project/openapi/models"
+)
+
+func validateSponsorConnectivityData(data *models.SponsorConnectivityData) error {
+	if len(data.AspIds) == 0 {
+		return fmt.Errorf("aspIds must not be empty")
+	}
+	for _, aspId := range data.AspIds {
+		if aspId == "" {
+			return fmt.Errorf("aspIds must not contain empty identifiers")
+		}
+	}
+	return nil
+}
+
+func validateUePolicySet(data *models.UePolicySet) error {
+	for sectionId, section := range data.UePolicySections {
+		if section.Upsi == "" {
+			return fmt.Errorf("uePolicySections[%s]: upsi is missing", sectionId)
+		}
+		if section.UePolicySectionInfo == "" {
+			return fmt.Errorf("uePolicySections[%s]: uePolicySectionInfo is missing", sectionId)
+		}
+	}
+	for _, upsi := range data.Upsis {
+		if upsi == "" {
+			return fmt.Errorf("upsis must not contain empty identifiers")
+		}
+	}
+	return nil
+}
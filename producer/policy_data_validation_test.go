@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR policy data writes
+ */
+
+package producer
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePolicyData(t *testing.T) {
+	assert.NoError(t, validateSponsorConnectivityData(&models.SponsorConnectivityData{AspIds: []string{"asp-1"}}))
+	assert.Error(t, validateSponsorConnectivityData(&models.SponsorConnectivityData{}))
+	assert.Error(t, validateSponsorConnectivityData(&models.SponsorConnectivityData{AspIds: []string{""}}))
+
+	assert.NoError(t, validateUePolicySet(&models.UePolicySet{Upsis: []string{"upsi-1"}}))
+	assert.Error(t, validateUePolicySet(&models.UePolicySet{
+		UePolicySections: map[string]models.UePolicySection{"1": {Upsi: "upsi-1"}},
+	}))
+
+	var data models.SponsorConnectivityData
+	assert.Error(t, decodeStrict([]byte(`{"aspIds": ["asp-1"], "unknown": 1}`), &data))
+	assert.NoError(t, decodeStrict([]byte(`{"aspIds": ["asp-1"]}`), &data))
+}
+
+func TestSponsorConnectivityDataPatch(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &countingDB{fakeDB: fakeDB{docs: map[string][]map[string]interface{}{}}}
+	CommonDBClient = db
+
+	pd := PolicyDataSponsorConnectivityDataSponsorIdPatchProcedure(POLICYDATA_SPONSORCONNECTIVITYDATA, "sponsor-1",
+		[]byte(`{"aspIds": ["asp-2"]}`))
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusNotFound), pd.Status)
+	}
+
+	db.docs[POLICYDATA_SPONSORCONNECTIVITYDATA] = []map[string]interface{}{
+		{"sponsorId": "sponsor-1", "aspIds": []interface{}{"asp-1"}},
+	}
+	pd = PolicyDataSponsorConnectivityDataSponsorIdPatchProcedure(POLICYDATA_SPONSORCONNECTIVITYDATA, "sponsor-1",
+		[]byte(`{"aspIds": null}`))
+	if assert.NotNil(t, pd, "the patched data must still be valid") {
+		assert.Equal(t, int32(http.StatusBadRequest), pd.Status)
+	}
+
+	pd = PolicyDataSponsorConnectivityDataSponsorIdPatchProcedure(POLICYDATA_SPONSORCONNECTIVITYDATA, "sponsor-1",
+		[]byte(`{"aspIds": ["asp-2"]}`))
+	assert.Nil(t, pd)
+	assert.Equal(t, []map[string]interface{}{{"sponsorId": "sponsor-1", "aspIds": []interface{}{"asp-2"}}},
+		db.docs[POLICYDATA_SPONSORCONNECTIVITYDATA])
+}
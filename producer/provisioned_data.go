@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// provisionedDataSetDecoder decodes the documents of one data set of the
+// provisioned-data resource.
+type provisionedDataSetDecoder struct {
+	Name   models.DataSetName
+	Many   bool
+	Decode func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error
+}
+
+var provisionedDataSetDecoders = []provisionedDataSetDecoder{
+	{
+		Name: models.DataSetName_AM,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp AccessAndMobilitySubscriptionData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.AmData = &tmp
+			return nil
+		},
+	},
+	{
+		Name: models.DataSetName_SMF_SEL,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp models.SmfSelectionSubscriptionData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.SmfSelData = &tmp
+			return nil
+		},
+	},
+	{
+		Name: models.DataSetName_SMS_SUB,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp models.SmsSubscriptionData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.SmsSubsData = &tmp
+			return nil
+		},
+	},
+	{
+		Name: models.DataSetName_SM,
+		Many: true,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp []models.SessionManagementSubscriptionData
+			if err := mapstructure.Decode(docs, &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.SmData = tmp
+			return nil
+		},
+	},
+	{
+		Name: models.DataSetName_TRACE,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp models.TraceData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.TraceData = &tmp
+			return nil
+		},
+	},
+	{
+		Name: models.DataSetName_SMS_MNG,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp models.SmsManagementSubscriptionData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.SmsMngData = &tmp
+			return nil
+		},
+	},
+	{
+		Name: DataSetName_LCS_PRIVACY,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp LcsPrivacyData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.LcsPrivacyData = &tmp
+			return nil
+		},
+	},
+	{
+		Name: DataSetName_LCS_MO,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp LcsMoData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.LcsMoData = &tmp
+			return nil
+		},
+	},
+	{
+		Name: DataSetName_LCS_BCA,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp LcsBroadcastAssistanceTypesData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.LcsBcaData = &tmp
+			return nil
+		},
+	},
+	{
+		Name: DataSetName_V2X,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp V2xSubscriptionData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.V2xData = &tmp
+			return nil
+		},
+	},
+	{
+		Name: DataSetName_PROSE,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp ProseSubscriptionData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.ProseData = &tmp
+			return nil
+		},
+	},
+	{
+		Name: DataSetName_5MBS,
+		Decode: func(docs []map[string]interface{}, provisionedDataSets *ProvisionedDataSets) error {
+			var tmp MbsSubscriptionData
+			if err := mapstructure.Decode(docs[0], &tmp); err != nil {
+				return err
+			}
+			provisionedDataSets.MbsData = &tmp
+			return nil
+		},
+	},
+}
+
+// decodeProvisionedDataSet decodes the documents of a data set. A malformed
+// document is reported as an error naming the data set, it never panics.
+func decodeProvisionedDataSet(dataSet provisionedDataSetDecoder, docs []map[string]interface{},
+	provisionedDataSets *ProvisionedDataSets,
+) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("decode %s data set: %v", dataSet.Name, r)
+		}
+	}()
+	if err := dataSet.Decode(docs, provisionedDataSets); err != nil {
+		return fmt.Errorf("decode %s data set: %w", dataSet.Name, err)
+	}
+	return nil
+}
+
+// ProvisionedDataResource is a data set of provisioned-data which can also
+// be retrieved on its own.
+type ProvisionedDataResource struct {
+	// Path of the resource under
+	// /subscription-data/{ueId}/{servingPlmnId}/provisioned-data
+	Path    string
+	DataSet models.DataSetName
+}
+
+var (
+	ProvisionedDataLcsPrivacy = &ProvisionedDataResource{Path: "lcs-privacy-data", DataSet: DataSetName_LCS_PRIVACY}
+	ProvisionedDataLcsMo      = &ProvisionedDataResource{Path: "lcs-mo-data", DataSet: DataSetName_LCS_MO}
+	ProvisionedDataLcsBca     = &ProvisionedDataResource{Path: "lcs-bca-data", DataSet: DataSetName_LCS_BCA}
+	ProvisionedDataV2x        = &ProvisionedDataResource{Path: "v2x-data", DataSet: DataSetName_V2X}
+	ProvisionedDataProse      = &ProvisionedDataResource{Path: "prose-data", DataSet: DataSetName_PROSE}
+	ProvisionedDataMbs        = &ProvisionedDataResource{Path: "mbs-data", DataSet: DataSetName_5MBS}
+)
+
+func HandleQueryProvisionedDataResource(ctx context.Context, resource *ProvisionedDataResource,
+	request *httpwrapper.Request,
+) *httpwrapper.Response {
+	ueId := request.Params["ueId"]
+	servingPlmnId := request.Params["servingPlmnId"]
+	logger.DataRepoLog.Infof("handle QueryProvisionedData %s: ueId=%q", resource.Path, ueId)
+
+	opts, err := parseQueryOptions(request.Query)
+	if err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("get", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	collName := provisionedDataSetCollections[resource.DataSet]
+	filter := bson.M{"ueId": ueId, "servingPlmnId": servingPlmnId}
+	docs, err := opts.findDocuments(ctx, CommonDBClient, collName, filter, true)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("get", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if len(docs) == 0 {
+		pd := util.ProblemDetailsNotFound("USER_NOT_FOUND")
+		stats.IncrementUdrSubscriptionDataStats("get", resource.Path, "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	data := withoutInternalFields(docs[0])
+	for attr := range filter {
+		delete(data, attr)
+	}
+	opts.stripUnsupported(collName, data)
+	stats.IncrementUdrSubscriptionDataStats("get", resource.Path, "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, data)
+}
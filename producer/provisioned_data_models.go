@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"github.com/omec-project/openapi/models"
+)
+
+// Provisioned data sets of TS 29.503 and TS 29.505 which the openapi models
+// do not provide.
+
+const (
+	DataSetName_LCS_PRIVACY models.DataSetName = "LCS_PRIVACY"
+	DataSetName_LCS_MO      models.DataSetName = "LCS_MO"
+	DataSetName_LCS_BCA     models.DataSetName = "LCS_BCA"
+	DataSetName_V2X         models.DataSetName = "V2X"
+	DataSetName_PROSE       models.DataSetName = "PROSE"
+	DataSetName_5MBS        models.DataSetName = "5MBS"
+)
+
+// ProvisionedDataSets extends models.ProvisionedDataSets with the data sets
+// of the later releases.
+type ProvisionedDataSets struct {
+	AmData         *AccessAndMobilitySubscriptionData         `json:"amData,omitempty" bson:"amData"`
+	SmfSelData     *models.SmfSelectionSubscriptionData       `json:"smfSelData,omitempty" bson:"smfSelData"`
+	SmsSubsData    *models.SmsSubscriptionData                `json:"smsSubsData,omitempty" bson:"smsSubsData"`
+	SmData         []models.SessionManagementSubscriptionData `json:"smData,omitempty" bson:"smData"`
+	TraceData      *models.TraceData                          `json:"traceData,omitempty" bson:"traceData"`
+	SmsMngData     *models.SmsManagementSubscriptionData      `json:"smsMngData,omitempty" bson:"smsMngData"`
+	LcsPrivacyData *LcsPrivacyData                            `json:"lcsPrivacyData,omitempty" bson:"lcsPrivacyData"`
+	LcsMoData      *LcsMoData                                 `json:"lcsMoData,omitempty" bson:"lcsMoData"`
+	LcsBcaData     *LcsBroadcastAssistanceTypesData           `json:"lcsBcaData,omitempty" bson:"lcsBcaData"`
+	V2xData        *V2xSubscriptionData                       `json:"v2xData,omitempty" bson:"v2xData"`
+	ProseData      *ProseSubscriptionData                     `json:"proseData,omitempty" bson:"proseData"`
+	MbsData        *MbsSubscriptionData                       `json:"mbsData,omitempty" bson:"mbsData"`
+}
+
+// AccessAndMobilitySubscriptionData is the am-data with the CAG data of the
+// UE.
+type AccessAndMobilitySubscriptionData struct {
+	models.AccessAndMobilitySubscriptionData `mapstructure:",squash" bson:",inline"`
+	CagData                                  *CagData `json:"cagData,omitempty" bson:"cagData"`
+}
+
+type CagData struct {
+	// CagInfos is keyed by PLMN ID, MCC followed by MNC
+	CagInfos         map[string]CagInfo `json:"cagInfos" bson:"cagInfos"`
+	ProvisioningTime string             `json:"provisioningTime,omitempty" bson:"provisioningTime"`
+}
+
+type CagInfo struct {
+	AllowedCagList   []string `json:"allowedCagList" bson:"allowedCagList"`
+	CagOnlyIndicator bool     `json:"cagOnlyIndicator,omitempty" bson:"cagOnlyIndicator"`
+}
+
+type LcsPrivacyData struct {
+	Lpi                 *Lpi                `json:"lpi,omitempty" bson:"lpi"`
+	UnrelatedClass      *UnrelatedClass     `json:"unrelatedClass,omitempty" bson:"unrelatedClass"`
+	PlmnOperatorClasses []PlmnOperatorClass `json:"plmnOperatorClasses,omitempty" bson:"plmnOperatorClasses"`
+}
+
+// Lpi is the location privacy indication of a UE.
+type Lpi struct {
+	LocationPrivacyInd string           `json:"locationPrivacyInd" bson:"locationPrivacyInd"`
+	ValidTimePeriod    *ValidTimePeriod `json:"validTimePeriod,omitempty" bson:"validTimePeriod"`
+}
+
+type ValidTimePeriod struct {
+	StartTime string `json:"startTime,omitempty" bson:"startTime"`
+	EndTime   string `json:"endTime,omitempty" bson:"endTime"`
+}
+
+type UnrelatedClass struct {
+	DefaultUnrelatedClass       *DefaultUnrelatedClass      `json:"defaultUnrelatedClass" bson:"defaultUnrelatedClass"`
+	ExternalUnrelatedClass      *ExternalUnrelatedClass     `json:"externalUnrelatedClass,omitempty" bson:"externalUnrelatedClass"`
+	ServiceTypeUnrelatedClasses []ServiceTypeUnrelatedClass `json:"serviceTypeUnrelatedClasses,omitempty" bson:"serviceTypeUnrelatedClasses"`
+}
+
+type DefaultUnrelatedClass struct {
+	AllowedGeographicArea     []interface{}    `json:"allowedGeographicArea,omitempty" bson:"allowedGeographicArea"`
+	PrivacyCheckRelatedAction string           `json:"privacyCheckRelatedAction,omitempty" bson:"privacyCheckRelatedAction"`
+	CodeWordInd               string           `json:"codeWordInd,omitempty" bson:"codeWordInd"`
+	ValidTimePeriod           *ValidTimePeriod `json:"validTimePeriod,omitempty" bson:"validTimePeriod"`
+	CodeWordList              []string         `json:"codeWordList,omitempty" bson:"codeWordList"`
+}
+
+type ExternalUnrelatedClass struct {
+	LcsClientExternals []LcsClientExternal `json:"lcsClientExternals,omitempty" bson:"lcsClientExternals"`
+	AfExternals        []AfExternal        `json:"afExternals,omitempty" bson:"afExternals"`
+}
+
+type LcsClientExternal struct {
+	AllowedGeographicArea     []interface{}    `json:"allowedGeographicArea,omitempty" bson:"allowedGeographicArea"`
+	PrivacyCheckRelatedAction string           `json:"privacyCheckRelatedAction,omitempty" bson:"privacyCheckRelatedAction"`
+	ValidTimePeriod           *ValidTimePeriod `json:"validTimePeriod,omitempty" bson:"validTimePeriod"`
+}
+
+type AfExternal struct {
+	AfId                      string           `json:"afId,omitempty" bson:"afId"`
+	AllowedGeographicArea     []interface{}    `json:"allowedGeographicArea,omitempty" bson:"allowedGeographicArea"`
+	PrivacyCheckRelatedAction string           `json:"privacyCheckRelatedAction,omitempty" bson:"privacyCheckRelatedAction"`
+	ValidTimePeriod           *ValidTimePeriod `json:"validTimePeriod,omitempty" bson:"validTimePeriod"`
+}
+
+type ServiceTypeUnrelatedClass struct {
+	ServiceType               int32            `json:"serviceType" bson:"serviceType"`
+	AllowedGeographicArea     []interface{}    `json:"allowedGeographicArea,omitempty" bson:"allowedGeographicArea"`
+	PrivacyCheckRelatedAction string           `json:"privacyCheckRelatedAction,omitempty" bson:"privacyCheckRelatedAction"`
+	CodeWordInd               string           `json:"codeWordInd,omitempty" bson:"codeWordInd"`
+	ValidTimePeriod           *ValidTimePeriod `json:"validTimePeriod,omitempty" bson:"validTimePeriod"`
+	CodeWordList              []string         `json:"codeWordList,omitempty" bson:"codeWordList"`
+}
+
+type PlmnOperatorClass struct {
+	LcsClientClass string   `json:"lcsClientClass" bson:"lcsClientClass"`
+	LcsClientIds   []string `json:"lcsClientIds" bson:"lcsClientIds"`
+}
+
+type LcsMoData struct {
+	AllowedServiceClasses []string                         `json:"allowedServiceClasses" bson:"allowedServiceClasses"`
+	MoAssistanceDataTypes *LcsBroadcastAssistanceTypesData `json:"moAssistanceDataTypes,omitempty" bson:"moAssistanceDataTypes"`
+}
+
+type LcsBroadcastAssistanceTypesData struct {
+	// LocationAssistanceType is the base64 encoded bitmap of TS 37.355
+	LocationAssistanceType string `json:"locationAssistanceType" bson:"locationAssistanceType"`
+}
+
+type V2xSubscriptionData struct {
+	NrV2xServicesAuth  *NrV2xAuth  `json:"nrV2xServicesAuth,omitempty" bson:"nrV2xServicesAuth"`
+	LteV2xServicesAuth *LteV2xAuth `json:"lteV2xServicesAuth,omitempty" bson:"lteV2xServicesAuth"`
+	NrUePc5Ambr        string      `json:"nrUePc5Ambr,omitempty" bson:"nrUePc5Ambr"`
+	LtePc5Ambr         string      `json:"ltePc5Ambr,omitempty" bson:"ltePc5Ambr"`
+}
+
+type NrV2xAuth struct {
+	VehicleUeAuth    string `json:"vehicleUeAuth,omitempty" bson:"vehicleUeAuth"`
+	PedestrianUeAuth string `json:"pedestrianUeAuth,omitempty" bson:"pedestrianUeAuth"`
+}
+
+type LteV2xAuth struct {
+	VehicleUeAuth    string `json:"vehicleUeAuth,omitempty" bson:"vehicleUeAuth"`
+	PedestrianUeAuth string `json:"pedestrianUeAuth,omitempty" bson:"pedestrianUeAuth"`
+}
+
+type ProseSubscriptionData struct {
+	ProseServiceAuth  *ProseServiceAuth  `json:"proseServiceAuth,omitempty" bson:"proseServiceAuth"`
+	NrUePc5Ambr       string             `json:"nrUePc5Ambr,omitempty" bson:"nrUePc5Ambr"`
+	ProseAllowedPlmn  []ProSeAllowedPlmn `json:"proseAllowedPlmn,omitempty" bson:"proseAllowedPlmn"`
+	SupportedFeatures string             `json:"supportedFeatures,omitempty" bson:"supportedFeatures"`
+}
+
+type ProseServiceAuth struct {
+	ProseDirectDiscoveryAuth     string `json:"proseDirectDiscoveryAuth,omitempty" bson:"proseDirectDiscoveryAuth"`
+	ProseDirectCommunicationAuth string `json:"proseDirectCommunicationAuth,omitempty" bson:"proseDirectCommunicationAuth"`
+}
+
+type ProSeAllowedPlmn struct {
+	VisitedPlmn        *models.PlmnId `json:"visitedPlmn" bson:"visitedPlmn"`
+	ProseDirectAllowed []string       `json:"proseDirectAllowed,omitempty" bson:"proseDirectAllowed"`
+}
+
+type MbsSubscriptionData struct {
+	MbsAllowed       bool           `json:"mbsAllowed,omitempty" bson:"mbsAllowed"`
+	MbsSessionIdList []MbsSessionId `json:"mbsSessionIdList,omitempty" bson:"mbsSessionIdList"`
+}
+
+// MbsSessionId identifies an MBS session by its TMGI or its source specific
+// multicast address.
+type MbsSessionId struct {
+	Tmgi *Tmgi  `json:"tmgi,omitempty" bson:"tmgi"`
+	Ssm  *Ssm   `json:"ssm,omitempty" bson:"ssm"`
+	Nid  string `json:"nid,omitempty" bson:"nid"`
+}
+
+type Tmgi struct {
+	MbsServiceId string         `json:"mbsServiceId" bson:"mbsServiceId"`
+	PlmnId       *models.PlmnId `json:"plmnId" bson:"plmnId"`
+}
+
+type Ssm struct {
+	SourceIpAddr *IpAddr `json:"sourceIpAddr" bson:"sourceIpAddr"`
+	DestIpAddr   *IpAddr `json:"destIpAddr" bson:"destIpAddr"`
+}
+
+type IpAddr struct {
+	Ipv4Addr   string `json:"ipv4Addr,omitempty" bson:"ipv4Addr"`
+	Ipv6Addr   string `json:"ipv6Addr,omitempty" bson:"ipv6Addr"`
+	Ipv6Prefix string `json:"ipv6Prefix,omitempty" bson:"ipv6Prefix"`
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR provisioned data retrieval with malformed documents
+ */
+
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fakeDB serves documents from memory, keyed by collection.
+type fakeDB struct {
+	DBInterface
+	docs map[string][]map[string]interface{}
+}
+
+func (db *fakeDB) RestfulAPIGetOne(collName string, filter bson.M) (map[string]interface{}, error) {
+	if docs := db.docs[collName]; len(docs) > 0 {
+		return docs[0], nil
+	}
+	return nil, nil
+}
+
+func (db *fakeDB) RestfulAPIGetMany(collName string, filter bson.M) ([]map[string]interface{}, error) {
+	return db.docs[collName], nil
+}
+
+func TestQueryProvisionedDataMalformedDocument(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &fakeDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_PROVISIONED_AMDATA: {{"gpsis": []string{"msisdn-1"}}},
+		SUBSCDATA_PROVISIONED_SMDATA: {{"singleNssai": "not an object"}},
+	}}
+
+	rsp, pd := QueryProvisionedDataProcedure(context.Background(), "imsi-1", "20893",
+		ProvisionedDataSets{}, &queryOptions{})
+	assert.Nil(t, rsp)
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusInternalServerError), pd.Status)
+		assert.Contains(t, pd.Detail, "SM data set")
+	}
+
+	rsp, pd = QueryProvisionedDataProcedure(context.Background(), "imsi-1", "20893",
+		ProvisionedDataSets{}, &queryOptions{DataSets: map[models.DataSetName]bool{models.DataSetName_AM: true}})
+	assert.Nil(t, pd)
+	if assert.NotNil(t, rsp) {
+		assert.Equal(t, []string{"msisdn-1"}, rsp.AmData.Gpsis)
+		assert.Nil(t, rsp.SmData)
+	}
+}
+
+func TestQueryProvisionedDataExtendedDataSets(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &fakeDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_PROVISIONED_AMDATA: {{
+			"gpsis": []string{"msisdn-1"},
+			"cagData": map[string]interface{}{"cagInfos": map[string]interface{}{
+				"20893": map[string]interface{}{"allowedCagList": []string{"cag-1"}},
+			}},
+		}},
+		SUBSCDATA_PROVISIONED_V2XDATA:   {{"nrV2xServicesAuth": map[string]interface{}{"vehicleUeAuth": "AUTHORIZED"}}},
+		SUBSCDATA_PROVISIONED_LCSMODATA: {{"allowedServiceClasses": []string{"BASIC_SELF_LOCATION"}}},
+	}}
+
+	rsp, pd := QueryProvisionedDataProcedure(context.Background(), "imsi-1", "20893", ProvisionedDataSets{},
+		&queryOptions{DataSets: map[models.DataSetName]bool{models.DataSetName_AM: true, DataSetName_V2X: true}})
+	assert.Nil(t, pd)
+	if assert.NotNil(t, rsp) {
+		assert.Equal(t, []string{"msisdn-1"}, rsp.AmData.Gpsis)
+		if assert.NotNil(t, rsp.AmData.CagData) {
+			assert.Equal(t, []string{"cag-1"}, rsp.AmData.CagData.CagInfos["20893"].AllowedCagList)
+		}
+		assert.Equal(t, "AUTHORIZED", rsp.V2xData.NrV2xServicesAuth.VehicleUeAuth)
+		assert.Nil(t, rsp.LcsMoData)
+	}
+
+	opts, err := parseQueryOptions(url.Values{"dataset-names": {"LCS_MO,5MBS"}})
+	if assert.NoError(t, err) {
+		assert.True(t, opts.wants(DataSetName_LCS_MO))
+		assert.False(t, opts.wants(DataSetName_V2X))
+	}
+}
+
+func TestQueryProvisionedDataResource(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &fakeDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_PROVISIONED_PROSEDATA: {{
+			"_id": "x", "ueId": "imsi-1", "servingPlmnId": "20893",
+			"proseServiceAuth": map[string]interface{}{"proseDirectDiscoveryAuth": "AUTHORIZED"},
+		}},
+	}}
+
+	ue := dbRequest(nil, map[string]string{"ueId": "imsi-1", "servingPlmnId": "20893"}, nil)
+	rsp := HandleQueryProvisionedDataResource(context.Background(), ProvisionedDataProse, ue)
+	assert.Equal(t, http.StatusOK, rsp.Status)
+	assert.Equal(t, bson.M{"proseServiceAuth": map[string]interface{}{"proseDirectDiscoveryAuth": "AUTHORIZED"}},
+		rsp.Body)
+
+	rsp = HandleQueryProvisionedDataResource(context.Background(), ProvisionedDataMbs, ue)
+	assert.Equal(t, http.StatusNotFound, rsp.Status)
+}
+
+func FuzzDecodeProvisionedDataSet(f *testing.F) {
+	f.Add(`{"nssai": {"defaultSingleNssais": [{"sst": 1}]}}`)
+	f.Add(`{"nssai": "x", "gpsis": 5}`)
+	f.Add(`{"dnnConfigurations": {"internet": []}}`)
+	f.Add(`{"singleNssai": null, "rfspIndex": "high"}`)
+	f.Fuzz(func(t *testing.T, data string) {
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &doc); err != nil {
+			return
+		}
+		for _, dataSet := range provisionedDataSetDecoders {
+			var provisionedDataSets ProvisionedDataSets
+			// must report malformed documents as errors, never panic
+			_ = decodeProvisionedDataSet(dataSet, []map[string]interface{}{doc}, &provisionedDataSets)
+		}
+	})
+}
@@ -0,0 +1,244 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/omec-project/openapi/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// provisionedDataSetCollections maps the data set names accepted by the
+// dataset-names query parameter of provisioned-data to their collection.
+var provisionedDataSetCollections = map[models.DataSetName]string{
+	models.DataSetName_AM:      SUBSCDATA_PROVISIONED_AMDATA,
+	models.DataSetName_SMF_SEL: SUBSCDATA_PROVISIONED_SMFSELDATA,
+	models.DataSetName_SMS_SUB: SUBSCDATA_PROVISIONED_SMSDATA,
+	models.DataSetName_SM:      SUBSCDATA_PROVISIONED_SMDATA,
+	models.DataSetName_TRACE:   SUBSCDATA_PROVISIONED_TRACEDATA,
+	models.DataSetName_SMS_MNG: SUBSCDATA_PROVISIONED_SMSMNGDATA,
+	DataSetName_LCS_PRIVACY:    SUBSCDATA_PROVISIONED_LCSPRIVACYDATA,
+	DataSetName_LCS_MO:         SUBSCDATA_PROVISIONED_LCSMODATA,
+	DataSetName_LCS_BCA:        SUBSCDATA_PROVISIONED_LCSBCADATA,
+	DataSetName_V2X:            SUBSCDATA_PROVISIONED_V2XDATA,
+	DataSetName_PROSE:          SUBSCDATA_PROVISIONED_PROSEDATA,
+	DataSetName_5MBS:           SUBSCDATA_PROVISIONED_MBSDATA,
+}
+
+// optionalFeature is a feature of the subscription data resources, negotiated
+// with the supported-features query parameter, and the document attributes
+// only returned to consumers supporting it.
+type optionalFeature struct {
+	Number     int
+	Name       string
+	Attributes map[string][]string // keyed by collection
+}
+
+var subscriptionDataFeatures = []optionalFeature{
+	{
+		Number: 1,
+		Name:   "SharedData",
+		Attributes: map[string][]string{
+			SUBSCDATA_PROVISIONED_AMDATA: {"sharedAmDataIds"},
+			SUBSCDATA_PROVISIONED_SMDATA: {"sharedDnnConfigurationsIds"},
+		},
+	},
+}
+
+// queryOptions holds the TS 29.505 query parameters common to the
+// subscription data GETs.
+type queryOptions struct {
+	Fields   []string
+	DataSets map[models.DataSetName]bool // nil selects all data sets
+	// SupportedFeatures is nil when the consumer did not negotiate features,
+	// in which case every attribute is returned.
+	SupportedFeatures *string
+}
+
+// parseQueryOptions reads the fields, dataset-names and supported-features
+// query parameters.
+func parseQueryOptions(query url.Values) (*queryOptions, error) {
+	opts := &queryOptions{}
+	for _, field := range splitQueryList(query["fields"]) {
+		if strings.HasPrefix(field, "$") || strings.Contains(field, ".$") {
+			return nil, fmt.Errorf("invalid field %q", field)
+		}
+		opts.Fields = append(opts.Fields, strings.ReplaceAll(strings.Trim(field, "/"), "/", "."))
+	}
+	if names := splitQueryList(query["dataset-names"]); len(names) > 0 {
+		opts.DataSets = make(map[models.DataSetName]bool, len(names))
+		for _, name := range names {
+			if _, ok := provisionedDataSetCollections[models.DataSetName(name)]; !ok {
+				return nil, fmt.Errorf("invalid dataset name %q", name)
+			}
+			opts.DataSets[models.DataSetName(name)] = true
+		}
+	}
+	if values, ok := query["supported-features"]; ok {
+		supportedFeatures := ""
+		if len(values) > 0 {
+			supportedFeatures = values[0]
+		}
+		for _, c := range supportedFeatures {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return nil, fmt.Errorf("invalid supported-features %q", supportedFeatures)
+			}
+		}
+		opts.SupportedFeatures = &supportedFeatures
+	}
+	return opts, nil
+}
+
+func splitQueryList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// wants reports whether the data set was selected by dataset-names.
+func (opts *queryOptions) wants(dataSet models.DataSetName) bool {
+	return opts.DataSets == nil || opts.DataSets[dataSet]
+}
+
+// projection returns the Mongo projection selecting the requested fields, nil
+// when the whole document is requested.
+func (opts *queryOptions) projection() bson.M {
+	if len(opts.Fields) == 0 {
+		return nil
+	}
+	projection := bson.M{"_id": 0}
+	for _, field := range opts.Fields {
+		projection[field] = 1
+	}
+	return projection
+}
+
+// supportsFeature reports whether feature number n (starting at 1) is set in
+// the hexadecimal supported features string, see TS 29.571 clause 5.2.2.
+func supportsFeature(supportedFeatures string, n int) bool {
+	idx := len(supportedFeatures) - 1 - (n-1)/4
+	if idx < 0 {
+		return false
+	}
+	digit, err := strconv.ParseUint(supportedFeatures[idx:idx+1], 16, 8)
+	if err != nil {
+		return false
+	}
+	return digit&(1<<((n-1)%4)) != 0
+}
+
+// negotiatedFeatures returns the features supported both by the consumer and
+// the UDR.
+func (opts *queryOptions) negotiatedFeatures() string {
+	if opts.SupportedFeatures == nil {
+		return ""
+	}
+	var digits []byte
+	for _, feature := range subscriptionDataFeatures {
+		if !supportsFeature(*opts.SupportedFeatures, feature.Number) {
+			continue
+		}
+		idx := (feature.Number - 1) / 4
+		for len(digits) <= idx {
+			digits = append(digits, 0)
+		}
+		digits[idx] |= 1 << ((feature.Number - 1) % 4)
+	}
+	var sb strings.Builder
+	for i := len(digits) - 1; i >= 0; i-- {
+		sb.WriteString(strconv.FormatUint(uint64(digits[i]), 16))
+	}
+	return sb.String()
+}
+
+// stripUnsupported removes from a document of collName the attributes of the
+// features the consumer did not negotiate.
+func (opts *queryOptions) stripUnsupported(collName string, doc map[string]interface{}) {
+	if opts.SupportedFeatures == nil || doc == nil {
+		return
+	}
+	for _, feature := range subscriptionDataFeatures {
+		if supportsFeature(*opts.SupportedFeatures, feature.Number) {
+			continue
+		}
+		for _, attribute := range feature.Attributes[collName] {
+			delete(doc, attribute)
+		}
+	}
+}
+
+// findDocuments reads the documents of collName matching filter under ctx,
+// applying the field projection at the DB level. With one set at most one
+// document is read. The fields internal to the UDR are left out.
+func (opts *queryOptions) findDocuments(ctx context.Context, db DBInterface, collName string, filter bson.M,
+	one bool,
+) ([]map[string]interface{}, error) {
+	if cached, ok := db.(*cachedDBClient); ok {
+		return cached.findDocuments(ctx, collName, filter, opts.projection(), one)
+	}
+	return findDocuments(ctx, db, collName, filter, opts.projection(), one)
+}
+
+func findDocuments(ctx context.Context, db DBInterface, collName string, filter bson.M, projection bson.M,
+	one bool,
+) ([]map[string]interface{}, error) {
+	collClient, ok := db.(collectionDBInterface)
+	if !ok {
+		if projection != nil {
+			return nil, fmt.Errorf("DB client does not support projections")
+		}
+		if one {
+			doc, err := db.RestfulAPIGetOne(collName, filter)
+			if err != nil || doc == nil {
+				return nil, err
+			}
+			return withoutInternalDocumentFields([]map[string]interface{}{doc}), nil
+		}
+		docs, err := db.RestfulAPIGetMany(collName, filter)
+		if err != nil {
+			return nil, err
+		}
+		return withoutInternalDocumentFields(docs), nil
+	}
+
+	if projection == nil {
+		projection = bson.M{"_id": 0}
+	}
+	findOptions := options.Find().SetProjection(projection)
+	if one {
+		findOptions.SetLimit(1)
+	}
+	cur, err := collClient.GetCollection(collName).Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("find %s: %w", collName, err)
+	}
+	var docs []map[string]interface{}
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("find %s: %w", collName, err)
+	}
+	return withoutInternalDocumentFields(docs), nil
+}
+
+// withoutInternalDocumentFields applies withoutInternalFields to each of docs.
+func withoutInternalDocumentFields(docs []map[string]interface{}) []map[string]interface{} {
+	if docs == nil {
+		return nil
+	}
+	stripped := make([]map[string]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		stripped = append(stripped, withoutInternalFields(doc))
+	}
+	return stripped
+}
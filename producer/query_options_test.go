@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR subscription data query parameters
+ */
+
+package producer
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/util/httpwrapper"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseQueryOptions(t *testing.T) {
+	query, _ := url.ParseQuery("fields=gpsis,nssai/defaultSingleNssais&dataset-names=AM,SM&supported-features=1")
+	opts, err := parseQueryOptions(query)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, bson.M{"_id": 0, "gpsis": 1, "nssai.defaultSingleNssais": 1}, opts.projection())
+	assert.True(t, opts.wants(models.DataSetName_AM))
+	assert.False(t, opts.wants(models.DataSetName_TRACE))
+	assert.Equal(t, "1", opts.negotiatedFeatures())
+
+	opts, err = parseQueryOptions(url.Values{})
+	assert.NoError(t, err)
+	assert.Nil(t, opts.projection())
+	assert.True(t, opts.wants(models.DataSetName_TRACE))
+
+	_, err = parseQueryOptions(url.Values{"dataset-names": {"AM,UNKNOWN"}})
+	assert.Error(t, err)
+	_, err = parseQueryOptions(url.Values{"fields": {"$where"}})
+	assert.Error(t, err)
+	_, err = parseQueryOptions(url.Values{"supported-features": {"xyz"}})
+	assert.Error(t, err)
+}
+
+func TestSupportsFeature(t *testing.T) {
+	assert.True(t, supportsFeature("1", 1))
+	assert.False(t, supportsFeature("2", 1))
+	assert.True(t, supportsFeature("2", 2))
+	assert.True(t, supportsFeature("10", 5))
+	assert.False(t, supportsFeature("10", 9))
+	assert.False(t, supportsFeature("", 1))
+}
+
+func TestStripUnsupported(t *testing.T) {
+	doc := map[string]interface{}{"gpsis": []string{"msisdn-1"}, "sharedAmDataIds": []string{"shared-1"}}
+	opts := &queryOptions{}
+	opts.stripUnsupported(SUBSCDATA_PROVISIONED_AMDATA, doc)
+	assert.Contains(t, doc, "sharedAmDataIds")
+
+	supportedFeatures := "0"
+	opts.SupportedFeatures = &supportedFeatures
+	assert.Equal(t, "", opts.negotiatedFeatures())
+	opts.stripUnsupported(SUBSCDATA_PROVISIONED_AMDATA, doc)
+	assert.NotContains(t, doc, "sharedAmDataIds")
+	assert.Contains(t, doc, "gpsis")
+}
+
+func TestProvisionedDataGetsRejectInvalidFields(t *testing.T) {
+	handlers := map[string]func(*httpwrapper.Request) *httpwrapper.Response{
+		"smf-selection-subscription-data": HandleQuerySmfSelectData,
+		"sms-data":                        HandleQuerySmsData,
+		"sms-mng-data":                    HandleQuerySmsMngData,
+		"trace-data":                      HandleQueryTraceData,
+	}
+	for name, handle := range handlers {
+		req := httpwrapper.NewRequest(&http.Request{URL: &url.URL{RawQuery: "fields=$where"}}, nil)
+		req.Params["ueId"] = "imsi-1"
+		req.Params["servingPlmnId"] = "20893"
+		assert.Equal(t, http.StatusBadRequest, handle(req).Status, name)
+	}
+}
+
+func TestQueriesLeaveOutInternalFields(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &filteringDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_PROVISIONED_AMDATA: {{
+			"_id": "id", "ueId": "imsi-1", "servingPlmnId": "20893", "gpsis": []string{"msisdn-1"},
+			documentRevisionField: int64(2),
+		}},
+		SUBSCDATA_PROVISIONED_TRACEDATA: {{"ueId": "imsi-1", "servingPlmnId": "20893", documentRevisionField: int64(1)}},
+	}}
+
+	amData, pd := QueryAmDataProcedure(SUBSCDATA_PROVISIONED_AMDATA, "imsi-1", "20893", &queryOptions{})
+	if assert.Nil(t, pd) {
+		assert.NotContains(t, *amData, documentRevisionField)
+		assert.NotContains(t, *amData, "_id")
+		assert.Contains(t, *amData, "gpsis")
+	}
+	traceData, pd := QueryTraceDataProcedure(SUBSCDATA_PROVISIONED_TRACEDATA, "imsi-1", "20893", &queryOptions{})
+	if assert.Nil(t, pd) {
+		assert.NotContains(t, *traceData, documentRevisionField)
+	}
+}
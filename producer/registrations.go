@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SUBSCDATA_CTXDATA_AMF_HISTORY keeps the AMFs replaced by the registration
+// of another AMF, one document per UE, access type and AMF.
+const SUBSCDATA_CTXDATA_AMF_HISTORY = "subscriptionData.contextData.amfRegistrationHistory"
+
+// smfRegistrationRefreshField is the internal field holding the time of the
+// last PUT of an SMF registration.
+const smfRegistrationRefreshField = "_refreshTime"
+
+// smfRegistrationReaperInterval is how often expired SMF registrations are
+// deleted.
+const smfRegistrationReaperInterval = time.Minute
+
+// amfRegistrationHistory is set while the replaced AMF registrations are kept.
+var amfRegistrationHistory atomic.Bool
+
+// EnableAmfRegistrationHistory keeps the deregCallbackUri of the AMF serving
+// a UE when another AMF registers for the same access type.
+func EnableAmfRegistrationHistory() {
+	amfRegistrationHistory.Store(true)
+}
+
+// previousAmfRegistration returns the AMF registration a new registration
+// replaces, nil unless the history is kept.
+func previousAmfRegistration(collName string, filter bson.M) map[string]interface{} {
+	if !amfRegistrationHistory.Load() {
+		return nil
+	}
+	previous, err := uncached(CommonDBClient).RestfulAPIGetOne(collName, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+	}
+	return previous
+}
+
+// recordAmfRegistrationHistory stores the previous AMF registration of the UE
+// if another AMF replaced it.
+func recordAmfRegistrationHistory(ueId string, accessType models.AccessType, previous map[string]interface{},
+	amfInstanceId string,
+) {
+	previousAmfInstanceId, _ := previous["amfInstanceId"].(string)
+	if previousAmfInstanceId == "" || previousAmfInstanceId == amfInstanceId {
+		return
+	}
+	filter := bson.M{"ueId": ueId, "accessType": accessType, "amfInstanceId": previousAmfInstanceId}
+	history := bson.M{
+		"ueId":             ueId,
+		"accessType":       accessType,
+		"amfInstanceId":    previousAmfInstanceId,
+		"deregCallbackUri": previous["deregCallbackUri"],
+		"guami":            previous["guami"],
+		"replacedBy":       amfInstanceId,
+		"replacedAt":       time.Now().UTC(),
+	}
+	if _, err := CommonDBClient.RestfulAPIPutOne(SUBSCDATA_CTXDATA_AMF_HISTORY, filter, history); err != nil {
+		logger.DataRepoLog.Warnf("keep AMF %s replaced for %s: %v", previousAmfInstanceId, ueId, err)
+	}
+}
+
+// ReplacedAmfRegistration is an AMF registration replaced at ReplacedAt by
+// the registration of the AMF ReplacedBy.
+type ReplacedAmfRegistration struct {
+	UeId             string            `json:"ueId" bson:"ueId"`
+	AccessType       models.AccessType `json:"accessType" bson:"accessType"`
+	AmfInstanceId    string            `json:"amfInstanceId" bson:"amfInstanceId"`
+	DeregCallbackUri string            `json:"deregCallbackUri,omitempty" bson:"deregCallbackUri"`
+	Guami            *models.Guami     `json:"guami,omitempty" bson:"guami"`
+	ReplacedBy       string            `json:"replacedBy" bson:"replacedBy"`
+	ReplacedAt       time.Time         `json:"replacedAt" bson:"replacedAt"`
+}
+
+// HandleQueryAmfRegistrationHistory lists the AMF registrations of ueId
+// replaced by another AMF, the latest first, of the access-type query
+// parameter only if set.
+func HandleQueryAmfRegistrationHistory(request *httpwrapper.Request) *httpwrapper.Response {
+	ueId := request.Params["ueId"]
+	logger.DataRepoLog.Infof("handle QueryAmfRegistrationHistory: ueId=%q", ueId)
+
+	filter := bson.M{"ueId": ueId}
+	if accessType := request.Query.Get("access-type"); accessType != "" {
+		filter["accessType"] = accessType
+	}
+	docs, err := CommonDBClient.RestfulAPIGetMany(SUBSCDATA_CTXDATA_AMF_HISTORY, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("get", "amf-registration-history", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	registrations := make([]ReplacedAmfRegistration, 0, len(docs))
+	for _, doc := range docs {
+		var registration ReplacedAmfRegistration
+		raw, err := bson.Marshal(doc)
+		if err == nil {
+			err = bson.Unmarshal(raw, &registration)
+		}
+		if err != nil {
+			logger.DataRepoLog.Warnf("replaced AMF registration of %s: %v", ueId, err)
+			pd := util.ProblemDetailsSystemFailure(err.Error())
+			stats.IncrementUdrSubscriptionDataStats("get", "amf-registration-history", "FAILURE")
+			return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+		}
+		registrations = append(registrations, registration)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].ReplacedAt.After(registrations[j].ReplacedAt)
+	})
+	stats.IncrementUdrSubscriptionDataStats("get", "amf-registration-history", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, registrations)
+}
+
+// purgeFlagPatch checks the purgeFlag operations of an AMF registration
+// patch. The flag is not stored while it is false, so it is replaced by
+// adding it.
+func purgeFlagPatch(patchItem []models.PatchItem) ([]models.PatchItem, *models.ProblemDetails) {
+	patched := make([]models.PatchItem, len(patchItem))
+	for i, item := range patchItem {
+		if item.Path == "/purgeFlag" {
+			switch item.Op {
+			case models.PatchOperation_ADD, models.PatchOperation_REPLACE:
+				if _, ok := item.Value.(bool); !ok {
+					return nil, util.ProblemDetailsMalformedReqSyntax(
+						fmt.Sprintf("purgeFlag must be a boolean, not %v", item.Value))
+				}
+				item.Op = models.PatchOperation_ADD
+			}
+		}
+		patched[i] = item
+	}
+	return patched, nil
+}
+
+// withoutRefreshTime returns an SMF registration without its refresh time.
+func withoutRefreshTime(smfRegistration map[string]interface{}) map[string]interface{} {
+	delete(smfRegistration, smfRegistrationRefreshField)
+	return smfRegistration
+}
+
+// expireSmfRegistrations deletes the SMF registrations not refreshed since
+// maxAge before now. The registrations stored without refresh time expire
+// maxAge after their creation.
+func expireSmfRegistrations(now time.Time, maxAge time.Duration) error {
+	expiry := now.Add(-maxAge)
+	filter := bson.M{"$or": []bson.M{
+		{smfRegistrationRefreshField: bson.M{"$lt": expiry}},
+		{
+			smfRegistrationRefreshField: bson.M{"$exists": false},
+			"_id":                       bson.M{"$lt": objectIDAt(expiry)},
+		},
+	}}
+	return CommonDBClient.RestfulAPIDeleteMany(SUBSCDATA_CTXDATA_SMF_REGISTRATION, filter)
+}
+
+// objectIDAt returns the lowest ObjectID generated at t, the ObjectIDs of the
+// documents created before t are lower.
+func objectIDAt(t time.Time) primitive.ObjectID {
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[0:4], uint32(t.Unix()))
+	return id
+}
+
+// StartSmfRegistrationReaper deletes the SMF registrations not refreshed for
+// maxAge in the background.
+func StartSmfRegistrationReaper(maxAge time.Duration) {
+	go func() {
+		ticker := time.NewTicker(smfRegistrationReaperInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if err := expireSmfRegistrations(now, maxAge); err != nil {
+				logger.DataRepoLog.Warnf("expire SMF registrations: %v", err)
+			}
+		}
+	}()
+}
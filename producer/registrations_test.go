@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR AMF and SMF registration handling
+ */
+
+package producer
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRecordAmfRegistrationHistory(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &identityDB{filterRecordingDB{fakeDB: fakeDB{docs: map[string][]map[string]interface{}{}}}}
+	CommonDBClient = db
+
+	previous := map[string]interface{}{"amfInstanceId": "amf-1", "deregCallbackUri": "http://amf-1/dereg"}
+	recordAmfRegistrationHistory("imsi-1", models.AccessType__3_GPP_ACCESS, previous, "amf-1")
+	recordAmfRegistrationHistory("imsi-1", models.AccessType__3_GPP_ACCESS, nil, "amf-2")
+	assert.Empty(t, db.docs[SUBSCDATA_CTXDATA_AMF_HISTORY])
+
+	recordAmfRegistrationHistory("imsi-1", models.AccessType__3_GPP_ACCESS, previous, "amf-2")
+	if assert.Len(t, db.docs[SUBSCDATA_CTXDATA_AMF_HISTORY], 1) {
+		history := db.docs[SUBSCDATA_CTXDATA_AMF_HISTORY][0]
+		assert.Equal(t, "amf-1", history["amfInstanceId"])
+		assert.Equal(t, "http://amf-1/dereg", history["deregCallbackUri"])
+		assert.Equal(t, "amf-2", history["replacedBy"])
+	}
+}
+
+func TestQueryAmfRegistrationHistory(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	replacedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	CommonDBClient = &filteringDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_CTXDATA_AMF_HISTORY: {
+			{
+				"_id": "x", "ueId": "imsi-1", "accessType": "3GPP_ACCESS", "amfInstanceId": "amf-1",
+				"deregCallbackUri": "http://amf-1/dereg", "replacedBy": "amf-2", "replacedAt": replacedAt,
+			},
+			{
+				"ueId": "imsi-1", "accessType": "3GPP_ACCESS", "amfInstanceId": "amf-2",
+				"deregCallbackUri": "http://amf-2/dereg", "replacedBy": "amf-3", "replacedAt": replacedAt.Add(time.Hour),
+			},
+			{
+				"ueId": "imsi-1", "accessType": "NON_3GPP_ACCESS", "amfInstanceId": "amf-4",
+				"replacedBy": "amf-5", "replacedAt": replacedAt,
+			},
+		},
+	}}
+
+	rsp := HandleQueryAmfRegistrationHistory(dbRequest(nil, map[string]string{"ueId": "imsi-1"},
+		url.Values{"access-type": {"3GPP_ACCESS"}}))
+	assert.Equal(t, http.StatusOK, rsp.Status)
+	assert.Equal(t, []ReplacedAmfRegistration{
+		{
+			UeId: "imsi-1", AccessType: models.AccessType__3_GPP_ACCESS, AmfInstanceId: "amf-2",
+			DeregCallbackUri: "http://amf-2/dereg", ReplacedBy: "amf-3", ReplacedAt: replacedAt.Add(time.Hour),
+		},
+		{
+			UeId: "imsi-1", AccessType: models.AccessType__3_GPP_ACCESS, AmfInstanceId: "amf-1",
+			DeregCallbackUri: "http://amf-1/dereg", ReplacedBy: "amf-2", ReplacedAt: replacedAt,
+		},
+	}, rsp.Body)
+
+	rsp = HandleQueryAmfRegistrationHistory(dbRequest(nil, map[string]string{"ueId": "imsi-2"}, nil))
+	assert.Equal(t, http.StatusOK, rsp.Status)
+	assert.Equal(t, []ReplacedAmfRegistration{}, rsp.Body)
+}
+
+func TestPurgeFlagPatch(t *testing.T) {
+	patchItem, pd := purgeFlagPatch([]models.PatchItem{
+		{Op: models.PatchOperation_REPLACE, Path: "/purgeFlag", Value: true},
+		{Op: models.PatchOperation_REPLACE, Path: "/pei", Value: "imeisv-1"},
+	})
+	assert.Nil(t, pd)
+	assert.Equal(t, []models.PatchItem{
+		{Op: models.PatchOperation_ADD, Path: "/purgeFlag", Value: true},
+		{Op: models.PatchOperation_REPLACE, Path: "/pei", Value: "imeisv-1"},
+	}, patchItem)
+
+	_, pd = purgeFlagPatch([]models.PatchItem{{Op: models.PatchOperation_REPLACE, Path: "/purgeFlag", Value: "yes"}})
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusBadRequest), pd.Status)
+	}
+}
+
+// deleteRecordingDB records the filters of the deletions of many documents.
+type deleteRecordingDB struct {
+	fakeDB
+	deleted []bson.M
+}
+
+func (db *deleteRecordingDB) RestfulAPIDeleteMany(collName string, filter bson.M) error {
+	db.deleted = append(db.deleted, filter)
+	return nil
+}
+
+func TestExpireSmfRegistrations(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &deleteRecordingDB{}
+	CommonDBClient = db
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, expireSmfRegistrations(now, time.Hour))
+	expiry := now.Add(-time.Hour)
+	assert.Equal(t, []bson.M{{"$or": []bson.M{
+		{smfRegistrationRefreshField: bson.M{"$lt": expiry}},
+		{
+			smfRegistrationRefreshField: bson.M{"$exists": false},
+			"_id":                       bson.M{"$lt": objectIDAt(expiry)},
+		},
+	}}}, db.deleted)
+	assert.Equal(t, expiry, objectIDAt(expiry).Timestamp())
+	assert.True(t, primitive.NewObjectIDFromTimestamp(expiry.Add(-time.Second)).Hex() < objectIDAt(expiry).Hex())
+}
+
+func TestSmfRegistrationWithoutRefreshTime(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &fakeDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_CTXDATA_SMF_REGISTRATION: {{"ueId": "imsi-1", "pduSessionId": 1, smfRegistrationRefreshField: time.Now()}},
+	}}
+
+	smfRegistration, pd := QuerySmfRegistrationProcedure(SUBSCDATA_CTXDATA_SMF_REGISTRATION, "imsi-1", "1")
+	assert.Nil(t, pd)
+	if assert.NotNil(t, smfRegistration) {
+		assert.NotContains(t, *smfRegistration, smfRegistrationRefreshField)
+	}
+}
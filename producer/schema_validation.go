@@ -0,0 +1,315 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/util"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// schemaValidationDisabled is set when the request bodies and the patched
+// documents are stored without checking them against their model.
+var schemaValidationDisabled atomic.Bool
+
+// DisableSchemaValidation stores the request bodies and the patched documents
+// without checking them against the 3GPP models.
+func DisableSchemaValidation() {
+	schemaValidationDisabled.Store(true)
+}
+
+// documentSchemas are the models of the documents patched with JSON Patch,
+// keyed by collection. The attributes of the filter of a document and the
+// internal fields are not part of the model.
+var documentSchemas = map[string]reflect.Type{
+	SUBSCDATA_AUTHENTICATION_SUBSCRIPTION: reflect.TypeOf(models.AuthenticationSubscription{}),
+	SUBSCDATA_CTXDATA_AMF_3GPPACCESS:      reflect.TypeOf(models.Amf3GppAccessRegistration{}),
+	SUBSCDATA_CTXDATA_AMF_NON3GPPACCESS:   reflect.TypeOf(models.AmfNon3GppAccessRegistration{}),
+	SUBSCDATA_OPERATORSPECIFICDATA:        reflect.TypeOf(map[string]models.OperatorSpecificDataContainer{}),
+	SUBSCDATA_PPDATA:                      reflect.TypeOf(models.PpData{}),
+	POLICYDATA_UES_OPSPECDATA:             reflect.TypeOf(map[string]models.OperatorSpecificDataContainer{}),
+}
+
+// operatorSpecificDataContainerMapField holds the operator specific data of a
+// UE in the policy data documents.
+const operatorSpecificDataContainerMapField = "operatorSpecificDataContainerMap"
+
+// documentSchemaRoots names the attribute holding the modeled value in the
+// documents of the collections which do not store it at their root.
+var documentSchemaRoots = map[string]string{
+	POLICYDATA_UES_OPSPECDATA: operatorSpecificDataContainerMapField,
+}
+
+// mandatoryAttributes are the mandatory attributes of the models whose JSON
+// tags do not follow their specification, keyed by model. The attributes of
+// the other models are mandatory when their tag has no omitempty.
+var mandatoryAttributes = map[reflect.Type][]string{
+	// TS 29.505, sorXmacIue is optional
+	reflect.TypeOf(models.SorData{}): nil,
+	// TS 29.505, only callbackReference is required
+	reflect.TypeOf(models.SubscriptionDataSubscriptions{}): {"callbackReference"},
+	// TS 29.519, trafficRoutes is optional
+	reflect.TypeOf(models.TrafficInfluData{}): nil,
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	unmarshalType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// ValidateRequestBody checks the JSON body of a request against the model of
+// target, a pointer to it. The mandatory attributes of the model are required
+// and the attributes it does not define are rejected.
+func ValidateRequestBody(target interface{}, body []byte) *models.ProblemDetails {
+	return validateJSON(target, body, "the request body does not match its schema")
+}
+
+// validateJSON checks data against the model of target, a pointer to it. Data
+// which is not JSON is left to its decoding to report.
+func validateJSON(target interface{}, data []byte, detail string) *models.ProblemDetails {
+	if schemaValidationDisabled.Load() {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	invalidParams := validateSchema(value, reflect.TypeOf(target).Elem())
+	if len(invalidParams) == 0 {
+		return nil
+	}
+	return util.ProblemDetailsInvalidParams(detail, invalidParams)
+}
+
+// validateDocument checks a document of collName against its model once the
+// attributes of filter and the internal fields are left out.
+func validateDocument(collName string, doc map[string]interface{}, filter bson.M) *models.ProblemDetails {
+	schema, ok := documentSchemas[collName]
+	if !ok || schemaValidationDisabled.Load() {
+		return nil
+	}
+	data := make(map[string]interface{}, len(doc))
+	for key, value := range withoutInternalFields(doc) {
+		if _, ok := filter[key]; !ok {
+			data[key] = value
+		}
+	}
+	// the BSON types of stored documents are checked as their JSON types
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(util.MapToByte(data), &normalized); err != nil {
+		return util.ProblemDetailsSystemFailure(err.Error())
+	}
+	var value interface{} = normalized
+	if root, ok := documentSchemaRoots[collName]; ok {
+		value = normalized[root]
+	}
+	invalidParams := validateSchema(value, schema)
+	if len(invalidParams) == 0 {
+		return nil
+	}
+	return util.ProblemDetailsInvalidParams("the patched resource does not match its schema", invalidParams)
+}
+
+// validatePatchedDocument applies patchItem to the document of collName and
+// checks the result against its model. A patch that does not apply is left to
+// the write to report.
+func validatePatchedDocument(collName string, doc map[string]interface{}, filter bson.M,
+	patchItem []models.PatchItem,
+) *models.ProblemDetails {
+	if doc == nil || schemaValidationDisabled.Load() {
+		return nil
+	}
+	patchJSON, err := json.Marshal(patchItem)
+	if err != nil {
+		return nil
+	}
+	patch, err := jsonpatch.DecodePatch(patchJSON)
+	if err != nil {
+		return nil
+	}
+	modified, err := patch.Apply(util.MapToByte(withoutInternalFields(doc)))
+	if err != nil {
+		return nil
+	}
+	patched := map[string]interface{}{}
+	if err := json.Unmarshal(modified, &patched); err != nil {
+		return nil
+	}
+	return validateDocument(collName, patched, filter)
+}
+
+// validateSchema returns the attributes of value, a decoded JSON value, which
+// do not match the type of the model.
+func validateSchema(value interface{}, model reflect.Type) []models.InvalidParam {
+	var invalidParams []models.InvalidParam
+	checkSchema("", value, model, &invalidParams)
+	return invalidParams
+}
+
+func checkSchema(pointer string, value interface{}, model reflect.Type, invalidParams *[]models.InvalidParam) {
+	invalid := func(reason string) {
+		param := pointer
+		if param == "" {
+			param = "/"
+		}
+		*invalidParams = append(*invalidParams, models.InvalidParam{Param: param, Reason: reason})
+	}
+	for model.Kind() == reflect.Ptr {
+		model = model.Elem()
+	}
+	if value == nil {
+		// null decodes to the zero value of any attribute
+		return
+	}
+	if model == timeType {
+		if s, ok := value.(string); !ok {
+			invalid("must be a date-time string")
+		} else if _, err := time.Parse(time.RFC3339, s); err != nil {
+			invalid("must be a date-time string")
+		}
+		return
+	}
+	if reflect.PointerTo(model).Implements(unmarshalType) {
+		return
+	}
+
+	switch model.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			invalid("must be an object")
+			return
+		}
+		fields := schemaFields(model)
+		for _, name := range sortedKeys(object) {
+			field, ok := fields[name]
+			if !ok {
+				*invalidParams = append(*invalidParams, models.InvalidParam{
+					Param: pointer + "/" + escapePointer(name), Reason: "unknown attribute",
+				})
+				continue
+			}
+			checkSchema(pointer+"/"+escapePointer(name), object[name], field.Type, invalidParams)
+		}
+		for _, name := range sortedKeys(fields) {
+			if _, ok := object[name]; !ok && fields[name].mandatory {
+				*invalidParams = append(*invalidParams, models.InvalidParam{
+					Param: pointer + "/" + escapePointer(name), Reason: "mandatory attribute is missing",
+				})
+			}
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			invalid("must be an object")
+			return
+		}
+		for _, key := range sortedKeys(object) {
+			checkSchema(pointer+"/"+escapePointer(key), object[key], model.Elem(), invalidParams)
+		}
+	case reflect.Slice, reflect.Array:
+		if model.Elem().Kind() == reflect.Uint8 {
+			if _, ok := value.(string); !ok {
+				invalid("must be a base64 string")
+			}
+			return
+		}
+		array, ok := value.([]interface{})
+		if !ok {
+			invalid("must be an array")
+			return
+		}
+		for i, item := range array {
+			checkSchema(fmt.Sprintf("%s/%d", pointer, i), item, model.Elem(), invalidParams)
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			invalid("must be a string")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			invalid("must be a boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) || reflect.Zero(model).OverflowInt(int64(number)) {
+			invalid("must be an integer")
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) || number < 0 || reflect.Zero(model).OverflowUint(uint64(number)) {
+			invalid("must be a non-negative integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := value.(float64); !ok {
+			invalid("must be a number")
+		}
+	}
+}
+
+type schemaField struct {
+	reflect.StructField
+	mandatory bool
+}
+
+// schemaFields returns the attributes of a model keyed by their JSON name,
+// the attributes of the embedded models included.
+func schemaFields(model reflect.Type) map[string]schemaField {
+	fields := map[string]schemaField{}
+	for i := 0; i < model.NumField(); i++ {
+		field := model.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for embeddedName, embeddedField := range schemaFields(embedded) {
+					fields[embeddedName] = embeddedField
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = schemaField{StructField: field, mandatory: !strings.Contains(options, "omitempty")}
+	}
+	if mandatory, ok := mandatoryAttributes[model]; ok {
+		for name, field := range fields {
+			field.mandatory = slices.Contains(mandatory, name)
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+func sortedKeys[V any](object map[string]V) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapePointer escapes a JSON Pointer reference token, RFC 6901.
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
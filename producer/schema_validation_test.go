@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR schema validation of request bodies and patch results
+ */
+
+package producer
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/omec-project/openapi"
+	"github.com/omec-project/openapi/models"
+	udr_context "github.com/omec-project/udr/context"
+	"github.com/omec-project/util/httpwrapper"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestValidateRequestBody(t *testing.T) {
+	var sponsorConnectivityData models.SponsorConnectivityData
+	assert.Nil(t, ValidateRequestBody(&sponsorConnectivityData, []byte(`{"aspIds":["asp-1"]}`)))
+	// not JSON, left to the decoding of the body
+	assert.Nil(t, ValidateRequestBody(&sponsorConnectivityData, []byte(`{`)))
+
+	pd := ValidateRequestBody(&sponsorConnectivityData, []byte(`{"aspIds":"asp-1","unknown":1}`))
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusBadRequest), pd.Status)
+		assert.Equal(t, []models.InvalidParam{
+			{Param: "/aspIds", Reason: "must be an array"},
+			{Param: "/unknown", Reason: "unknown attribute"},
+		}, pd.InvalidParams)
+	}
+	pd = ValidateRequestBody(&sponsorConnectivityData, []byte(`{}`))
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, []models.InvalidParam{{Param: "/aspIds", Reason: "mandatory attribute is missing"}},
+			pd.InvalidParams)
+	}
+
+	var patchItems []models.PatchItem
+	pd = ValidateRequestBody(&patchItems, []byte(`[{"op":"replace","path":"/ratType","value":1},{"path":1}]`))
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, []models.InvalidParam{
+			{Param: "/1/path", Reason: "must be a string"},
+			{Param: "/1/op", Reason: "mandatory attribute is missing"},
+		}, pd.InvalidParams)
+	}
+}
+
+func TestPostSubscriptionWithCallbackReferenceOnly(t *testing.T) {
+	udrSelf := udr_context.UDR_Self()
+	defer udrSelf.Reset()
+
+	body := []byte(`{"callbackReference":"http://udm/notify"}`)
+	var subscription models.SubscriptionDataSubscriptions
+	assert.Nil(t, openapi.Deserialize(&subscription, body, "application/json"))
+	assert.Nil(t, ValidateRequestBody(&subscription, body))
+	rsp := HandlePostSubscriptionDataSubscriptions(&httpwrapper.Request{Body: subscription})
+	assert.Equal(t, http.StatusCreated, rsp.Status)
+	// a subscription without ueId is notified of the shared data changes
+	assert.Len(t, udrSelf.SubscriptionDataSubscribers("", time.Now()), 1)
+
+	pd := ValidateRequestBody(&subscription, []byte(`{"ueId":"imsi-1"}`))
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, []models.InvalidParam{{Param: "/callbackReference", Reason: "mandatory attribute is missing"}},
+			pd.InvalidParams)
+	}
+}
+
+func TestValidatePatchedDocument(t *testing.T) {
+	filter := bson.M{"ueId": "imsi-1"}
+	doc := map[string]interface{}{
+		"_id": "id", "ueId": "imsi-1", documentRevisionField: int64(2),
+		"authenticationMethod": "5G_AKA", "sequenceNumber": "000000000001",
+		"permanentKey": map[string]interface{}{"permanentKeyValue": "key", "encryptionKey": 0, "encryptionAlgorithm": 0},
+	}
+
+	assert.Nil(t, validatePatchedDocument(SUBSCDATA_AUTHENTICATION_SUBSCRIPTION, doc, filter, []models.PatchItem{
+		{Op: models.PatchOperation_REPLACE, Path: "/sequenceNumber", Value: "000000000002"},
+	}))
+	pd := validatePatchedDocument(SUBSCDATA_AUTHENTICATION_SUBSCRIPTION, doc, filter, []models.PatchItem{
+		{Op: models.PatchOperation_REPLACE, Path: "/authenticationMethod", Value: 42},
+		{Op: models.PatchOperation_REMOVE, Path: "/permanentKey"},
+	})
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusBadRequest), pd.Status)
+		assert.Equal(t, []models.InvalidParam{
+			{Param: "/authenticationMethod", Reason: "must be a string"},
+			{Param: "/permanentKey", Reason: "mandatory attribute is missing"},
+		}, pd.InvalidParams)
+	}
+
+	// the documents of collections without a model are not checked
+	assert.Nil(t, validatePatchedDocument(SUBSCDATA_PROVISIONED_AMDATA, doc, filter, []models.PatchItem{
+		{Op: models.PatchOperation_ADD, Path: "/unknown", Value: 1},
+	}))
+}
+
+func TestAmfContextNon3gppPatchValidation(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &fakeDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_CTXDATA_AMF_NON3GPPACCESS: {{
+			"ueId": "imsi-1", "amfInstanceId": "amf-1", "imsVoPs": "HOMOGENEOUS_SUPPORT",
+			"deregCallbackUri": "http://amf/dereg", "ratType": "WLAN",
+			"guami": map[string]interface{}{
+				"plmnId": map[string]interface{}{"mcc": "208", "mnc": "93"}, "amfId": "cafe00",
+			},
+		}},
+	}}
+
+	// rejected before the write, which the DB does not support
+	_, pd := AmfContextNon3gppProcedure("imsi-1", SUBSCDATA_CTXDATA_AMF_NON3GPPACCESS, []models.PatchItem{
+		{Op: models.PatchOperation_REMOVE, Path: "/amfInstanceId"},
+	}, http.Header{})
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusBadRequest), pd.Status)
+		assert.Equal(t, []models.InvalidParam{{Param: "/amfInstanceId", Reason: "mandatory attribute is missing"}},
+			pd.InvalidParams)
+	}
+}
+
+func TestPolicyOperatorSpecificDataPatchValidation(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &fakeDB{docs: map[string][]map[string]interface{}{
+		POLICYDATA_UES_OPSPECDATA: {{
+			"ueId": "imsi-1",
+			"operatorSpecificDataContainerMap": bson.M{
+				"container-1": bson.M{"IntegerTypeElements": bson.M{"quota": 1}},
+			},
+		}},
+	}}
+
+	_, pd := PolicyDataUesUeIdOperatorSpecificDataPatchProcedure(POLICYDATA_UES_OPSPECDATA, "imsi-1",
+		[]models.PatchItem{{Op: models.PatchOperation_REPLACE, Path: "/container-1/IntegerTypeElements/quota", Value: 1.5}},
+		http.Header{})
+	if assert.NotNil(t, pd) {
+		assert.Equal(t, int32(http.StatusBadRequest), pd.Status)
+		assert.Equal(t, []models.InvalidParam{
+			{Param: "/container-1/IntegerTypeElements/quota", Reason: "must be an integer"},
+		}, pd.InvalidParams)
+	}
+}
+
+func TestDisableSchemaValidation(t *testing.T) {
+	defer schemaValidationDisabled.Store(false)
+	DisableSchemaValidation()
+
+	var sponsorConnectivityData models.SponsorConnectivityData
+	assert.Nil(t, ValidateRequestBody(&sponsorConnectivityData, []byte(`{"aspIds":"asp-1"}`)))
+	assert.Nil(t, validatePatchedDocument(SUBSCDATA_AUTHENTICATION_SUBSCRIPTION,
+		map[string]interface{}{"authenticationMethod": "5G_AKA"}, nil, []models.PatchItem{
+			{Op: models.PatchOperation_REPLACE, Path: "/authenticationMethod", Value: 42},
+		}))
+}
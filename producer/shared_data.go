@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sharedDataReferences maps the collections of the UE data referencing
+// shared data to their referencing attribute, a sharedDataId or a list of
+// them.
+var sharedDataReferences = map[string]string{
+	SUBSCDATA_PROVISIONED_AMDATA:     "sharedAmDataIds",
+	SUBSCDATA_PROVISIONED_SMDATA:     "sharedDnnConfigurationsIds",
+	SUBSCDATA_PROVISIONED_SMFSELDATA: "sharedSnssaiInfosId",
+	SUBSCDATA_PROVISIONED_SMSDATA:    "sharedSmsSubsDataId",
+}
+
+// CreateSharedDataIndexes creates the indexes to fetch the shared data by
+// their identifiers and to find the UE data referencing them.
+func CreateSharedDataIndexes() {
+	collClient, ok := uncached(CommonDBClient).(collectionDBInterface)
+	if !ok {
+		return
+	}
+	_, err := collClient.GetCollection(SUBSCDATA_SHAREDDATA).Indexes().CreateOne(context.Background(),
+		mongo.IndexModel{Keys: bson.D{{Key: "sharedDataId", Value: 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		logger.DataRepoLog.Warnf("create indexes of %s: %v", SUBSCDATA_SHAREDDATA, err)
+	}
+	for collName, attribute := range sharedDataReferences {
+		_, err := collClient.GetCollection(collName).Indexes().CreateOne(context.Background(),
+			mongo.IndexModel{Keys: bson.D{{Key: attribute, Value: 1}}})
+		if err != nil {
+			logger.DataRepoLog.Warnf("create indexes of %s: %v", collName, err)
+		}
+	}
+}
+
+func validateSharedData(sharedData *models.SharedData) error {
+	if sharedData.SharedAmData == nil && sharedData.SharedSmsSubsData == nil &&
+		sharedData.SharedSmsMngSubsData == nil && len(sharedData.SharedDnnConfigurations) == 0 &&
+		sharedData.SharedTraceData == nil && len(sharedData.SharedSnssaiInfos) == 0 {
+		return fmt.Errorf("no shared data")
+	}
+	return nil
+}
+
+func sharedDataUri(sharedDataId string) string {
+	return strings.ReplaceAll(subscriptionDataResourceUri(SUBSCDATA_SHAREDDATA, "", ""), "{sharedDataId}",
+		sharedDataId)
+}
+
+func HandleGetIndividualSharedData(sharedDataId string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle GetIndividualSharedData: sharedDataId=%q", sharedDataId)
+
+	sharedData, problemDetails := getDataFromDB(SUBSCDATA_SHAREDDATA, bson.M{"sharedDataId": sharedDataId})
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("get", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("get", "shared-data", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, sharedData)
+}
+
+// HandlePutSharedData creates or replaces the shared data sharedDataId.
+func HandlePutSharedData(sharedDataId string, body []byte) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle PutSharedData: sharedDataId=%q", sharedDataId)
+
+	var sharedData models.SharedData
+	if pd := ValidateRequestBody(&sharedData, body); pd != nil {
+		stats.IncrementUdrSubscriptionDataStats("create", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := decodeStrict(body, &sharedData); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("create", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if sharedData.SharedDataId == "" {
+		sharedData.SharedDataId = sharedDataId
+	}
+	if sharedData.SharedDataId != sharedDataId {
+		pd := util.ProblemDetailsMalformedReqSyntax("sharedDataId does not match the resource")
+		stats.IncrementUdrSubscriptionDataStats("create", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := validateSharedData(&sharedData); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("create", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	filter := bson.M{"sharedDataId": sharedDataId}
+	origValue, err := CommonDBClient.RestfulAPIGetOne(SUBSCDATA_SHAREDDATA, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("create", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if _, err := CommonDBClient.RestfulAPIPutOne(SUBSCDATA_SHAREDDATA, filter, util.ToBsonM(sharedData)); err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("create", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrSubscriptionDataStats("create", "shared-data", "SUCCESS")
+
+	resourceUri := sharedDataUri(sharedDataId)
+	if origValue != nil {
+		notifySubscriptionDataChange("", resourceUri, models.ChangeItem{
+			Op: models.ChangeType_REPLACE, Path: "/", OrigValue: withoutInternalFields(origValue), NewValue: sharedData,
+		})
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	}
+	notifySubscriptionDataChange("", resourceUri, models.ChangeItem{
+		Op: models.ChangeType_ADD, Path: "/", NewValue: sharedData,
+	})
+	headers := http.Header{}
+	headers.Set("Location", resourceUri)
+	return httpwrapper.NewResponse(http.StatusCreated, headers, sharedData)
+}
+
+// HandleModifySharedData applies the JSON merge patch in body to the shared
+// data sharedDataId.
+func HandleModifySharedData(sharedDataId string, body []byte) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle ModifySharedData: sharedDataId=%q", sharedDataId)
+
+	var sharedData models.SharedData
+	problemDetails := mergePatchDocument(SUBSCDATA_SHAREDDATA, bson.M{"sharedDataId": sharedDataId}, body,
+		&sharedData, func() error {
+			if sharedData.SharedDataId != "" && sharedData.SharedDataId != sharedDataId {
+				return fmt.Errorf("sharedDataId cannot be modified")
+			}
+			sharedData.SharedDataId = sharedDataId
+			return validateSharedData(&sharedData)
+		})
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("update", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("update", "shared-data", "SUCCESS")
+	notifySubscriptionDataChange("", sharedDataUri(sharedDataId),
+		models.ChangeItem{Op: models.ChangeType_REPLACE, Path: "/", NewValue: sharedData})
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
+
+// HandleDeleteSharedData deletes the shared data sharedDataId, unless the
+// data of a UE still references it.
+func HandleDeleteSharedData(sharedDataId string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle DeleteSharedData: sharedDataId=%q", sharedDataId)
+
+	referencingUes, err := sharedDataReferencingUes(sharedDataId)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("delete", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if len(referencingUes) > 0 {
+		pd := util.ProblemDetailsConflict(fmt.Sprintf("shared data %s is referenced by %s",
+			sharedDataId, strings.Join(referencingUes, ", ")))
+		stats.IncrementUdrSubscriptionDataStats("delete", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	filter := bson.M{"sharedDataId": sharedDataId}
+	origValue, err := CommonDBClient.RestfulAPIGetOne(SUBSCDATA_SHAREDDATA, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("delete", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	problemDetails := deleteExistingDocument(SUBSCDATA_SHAREDDATA, filter)
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("delete", "shared-data", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("delete", "shared-data", "SUCCESS")
+	notifySubscriptionDataChange("", sharedDataUri(sharedDataId), models.ChangeItem{
+		Op: models.ChangeType_REMOVE, Path: "/", OrigValue: withoutInternalFields(origValue),
+	})
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
+
+// sharedDataReferencingUes returns the UEs whose data references the shared
+// data sharedDataId.
+func sharedDataReferencingUes(sharedDataId string) ([]string, error) {
+	var ueIds []string
+	seen := map[string]bool{}
+	for collName, attribute := range sharedDataReferences {
+		docs, err := CommonDBClient.RestfulAPIGetMany(collName, bson.M{attribute: sharedDataId})
+		if err != nil {
+			return nil, fmt.Errorf("references to shared data %s: %w", sharedDataId, err)
+		}
+		for _, doc := range docs {
+			if ueId, ok := doc["ueId"].(string); ok && !seen[ueId] {
+				seen[ueId] = true
+				ueIds = append(ueIds, ueId)
+			}
+		}
+	}
+	sort.Strings(ueIds)
+	return ueIds, nil
+}
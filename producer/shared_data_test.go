@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR shared data
+ */
+
+package producer
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGetSharedData(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &filterRecordingDB{fakeDB: fakeDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_SHAREDDATA: {
+			{"_id": "x", "sharedDataId": "sd-2", "sharedTraceData": map[string]interface{}{"traceRef": "1"}},
+			{"_id": "y", "sharedDataId": "sd-1", "sharedAmData": map[string]interface{}{"rfspIndex": 1}},
+		},
+	}}}
+	CommonDBClient = db
+
+	rsp, pd := GetSharedDataProcedure(SUBSCDATA_SHAREDDATA, []string{"sd-1", "sd-3", "sd-2"})
+	assert.Nil(t, pd)
+	if assert.NotNil(t, rsp) {
+		assert.Equal(t, []map[string]interface{}{
+			{"sharedDataId": "sd-1", "sharedAmData": map[string]interface{}{"rfspIndex": 1}},
+			{"sharedDataId": "sd-2", "sharedTraceData": map[string]interface{}{"traceRef": "1"}},
+		}, *rsp)
+	}
+	assert.Equal(t, []bson.M{{"sharedDataId": bson.M{"$in": []string{"sd-1", "sd-3", "sd-2"}}}}, db.filters)
+}
+
+func TestDeleteReferencedSharedData(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &filteringDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_SHAREDDATA: {{"sharedDataId": "sd-1"}, {"sharedDataId": "sd-2"}},
+		SUBSCDATA_PROVISIONED_AMDATA: {
+			{"ueId": "imsi-1", "sharedAmDataIds": []string{"sd-1"}},
+			{"ueId": "imsi-2", "sharedAmDataIds": []string{"sd-3"}},
+		},
+		SUBSCDATA_PROVISIONED_SMDATA: {{"ueId": "imsi-3", "sharedDnnConfigurationsIds": "sd-1"}},
+	}}
+	CommonDBClient = db
+
+	rsp := HandleDeleteSharedData("sd-1")
+	assert.Equal(t, http.StatusConflict, rsp.Status)
+	if pd, ok := rsp.Body.(*models.ProblemDetails); assert.True(t, ok) {
+		assert.Contains(t, pd.Detail, "imsi-1, imsi-3")
+	}
+	assert.Len(t, db.docs[SUBSCDATA_SHAREDDATA], 2)
+
+	// no UE references sd-2
+	rsp = HandleDeleteSharedData("sd-2")
+	assert.Equal(t, http.StatusNoContent, rsp.Status)
+	assert.Equal(t, []map[string]interface{}{{"sharedDataId": "sd-1"}}, db.docs[SUBSCDATA_SHAREDDATA])
+}
+
+func TestPutSharedDataValidation(t *testing.T) {
+	rsp := HandlePutSharedData("sd-1", []byte(`{"sharedDataId":"sd-2","sharedAmData":{}}`))
+	assert.Equal(t, http.StatusBadRequest, rsp.Status)
+	rsp = HandlePutSharedData("sd-1", []byte(`{"sharedDataId":"sd-1"}`))
+	assert.Equal(t, http.StatusBadRequest, rsp.Status)
+}
+
+func TestSharedDataChangeNotifyItem(t *testing.T) {
+	event := &changeEvent{OperationType: "insert"}
+	event.Ns.Coll = SUBSCDATA_SHAREDDATA
+	event.FullDocument = bson.M{"sharedDataId": "sd-1"}
+	ueId, notifyItem := dataChangeNotifyItem(event)
+	assert.Equal(t, "", ueId)
+	if assert.NotNil(t, notifyItem) {
+		assert.True(t, strings.HasSuffix(notifyItem.ResourceId, "/subscription-data/shared-data/sd-1"))
+	}
+
+	// the data of a UE without ueId is not notified
+	event.Ns.Coll = SUBSCDATA_PROVISIONED_AMDATA
+	_, notifyItem = dataChangeNotifyItem(event)
+	assert.Nil(t, notifyItem)
+}
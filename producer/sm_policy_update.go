@@ -0,0 +1,267 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	protos "github.com/5GC-DEV/config5g-cdac/proto/sdcoreConfig"
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/logger"
+	"github.com/omec-project/udr/util"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	POLICYDATA_UES_SMDATA = "policyData.ues.smData"
+	smPolicyMaxAttempts   = 3
+)
+
+// SmPolicyUpdate adds or removes one IMSI/DNN/S-NSSAI entry of policyData.ues.smData.
+type SmPolicyUpdate struct {
+	Imsi   string
+	Dnn    string
+	Snssai *protos.NSSAI
+	Remove bool
+}
+
+// SmPolicyBatchError reports the UEs of a batch whose update failed.
+type SmPolicyBatchError struct {
+	Failed map[string]error // keyed by ueId
+}
+
+func (e *SmPolicyBatchError) Error() string {
+	errs := make([]error, 0, len(e.Failed))
+	for ueId, err := range e.Failed {
+		errs = append(errs, fmt.Errorf("%s: %w", ueId, err))
+	}
+	return fmt.Sprintf("%d UE(s) failed: %v", len(e.Failed), errors.Join(errs...))
+}
+
+// collectionDBInterface is implemented by DB clients giving access to the
+// underlying MongoDB collection, which is needed for bulk and versioned
+// writes and for projected reads.
+type collectionDBInterface interface {
+	GetCollection(collName string) *mongo.Collection
+}
+
+type smPolicyDoc struct {
+	revision int64
+	data     models.SmPolicyData
+	exists   bool
+}
+
+// ApplySmPolicyUpdates applies a batch of updates with one read and one
+// unordered bulk write per attempt. Each UE document is updated atomically:
+// a write only succeeds if the document revision is unchanged since it was
+// read, otherwise the UE is re-read and retried. Updates are idempotent.
+// It returns the number of UEs updated and a *SmPolicyBatchError for the rest.
+func ApplySmPolicyUpdates(updates []SmPolicyUpdate) (int, error) {
+	pending := make(map[string][]SmPolicyUpdate)
+	for _, update := range updates {
+		ueId := "imsi-" + update.Imsi
+		pending[ueId] = append(pending[ueId], update)
+	}
+	total := len(pending)
+	failed := make(map[string]error)
+
+	for attempt := 1; attempt <= smPolicyMaxAttempts && len(pending) > 0; attempt++ {
+		conflicts, err := applySmPolicyAttempt(pending, failed)
+		if err != nil {
+			for ueId := range pending {
+				failed[ueId] = err
+			}
+			pending = nil
+			break
+		}
+		if len(conflicts) > 0 {
+			logger.CfgLog.Infof("sm policy batch attempt %d: %d UE(s) changed concurrently, retrying",
+				attempt, len(conflicts))
+		}
+		pending = conflicts
+	}
+	for ueId := range pending {
+		failed[ueId] = fmt.Errorf("concurrent updates after %d attempts", smPolicyMaxAttempts)
+	}
+
+	if len(failed) > 0 {
+		return total - len(failed), &SmPolicyBatchError{Failed: failed}
+	}
+	return total, nil
+}
+
+// applySmPolicyAttempt writes the pending updates and returns the UEs whose
+// document changed concurrently. UEs with invalid updates are moved to failed.
+func applySmPolicyAttempt(pending map[string][]SmPolicyUpdate, failed map[string]error) (
+	map[string][]SmPolicyUpdate, error,
+) {
+	collClient, ok := CommonDBClient.(collectionDBInterface)
+	if !ok {
+		return nil, fmt.Errorf("DB client does not support bulk writes")
+	}
+
+	ueIds := make([]string, 0, len(pending))
+	for ueId := range pending {
+		ueIds = append(ueIds, ueId)
+	}
+	docs, err := readSmPolicyDocs(ueIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var writes []mongo.WriteModel
+	var writeUeIds []string
+	var inserts []int
+	for _, ueId := range ueIds {
+		doc := docs[ueId]
+		changed, err := applySmPolicyOps(&doc.data, pending[ueId])
+		if err != nil {
+			failed[ueId] = err
+			delete(pending, ueId)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		empty := len(doc.data.SmPolicySnssaiData) == 0 && doc.data.UmDataLimits == nil && doc.data.UmData == nil
+		switch {
+		case !doc.exists && empty:
+			continue
+		case !doc.exists:
+			inserts = append(inserts, len(writes))
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"ueId": ueId}).
+				SetUpdate(bson.M{"$setOnInsert": bson.M{
+					"ueId":                ueId,
+					"smPolicySnssaiData":  toBsonM(doc.data.SmPolicySnssaiData),
+					documentRevisionField: int64(1),
+				}}).
+				SetUpsert(true))
+		case empty:
+			writes = append(writes, mongo.NewDeleteOneModel().
+				SetFilter(bson.M{"ueId": ueId, documentRevisionField: revisionFilter(doc.revision)}))
+		default:
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"ueId": ueId, documentRevisionField: revisionFilter(doc.revision)}).
+				SetUpdate(bson.M{"$set": bson.M{
+					"smPolicySnssaiData":  toBsonM(doc.data.SmPolicySnssaiData),
+					documentRevisionField: doc.revision + 1,
+				}}))
+		}
+		writeUeIds = append(writeUeIds, ueId)
+	}
+	if len(writes) == 0 {
+		return nil, nil
+	}
+
+	collection := collClient.GetCollection(POLICYDATA_UES_SMDATA)
+	result, err := collection.BulkWrite(context.TODO(), writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return nil, fmt.Errorf("bulk write: %w", err)
+	}
+
+	// an unordered bulk write does not tell which update matched, so when any
+	// of them lost a race every UE of the batch is re-read; retrying an update
+	// that did apply is harmless since updates are idempotent
+	conflicts := make(map[string][]SmPolicyUpdate)
+	for _, idx := range inserts {
+		if _, ok := result.UpsertedIDs[int64(idx)]; !ok {
+			conflicts[writeUeIds[idx]] = pending[writeUeIds[idx]]
+		}
+	}
+	expected := int64(len(writes) - len(inserts))
+	if result.MatchedCount+result.DeletedCount-(int64(len(inserts))-result.UpsertedCount) < expected {
+		for _, ueId := range writeUeIds {
+			conflicts[ueId] = pending[ueId]
+		}
+	}
+	return conflicts, nil
+}
+
+func revisionFilter(revision int64) interface{} {
+	if revision == 0 {
+		return bson.M{"$exists": false}
+	}
+	return revision
+}
+
+func readSmPolicyDocs(ueIds []string) (map[string]*smPolicyDoc, error) {
+	docs := make(map[string]*smPolicyDoc, len(ueIds))
+	for _, ueId := range ueIds {
+		docs[ueId] = &smPolicyDoc{data: models.SmPolicyData{
+			SmPolicySnssaiData: make(map[string]models.SmPolicySnssaiData),
+		}}
+	}
+	raw, err := CommonDBClient.RestfulAPIGetMany(POLICYDATA_UES_SMDATA, bson.M{"ueId": bson.M{"$in": ueIds}})
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", POLICYDATA_UES_SMDATA, err)
+	}
+	for _, smPolicyData := range raw {
+		ueId, _ := smPolicyData["ueId"].(string)
+		doc, ok := docs[ueId]
+		if !ok || doc.exists {
+			continue
+		}
+		doc.exists = true
+		doc.revision = documentRevision(smPolicyData)
+		if err := json.Unmarshal(util.MapToByte(smPolicyData), &doc.data); err != nil {
+			return nil, fmt.Errorf("decode %s of %s: %w", POLICYDATA_UES_SMDATA, ueId, err)
+		}
+		if doc.data.SmPolicySnssaiData == nil {
+			doc.data.SmPolicySnssaiData = make(map[string]models.SmPolicySnssaiData)
+		}
+	}
+	return docs, nil
+}
+
+// applySmPolicyOps applies the updates of one UE in order and reports
+// whether the document changed.
+func applySmPolicyOps(data *models.SmPolicyData, updates []SmPolicyUpdate) (bool, error) {
+	changed := false
+	for _, update := range updates {
+		sval, err := strconv.ParseUint(update.Snssai.Sst, 10, 8)
+		if err != nil {
+			return false, fmt.Errorf("invalid sst %q: %w", update.Snssai.Sst, err)
+		}
+		modelNssai := models.Snssai{Sd: update.Snssai.Sd, Sst: int32(sval)}
+		hexSnssai := util.SnssaiModelsToHex(modelNssai)
+		snssaiData, exists := data.SmPolicySnssaiData[hexSnssai]
+
+		if update.Remove {
+			if !exists {
+				continue
+			}
+			if _, ok := snssaiData.SmPolicyDnnData[update.Dnn]; !ok {
+				continue
+			}
+			delete(snssaiData.SmPolicyDnnData, update.Dnn)
+			if len(snssaiData.SmPolicyDnnData) == 0 {
+				delete(data.SmPolicySnssaiData, hexSnssai)
+			} else {
+				data.SmPolicySnssaiData[hexSnssai] = snssaiData
+			}
+			changed = true
+			continue
+		}
+
+		if !exists {
+			snssaiData = models.SmPolicySnssaiData{Snssai: &modelNssai}
+		}
+		if snssaiData.SmPolicyDnnData == nil {
+			snssaiData.SmPolicyDnnData = make(map[string]models.SmPolicyDnnData)
+		}
+		if _, ok := snssaiData.SmPolicyDnnData[update.Dnn]; ok {
+			continue
+		}
+		snssaiData.SmPolicyDnnData[update.Dnn] = models.SmPolicyDnnData{Dnn: update.Dnn}
+		data.SmPolicySnssaiData[hexSnssai] = snssaiData
+		changed = true
+	}
+	return changed, nil
+}
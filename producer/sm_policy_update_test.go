@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"testing"
+
+	protos "github.com/5GC-DEV/config5g-cdac/proto/sdcoreConfig"
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplySmPolicyOpsIsIdempotent(t *testing.T) {
+	snssai := &protos.NSSAI{Sst: "1", Sd: "010203"}
+	data := models.SmPolicyData{SmPolicySnssaiData: make(map[string]models.SmPolicySnssaiData)}
+	add := []SmPolicyUpdate{
+		{Imsi: "1", Dnn: "internet", Snssai: snssai},
+		{Imsi: "1", Dnn: "ims.mnc001.mcc001.gprs", Snssai: snssai},
+	}
+
+	changed, err := applySmPolicyOps(&data, add)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Len(t, data.SmPolicySnssaiData["01010203"].SmPolicyDnnData, 2)
+
+	changed, err = applySmPolicyOps(&data, add)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	changed, err = applySmPolicyOps(&data, []SmPolicyUpdate{
+		{Imsi: "1", Dnn: "internet", Snssai: snssai, Remove: true},
+		{Imsi: "1", Dnn: "ims.mnc001.mcc001.gprs", Snssai: snssai, Remove: true},
+		{Imsi: "1", Dnn: "internet", Snssai: snssai, Remove: true},
+	})
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Empty(t, data.SmPolicySnssaiData)
+
+	_, err = applySmPolicyOps(&data, []SmPolicyUpdate{{Imsi: "1", Dnn: "internet", Snssai: &protos.NSSAI{Sst: "x"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/omec-project/udr/factory"
+	"github.com/omec-project/udr/logger"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CONFIGPOD_PROVISIONED_DATA records per UE the subscriber data documents
+// the config pod wrote, so that only those are removed once stale and the
+// documents written through webui are left alone.
+const CONFIGPOD_PROVISIONED_DATA = "configPod.provisionedData"
+
+// provisionedData identifies the documents the config pod wrote for a UE.
+type provisionedData struct {
+	// ServingPlmnIds are the serving PLMNs of the AM and SMF selection data.
+	ServingPlmnIds []string            `bson:"servingPlmnIds"`
+	SmData         []provisionedSmData `bson:"smData"`
+	AmPolicyData   bool                `bson:"amPolicyData"`
+}
+
+// provisionedSmData identifies the SM data of a serving PLMN and S-NSSAI.
+type provisionedSmData struct {
+	ServingPlmnId string `bson:"servingPlmnId"`
+	Sst           int32  `bson:"sst"`
+	Sd            string `bson:"sd"`
+}
+
+func (key provisionedSmData) filter(ueId string) bson.M {
+	filter := bson.M{
+		"ueId":            ueId,
+		"servingPlmnId":   key.ServingPlmnId,
+		"singleNssai.sst": key.Sst,
+		"singleNssai.sd":  key.Sd,
+	}
+	if key.Sd == "" {
+		// an empty SD is omitted from the stored document
+		filter["singleNssai.sd"] = bson.M{"$in": bson.A{"", nil}}
+	}
+	return filter
+}
+
+// without returns the documents of data that are not in current.
+func (data provisionedData) without(current provisionedData) provisionedData {
+	var stale provisionedData
+	for _, plmnId := range data.ServingPlmnIds {
+		if !slices.Contains(current.ServingPlmnIds, plmnId) {
+			stale.ServingPlmnIds = append(stale.ServingPlmnIds, plmnId)
+		}
+	}
+	for _, key := range data.SmData {
+		if !slices.Contains(current.SmData, key) {
+			stale.SmData = append(stale.SmData, key)
+		}
+	}
+	stale.AmPolicyData = data.AmPolicyData && !current.AmPolicyData
+	return stale
+}
+
+// provisionedDataOf returns the documents the config pod wrote for ueId.
+func provisionedDataOf(ueId string) (provisionedData, error) {
+	var data provisionedData
+	doc, err := CommonDBClient.RestfulAPIGetOne(CONFIGPOD_PROVISIONED_DATA, bson.M{"ueId": ueId})
+	if err != nil || doc == nil {
+		return data, err
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return data, err
+	}
+	err = bson.Unmarshal(raw, &data)
+	return data, err
+}
+
+// recordProvisionedData records data as the documents the config pod wrote
+// for ueId.
+func recordProvisionedData(ueId string, data provisionedData) error {
+	raw, err := bson.Marshal(data)
+	if err != nil {
+		return err
+	}
+	record := bson.M{}
+	if err := bson.Unmarshal(raw, &record); err != nil {
+		return err
+	}
+	record["ueId"] = ueId
+	_, err = CommonDBClient.RestfulAPIPutOne(CONFIGPOD_PROVISIONED_DATA, bson.M{"ueId": ueId}, record)
+	return err
+}
+
+// removeProvisionedData deletes the documents data of ueId.
+func removeProvisionedData(ueId string, data provisionedData) []error {
+	var errs []error
+	if len(data.ServingPlmnIds) > 0 {
+		filter := bson.M{"ueId": ueId, "servingPlmnId": bson.M{"$in": toBsonA(data.ServingPlmnIds)}}
+		for _, collName := range []string{SUBSCDATA_PROVISIONED_AMDATA, SUBSCDATA_PROVISIONED_SMFSELDATA} {
+			if err := deleteDocumentsWithHistory(CommonDBClient, collName, filter, configPodActor); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, key := range data.SmData {
+		if err := CommonDBClient.RestfulAPIDeleteMany(SUBSCDATA_PROVISIONED_SMDATA, key.filter(ueId)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if data.AmPolicyData {
+		err := deleteDocumentsWithHistory(CommonDBClient, POLICYDATA_UES_AMDATA, bson.M{"ueId": ueId}, configPodActor)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func toBsonA(values []string) bson.A {
+	array := make(bson.A, 0, len(values))
+	for _, value := range values {
+		array = append(array, value)
+	}
+	return array
+}
+
+// ProvisionSubscriberData writes the subscriber data the config pod implies
+// for one IMSI: AM data, SMF selection data and SM data per serving PLMN, and
+// the AM policy data. Fields of these documents that the config pod does not
+// own (e.g. GPSIs) are left untouched; the documents it wrote before for
+// serving PLMNs or S-NSSAIs the IMSI no longer has are removed. The documents
+// it changes get a new revision, and the versions of the AM and SMF selection
+// data it changes are kept in the document history.
+func ProvisionSubscriberData(entry *factory.SubscriberDataEntry) error {
+	ueId := "imsi-" + entry.Imsi
+	logger.CfgLog.Infof("provisioning subscriber data of %s", ueId)
+	owned, err := provisionedDataOf(ueId)
+	if err != nil {
+		return fmt.Errorf("provisioning subscriber data of %s: %w", ueId, err)
+	}
+	var errs []error
+
+	current := provisionedData{AmPolicyData: entry.AmPolicyData != nil}
+	for plmnId, data := range entry.ServingPlmns {
+		current.ServingPlmnIds = append(current.ServingPlmnIds, plmnId)
+		filter := bson.M{"ueId": ueId, "servingPlmnId": plmnId}
+
+		amData := bson.M{"ueId": ueId, "servingPlmnId": plmnId, "nssai": toBsonM(data.AmData.Nssai)}
+		if data.AmData.SubscribedUeAmbr != nil {
+			amData["subscribedUeAmbr"] = toBsonM(data.AmData.SubscribedUeAmbr)
+		}
+		if err := putDocumentWithHistory(CommonDBClient, SUBSCDATA_PROVISIONED_AMDATA, filter, amData,
+			configPodActor); err != nil {
+			errs = append(errs, err)
+		}
+
+		smfSelData := bson.M{
+			"ueId":                  ueId,
+			"servingPlmnId":         plmnId,
+			"subscribedSnssaiInfos": toBsonM(data.SmfSelData.SubscribedSnssaiInfos),
+		}
+		if err := putDocumentWithHistory(CommonDBClient, SUBSCDATA_PROVISIONED_SMFSELDATA, filter, smfSelData,
+			configPodActor); err != nil {
+			errs = append(errs, err)
+		}
+
+		for _, sm := range data.SmData {
+			key := provisionedSmData{ServingPlmnId: plmnId, Sst: sm.SingleNssai.Sst, Sd: sm.SingleNssai.Sd}
+			smData := bson.M{
+				"ueId":              ueId,
+				"servingPlmnId":     plmnId,
+				"singleNssai":       toBsonM(sm.SingleNssai),
+				"dnnConfigurations": toBsonM(sm.DnnConfigurations),
+			}
+			if err := putDocumentWithHistory(CommonDBClient, SUBSCDATA_PROVISIONED_SMDATA, key.filter(ueId),
+				smData, configPodActor); err != nil {
+				errs = append(errs, err)
+			}
+			current.SmData = append(current.SmData, key)
+		}
+	}
+
+	if entry.AmPolicyData != nil {
+		amPolicyData := bson.M{"ueId": ueId, "subscCats": entry.AmPolicyData.SubscCats}
+		err := putDocumentWithHistory(CommonDBClient, POLICYDATA_UES_AMDATA, bson.M{"ueId": ueId}, amPolicyData,
+			configPodActor)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, removeProvisionedData(ueId, owned.without(current))...)
+	if err := recordProvisionedData(ueId, current); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("provisioning subscriber data of %s: %w", ueId, err)
+	}
+	return nil
+}
+
+// RemoveSubscriberData deletes the config pod provisioned datasets of an IMSI
+// that is no longer part of any network slice.
+func RemoveSubscriberData(imsi string) error {
+	ueId := "imsi-" + imsi
+	logger.CfgLog.Infof("removing subscriber data of %s", ueId)
+	owned, err := provisionedDataOf(ueId)
+	if err != nil {
+		return fmt.Errorf("removing subscriber data of %s: %w", ueId, err)
+	}
+	errs := removeProvisionedData(ueId, owned)
+	if len(errs) == 0 {
+		if err := CommonDBClient.RestfulAPIDeleteOne(CONFIGPOD_PROVISIONED_DATA, bson.M{"ueId": ueId}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("removing subscriber data of %s: %w", ueId, err)
+	}
+	return nil
+}
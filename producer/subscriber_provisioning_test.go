@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR subscriber data provisioned by the config pod
+ */
+
+package producer
+
+import (
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/omec-project/udr/factory"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func provisioningEntry(plmnIds ...string) *factory.SubscriberDataEntry {
+	entry := &factory.SubscriberDataEntry{
+		Imsi:         "1",
+		ServingPlmns: map[string]*factory.ServingPlmnSubscriberData{},
+		AmPolicyData: &models.AmPolicyData{SubscCats: []string{"slice1"}},
+	}
+	for _, plmnId := range plmnIds {
+		entry.ServingPlmns[plmnId] = &factory.ServingPlmnSubscriberData{
+			AmData:     &models.AccessAndMobilitySubscriptionData{Nssai: &models.Nssai{}},
+			SmfSelData: &models.SmfSelectionSubscriptionData{},
+			SmData:     []models.SessionManagementSubscriptionData{{SingleNssai: &models.Snssai{Sst: 1}}},
+		}
+	}
+	return entry
+}
+
+func TestProvisionSubscriberDataKeepsOtherDocuments(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("provision", func(mt *mtest.T) {
+		defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+		db := newVersionedDB(mt, map[string][]map[string]interface{}{
+			// written through webui
+			SUBSCDATA_PROVISIONED_AMDATA: {{"ueId": "imsi-1", "servingPlmnId": "00101"}},
+			SUBSCDATA_PROVISIONED_SMDATA: {{
+				"ueId": "imsi-1", "servingPlmnId": "00101", "singleNssai": bson.M{"sst": int32(1)},
+			}},
+		})
+		CommonDBClient = db
+		servingPlmnIds := func(collName string) []interface{} {
+			db.applyWrites()
+			var plmnIds []interface{}
+			for _, doc := range db.docs[collName] {
+				plmnIds = append(plmnIds, doc["servingPlmnId"])
+			}
+			return plmnIds
+		}
+
+		assert.NoError(mt, ProvisionSubscriberData(provisioningEntry("20893", "310410")))
+		assert.ElementsMatch(mt, []interface{}{"00101", "20893", "310410"}, servingPlmnIds(SUBSCDATA_PROVISIONED_AMDATA))
+
+		// only the data of the PLMN the config pod no longer provisions is stale
+		assert.NoError(mt, ProvisionSubscriberData(provisioningEntry("20893")))
+		assert.ElementsMatch(mt, []interface{}{"00101", "20893"}, servingPlmnIds(SUBSCDATA_PROVISIONED_AMDATA))
+		assert.ElementsMatch(mt, []interface{}{"20893"}, servingPlmnIds(SUBSCDATA_PROVISIONED_SMFSELDATA))
+		assert.ElementsMatch(mt, []interface{}{"00101", "20893"}, servingPlmnIds(SUBSCDATA_PROVISIONED_SMDATA))
+
+		assert.NoError(mt, RemoveSubscriberData("1"))
+		assert.Equal(mt, []interface{}{"00101"}, servingPlmnIds(SUBSCDATA_PROVISIONED_AMDATA))
+		assert.Equal(mt, []interface{}{"00101"}, servingPlmnIds(SUBSCDATA_PROVISIONED_SMDATA))
+		assert.Empty(mt, db.docs[POLICYDATA_UES_AMDATA])
+		assert.Empty(mt, db.docs[CONFIGPOD_PROVISIONED_DATA])
+
+		// the config pod writes are new revisions, like the writes of the NFs
+		plmnFilter := bson.M{"ueId": "imsi-1", "servingPlmnId": "20893"}
+		revisions := func(revision int) {
+			for collName, filter := range map[string]bson.M{
+				SUBSCDATA_PROVISIONED_AMDATA:     plmnFilter,
+				SUBSCDATA_PROVISIONED_SMFSELDATA: plmnFilter,
+				SUBSCDATA_PROVISIONED_SMDATA:     plmnFilter,
+				POLICYDATA_UES_AMDATA:            {"ueId": "imsi-1"},
+			} {
+				doc, err := db.RestfulAPIGetOne(collName, filter)
+				assert.NoError(mt, err)
+				assert.Equal(mt, int64(revision), documentRevision(doc), collName)
+			}
+		}
+		entry := provisioningEntry("20893")
+		assert.NoError(mt, ProvisionSubscriberData(entry))
+		revisions(1)
+		entry.AmPolicyData.SubscCats = []string{"slice2"}
+		plmnData := entry.ServingPlmns["20893"]
+		plmnData.AmData.Nssai.DefaultSingleNssais = []models.Snssai{{Sst: 1}}
+		plmnData.SmfSelData.SubscribedSnssaiInfos = map[string]models.SnssaiInfo{
+			"01": {DnnInfos: []models.DnnInfo{{Dnn: "internet"}}},
+		}
+		plmnData.SmData[0].DnnConfigurations = map[string]models.DnnConfiguration{"internet": {}}
+		assert.NoError(mt, ProvisionSubscriberData(entry))
+		revisions(2)
+		// unchanged data is not written again
+		assert.NoError(mt, ProvisionSubscriberData(entry))
+		revisions(2)
+	})
+}
@@ -0,0 +1,327 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/omec-project/openapi/models"
+	udr_context "github.com/omec-project/udr/context"
+	"github.com/omec-project/udr/logger"
+	stats "github.com/omec-project/udr/metrics"
+	"github.com/omec-project/udr/producer/callback"
+	"github.com/omec-project/udr/util"
+	"github.com/omec-project/util/httpwrapper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VnGroupConfiguration is the 5GVnGroupConfiguration of TS 29.503, the
+// configuration of a 5G VN group used for 5G LAN-type services.
+type VnGroupConfiguration struct {
+	VnGroupData *VnGroupData `json:"5gVnGroupData,omitempty" bson:"5gVnGroupData"`
+	// GPSIs or SUPIs of the members
+	Members                 []string `json:"members,omitempty" bson:"members"`
+	ReferenceId             int32    `json:"referenceId,omitempty" bson:"referenceId"`
+	AfInstanceId            string   `json:"afInstanceId,omitempty" bson:"afInstanceId"`
+	InternalGroupIdentifier string   `json:"internalGroupIdentifier,omitempty" bson:"internalGroupIdentifier"`
+	MtcProviderInformation  string   `json:"mtcProviderInformation,omitempty" bson:"mtcProviderInformation"`
+}
+
+type VnGroupData struct {
+	PduSessionTypes []models.PduSessionType `json:"pduSessionTypes,omitempty" bson:"pduSessionTypes"`
+	Dnn             string                  `json:"dnn" bson:"dnn"`
+	SNssai          *models.Snssai          `json:"sNssai" bson:"sNssai"`
+	AppDescriptors  []AppDescriptor         `json:"appDescriptors,omitempty" bson:"appDescriptors"`
+	SecondaryAuth   bool                    `json:"secondaryAuth,omitempty" bson:"secondaryAuth"`
+}
+
+type AppDescriptor struct {
+	OsId  string `json:"osId,omitempty" bson:"osId"`
+	AppId string `json:"appId,omitempty" bson:"appId"`
+}
+
+// CreateVnGroupIndexes creates the indexes to look up the 5G VN groups by
+// their identifiers and by their members.
+func CreateVnGroupIndexes() {
+	collClient, ok := uncached(CommonDBClient).(collectionDBInterface)
+	if !ok {
+		return
+	}
+	indexes := []mongo.IndexModel{
+		{Keys: bson.D{{Key: "extGroupId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "internalGroupIdentifier", Value: 1}}},
+		{Keys: bson.D{{Key: "members", Value: 1}}},
+	}
+	_, err := collClient.GetCollection(SUBSCDATA_GROUPDATA_5GVNGROUPS).Indexes().CreateMany(context.Background(), indexes)
+	if err != nil {
+		logger.DataRepoLog.Warnf("create indexes of %s: %v", SUBSCDATA_GROUPDATA_5GVNGROUPS, err)
+	}
+}
+
+func validateVnGroupConfiguration(vnGroupConfiguration *VnGroupConfiguration) error {
+	vnGroupData := vnGroupConfiguration.VnGroupData
+	if vnGroupData == nil {
+		return fmt.Errorf("5gVnGroupData is missing")
+	}
+	if vnGroupData.Dnn == "" {
+		return fmt.Errorf("5gVnGroupData: dnn is missing")
+	}
+	if vnGroupData.SNssai == nil || vnGroupData.SNssai.Sst < 0 || vnGroupData.SNssai.Sst > 255 {
+		return fmt.Errorf("5gVnGroupData: invalid sNssai")
+	}
+	for _, pduSessionType := range vnGroupData.PduSessionTypes {
+		switch pduSessionType {
+		case models.PduSessionType_IPV4, models.PduSessionType_IPV6, models.PduSessionType_IPV4_V6,
+			models.PduSessionType_UNSTRUCTURED, models.PduSessionType_ETHERNET:
+		default:
+			return fmt.Errorf("5gVnGroupData: invalid pduSessionType %q", pduSessionType)
+		}
+	}
+	members := make(map[string]bool, len(vnGroupConfiguration.Members))
+	for _, member := range vnGroupConfiguration.Members {
+		if !isGpsiOrSupi(member) {
+			return fmt.Errorf("members: %q is neither a GPSI nor a SUPI", member)
+		}
+		if members[member] {
+			return fmt.Errorf("members: %q is listed twice", member)
+		}
+		members[member] = true
+	}
+	if internalGroupId := vnGroupConfiguration.InternalGroupIdentifier; internalGroupId != "" &&
+		!intGroupIdPattern.MatchString(internalGroupId) {
+		return fmt.Errorf("invalid internalGroupIdentifier %q", internalGroupId)
+	}
+	return nil
+}
+
+func isGpsiOrSupi(ueId string) bool {
+	for _, prefix := range []string{"msisdn-", "extid-", "imsi-", "nai-", "gci-", "gli-"} {
+		if strings.HasPrefix(ueId, prefix) && len(ueId) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func vnGroupUri(extGroupId string) string {
+	return fmt.Sprintf("%s/subscription-data/group-data/5g-vn-groups/%s",
+		udr_context.UDR_Self().GetIPv4GroupUri(udr_context.NUDR_DR), url.PathEscape(extGroupId))
+}
+
+func HandleQuery5GVnGroups(queryParams url.Values) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle Query5GVnGroups: queryParams=%#v", queryParams)
+
+	filter := bson.M{}
+	if ueIds := splitQueryValues(queryParams["ue-ids"]); len(ueIds) != 0 {
+		filter["members"] = bson.M{"$in": ueIds}
+	}
+	if internalGroupIds := splitQueryValues(queryParams["internal-group-ids"]); len(internalGroupIds) != 0 {
+		filter["internalGroupIdentifier"] = bson.M{"$in": internalGroupIds}
+	}
+	docs, err := CommonDBClient.RestfulAPIGetMany(SUBSCDATA_GROUPDATA_5GVNGROUPS, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("get", "5g-vn-groups", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	response := make(map[string]VnGroupConfiguration, len(docs))
+	for _, doc := range docs {
+		var vnGroupConfiguration VnGroupConfiguration
+		if err := json.Unmarshal(util.MapToByte(doc), &vnGroupConfiguration); err != nil {
+			logger.DataRepoLog.Warnf("5G VN group %v: %v", doc["extGroupId"], err)
+			pd := util.ProblemDetailsSystemFailure(fmt.Sprintf("malformed 5G VN group %v", doc["extGroupId"]))
+			stats.IncrementUdrSubscriptionDataStats("get", "5g-vn-groups", "FAILURE")
+			return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+		}
+		extGroupId, _ := doc["extGroupId"].(string)
+		response[extGroupId] = vnGroupConfiguration
+	}
+	stats.IncrementUdrSubscriptionDataStats("get", "5g-vn-groups", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, response)
+}
+
+func HandleGet5GVnGroup(extGroupId string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle Get5GVnGroup: extGroupId=%q", extGroupId)
+
+	data, problemDetails := getDataFromDB(SUBSCDATA_GROUPDATA_5GVNGROUPS, bson.M{"extGroupId": extGroupId})
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("get", "5g-vn-groups", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	delete(data, "extGroupId")
+	stats.IncrementUdrSubscriptionDataStats("get", "5g-vn-groups", "SUCCESS")
+	return httpwrapper.NewResponse(http.StatusOK, nil, data)
+}
+
+// HandleCreate5GVnGroup creates or replaces the 5G VN group extGroupId.
+func HandleCreate5GVnGroup(extGroupId string, vnGroupConfiguration *VnGroupConfiguration) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle Create5GVnGroup: extGroupId=%q", extGroupId)
+
+	if !extGroupIdPattern.MatchString(extGroupId) {
+		pd := util.ProblemDetailsMalformedReqSyntax(fmt.Sprintf("invalid extGroupId %q", extGroupId))
+		stats.IncrementUdrSubscriptionDataStats("create", "5g-vn-groups", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	if err := validateVnGroupConfiguration(vnGroupConfiguration); err != nil {
+		pd := util.ProblemDetailsMalformedReqSyntax(err.Error())
+		stats.IncrementUdrSubscriptionDataStats("create", "5g-vn-groups", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+
+	filter := bson.M{"extGroupId": extGroupId}
+	oldMembers, err := vnGroupMembers(filter)
+	if err != nil {
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("create", "5g-vn-groups", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	putData := util.ToBsonM(*vnGroupConfiguration)
+	putData["extGroupId"] = extGroupId
+	isExisted, err := CommonDBClient.RestfulAPIPutOne(SUBSCDATA_GROUPDATA_5GVNGROUPS, filter, putData)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("create", "5g-vn-groups", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	stats.IncrementUdrSubscriptionDataStats("create", "5g-vn-groups", "SUCCESS")
+	notifyVnGroupMembersChange(extGroupId, oldMembers, vnGroupConfiguration.Members)
+
+	if isExisted {
+		return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+	}
+	headers := http.Header{}
+	headers.Set("Location", vnGroupUri(extGroupId))
+	return httpwrapper.NewResponse(http.StatusCreated, headers, vnGroupConfiguration)
+}
+
+// HandleModify5GVnGroup applies the JSON merge patch in body to the 5G VN
+// group extGroupId.
+func HandleModify5GVnGroup(extGroupId string, body []byte) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle Modify5GVnGroup: extGroupId=%q", extGroupId)
+
+	filter := bson.M{"extGroupId": extGroupId}
+	oldMembers, err := vnGroupMembers(filter)
+	if err != nil {
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("update", "5g-vn-groups", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	var vnGroupConfiguration VnGroupConfiguration
+	problemDetails := mergePatchDocument(SUBSCDATA_GROUPDATA_5GVNGROUPS, filter, body, &vnGroupConfiguration,
+		func() error { return validateVnGroupConfiguration(&vnGroupConfiguration) })
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("update", "5g-vn-groups", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("update", "5g-vn-groups", "SUCCESS")
+	notifyVnGroupMembersChange(extGroupId, oldMembers, vnGroupConfiguration.Members)
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
+
+func HandleDelete5GVnGroup(extGroupId string) *httpwrapper.Response {
+	logger.DataRepoLog.Infof("handle Delete5GVnGroup: extGroupId=%q", extGroupId)
+
+	filter := bson.M{"extGroupId": extGroupId}
+	oldMembers, err := vnGroupMembers(filter)
+	if err != nil {
+		pd := dbProblemDetails(err)
+		stats.IncrementUdrSubscriptionDataStats("delete", "5g-vn-groups", "FAILURE")
+		return httpwrapper.NewResponse(int(pd.Status), nil, pd)
+	}
+	problemDetails := deleteExistingDocument(SUBSCDATA_GROUPDATA_5GVNGROUPS, filter)
+	if problemDetails != nil {
+		stats.IncrementUdrSubscriptionDataStats("delete", "5g-vn-groups", "FAILURE")
+		return httpwrapper.NewResponse(int(problemDetails.Status), nil, problemDetails)
+	}
+	stats.IncrementUdrSubscriptionDataStats("delete", "5g-vn-groups", "SUCCESS")
+	notifyVnGroupMembersChange(extGroupId, oldMembers, nil)
+	return httpwrapper.NewResponse(http.StatusNoContent, nil, map[string]interface{}{})
+}
+
+// vnGroupMembers returns the members of the 5G VN group matching filter,
+// none if there is no such group.
+func vnGroupMembers(filter bson.M) ([]string, error) {
+	doc, err := CommonDBClient.RestfulAPIGetOne(SUBSCDATA_GROUPDATA_5GVNGROUPS, filter)
+	if err != nil {
+		logger.DataRepoLog.Warnln(err)
+		return nil, err
+	}
+	var vnGroupConfiguration VnGroupConfiguration
+	if doc != nil {
+		if err := json.Unmarshal(util.MapToByte(doc), &vnGroupConfiguration); err != nil {
+			logger.DataRepoLog.Warnf("5G VN group %v: %v", doc["extGroupId"], err)
+		}
+	}
+	return vnGroupConfiguration.Members, nil
+}
+
+// notifyVnGroupMembersChange notifies the subscribers to the data of the
+// members added to or removed from the 5G VN group extGroupId.
+func notifyVnGroupMembersChange(extGroupId string, oldMembers []string, newMembers []string) {
+	added, removed := membersDiff(oldMembers, newMembers)
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+	go func() {
+		notifyItems := vnGroupMemberNotifyItems(extGroupId, added, removed)
+		for _, supi := range sortedKeys(notifyItems) {
+			callback.SendOnDataChangeNotify(supi, notifyItems[supi])
+		}
+	}()
+}
+
+// vnGroupMemberNotifyItems returns the notify items of the members added to
+// and removed from the 5G VN group extGroupId, keyed by the SUPI the
+// subscriptions are made for. The members are mostly GPSIs.
+func vnGroupMemberNotifyItems(extGroupId string, added []string, removed []string) map[string][]models.NotifyItem {
+	resourceId := vnGroupUri(extGroupId)
+	notifyItems := make(map[string][]models.NotifyItem)
+	addItem := func(member string, change models.ChangeItem) {
+		supi, err := ResolveUeId(member)
+		if err != nil {
+			logger.DataRepoLog.Warnf("notify 5G VN group %s member %s: %v", extGroupId, member, err)
+			return
+		}
+		notifyItems[supi] = append(notifyItems[supi], models.NotifyItem{
+			ResourceId: resourceId,
+			Changes:    []models.ChangeItem{change},
+		})
+	}
+	for _, member := range added {
+		addItem(member, models.ChangeItem{Op: models.ChangeType_ADD, Path: "/members/-", NewValue: member})
+	}
+	for _, member := range removed {
+		addItem(member, models.ChangeItem{Op: models.ChangeType_REMOVE, Path: "/members", OrigValue: member})
+	}
+	return notifyItems
+}
+
+func membersDiff(oldMembers []string, newMembers []string) (added []string, removed []string) {
+	old := make(map[string]bool, len(oldMembers))
+	for _, member := range oldMembers {
+		old[member] = true
+	}
+	current := make(map[string]bool, len(newMembers))
+	for _, member := range newMembers {
+		current[member] = true
+		if !old[member] {
+			added = append(added, member)
+		}
+	}
+	for _, member := range oldMembers {
+		if !current[member] {
+			removed = append(removed, member)
+		}
+	}
+	return added, removed
+}
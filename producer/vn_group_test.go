@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+ *  Tests for UDR 5G VN group data
+ */
+
+package producer
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/omec-project/openapi/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestValidateVnGroupConfiguration(t *testing.T) {
+	vnGroupConfiguration := &VnGroupConfiguration{
+		VnGroupData: &VnGroupData{
+			Dnn:             "lan",
+			SNssai:          &models.Snssai{Sst: 1},
+			PduSessionTypes: []models.PduSessionType{models.PduSessionType_ETHERNET},
+		},
+		Members: []string{"msisdn-1", "imsi-2"},
+	}
+	assert.NoError(t, validateVnGroupConfiguration(vnGroupConfiguration))
+
+	vnGroupConfiguration.Members = []string{"msisdn-1", "msisdn-1"}
+	assert.Error(t, validateVnGroupConfiguration(vnGroupConfiguration))
+	vnGroupConfiguration.Members = []string{"1"}
+	assert.Error(t, validateVnGroupConfiguration(vnGroupConfiguration))
+	vnGroupConfiguration.Members = nil
+	vnGroupConfiguration.VnGroupData.PduSessionTypes = []models.PduSessionType{"IPV5"}
+	assert.Error(t, validateVnGroupConfiguration(vnGroupConfiguration))
+	assert.Error(t, validateVnGroupConfiguration(&VnGroupConfiguration{}))
+}
+
+func TestMembersDiff(t *testing.T) {
+	added, removed := membersDiff([]string{"msisdn-1", "msisdn-2"}, []string{"msisdn-2", "imsi-3"})
+	assert.Equal(t, []string{"imsi-3"}, added)
+	assert.Equal(t, []string{"msisdn-1"}, removed)
+
+	added, removed = membersDiff([]string{"msisdn-1"}, []string{"msisdn-1"})
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+}
+
+func TestVnGroupMemberNotifyItems(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	CommonDBClient = &filteringDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_IDENTITYDATA: {{"ueId": "imsi-208930000000001", "gpsiList": []interface{}{"msisdn-33612345678"}}},
+	}}
+
+	// the subscriptions are made for the SUPI of the GPSI members
+	notifyItems := vnGroupMemberNotifyItems("extgroupid-vn@example.com",
+		[]string{"msisdn-33612345678"}, []string{"imsi-208930000000002"})
+	assert.Equal(t, []string{"imsi-208930000000001", "imsi-208930000000002"}, sortedKeys(notifyItems))
+	if items := notifyItems["imsi-208930000000001"]; assert.Len(t, items, 1) {
+		assert.Equal(t, models.ChangeItem{
+			Op: models.ChangeType_ADD, Path: "/members/-", NewValue: "msisdn-33612345678",
+		}, items[0].Changes[0])
+	}
+	if items := notifyItems["imsi-208930000000002"]; assert.Len(t, items, 1) {
+		assert.Equal(t, models.ChangeType_REMOVE, items[0].Changes[0].Op)
+	}
+}
+
+func TestQuery5GVnGroups(t *testing.T) {
+	defer func(db DBInterface) { CommonDBClient = db }(CommonDBClient)
+	db := &filterRecordingDB{fakeDB: fakeDB{docs: map[string][]map[string]interface{}{
+		SUBSCDATA_GROUPDATA_5GVNGROUPS: {{
+			"_id": "x", "extGroupId": "extgroupid-lan@example.com", "members": []string{"msisdn-1"},
+		}},
+	}}}
+	CommonDBClient = db
+
+	rsp := HandleQuery5GVnGroups(url.Values{"ue-ids": {"msisdn-1,imsi-1"}})
+	assert.Equal(t, http.StatusOK, rsp.Status)
+	assert.Equal(t, map[string]VnGroupConfiguration{
+		"extgroupid-lan@example.com": {Members: []string{"msisdn-1"}},
+	}, rsp.Body)
+	assert.Equal(t, []bson.M{{"members": bson.M{"$in": []string{"msisdn-1", "imsi-1"}}}}, db.filters)
+}
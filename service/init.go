@@ -8,8 +8,10 @@ package service
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -47,12 +49,37 @@ type (
 
 var config Config
 
-var udrCLi = []cli.Flag{
+var udrCLi = append([]cli.Flag{
 	&cli.StringFlag{
 		Name:     "cfg",
 		Usage:    "udr config file",
 		Required: true,
 	},
+}, overrideFlags()...)
+
+// overrideFlags returns one flag per configuration field, named after its
+// dotted YAML path (e.g. --configuration.sbi.port). A flag that is set takes
+// precedence over both the config file and the UDR_* environment variables.
+func overrideFlags() []cli.Flag {
+	var flags []cli.Flag
+	for _, key := range factory.OverrideKeys() {
+		flags = append(flags, &cli.StringFlag{
+			Name:  key,
+			Usage: fmt.Sprintf("override %s (env %s)", key, factory.EnvName(key)),
+		})
+	}
+	return flags
+}
+
+// flagOverrides returns the configuration overrides given on the command line.
+func flagOverrides(c *cli.Command) map[string]string {
+	values := make(map[string]string)
+	for _, key := range factory.OverrideKeys() {
+		if c.IsSet(key) {
+			values[key] = c.String(key)
+		}
+	}
+	return values
 }
 
 var (
@@ -75,7 +102,7 @@ func (udr *UDR) Initialize(c *cli.Command) error {
 		return err
 	}
 
-	if err := factory.InitConfigFactory(absPath); err != nil {
+	if err := factory.InitConfigFactoryWithOverrides(absPath, flagOverrides(c)); err != nil {
 		return err
 	}
 
@@ -83,12 +110,18 @@ func (udr *UDR) Initialize(c *cli.Command) error {
 
 	udr.setLogLevel()
 
+	logger.CfgLog.Infof("effective configuration:\n%s", factory.UdrConfig.Redacted())
+
+	if err := factory.UdrConfig.Validate(); err != nil {
+		return err
+	}
+
 	if err := factory.CheckConfigVersion(); err != nil {
 		return err
 	}
 
-	if os.Getenv("MANAGED_BY_CONFIG_POD") == "true" {
-		logger.InitLog.Infoln("MANAGED_BY_CONFIG_POD is true")
+	if factory.UdrConfig.Configuration.ManagedByConfigPod {
+		logger.InitLog.Infoln("managed by config pod")
 		go manageGrpcClient(factory.UdrConfig.Configuration.WebuiUri)
 	} else {
 		go func() {
@@ -100,6 +133,25 @@ func (udr *UDR) Initialize(c *cli.Command) error {
 	return nil
 }
 
+// ValidateConfig loads the configuration file given by --cfg and runs the
+// same checks as Initialize without connecting to any peer.
+func (udr *UDR) ValidateConfig(c *cli.Command) error {
+	absPath, err := filepath.Abs(c.String("cfg"))
+	if err != nil {
+		return err
+	}
+
+	if err := factory.InitConfigFactoryWithOverrides(absPath, flagOverrides(c)); err != nil {
+		return err
+	}
+
+	if err := factory.UdrConfig.Validate(); err != nil {
+		return err
+	}
+
+	return factory.CheckConfigVersion()
+}
+
 // manageGrpcClient connects the config pod GRPC server and subscribes the config changes.
 // Then it updates UDR configuration.
 func manageGrpcClient(webuiUri string) {
@@ -213,6 +265,45 @@ func (udr *UDR) Start() {
 
 	// Connect to MongoDB
 	producer.ConnectMongo(mongodb.Url, mongodb.Name, mongodb.AuthUrl, mongodb.AuthKeysDbName)
+	producer.CreateGroupDataIndexes()
+	producer.CreateVnGroupIndexes()
+	producer.CreateIdentityDataIndexes()
+	producer.CreateSharedDataIndexes()
+	if config.Configuration.ManagedByConfigPod {
+		slices, err := producer.AppliedSlices()
+		if err != nil {
+			logger.InitLog.Errorf("restore applied network slices: %+v", err)
+		}
+		factory.RestoreAppliedSlices(slices)
+	}
+	if cache := config.Configuration.Cache; cache != nil && cache.Enable {
+		producer.EnableDBCache(cache.GetMaxEntries(), cache.GetTtl(), cache.ChangeStreams)
+	}
+	if config.Configuration.NotifyOnDbChanges {
+		producer.StartChangeWatcher()
+	}
+	producer.StartSubscriptionReaper()
+	if config.Configuration.DisableSchemaValidation {
+		producer.DisableSchemaValidation()
+	}
+	if history := config.Configuration.DocumentHistory; history != nil && history.Enable {
+		producer.CreateDocumentHistoryIndexes(history.GetMaxAge())
+		producer.EnableDocumentHistory(history.GetMaxVersions())
+		if history.EnableRestore {
+			producer.EnableDocumentRestore()
+		}
+	}
+	if registrations := config.Configuration.Registrations; registrations != nil {
+		if registrations.AmfHistory {
+			producer.EnableAmfRegistrationHistory()
+		}
+		if maxAge := registrations.GetSmfMaxAge(); maxAge > 0 {
+			producer.StartSmfRegistrationReaper(maxAge)
+		}
+	}
+	if config.Configuration.AdminAPIEnabled() {
+		go serveAdminAPI(config.Configuration.GetAdminAddr())
+	}
 	logger.InitLog.Infoln("server started")
 
 	router := utilLogger.NewGinWithZap(logger.GinLog)
@@ -264,6 +355,17 @@ func (udr *UDR) Start() {
 	}
 }
 
+// serveAdminAPI serves the admin API on addr, apart from the SBI.
+func serveAdminAPI(addr string) {
+	router := utilLogger.NewGinWithZap(logger.GinLog)
+	datarepository.AddAdminService(router)
+	server := &http.Server{Addr: addr, Handler: router, ReadHeaderTimeout: 10 * time.Second}
+	logger.InitLog.Infof("admin API listening on %s", addr)
+	if err := server.ListenAndServe(); err != nil {
+		logger.InitLog.Errorf("admin API on %s: %+v", addr, err)
+	}
+}
+
 func (udr *UDR) Exec(c *cli.Command) error {
 	// UDR.Initialize(cfgPath, c)
 	logger.InitLog.Debugln("args:", c.String("cfg"))
@@ -332,17 +434,113 @@ func (udr *UDR) Terminate() {
 	logger.InitLog.Infoln("UDR terminated")
 }
 
+const (
+	configUpdateDbBatchSize    = 500
+	configUpdateDbBatchTimeout = 100 * time.Millisecond
+)
+
+// configUpdateDb applies config pod driven DB updates in batches of up to
+// configUpdateDbBatchSize, waiting at most configUpdateDbBatchTimeout to fill one.
 func (udr *UDR) configUpdateDb() {
 	for msg := range factory.ConfigUpdateDbTrigger {
-		logger.InitLog.Infoln("config update DB trigger")
-		err := producer.AddEntrySmPolicyTable(
-			msg.SmPolicyTable.Imsi,
-			msg.SmPolicyTable.Dnn,
-			msg.SmPolicyTable.Snssai)
-		if err == nil {
-			logger.InitLog.Infoln("added entry to sm policy table success")
+		batch := []*factory.UpdateDb{msg}
+		timeout := time.After(configUpdateDbBatchTimeout)
+	collect:
+		for len(batch) < configUpdateDbBatchSize {
+			select {
+			case msg, ok := <-factory.ConfigUpdateDbTrigger:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, msg)
+			case <-timeout:
+				break collect
+			}
+		}
+		metrics.SetUdrConfigDbUpdatePending(len(factory.ConfigUpdateDbTrigger) + len(batch))
+		logger.InitLog.Infof("config update DB trigger, batch of %d update(s)", len(batch))
+		udr.configUpdateDbBatch(batch)
+		metrics.SetUdrConfigDbUpdatePending(len(factory.ConfigUpdateDbTrigger))
+	}
+}
+
+func (udr *UDR) configUpdateDbBatch(batch []*factory.UpdateDb) {
+	var smPolicyUpdates []producer.SmPolicyUpdate
+	// subscriber data entries carry the full state of an IMSI, so only the
+	// latest one per IMSI needs to be written
+	subscriberData := make(map[string]*factory.UpdateDb)
+	var imsis []string
+	var appliedSlices []*factory.UpdateDb
+	for _, msg := range batch {
+		if msg.SmPolicyTable != nil {
+			smPolicyUpdates = append(smPolicyUpdates, producer.SmPolicyUpdate{
+				Imsi:   msg.SmPolicyTable.Imsi,
+				Dnn:    msg.SmPolicyTable.Dnn,
+				Snssai: msg.SmPolicyTable.Snssai,
+				Remove: msg.Operation == factory.DbOperationRemove,
+			})
+		}
+		if msg.SubscriberData != nil {
+			if _, ok := subscriberData[msg.SubscriberData.Imsi]; !ok {
+				imsis = append(imsis, msg.SubscriberData.Imsi)
+			}
+			subscriberData[msg.SubscriberData.Imsi] = msg
+		}
+		if msg.AppliedSlice != nil {
+			appliedSlices = append(appliedSlices, msg)
+		}
+	}
+
+	if len(smPolicyUpdates) > 0 {
+		applied, err := producer.ApplySmPolicyUpdates(smPolicyUpdates)
+		metrics.AddUdrConfigDbUpdateStats("sm-policy-data", "applied", applied)
+		if err != nil {
+			var batchErr *producer.SmPolicyBatchError
+			failed := len(smPolicyUpdates)
+			if errors.As(err, &batchErr) {
+				failed = len(batchErr.Failed)
+			}
+			metrics.AddUdrConfigDbUpdateStats("sm-policy-data", "failed", failed)
+			logger.InitLog.Errorf("sm policy batch: %d UE(s) updated, %v", applied, err)
 		} else {
-			logger.InitLog.Errorf("entry add failed %+v", err)
+			logger.InitLog.Infof("sm policy batch: %d UE(s) updated", applied)
+		}
+	}
+
+	var applied, failed int
+	for _, imsi := range imsis {
+		msg := subscriberData[imsi]
+		var err error
+		switch msg.Operation {
+		case factory.DbOperationAdd:
+			err = producer.ProvisionSubscriberData(msg.SubscriberData)
+		case factory.DbOperationRemove:
+			err = producer.RemoveSubscriberData(imsi)
+		}
+		if err != nil {
+			failed++
+			logger.InitLog.Errorf("subscriber data update failed %+v", err)
+		} else {
+			applied++
+		}
+	}
+	if len(imsis) > 0 {
+		metrics.AddUdrConfigDbUpdateStats("subscriber-data", "applied", applied)
+		metrics.AddUdrConfigDbUpdateStats("subscriber-data", "failed", failed)
+		logger.InitLog.Infof("subscriber data batch: %d IMSI(s) updated, %d failed", applied, failed)
+	}
+
+	// the slices are recorded after their data, see factory.RestoreAppliedSlices
+	for _, msg := range appliedSlices {
+		var err error
+		switch msg.Operation {
+		case factory.DbOperationAdd:
+			err = producer.SaveAppliedSlice(msg.AppliedSlice)
+		case factory.DbOperationRemove:
+			err = producer.RemoveAppliedSlice(msg.AppliedSlice.Name)
+		}
+		if err != nil {
+			logger.InitLog.Errorf("recording network slice %s failed %+v", msg.AppliedSlice.Name, err)
 		}
 	}
 }
@@ -24,6 +24,15 @@ func main() {
 	app.UsageText = "udr -cfg <udr_config_file.conf>"
 	app.Action = action
 	app.Flags = UDR.GetCliCmd()
+	app.Commands = []*cli.Command{
+		{
+			Name:      "validate",
+			Usage:     "Validate the UDR configuration file and exit",
+			UsageText: "udr validate --cfg <udr_config_file.conf>",
+			Flags:     UDR.GetCliCmd(),
+			Action:    validate,
+		},
+	}
 	if err := app.Run(context.Background(), os.Args); err != nil {
 		logger.AppLog.Fatalf("UDR run error: %v", err)
 	}
@@ -39,3 +48,13 @@ func action(ctx context.Context, c *cli.Command) error {
 
 	return nil
 }
+
+func validate(ctx context.Context, c *cli.Command) error {
+	if err := UDR.ValidateConfig(c); err != nil {
+		logger.CfgLog.Errorf("%+v", err)
+		return fmt.Errorf("configuration is invalid")
+	}
+
+	logger.CfgLog.Infof("configuration %s is valid", c.String("cfg"))
+	return nil
+}
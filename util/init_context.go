@@ -42,7 +42,8 @@ func InitUdrContext(context *context.UDRContext) {
 
 		context.BindingIPv4 = os.Getenv(sbi.BindingIPv4)
 		if context.BindingIPv4 != "" {
-			logger.UtilLog.Infoln("parsing ServerIPv4 address from ENV variable")
+			logger.UtilLog.Warnf("parsing ServerIPv4 address from ENV variable %s is deprecated, use %s instead",
+				sbi.BindingIPv4, factory.EnvName("configuration.sbi.bindingIPv4"))
 		} else {
 			context.BindingIPv4 = sbi.BindingIPv4
 			if context.BindingIPv4 == "" {
@@ -59,8 +59,63 @@ func ProblemDetailsModifyNotAllowed(detail string) *models.ProblemDetails {
 func ProblemDetailsUnspecified(detail string) *models.ProblemDetails {
 	return &models.ProblemDetails{
 		Title:  "Unspecified",
-		Status: http.StatusForbidden,
-		Cause:  "UNSPECIFIED",
+		Status: http.StatusInternalServerError,
+		Cause:  "UNSPECIFIED_NF_FAILURE",
+		Detail: detail,
+	}
+}
+
+func ProblemDetailsInvalidData(detail string) *models.ProblemDetails {
+	return &models.ProblemDetails{
+		Title:  "Invalid data",
+		Status: http.StatusBadRequest,
+		Cause:  "MANDATORY_IE_INCORRECT",
+		Detail: detail,
+	}
+}
+
+// ProblemDetailsConflict has no cause, TS 29.500 defines none for 409.
+func ProblemDetailsConflict(detail string) *models.ProblemDetails {
+	return &models.ProblemDetails{
+		Title:  "Conflict",
+		Status: http.StatusConflict,
+		Detail: detail,
+	}
+}
+
+func ProblemDetailsUnavailable(detail string) *models.ProblemDetails {
+	return &models.ProblemDetails{
+		Title:  "Service unavailable",
+		Status: http.StatusServiceUnavailable,
+		Cause:  "NF_CONGESTION",
+		Detail: detail,
+	}
+}
+
+func ProblemDetailsTimeout(detail string) *models.ProblemDetails {
+	return &models.ProblemDetails{
+		Title:  "Timed out",
+		Status: http.StatusGatewayTimeout,
+		Cause:  "TIMED_OUT_REQUEST",
+		Detail: detail,
+	}
+}
+
+func ProblemDetailsInvalidParams(detail string, invalidParams []models.InvalidParam) *models.ProblemDetails {
+	return &models.ProblemDetails{
+		Title:         "Invalid message format",
+		Status:        http.StatusBadRequest,
+		Cause:         "INVALID_MSG_FORMAT",
+		Detail:        detail,
+		InvalidParams: invalidParams,
+	}
+}
+
+func ProblemDetailsPreconditionFailed(detail string) *models.ProblemDetails {
+	return &models.ProblemDetails{
+		Title:  "Precondition failed",
+		Status: http.StatusPreconditionFailed,
+		Cause:  "PRECONDITION_FAILED",
 		Detail: detail,
 	}
 }